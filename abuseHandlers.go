@@ -0,0 +1,377 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/auth"
+	"github.com/fkl13/chirpy/internal/captcha"
+	"github.com/fkl13/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+var abuseReasons = map[string]bool{
+	"spam":          true,
+	"harassment":    true,
+	"illegal":       true,
+	"impersonation": true,
+	"copyright":     true,
+	"other":         true,
+}
+
+// Outcomes of a resolved abuse report.
+const (
+	reportActioned      = "actioned"
+	reportDismissed     = "dismissed"
+	reportAutoDismissed = "auto_dismissed"
+)
+
+const (
+	// reputationMinReports is how many of a reporter's reports moderators
+	// have to decide before their accuracy can count against them.
+	reputationMinReports = 5
+	// reputationBadFaith is the accuracy below which new reports are
+	// dismissed without reaching the moderation queue.
+	reputationBadFaith = 0.2
+)
+
+// reporterReputation summarizes how moderators decided a signed-in
+// reporter's earlier reports. Automatic dismissals don't count, so a
+// reporter can't sink further on reports no one looked at.
+type reporterReputation struct {
+	Actioned  int64 `json:"actioned"`
+	Dismissed int64 `json:"dismissed"`
+	// Weight is the smoothed share of actioned reports: 0.5 without
+	// history, towards 1 for reliable reporters and 0 for bad-faith ones.
+	Weight   float64 `json:"weight"`
+	BadFaith bool    `json:"bad_faith"`
+}
+
+func newReporterReputation(actioned, dismissed int64) reporterReputation {
+	decided := actioned + dismissed
+	return reporterReputation{
+		Actioned:  actioned,
+		Dismissed: dismissed,
+		Weight:    float64(actioned+1) / float64(decided+2),
+		BadFaith:  decided >= reputationMinReports && float64(actioned)/float64(decided) < reputationBadFaith,
+	}
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// securityTxtHandler serves RFC 9116 security.txt. It's generated on every
+// request so Expires stays in the future without redeploying.
+func (cfg *apiConfig) securityTxtHandler(w http.ResponseWriter, r *http.Request) {
+	if len(cfg.securityContacts) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	var b strings.Builder
+	for _, contact := range cfg.securityContacts {
+		fmt.Fprintf(&b, "Contact: %s\n", contact)
+	}
+	fmt.Fprintf(&b, "Expires: %s\n", time.Now().UTC().AddDate(0, 0, 30).Truncate(24*time.Hour).Format(time.RFC3339))
+	if cfg.securityPolicyURL != "" {
+		fmt.Fprintf(&b, "Policy: %s\n", cfg.securityPolicyURL)
+	}
+	fmt.Fprintf(&b, "Preferred-Languages: en\n")
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Write([]byte(b.String()))
+}
+
+// reportedChirpID finds the chirp a reported URL points at, if any. Both
+// API URLs (/api/v1/chirps/{id}) and embed pages (/embed/chirp/{id}) work.
+func (cfg *apiConfig) reportedChirpID(rawURL string) (uuid.UUID, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := 0; i+1 < len(segments); i++ {
+		if segments[i] != "chirps" && segments[i] != "chirp" {
+			continue
+		}
+		id, err := cfg.parseID(segments[i+1])
+		if err == nil {
+			return id, true
+		}
+	}
+	return uuid.Nil, false
+}
+
+// createAbuseReportHandler lets anyone report content without an account.
+// Reports need a solved captcha and are limited per user when the reporter
+// is signed in, or more strictly per client IP when they aren't; they end
+// up in the moderation queue at /admin/abuse-reports. Reports of signed-in
+// reporters with a bad-faith reputation are accepted but dismissed right
+// away.
+func (cfg *apiConfig) createAbuseReportHandler(w http.ResponseWriter, r *http.Request) {
+	const maxURLLength = 2048
+	const maxDetailsLength = 2000
+
+	type parameters struct {
+		URL          string `json:"url"`
+		Reason       string `json:"reason"`
+		Details      string `json:"details"`
+		ContactEmail string `json:"contact_email"`
+		CaptchaToken string `json:"captcha_token"`
+	}
+	type response struct {
+		ID        uuid.UUID `json:"id"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	if cfg.captcha == nil {
+		respondWithError(w, http.StatusNotImplemented, "Abuse reporting isn't enabled", nil)
+		return
+	}
+
+	limiter, limitKey := cfg.anonymousAbuseLimiter, cfg.clientIP(r)
+	reporterID := uuid.NullUUID{}
+	if r.Header.Get("Authorization") != "" {
+		token, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+			return
+		}
+		userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+			return
+		}
+		reporterID = uuid.NullUUID{UUID: userId, Valid: true}
+		limiter, limitKey = cfg.abuseLimiter, "user:"+userId.String()
+	}
+	if limiter.Enabled() {
+		result := limiter.Allow(limitKey)
+		if !result.Allowed {
+			resetIn := max(int(time.Until(result.Reset).Round(time.Second).Seconds()), 0)
+			w.Header().Set("Retry-After", strconv.Itoa(resetIn))
+			respondWithError(w, http.StatusTooManyRequests, "Too many reports", nil)
+			return
+		}
+	}
+
+	params := parameters{}
+	err := decodeJSONBody(w, r, &params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	err = cfg.captcha.Verify(r.Context(), params.CaptchaToken, cfg.clientIP(r))
+	if errors.Is(err, captcha.ErrFailed) {
+		respondWithError(w, http.StatusForbidden, "Captcha verification failed", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadGateway, "Couldn't verify captcha", err)
+		return
+	}
+
+	params.URL = strings.TrimSpace(params.URL)
+	if params.URL == "" || len(params.URL) > maxURLLength {
+		respondWithError(w, http.StatusBadRequest, "A URL is required", nil)
+		return
+	}
+	if !abuseReasons[params.Reason] {
+		respondWithError(w, http.StatusBadRequest, "Unknown reason", nil)
+		return
+	}
+	if len(params.Details) > maxDetailsLength {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Details can be at most %d characters", maxDetailsLength), nil)
+		return
+	}
+
+	chirpID := uuid.NullUUID{}
+	if id, ok := cfg.reportedChirpID(params.URL); ok {
+		if _, err := cfg.getChirp(r.Context(), id); err == nil {
+			chirpID = uuid.NullUUID{UUID: id, Valid: true}
+		}
+	}
+
+	outcome := sql.NullString{}
+	if reporterID.Valid {
+		stats, err := cfg.dbQueries.GetReporterStats(r.Context(), reporterID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't get reporter reputation", err)
+			return
+		}
+		if newReporterReputation(stats.Actioned, stats.Dismissed).BadFaith {
+			outcome = sql.NullString{String: reportAutoDismissed, Valid: true}
+		}
+	}
+
+	report, err := cfg.dbQueries.CreateAbuseReport(r.Context(), database.CreateAbuseReportParams{
+		Url:          params.URL,
+		Reason:       params.Reason,
+		Details:      params.Details,
+		ContactEmail: strings.TrimSpace(params.ContactEmail),
+		ChirpID:      chirpID,
+		ReporterID:   reporterID,
+		Outcome:      outcome,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't store report", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, response{
+		ID:        report.ID,
+		CreatedAt: report.CreatedAt,
+	})
+}
+
+type abuseReport struct {
+	ID           uuid.UUID  `json:"id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	URL          string     `json:"url"`
+	Reason       string     `json:"reason"`
+	Details      string     `json:"details"`
+	ContactEmail string     `json:"contact_email,omitempty"`
+	ChirpID      *uuid.UUID `json:"chirp_id,omitempty"`
+	ResolvedAt   *time.Time `json:"resolved_at,omitempty"`
+	Outcome      string     `json:"outcome,omitempty"`
+	ReporterID   *uuid.UUID `json:"reporter_id,omitempty"`
+	// Reporter is only set on open reports of signed-in reporters.
+	Reporter *reporterReputation `json:"reporter,omitempty"`
+}
+
+func newAbuseReport(report database.AbuseReport) abuseReport {
+	res := abuseReport{
+		ID:           report.ID,
+		CreatedAt:    report.CreatedAt,
+		URL:          report.Url,
+		Reason:       report.Reason,
+		Details:      report.Details,
+		ContactEmail: report.ContactEmail,
+	}
+	if report.ChirpID.Valid {
+		res.ChirpID = &report.ChirpID.UUID
+	}
+	if report.ResolvedAt.Valid {
+		res.ResolvedAt = &report.ResolvedAt.Time
+	}
+	if report.Outcome.Valid {
+		res.Outcome = report.Outcome.String
+	}
+	if report.ReporterID.Valid {
+		res.ReporterID = &report.ReporterID.UUID
+	}
+	return res
+}
+
+// getAbuseReportsHandler lists open reports, oldest first. With
+// ?sort=weight reports of the most reliable reporters come first.
+func (cfg *apiConfig) getAbuseReportsHandler(w http.ResponseWriter, r *http.Request) {
+	const maxReports = 100
+
+	sortBy := r.URL.Query().Get("sort")
+	if sortBy != "" && sortBy != "weight" {
+		respondWithError(w, http.StatusBadRequest, "Invalid sort", nil)
+		return
+	}
+
+	reports, err := cfg.dbQueries.GetOpenAbuseReports(r.Context(), maxReports)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get reports", err)
+		return
+	}
+	res := make([]abuseReport, 0, len(reports))
+	for _, report := range reports {
+		item := newAbuseReport(database.AbuseReport{
+			ID:           report.ID,
+			CreatedAt:    report.CreatedAt,
+			Url:          report.Url,
+			Reason:       report.Reason,
+			Details:      report.Details,
+			ContactEmail: report.ContactEmail,
+			ChirpID:      report.ChirpID,
+			ResolvedAt:   report.ResolvedAt,
+			ReporterID:   report.ReporterID,
+			Outcome:      report.Outcome,
+		})
+		if report.ReporterID.Valid {
+			reputation := newReporterReputation(report.ReporterActioned, report.ReporterDismissed)
+			item.Reporter = &reputation
+		}
+		res = append(res, item)
+	}
+	if sortBy == "weight" {
+		sort.SliceStable(res, func(i, j int) bool {
+			return res[i].weight() > res[j].weight()
+		})
+	}
+	respondWithList(w, http.StatusOK, res, cfg.wantsEnvelope(r))
+}
+
+// weight ranks anonymous reports like those of reporters without history.
+func (report abuseReport) weight() float64 {
+	if report.Reporter == nil {
+		return newReporterReputation(0, 0).Weight
+	}
+	return report.Reporter.Weight
+}
+
+// resolveAbuseReportHandler closes a report as actioned or dismissed; the
+// outcome feeds the reporter's reputation. Without a body the report counts
+// as actioned. Automatically dismissed reports can be resolved again to
+// overturn the dismissal.
+func (cfg *apiConfig) resolveAbuseReportHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Outcome string `json:"outcome"`
+	}
+
+	reportId, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Report not found", err)
+		return
+	}
+	params := parameters{}
+	err = decodeJSONBody(w, r, &params)
+	if err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	if params.Outcome == "" {
+		params.Outcome = reportActioned
+	}
+	if params.Outcome != reportActioned && params.Outcome != reportDismissed {
+		respondWithError(w, http.StatusBadRequest, "Outcome must be actioned or dismissed", nil)
+		return
+	}
+
+	report, err := cfg.dbQueries.ResolveAbuseReport(r.Context(), database.ResolveAbuseReportParams{
+		ID:      reportId,
+		Outcome: sql.NullString{String: params.Outcome, Valid: true},
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "Report not found", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't resolve report", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newAbuseReport(report))
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/fkl13/chirpy/internal/auth"
+	"github.com/fkl13/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// deleteUserHandler deletes the caller's account right away, unlike
+// deactivation which can be undone. The password has to be sent again.
+// Chirps, tokens, likes, follows and the rest go with the user through the
+// foreign keys; counts on other users' chirps are corrected first.
+func (cfg *apiConfig) deleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Password string `json:"password"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	params := parameters{}
+	err = decodeJSONBody(w, r, &params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	user, err := cfg.getUser(r.Context(), userId)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+	err = auth.CheckPasswordHash(params.Password, user.HashedPassword)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Incorrect password", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	err = deleteUser(r.Context(), qtx, userId)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete user", err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete user", err)
+		return
+	}
+	cfg.userCache.Invalidate(userId)
+	cfg.chirpCache.Clear()
+	cfg.wakeOutboxRelay()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteUser removes a user and everything they made. It runs in a
+// transaction and records a chirp.deleted event for each of their chirps so
+// they leave the search index too.
+func deleteUser(ctx context.Context, q *database.Queries, userId uuid.UUID) error {
+	chirpIds, err := q.GetUserChirpIDs(ctx, userId)
+	if err != nil {
+		return err
+	}
+	for _, id := range chirpIds {
+		err = addOutboxEvent(ctx, q, eventChirpDeleted, chirpDeletedEvent{ID: id, UserId: userId})
+		if err != nil {
+			return err
+		}
+	}
+	_, err = q.DeleteUserLikes(ctx, userId)
+	if err != nil {
+		return err
+	}
+	_, err = q.DeleteUserRechirps(ctx, userId)
+	if err != nil {
+		return err
+	}
+	_, err = q.UncountUserReplies(ctx, userId)
+	if err != nil {
+		return err
+	}
+	_, err = q.DeleteUser(ctx, userId)
+	return err
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/fkl13/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const auditUserMerged = "user.merged"
+
+// mergeCounts says how many rows a merge moved to the primary account.
+// Likes, rechirps, bookmarks, follows, blocks and mutes both accounts had
+// are kept once, and follows, blocks and mutes between the two accounts are
+// dropped; those rows of the duplicate are counted in Duplicates.
+type mergeCounts struct {
+	Chirps     int64 `json:"chirps"`
+	Likes      int64 `json:"likes"`
+	Rechirps   int64 `json:"rechirps"`
+	Bookmarks  int64 `json:"bookmarks"`
+	Follows    int64 `json:"follows"`
+	Blocks     int64 `json:"blocks"`
+	Mutes      int64 `json:"mutes"`
+	Duplicates int64 `json:"duplicates"`
+}
+
+// mergeUsers moves everything the duplicate account owns to the primary
+// one and revokes the duplicate's sessions. It runs in the caller's
+// transaction.
+func mergeUsers(ctx context.Context, q *database.Queries, from, into uuid.UUID) (mergeCounts, error) {
+	var counts mergeCounts
+	var err error
+	steps := []struct {
+		count *int64
+		merge func() (int64, error)
+	}{
+		{&counts.Chirps, func() (int64, error) {
+			return q.MergeUserChirps(ctx, database.MergeUserChirpsParams{IntoUserID: into, FromUserID: from})
+		}},
+		{&counts.Likes, func() (int64, error) {
+			return q.MergeUserLikes(ctx, database.MergeUserLikesParams{IntoUserID: into, FromUserID: from})
+		}},
+		{&counts.Rechirps, func() (int64, error) {
+			return q.MergeUserRechirps(ctx, database.MergeUserRechirpsParams{IntoUserID: into, FromUserID: from})
+		}},
+		{&counts.Bookmarks, func() (int64, error) {
+			return q.MergeUserBookmarks(ctx, database.MergeUserBookmarksParams{IntoUserID: into, FromUserID: from})
+		}},
+		{&counts.Follows, func() (int64, error) {
+			return q.MergeUserFollowing(ctx, database.MergeUserFollowingParams{IntoUserID: into, FromUserID: from})
+		}},
+		{&counts.Follows, func() (int64, error) {
+			return q.MergeUserFollowers(ctx, database.MergeUserFollowersParams{IntoUserID: into, FromUserID: from})
+		}},
+		{&counts.Blocks, func() (int64, error) {
+			return q.MergeUserBlocking(ctx, database.MergeUserBlockingParams{IntoUserID: into, FromUserID: from})
+		}},
+		{&counts.Blocks, func() (int64, error) {
+			return q.MergeUserBlockers(ctx, database.MergeUserBlockersParams{IntoUserID: into, FromUserID: from})
+		}},
+		{&counts.Mutes, func() (int64, error) {
+			return q.MergeUserMuting(ctx, database.MergeUserMutingParams{IntoUserID: into, FromUserID: from})
+		}},
+		{&counts.Mutes, func() (int64, error) {
+			return q.MergeUserMuters(ctx, database.MergeUserMutersParams{IntoUserID: into, FromUserID: from})
+		}},
+	}
+	for _, step := range steps {
+		n, err := step.merge()
+		if err != nil {
+			return mergeCounts{}, err
+		}
+		*step.count += n
+	}
+
+	// What's left are rows the primary account already had and follows,
+	// blocks and mutes between the two accounts.
+	for _, drop := range []func(context.Context, uuid.UUID) (int64, error){
+		q.DeleteDuplicateLikes,
+		q.DeleteDuplicateRechirps,
+		q.DeleteDuplicateBookmarks,
+		q.DeleteDuplicateFollows,
+		q.DeleteDuplicateBlocks,
+		q.DeleteDuplicateMutes,
+	} {
+		n, err := drop(ctx, from)
+		if err != nil {
+			return mergeCounts{}, err
+		}
+		counts.Duplicates += n
+	}
+
+	// A block one account had can now cover a follow the other had.
+	_, err = q.DeleteBlockedFollows(ctx, into)
+	if err != nil {
+		return mergeCounts{}, err
+	}
+
+	// Whoever is logged in to the duplicate shouldn't end up in the primary
+	// account.
+	err = q.RevokeAllUserTokens(ctx, from)
+	if err != nil {
+		return mergeCounts{}, err
+	}
+	return counts, nil
+}
+
+// mergeUsersHandler lets support staff fold a duplicate account into the
+// user's primary one. With dry_run set it reports what would move without
+// changing anything. The duplicate account itself is left in place, empty.
+func (cfg *apiConfig) mergeUsersHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Into   string `json:"into"`
+		DryRun bool   `json:"dry_run"`
+		Reason string `json:"reason"`
+	}
+	type response struct {
+		From   uuid.UUID   `json:"from"`
+		Into   uuid.UUID   `json:"into"`
+		DryRun bool        `json:"dry_run"`
+		Moved  mergeCounts `json:"moved"`
+	}
+
+	fromId, err := cfg.parseID(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+
+	params := parameters{}
+	err = decodeJSONBody(w, r, &params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	intoId, err := cfg.parseID(params.Into)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "into must be a user ID", err)
+		return
+	}
+	if intoId == fromId {
+		respondWithError(w, http.StatusBadRequest, "Can't merge an account into itself", nil)
+		return
+	}
+	params.Reason = strings.TrimSpace(params.Reason)
+	if params.Reason == "" && !params.DryRun {
+		respondWithError(w, http.StatusBadRequest, "A reason is required", nil)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	for _, id := range []uuid.UUID{fromId, intoId} {
+		_, err = qtx.GetUser(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				respondWithError(w, http.StatusNotFound, "Couldn't find user "+id.String(), err)
+				return
+			}
+			respondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+			return
+		}
+	}
+
+	counts, err := mergeUsers(r.Context(), qtx, fromId, intoId)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't merge accounts", err)
+		return
+	}
+	res := response{From: fromId, Into: intoId, DryRun: params.DryRun, Moved: counts}
+	if params.DryRun {
+		// The deferred rollback undoes the merge.
+		respondWithJSON(w, http.StatusOK, res)
+		return
+	}
+
+	err = addAuditLogEntry(r.Context(), qtx, adminActor(r), auditUserMerged, fromId, "merged into "+intoId.String()+": "+params.Reason)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't write audit log", err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't merge accounts", err)
+		return
+	}
+	// Chirps changed authors and counts changed, so cached chirps are stale.
+	cfg.chirpCache.Clear()
+
+	respondWithJSON(w, http.StatusOK, res)
+}
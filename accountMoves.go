@@ -0,0 +1,169 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/fkl13/chirpy/internal/auth"
+	"github.com/fkl13/chirpy/internal/database"
+	"github.com/fkl13/chirpy/internal/validate"
+	"github.com/google/uuid"
+)
+
+// normalizeMoveTarget accepts the account a user moved to as a handle
+// (user@example.com, with or without a leading @) or an https profile URL.
+func normalizeMoveTarget(target string) (string, bool) {
+	target = strings.TrimSpace(target)
+	if strings.HasPrefix(target, "https://") {
+		u, err := url.Parse(target)
+		if err != nil || u.Host == "" || u.User != nil {
+			return "", false
+		}
+		return u.String(), true
+	}
+	name, domain, ok := strings.Cut(strings.TrimPrefix(target, "@"), "@")
+	if !ok || name == "" || !strings.Contains(domain, ".") || strings.ContainsAny(name+domain, " /@") {
+		return "", false
+	}
+	return name + "@" + strings.ToLower(domain), true
+}
+
+// cannotPost returns why the user may not post, or "" if they may.
+// Accounts need to be active and, when email verification is on, have a
+// verified email address. Accounts that moved elsewhere are frozen until
+// the move is undone.
+func (cfg *apiConfig) cannotPost(user database.User) string {
+	if user.DeactivatedAt.Valid {
+		return "This account is deactivated"
+	}
+	if user.MovedTo.Valid {
+		return "This account moved to " + user.MovedTo.String
+	}
+	if cfg.publicURL != "" && !user.EmailVerifiedAt.Valid {
+		return "Confirm your email address before posting"
+	}
+	return ""
+}
+
+// checkCanPost reports whether the user may post. If not, it has already
+// written the response.
+func (cfg *apiConfig) checkCanPost(w http.ResponseWriter, r *http.Request, userID uuid.UUID) bool {
+	user, err := cfg.getUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+		return false
+	}
+	if reason := cfg.cannotPost(user); reason != "" {
+		respondWithError(w, http.StatusForbidden, reason, nil)
+		return false
+	}
+	return true
+}
+
+// moveUserHandler points the account at the one the user moved to. The
+// account stops accepting new chirps and a user.moved event tells other
+// services where followers should go.
+func (cfg *apiConfig) moveUserHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Target   string `json:"target"`
+		Password string `json:"password"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	params := parameters{}
+	err = decodeJSONBody(w, r, &params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	target, ok := normalizeMoveTarget(params.Target)
+	if !ok {
+		v := validate.Validator{}
+		v.Add("target", validate.CodeInvalid, "must be a handle like name@example.com or an https URL")
+		respondWithValidationError(w, v.Err())
+		return
+	}
+
+	user, err := cfg.getUser(r.Context(), userId)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+	err = auth.CheckPasswordHash(params.Password, user.HashedPassword)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Incorrect password", err)
+		return
+	}
+
+	cfg.setMovedTo(w, r, userId, sql.NullString{String: target, Valid: true})
+}
+
+// undoMoveHandler unfreezes an account that was moved, e.g. when the move
+// was a mistake.
+func (cfg *apiConfig) undoMoveHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	cfg.setMovedTo(w, r, userId, sql.NullString{})
+}
+
+func (cfg *apiConfig) setMovedTo(w http.ResponseWriter, r *http.Request, userID uuid.UUID, movedTo sql.NullString) {
+	type response struct {
+		User
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	user, err := qtx.SetUserMovedTo(r.Context(), database.SetUserMovedToParams{
+		ID:      userID,
+		MovedTo: movedTo,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save move", err)
+		return
+	}
+	if movedTo.Valid {
+		err = addOutboxEvent(r.Context(), qtx, eventUserMoved, userMovedEvent{ID: user.ID, MovedTo: user.MovedTo.String})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't store user event", err)
+			return
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save move", err)
+		return
+	}
+	cfg.userCache.Put(user.ID, user)
+	cfg.wakeOutboxRelay()
+
+	respondWithJSON(w, http.StatusOK, response{
+		User: cfg.newUser(user),
+	})
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// adminSearchResult is a chirp as moderators see it: whatever its status,
+// with its author's email and what came of reports against it.
+type adminSearchResult struct {
+	ID                uuid.UUID     `json:"id"`
+	CreatedAt         time.Time     `json:"created_at"`
+	UserID            uuid.UUID     `json:"user_id"`
+	AuthorEmail       string        `json:"author_email"`
+	AuthorDeactivated bool          `json:"author_deactivated"`
+	Body              string        `json:"body"`
+	Status            string        `json:"status"`
+	DeletedAt         *time.Time    `json:"deleted_at,omitempty"`
+	Reports           reportSummary `json:"reports"`
+}
+
+type reportSummary struct {
+	Open      int64 `json:"open"`
+	Actioned  int64 `json:"actioned"`
+	Dismissed int64 `json:"dismissed"`
+}
+
+// parseSearchTime reads a ?since= or ?until= value, either RFC 3339 or a
+// plain date. A plain date in ?until= includes the whole day.
+func parseSearchTime(s string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// adminSearchHandler finds chirps for moderators, newest first. Unlike the
+// public search it also finds drafts, deleted chirps and chirps of
+// deactivated accounts. Filters combine: ?q= matches the body, ?email= the
+// author, ?ip= authors who ever logged in from that address, ?since= and
+// ?until= the creation date, and ?reported=open|any chirps with reports.
+func (cfg *apiConfig) adminSearchHandler(w http.ResponseWriter, r *http.Request) {
+	const defaultLimit = 50
+	const maxLimit = 100
+
+	query := r.URL.Query()
+	params := database.AdminSearchChirpsParams{MaxResults: defaultLimit}
+	if limitParam := query.Get("limit"); limitParam != "" {
+		n, err := strconv.Atoi(limitParam)
+		if err != nil || n <= 0 {
+			respondWithError(w, http.StatusBadRequest, "Invalid limit", err)
+			return
+		}
+		params.MaxResults = int32(min(n, maxLimit))
+	}
+	for _, f := range []struct {
+		name  string
+		field *sql.NullString
+	}{
+		{"q", &params.Query},
+		{"email", &params.Email},
+		{"ip", &params.IpAddress},
+	} {
+		if v := strings.TrimSpace(query.Get(f.name)); v != "" {
+			*f.field = sql.NullString{String: v, Valid: true}
+		}
+	}
+	for _, f := range []struct {
+		name     string
+		field    *sql.NullTime
+		endOfDay bool
+	}{
+		{"since", &params.Since, false},
+		{"until", &params.Until, true},
+	} {
+		v := query.Get(f.name)
+		if v == "" {
+			continue
+		}
+		t, err := parseSearchTime(v, f.endOfDay)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid "+f.name, err)
+			return
+		}
+		*f.field = sql.NullTime{Time: t, Valid: true}
+	}
+	if params.Since.Valid && params.Until.Valid && !params.Since.Time.Before(params.Until.Time) {
+		respondWithError(w, http.StatusBadRequest, "since must be before until", nil)
+		return
+	}
+	switch reported := query.Get("reported"); reported {
+	case "":
+	case "open", "any":
+		params.Reported = sql.NullString{String: reported, Valid: true}
+	default:
+		respondWithError(w, http.StatusBadRequest, "reported must be open or any", nil)
+		return
+	}
+
+	rows, err := cfg.dbQueries.AdminSearchChirps(r.Context(), params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't search chirps", err)
+		return
+	}
+	res := make([]adminSearchResult, 0, len(rows))
+	for _, row := range rows {
+		item := adminSearchResult{
+			ID:                row.ID,
+			CreatedAt:         row.CreatedAt,
+			UserID:            row.UserID,
+			AuthorEmail:       row.AuthorEmail,
+			AuthorDeactivated: row.AuthorDeactivatedAt.Valid,
+			Body:              row.Body,
+			Status:            row.Status,
+			Reports: reportSummary{
+				Open:      row.OpenReports,
+				Actioned:  row.ActionedReports,
+				Dismissed: row.DismissedReports,
+			},
+		}
+		if row.DeletedAt.Valid {
+			item.DeletedAt = &row.DeletedAt.Time
+		}
+		res = append(res, item)
+	}
+	respondWithList(w, http.StatusOK, res, cfg.wantsEnvelope(r))
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/database"
+)
+
+type allowedWord struct {
+	Word      string    `json:"word"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// refreshAllowedWords applies the stored allowlist to the running settings.
+// Other instances pick it up on their next settings reload.
+func (cfg *apiConfig) refreshAllowedWords() error {
+	cfg.reloadMu.Lock()
+	defer cfg.reloadMu.Unlock()
+
+	words, err := cfg.loadAllowedWords()
+	if err != nil {
+		return err
+	}
+	cfg.runtime.Store(cfg.settings().withAllowedWords(words))
+	return nil
+}
+
+// getAllowedWordsHandler lists the words exempt from the banned-word list.
+func (cfg *apiConfig) getAllowedWordsHandler(w http.ResponseWriter, r *http.Request) {
+	rows, err := cfg.dbQueries.GetAllowedWords(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get allowed words", err)
+		return
+	}
+	words := make([]allowedWord, 0, len(rows))
+	for _, row := range rows {
+		words = append(words, allowedWord{Word: row.Word, Reason: row.Reason, CreatedAt: row.CreatedAt})
+	}
+	respondWithList(w, http.StatusOK, words, cfg.wantsEnvelope(r))
+}
+
+// addAllowedWordHandler exempts a word from the banned-word list, e.g. a
+// place name that happens to be banned.
+func (cfg *apiConfig) addAllowedWordHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Reason string `json:"reason"`
+	}
+
+	word := strings.ToLower(strings.TrimSpace(r.PathValue("word")))
+	if word == "" || strings.ContainsAny(word, " \t\n") {
+		respondWithError(w, http.StatusBadRequest, "Invalid word", nil)
+		return
+	}
+	params := parameters{}
+	err := decodeJSONBody(w, r, &params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	row, err := cfg.dbQueries.AddAllowedWord(r.Context(), database.AddAllowedWordParams{
+		Word:   word,
+		Reason: strings.TrimSpace(params.Reason),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't store allowed word", err)
+		return
+	}
+	err = cfg.refreshAllowedWords()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't apply allowed words", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, allowedWord{Word: row.Word, Reason: row.Reason, CreatedAt: row.CreatedAt})
+}
+
+func (cfg *apiConfig) deleteAllowedWordHandler(w http.ResponseWriter, r *http.Request) {
+	word := strings.ToLower(strings.TrimSpace(r.PathValue("word")))
+	n, err := cfg.dbQueries.DeleteAllowedWord(r.Context(), word)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete allowed word", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "Word isn't allowed", nil)
+		return
+	}
+	err = cfg.refreshAllowedWords()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't apply allowed words", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type analyticsKey struct {
+	day     time.Time
+	route   string
+	country string
+	visitor string
+}
+
+// analyticsCounter counts requests per day, route, country and visitor in
+// memory until they're flushed, like usageCounter does for API calls. It
+// never sees a user ID or an IP address, only the visitor hash.
+type analyticsCounter struct {
+	key       []byte
+	retention time.Duration
+
+	mu      sync.Mutex
+	pending map[analyticsKey]int64
+}
+
+// newAnalyticsCounter returns nil when retention is 0, which turns
+// analytics off.
+func newAnalyticsCounter(secret string, retention time.Duration) *analyticsCounter {
+	if retention == 0 {
+		return nil
+	}
+	return &analyticsCounter{
+		key:       []byte("analytics:" + secret),
+		retention: retention,
+		pending:   map[analyticsKey]int64{},
+	}
+}
+
+// visitor hashes a user ID together with the day, so a visitor can be
+// counted once per day but not followed from one day to the next.
+func (c *analyticsCounter) visitor(userID uuid.UUID, day time.Time) string {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(day.Format(time.DateOnly)))
+	mac.Write(userID[:])
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+func (c *analyticsCounter) add(route, country string, viewer uuid.NullUUID, at time.Time) {
+	key := analyticsKey{day: usageDay(at), route: route, country: country}
+	if viewer.Valid {
+		key.visitor = c.visitor(viewer.UUID, key.day)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending[key]++
+}
+
+func (c *analyticsCounter) take() map[analyticsKey]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pending := c.pending
+	c.pending = map[analyticsKey]int64{}
+	return pending
+}
+
+func (c *analyticsCounter) restore(key analyticsKey, requests int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending[key] += requests
+}
+
+// middlewareAnalytics counts requests to route. The country comes from the
+// trusted proxy's country header, so the client IP is never looked at.
+func (cfg *apiConfig) middlewareAnalytics(route string, next http.Handler) http.Handler {
+	if cfg.analytics == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		country := ""
+		if cfg.countryHeader != "" {
+			country = r.Header.Get(cfg.countryHeader)
+		}
+		cfg.analytics.add(route, country, cfg.viewerID(r), time.Now())
+		next.ServeHTTP(w, r)
+	})
+}
+
+// flushAnalytics writes the pending counts to the database. Counts that
+// can't be written are put back for the next flush.
+func (cfg *apiConfig) flushAnalytics(ctx context.Context) {
+	if cfg.analytics == nil {
+		return
+	}
+	for key, requests := range cfg.analytics.take() {
+		err := cfg.dbQueries.AddAnalyticsEvents(ctx, database.AddAnalyticsEventsParams{
+			Day:      key.day,
+			Route:    key.route,
+			Country:  key.country,
+			Visitor:  key.visitor,
+			Requests: requests,
+		})
+		if err != nil {
+			log.Printf("Couldn't save analytics: %v", err)
+			cfg.analytics.restore(key, requests)
+		}
+	}
+}
+
+// flushAnalyticsEvery flushes analytics every interval and drops those
+// older than the retention period once an hour.
+func (cfg *apiConfig) flushAnalyticsEvery(interval time.Duration) {
+	if cfg.analytics == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var purged time.Time
+	for range ticker.C {
+		cfg.flushAnalytics(context.Background())
+		if time.Since(purged) < time.Hour {
+			continue
+		}
+		_, err := cfg.purgeAnalytics(context.Background())
+		if err != nil {
+			log.Printf("Couldn't purge analytics: %v", err)
+			continue
+		}
+		purged = time.Now()
+	}
+}
+
+func (cfg *apiConfig) purgeAnalytics(ctx context.Context) (int64, error) {
+	before := usageDay(time.Now().Add(-cfg.analytics.retention))
+	return cfg.dbQueries.DeleteAnalyticsEventsBefore(ctx, before)
+}
+
+type analyticsDay struct {
+	Date     string `json:"date"`
+	Visitors int64  `json:"visitors"`
+	Requests int64  `json:"requests"`
+}
+
+type analyticsCount struct {
+	Name     string `json:"name"`
+	Requests int64  `json:"requests"`
+}
+
+type analyticsStats struct {
+	Days      []analyticsDay   `json:"days"`
+	Routes    []analyticsCount `json:"top_routes"`
+	Countries []analyticsCount `json:"top_countries"`
+}
+
+// analyticsStats sums up the analytics of the last days. Counts that
+// weren't flushed yet are left out.
+func (cfg *apiConfig) analyticsStats(ctx context.Context, days int) (analyticsStats, error) {
+	const top = 10
+
+	since := time.Now().UTC().AddDate(0, 0, -days+1).Truncate(24 * time.Hour)
+	s := analyticsStats{Days: []analyticsDay{}, Routes: []analyticsCount{}, Countries: []analyticsCount{}}
+
+	byDay, err := cfg.dbQueries.GetAnalyticsByDay(ctx, since)
+	if err != nil {
+		return analyticsStats{}, err
+	}
+	for _, row := range byDay {
+		s.Days = append(s.Days, analyticsDay{Date: row.Day.Format(time.DateOnly), Visitors: row.Visitors, Requests: row.Requests})
+	}
+	routes, err := cfg.dbQueries.GetTopAnalyticsRoutes(ctx, database.GetTopAnalyticsRoutesParams{Day: since, Limit: top})
+	if err != nil {
+		return analyticsStats{}, err
+	}
+	for _, row := range routes {
+		s.Routes = append(s.Routes, analyticsCount{Name: row.Route, Requests: row.Requests})
+	}
+	countries, err := cfg.dbQueries.GetTopAnalyticsCountries(ctx, database.GetTopAnalyticsCountriesParams{Day: since, Limit: top})
+	if err != nil {
+		return analyticsStats{}, err
+	}
+	for _, row := range countries {
+		s.Countries = append(s.Countries, analyticsCount{Name: row.Country, Requests: row.Requests})
+	}
+	return s, nil
+}
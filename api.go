@@ -0,0 +1,118 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// legacyAPISunset is when the unversioned /api/* alias stops being served.
+var legacyAPISunset = time.Date(2027, time.June, 30, 0, 0, 0, 0, time.UTC)
+
+func deprecatedAlias(prefix, successor string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Sunset", legacyAPISunset.Format(http.TimeFormat))
+			w.Header().Set("Link", `<`+successor+strings.TrimPrefix(r.URL.Path, prefix)+`>; rel="successor-version"`)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// registerAPIRoutes mounts the API under prefix. A non-nil middleware wraps
+// every route, e.g. to mark an alias as deprecated.
+func (cfg *apiConfig) registerAPIRoutes(mux *http.ServeMux, prefix string, middleware func(http.Handler) http.Handler) {
+	handle := func(method, path string, h http.HandlerFunc) {
+		handler := cfg.middlewareSessions(cfg.middlewareAPIUsage(cfg.middlewareAnalytics(method+" "+path, cfg.middlewareBreaker(method+" "+path, h))))
+		if middleware != nil {
+			handler = middleware(handler)
+		}
+		mux.Handle(method+" "+prefix+path, handler)
+	}
+
+	handle("GET", "/healthz", healthzHandler)
+	handle("POST", "/users", cfg.createUserHandler)
+	handle("PUT", "/users", cfg.updateUserHandler)
+	handle("DELETE", "/users", cfg.deleteUserHandler)
+	handle("POST", "/users/verify", cfg.verifyEmailHandler)
+	handle("POST", "/users/me/verification", cfg.resendVerificationHandler)
+	handle("GET", "/users/me", cfg.getCurrentUserHandler)
+	handle("GET", "/users/me/logins", cfg.getLoginEventsHandler)
+	handle("GET", "/users/me/usage", cfg.getUserUsageHandler)
+	handle("GET", "/users/me/settings/location", cfg.getLocationSettingsHandler)
+	handle("PUT", "/users/me/settings/location", cfg.setLocationSettingsHandler)
+	handle("GET", "/users/me/likes", cfg.getLikedChirpsHandler)
+	handle("GET", "/users/me/export/likes", cfg.exportLikesHandler)
+	handle("GET", "/users/me/export/bookmarks", cfg.exportBookmarksHandler)
+	handle("GET", "/users/me/export/following", cfg.exportFollowingHandler)
+	handle("GET", "/users/me/drafts", cfg.getDraftsHandler)
+	handle("POST", "/users/me/drafts/{chirpID}/publish", cfg.publishDraftHandler)
+	handle("DELETE", "/users/me/drafts/{chirpID}", cfg.deleteDraftHandler)
+	handle("GET", "/users/me/markers", cfg.getMarkersHandler)
+	handle("PUT", "/users/me/markers", cfg.saveMarkersHandler)
+	handle("POST", "/users/me/logins/{loginID}/report", cfg.reportLoginEventHandler)
+	handle("GET", "/users/me/passkeys", cfg.getPasskeysHandler)
+	handle("POST", "/users/me/passkeys/begin", cfg.beginPasskeyRegistrationHandler)
+	handle("POST", "/users/me/passkeys/finish", cfg.finishPasskeyRegistrationHandler)
+	handle("DELETE", "/users/me/passkeys/{passkeyID}", cfg.deletePasskeyHandler)
+	handle("POST", "/users/me/deactivate", cfg.deactivateUserHandler)
+	handle("POST", "/users/me/move", cfg.moveUserHandler)
+	handle("DELETE", "/users/me/move", cfg.undoMoveHandler)
+	handle("POST", "/users/reactivate", cfg.reactivateUserHandler)
+	handle("POST", "/users/{userID}/follow", cfg.followUserHandler)
+	handle("DELETE", "/users/{userID}/follow", cfg.unfollowUserHandler)
+	handle("GET", "/users/{userID}", cfg.getUserProfileHandler)
+	handle("GET", "/users/{userID}/followers", cfg.getFollowersHandler)
+	handle("GET", "/users/{userID}/following", cfg.getFollowingHandler)
+	handle("GET", "/users/me/blocks", cfg.getBlockedUsersHandler)
+	handle("GET", "/users/me/mutes", cfg.getMutedUsersHandler)
+	handle("POST", "/users/{userID}/block", cfg.blockUserHandler)
+	handle("DELETE", "/users/{userID}/block", cfg.unblockUserHandler)
+	handle("POST", "/users/{userID}/mute", cfg.muteUserHandler)
+	handle("DELETE", "/users/{userID}/mute", cfg.unmuteUserHandler)
+
+	handle("POST", "/login", cfg.loginHandler)
+	handle("POST", "/login/passkey/begin", cfg.beginPasskeyLoginHandler)
+	handle("POST", "/login/passkey/finish", cfg.finishPasskeyLoginHandler)
+	handle("POST", "/refresh", cfg.refreshHandler)
+	handle("POST", "/revoke", cfg.revokeHandler)
+	handle("POST", "/revoke-all", cfg.revokeAllHandler)
+
+	handle("POST", "/chirps", cfg.createChirpHandler)
+	handle("POST", "/chirps/batch", cfg.createChirpBatchHandler)
+	handle("POST", "/chirps/render", cfg.renderChirpHandler)
+	handle("POST", "/chirps/preview", cfg.previewChirpHandler)
+	handle("GET", "/chirps", cfg.rollout("chirps.cursor", cfg.getChirpsPageHandler, cfg.getAllChirpsHandler))
+	handle("GET", "/chirps/updates", cfg.chirpUpdatesHandler)
+	handle("GET", "/chirps/search", cfg.searchChirpsHandler)
+	handle("GET", "/chirps/{chirpID}", cfg.getChirpHandler)
+	handle("GET", "/chirps/{chirpID}/replies", cfg.getChirpRepliesHandler)
+	handle("PUT", "/chirps/{chirpID}", cfg.updateChirpHandler)
+	handle("DELETE", "/chirps/{chirpID}", cfg.deleteChirpHandler)
+	handle("POST", "/chirps/{chirpID}/redraft", cfg.redraftChirpHandler)
+	handle("POST", "/chirps/{chirpID}/share", cfg.createChirpShareHandler)
+	handle("POST", "/chirps/{chirpID}/like", cfg.likeChirpHandler)
+	handle("DELETE", "/chirps/{chirpID}/like", cfg.unlikeChirpHandler)
+	handle("POST", "/chirps/{chirpID}/rechirp", cfg.createRechirpHandler)
+	handle("DELETE", "/chirps/{chirpID}/rechirp", cfg.deleteRechirpHandler)
+
+	handle("POST", "/bookmarks", cfg.createBookmarkHandler)
+	handle("GET", "/bookmarks", cfg.getBookmarksHandler)
+	handle("DELETE", "/bookmarks/{chirpID}", cfg.deleteBookmarkHandler)
+
+	handle("POST", "/threads", cfg.createThreadHandler)
+
+	handle("GET", "/feed", cfg.getFeedHandler)
+
+	handle("GET", "/places", cfg.getPlacesHandler)
+
+	handle("GET", "/hashtags/trending", cfg.getTrendingHashtagsHandler)
+	handle("GET", "/hashtags/{tag}/chirps", cfg.getHashtagChirpsHandler)
+
+	handle("GET", "/embed/chirps/{chirpID}", cfg.embedChirpHandler)
+
+	handle("POST", "/abuse", cfg.createAbuseReportHandler)
+
+	handle("POST", "/polka/webhooks", cfg.addUserSubscribtionHandler)
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/auth"
+	"github.com/fkl13/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type usageKey struct {
+	userID uuid.UUID
+	day    time.Time
+}
+
+// usageCounter counts API calls per user and day in memory until they're
+// flushed, so counting doesn't cost a database write per request.
+type usageCounter struct {
+	mu      sync.Mutex
+	pending map[usageKey]int64
+	// today holds the calls of users whose quota was checked today: what
+	// the database had when we first looked plus everything counted since.
+	today    map[uuid.UUID]int64
+	todayDay time.Time
+}
+
+func newUsageCounter() *usageCounter {
+	return &usageCounter{
+		pending: map[usageKey]int64{},
+		today:   map[uuid.UUID]int64{},
+	}
+}
+
+func usageDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+func (c *usageCounter) add(userID uuid.UUID, at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	day := usageDay(at)
+	c.pending[usageKey{userID: userID, day: day}]++
+	c.rollDay(day)
+	if _, ok := c.today[userID]; ok {
+		c.today[userID]++
+	}
+}
+
+// rollDay forgets today's counts once the day changes. Callers hold c.mu.
+func (c *usageCounter) rollDay(day time.Time) {
+	if !c.todayDay.Equal(day) {
+		c.today = map[uuid.UUID]int64{}
+		c.todayDay = day
+	}
+}
+
+// callsToday returns a user's calls for the current day, if they're known.
+func (c *usageCounter) callsToday(userID uuid.UUID, now time.Time) (int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rollDay(usageDay(now))
+	calls, ok := c.today[userID]
+	return calls, ok
+}
+
+// seedToday records a user's calls for the current day unless another
+// request got there first, and returns the count in effect.
+func (c *usageCounter) seedToday(userID uuid.UUID, now time.Time, calls int64) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rollDay(usageDay(now))
+	if known, ok := c.today[userID]; ok {
+		return known
+	}
+	c.today[userID] = calls
+	return calls
+}
+
+// pendingFor returns the unflushed calls of a user per day.
+func (c *usageCounter) pendingFor(userID uuid.UUID) map[time.Time]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counts := map[time.Time]int64{}
+	for key, calls := range c.pending {
+		if key.userID == userID {
+			counts[key.day] += calls
+		}
+	}
+	return counts
+}
+
+func (c *usageCounter) take() map[usageKey]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pending := c.pending
+	c.pending = map[usageKey]int64{}
+	return pending
+}
+
+func (c *usageCounter) restore(key usageKey, calls int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending[key] += calls
+}
+
+// middlewareAPIUsage counts calls made with a valid access token and
+// rejects them once the user's daily API quota is used up.
+func (cfg *apiConfig) middlewareAPIUsage(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := auth.GetBearerToken(r.Header)
+		if err == nil {
+			userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+			if err == nil {
+				if !cfg.checkAPIQuota(w, r, userId) {
+					return
+				}
+				cfg.apiUsage.add(userId, time.Now())
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// apiCallsToday returns the calls a user made today. The first lookup of
+// the day reads the database; after that the count is kept in memory, so
+// calls served by other instances in the meantime aren't seen.
+func (cfg *apiConfig) apiCallsToday(ctx context.Context, userID uuid.UUID) (int64, error) {
+	now := time.Now()
+	if calls, ok := cfg.apiUsage.callsToday(userID, now); ok {
+		return calls, nil
+	}
+	day := usageDay(now)
+	rows, err := cfg.dbQueries.GetUserAPIUsage(ctx, database.GetUserAPIUsageParams{
+		UserID: userID,
+		Day:    day,
+	})
+	if err != nil {
+		return 0, err
+	}
+	calls := cfg.apiUsage.pendingFor(userID)[day]
+	for _, row := range rows {
+		calls += row.Calls
+	}
+	return cfg.apiUsage.seedToday(userID, now, calls), nil
+}
+
+// flushAPIUsage writes the pending counts to the database. Counts that
+// can't be written are put back for the next flush.
+func (cfg *apiConfig) flushAPIUsage(ctx context.Context) {
+	for key, calls := range cfg.apiUsage.take() {
+		err := cfg.dbQueries.AddAPIUsage(ctx, database.AddAPIUsageParams{
+			UserID: key.userID,
+			Day:    key.day,
+			Calls:  calls,
+		})
+		if err != nil {
+			log.Printf("Couldn't save API usage: %v", err)
+			cfg.apiUsage.restore(key, calls)
+		}
+	}
+}
+
+func (cfg *apiConfig) flushAPIUsageEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		cfg.flushAPIUsage(context.Background())
+	}
+}
+
+func usageDays(r *http.Request) (int, error) {
+	const defaultDays = 30
+	const maxDays = 365
+
+	daysParam := r.URL.Query().Get("days")
+	if daysParam == "" {
+		return defaultDays, nil
+	}
+	days, err := strconv.Atoi(daysParam)
+	if err != nil || days <= 0 {
+		return 0, fmt.Errorf("invalid days %q", daysParam)
+	}
+	return min(days, maxDays), nil
+}
+
+func (cfg *apiConfig) getUserUsageHandler(w http.ResponseWriter, r *http.Request) {
+	type dayUsage struct {
+		Date  string `json:"date"`
+		Calls int64  `json:"calls"`
+	}
+	type response struct {
+		Days  []dayUsage `json:"days"`
+		Total int64      `json:"total"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	days, err := usageDays(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid days", err)
+		return
+	}
+	since := time.Now().UTC().AddDate(0, 0, -days+1).Truncate(24 * time.Hour)
+
+	rows, err := cfg.dbQueries.GetUserAPIUsage(r.Context(), database.GetUserAPIUsageParams{
+		UserID: userId,
+		Day:    since,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get API usage", err)
+		return
+	}
+
+	// Rows come newest first; merge in calls that weren't flushed yet.
+	pending := cfg.apiUsage.pendingFor(userId)
+	res := response{Days: []dayUsage{}}
+	for day := usageDay(time.Now()); !day.Before(since); day = day.AddDate(0, 0, -1) {
+		calls := pending[day]
+		for _, row := range rows {
+			if row.Day.Equal(day) {
+				calls += row.Calls
+			}
+		}
+		res.Days = append(res.Days, dayUsage{Date: day.Format(time.DateOnly), Calls: calls})
+		res.Total += calls
+	}
+	respondWithJSON(w, http.StatusOK, res)
+}
+
+func (cfg *apiConfig) getUsageStatsHandler(w http.ResponseWriter, r *http.Request) {
+	const topUsers = 20
+
+	type dayStats struct {
+		Date  string `json:"date"`
+		Users int64  `json:"users"`
+		Calls int64  `json:"calls"`
+	}
+	type userStats struct {
+		UserID uuid.UUID `json:"user_id"`
+		Email  string    `json:"email"`
+		Calls  int64     `json:"calls"`
+	}
+	type response struct {
+		Days     []dayStats  `json:"days"`
+		TopUsers []userStats `json:"top_users"`
+	}
+
+	days, err := usageDays(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid days", err)
+		return
+	}
+	since := time.Now().UTC().AddDate(0, 0, -days+1).Truncate(24 * time.Hour)
+
+	cfg.flushAPIUsage(r.Context())
+	byDay, err := cfg.dbQueries.GetAPIUsageByDay(r.Context(), since)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get API usage", err)
+		return
+	}
+	top, err := cfg.dbQueries.GetTopAPIUsers(r.Context(), database.GetTopAPIUsersParams{
+		Day:   since,
+		Limit: topUsers,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get API usage", err)
+		return
+	}
+
+	res := response{Days: []dayStats{}, TopUsers: []userStats{}}
+	for _, row := range byDay {
+		res.Days = append(res.Days, dayStats{Date: row.Day.Format(time.DateOnly), Users: row.Users, Calls: row.Calls})
+	}
+	for _, row := range top {
+		res.TopUsers = append(res.TopUsers, userStats{UserID: row.UserID, Email: row.Email, Calls: row.Calls})
+	}
+	respondWithJSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	auditMembershipGranted = "membership.granted"
+	auditMembershipRevoked = "membership.revoked"
+	auditChirpRestored     = "chirp.restored"
+)
+
+type auditLogEntry struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Actor     string     `json:"actor"`
+	Action    string     `json:"action"`
+	UserID    *uuid.UUID `json:"user_id,omitempty"`
+	Reason    string     `json:"reason"`
+}
+
+func newAuditLogEntry(entry database.AuditLog) auditLogEntry {
+	res := auditLogEntry{
+		ID:        entry.ID,
+		CreatedAt: entry.CreatedAt,
+		Actor:     entry.Actor,
+		Action:    entry.Action,
+		Reason:    entry.Reason,
+	}
+	if entry.UserID.Valid {
+		res.UserID = &entry.UserID.UUID
+	}
+	return res
+}
+
+// addAuditLogEntry records a manual change by support staff. Like outbox
+// events it's written in the transaction of the change itself.
+func addAuditLogEntry(ctx context.Context, q *database.Queries, actor, action string, userID uuid.UUID, reason string) error {
+	_, err := q.CreateAuditLogEntry(ctx, database.CreateAuditLogEntryParams{
+		Actor:  actor,
+		Action: action,
+		UserID: uuid.NullUUID{UUID: userID, Valid: true},
+		Reason: reason,
+	})
+	return err
+}
+
+// setUserMembershipHandler lets support staff grant or revoke Chirpy Red by
+// hand, e.g. when a billing webhook never arrived.
+func (cfg *apiConfig) setUserMembershipHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		IsChirpyRed *bool  `json:"is_chirpy_red"`
+		Reason      string `json:"reason"`
+	}
+	type response struct {
+		User
+	}
+
+	userId, err := cfg.parseID(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+
+	params := parameters{}
+	err = decodeJSONBody(w, r, &params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	if params.IsChirpyRed == nil {
+		respondWithError(w, http.StatusBadRequest, "is_chirpy_red is required", nil)
+		return
+	}
+	params.Reason = strings.TrimSpace(params.Reason)
+	if params.Reason == "" {
+		respondWithError(w, http.StatusBadRequest, "A reason is required", nil)
+		return
+	}
+
+	action, event := auditMembershipGranted, eventUserUpgraded
+	if !*params.IsChirpyRed {
+		action, event = auditMembershipRevoked, eventUserDowngraded
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	user, err := qtx.UpdateUserMembership(r.Context(), database.UpdateUserMembershipParams{
+		ID:          userId,
+		IsChirpyRed: *params.IsChirpyRed,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't set membership", err)
+		return
+	}
+	err = addAuditLogEntry(r.Context(), qtx, adminActor(r), action, user.ID, params.Reason)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't write audit log", err)
+		return
+	}
+	var payload any = userUpgradedEvent{ID: user.ID}
+	if !user.IsChirpyRed {
+		payload = userDowngradedEvent{ID: user.ID}
+	}
+	err = addOutboxEvent(r.Context(), qtx, event, payload)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't store user event", err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't set membership", err)
+		return
+	}
+	cfg.userCache.Put(user.ID, user)
+	cfg.wakeOutboxRelay()
+
+	respondWithJSON(w, http.StatusOK, response{
+		User: cfg.newUser(user),
+	})
+}
+
+// restoreChirpHandler brings back a chirp its author deleted, e.g. after an
+// accidental deletion reported to support.
+func (cfg *apiConfig) restoreChirpHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Reason string `json:"reason"`
+	}
+
+	chirpId, err := cfg.parseID(r.PathValue("chirpID"))
+	if err != nil {
+		chirpPolicy.notFound(w, err)
+		return
+	}
+
+	params := parameters{}
+	err = decodeJSONBody(w, r, &params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	params.Reason = strings.TrimSpace(params.Reason)
+	if params.Reason == "" {
+		respondWithError(w, http.StatusBadRequest, "A reason is required", nil)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	chirp, err := qtx.RestoreChirp(r.Context(), chirpId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "No deleted chirp with this ID", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't restore chirp", err)
+		return
+	}
+	if chirp.ParentChirpID.Valid {
+		err = qtx.AddChirpReplies(r.Context(), database.AddChirpRepliesParams{Delta: 1, ID: chirp.ParentChirpID.UUID})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't count reply", err)
+			return
+		}
+	}
+	err = addAuditLogEntry(r.Context(), qtx, adminActor(r), auditChirpRestored, chirp.UserID, params.Reason)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't write audit log", err)
+		return
+	}
+	err = addOutboxEvent(r.Context(), qtx, eventChirpRestored, chirpRestoredEvent{
+		ID:        chirp.ID,
+		UserId:    chirp.UserID,
+		Body:      chirp.Body,
+		CreatedAt: chirp.CreatedAt,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't store chirp event", err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't restore chirp", err)
+		return
+	}
+	cfg.chirpCache.Put(chirp.ID, chirp)
+	if chirp.ParentChirpID.Valid {
+		cfg.chirpCache.Invalidate(chirp.ParentChirpID.UUID)
+	}
+	cfg.wakeOutboxRelay()
+
+	respondWithJSON(w, http.StatusOK, cfg.newChirp(r.Context(), chirp))
+}
+
+// getAuditLogHandler lists the most recent manual changes, newest first.
+func (cfg *apiConfig) getAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	const maxEntries = 100
+
+	entries, err := cfg.dbQueries.GetAuditLog(r.Context(), maxEntries)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get audit log", err)
+		return
+	}
+	res := make([]auditLogEntry, 0, len(entries))
+	for _, entry := range entries {
+		res = append(res, newAuditLogEntry(entry))
+	}
+	respondWithList(w, http.StatusOK, res, cfg.wantsEnvelope(r))
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/auth"
+	"github.com/fkl13/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type batchItemResult struct {
+	Index int    `json:"index"`
+	OK    bool   `json:"ok"`
+	Chirp *Chirp `json:"chirp,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// createChirpBatchHandler creates up to maxBatchSize chirps in a single
+// transaction. Every item is validated first; if any item fails, nothing is
+// stored and the per-item results say which ones need fixing.
+func (cfg *apiConfig) createChirpBatchHandler(w http.ResponseWriter, r *http.Request) {
+	const maxBatchSize = 50
+
+	type batchItem struct {
+		Body      string     `json:"body"`
+		PublishAt *time.Time `json:"publish_at"`
+	}
+	type parameters struct {
+		Chirps []batchItem `json:"chirps"`
+	}
+	type response struct {
+		Results []batchItemResult `json:"results"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	params := parameters{}
+	err = decodeJSONBody(w, r, &params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	if len(params.Chirps) == 0 {
+		respondWithError(w, http.StatusBadRequest, "No chirps provided", nil)
+		return
+	}
+	if len(params.Chirps) > maxBatchSize {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("At most %d chirps per batch", maxBatchSize), nil)
+		return
+	}
+
+	results := make([]batchItemResult, len(params.Chirps))
+	cleaned := make([]string, len(params.Chirps))
+	statuses := make([]string, len(params.Chirps))
+	publishAt := make([]sql.NullTime, len(params.Chirps))
+	failed := false
+	now := time.Now()
+	for i, item := range params.Chirps {
+		results[i].Index = i
+		body, err := validateChirp(item.Body, cfg.settings().badWords)
+		if err != nil {
+			results[i].Error = err.Error()
+			failed = true
+			continue
+		}
+		statuses[i], publishAt[i], err = chirpStatus("", item.PublishAt, now)
+		if err != nil {
+			results[i].Error = err.Error()
+			failed = true
+			continue
+		}
+		cleaned[i] = body
+	}
+	if failed {
+		respondWithJSON(w, http.StatusBadRequest, response{Results: results})
+		return
+	}
+	if !cfg.checkCanPost(w, r, userId) {
+		return
+	}
+	if !cfg.checkChirpQuota(w, r, userId, len(cleaned)) {
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	created := make([]database.Chirp, 0, len(cleaned))
+	for i, body := range cleaned {
+		id, err := uuid.NewV7()
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't create chirp ID", err)
+			return
+		}
+		chirp, err := qtx.CreateChirp(r.Context(), database.CreateChirpParams{
+			ID:        id,
+			Body:      body,
+			UserID:    userId,
+			Status:    statuses[i],
+			PublishAt: publishAt[i],
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't store chirps", err)
+			return
+		}
+		if chirp.Status == chirpPublished {
+			err = cfg.publishChirp(r.Context(), qtx, chirp)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Couldn't publish chirps", err)
+				return
+			}
+		}
+		created = append(created, chirp)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't store chirps", err)
+		return
+	}
+	cfg.wakeOutboxRelay()
+
+	for _, chirp := range created {
+		if chirp.Status == chirpPublished {
+			cfg.chirpCache.Put(chirp.ID, chirp)
+		}
+	}
+	for i, chirp := range cfg.newChirps(r.Context(), created) {
+		results[i].OK = true
+		results[i].Chirp = &chirp
+	}
+	respondWithJSON(w, http.StatusCreated, response{Results: results})
+}
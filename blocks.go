@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/auth"
+	"github.com/fkl13/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Blocking and muting both hide the other account's chirps from every list
+// the caller reads. Blocking also stops the other account from replying to
+// the caller's chirps, and ends any follow between the two accounts in
+// either direction.
+
+// HiddenUser is one entry of the caller's block or mute list.
+type HiddenUser struct {
+	UserID    string    `json:"user_id"`
+	PublicID  string    `json:"public_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// viewerID returns the signed-in caller of an endpoint that doesn't need
+// one. A missing or invalid token reads as signed out, like before blocks
+// existed.
+func (cfg *apiConfig) viewerID(r *http.Request) uuid.NullUUID {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: userId, Valid: true}
+}
+
+// hiddenFrom reports whether the viewer blocked or muted the author, or the
+// author blocked the viewer.
+func (cfg *apiConfig) hiddenFrom(ctx context.Context, viewer uuid.NullUUID, authorID uuid.UUID) (bool, error) {
+	if !viewer.Valid || viewer.UUID == authorID {
+		return false, nil
+	}
+	return cfg.dbQueries.IsHiddenFrom(ctx, database.IsHiddenFromParams{
+		ViewerID: viewer.UUID,
+		UserID:   authorID,
+	})
+}
+
+// visibleChirps drops the chirps whose authors the viewer blocked or muted,
+// for chirps that didn't come from a query that already did.
+func (cfg *apiConfig) visibleChirps(ctx context.Context, viewer uuid.NullUUID, chirps []Chirp) ([]Chirp, error) {
+	if !viewer.Valid || len(chirps) == 0 {
+		return chirps, nil
+	}
+	ids := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		id, err := cfg.parseID(chirp.ID)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	visible, err := cfg.dbQueries.GetVisibleChirpIDs(ctx, database.GetVisibleChirpIDsParams{
+		Ids:      ids,
+		ViewerID: viewer.UUID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Chirp, 0, len(visible))
+	for i, j := 0, 0; i < len(chirps) && j < len(visible); i++ {
+		if ids[i] == visible[j] {
+			res = append(res, chirps[i])
+			j++
+		}
+	}
+	return res, nil
+}
+
+func (cfg *apiConfig) blockUserHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.setHidden(w, r, "block", cfg.blockUser)
+}
+
+// blockUser saves the block and drops the follows it covers.
+func (cfg *apiConfig) blockUser(ctx context.Context, userId, targetId uuid.UUID) (int64, error) {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	n, err := qtx.BlockUser(ctx, database.BlockUserParams{BlockerID: userId, BlockedID: targetId})
+	if err != nil {
+		return 0, err
+	}
+	_, err = qtx.DeleteBlockedFollows(ctx, userId)
+	if err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+func (cfg *apiConfig) unblockUserHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.setHidden(w, r, "block", func(ctx context.Context, userId, targetId uuid.UUID) (int64, error) {
+		return cfg.dbQueries.UnblockUser(ctx, database.UnblockUserParams{BlockerID: userId, BlockedID: targetId})
+	})
+}
+
+func (cfg *apiConfig) muteUserHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.setHidden(w, r, "mute", func(ctx context.Context, userId, targetId uuid.UUID) (int64, error) {
+		return cfg.dbQueries.MuteUser(ctx, database.MuteUserParams{MuterID: userId, MutedID: targetId})
+	})
+}
+
+func (cfg *apiConfig) unmuteUserHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.setHidden(w, r, "mute", func(ctx context.Context, userId, targetId uuid.UUID) (int64, error) {
+		return cfg.dbQueries.UnmuteUser(ctx, database.UnmuteUserParams{MuterID: userId, MutedID: targetId})
+	})
+}
+
+// setHidden blocks, mutes or undoes either for the user in the path. Doing
+// it twice is not an error.
+func (cfg *apiConfig) setHidden(w http.ResponseWriter, r *http.Request, verb string, set func(ctx context.Context, userId, targetId uuid.UUID) (int64, error)) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	targetId, err := cfg.parseID(r.PathValue("userID"))
+	if err != nil {
+		userPolicy.notFound(w, err)
+		return
+	}
+	if targetId == userId {
+		respondWithError(w, http.StatusBadRequest, "You can't "+verb+" yourself", nil)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		_, err = cfg.getUser(r.Context(), targetId)
+		if err != nil {
+			userPolicy.notFound(w, err)
+			return
+		}
+	}
+
+	_, err = set(r.Context(), userId, targetId)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save "+verb, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getBlockedUsersHandler lists who the caller blocked, most recent first.
+func (cfg *apiConfig) getBlockedUsersHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.listHidden(w, r, func(ctx context.Context, userId uuid.UUID) ([]HiddenUser, error) {
+		rows, err := cfg.dbQueries.GetBlockedUsers(ctx, userId)
+		if err != nil {
+			return nil, err
+		}
+		res := make([]HiddenUser, 0, len(rows))
+		for _, row := range rows {
+			res = append(res, cfg.newHiddenUser(row.UserID, row.CreatedAt))
+		}
+		return res, nil
+	})
+}
+
+// getMutedUsersHandler lists who the caller muted, most recent first.
+func (cfg *apiConfig) getMutedUsersHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.listHidden(w, r, func(ctx context.Context, userId uuid.UUID) ([]HiddenUser, error) {
+		rows, err := cfg.dbQueries.GetMutedUsers(ctx, userId)
+		if err != nil {
+			return nil, err
+		}
+		res := make([]HiddenUser, 0, len(rows))
+		for _, row := range rows {
+			res = append(res, cfg.newHiddenUser(row.UserID, row.CreatedAt))
+		}
+		return res, nil
+	})
+}
+
+func (cfg *apiConfig) listHidden(w http.ResponseWriter, r *http.Request, list func(ctx context.Context, userId uuid.UUID) ([]HiddenUser, error)) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	users, err := list(r.Context(), userId)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get users", err)
+		return
+	}
+	respondWithList(w, http.StatusOK, users, cfg.wantsEnvelope(r))
+}
+
+func (cfg *apiConfig) newHiddenUser(userID uuid.UUID, createdAt time.Time) HiddenUser {
+	return HiddenUser{
+		UserID:    cfg.exposedID(userID),
+		PublicID:  cfg.publicID(userID),
+		CreatedAt: createdAt,
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/auth"
+	"github.com/fkl13/chirpy/internal/database"
+	"github.com/fkl13/chirpy/internal/validate"
+	"github.com/google/uuid"
+)
+
+// Bookmark is a chirp a user saved for later. Bookmarks are private: only
+// their owner can list them and they don't show up in any counts.
+type Bookmark struct {
+	ChirpID   string    `json:"chirp_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// createBookmarkHandler saves a chirp for the caller. Bookmarking a chirp
+// again keeps the original bookmark.
+func (cfg *apiConfig) createBookmarkHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		ChirpID string `json:"chirp_id"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	params := parameters{}
+	err = decodeJSONBody(w, r, &params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	var chirpId uuid.UUID
+	v := validate.Validator{}
+	if v.Required("chirp_id", params.ChirpID) {
+		chirpId, err = cfg.parseID(params.ChirpID)
+		if err != nil {
+			v.Add("chirp_id", validate.CodeInvalid, "must be a chirp ID")
+		}
+	}
+	if err := v.Err(); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+	_, err = cfg.getChirp(r.Context(), chirpId)
+	if err != nil {
+		chirpPolicy.notFound(w, err)
+		return
+	}
+
+	bookmark, err := cfg.dbQueries.CreateBookmark(r.Context(), database.CreateBookmarkParams{
+		UserID:  userId,
+		ChirpID: chirpId,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save bookmark", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, Bookmark{
+		ChirpID:   cfg.exposedID(bookmark.ChirpID),
+		CreatedAt: bookmark.CreatedAt,
+	})
+}
+
+func (cfg *apiConfig) deleteBookmarkHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	chirpId, err := cfg.parseID(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	deleted, err := cfg.dbQueries.DeleteBookmark(r.Context(), database.DeleteBookmarkParams{
+		UserID:  userId,
+		ChirpID: chirpId,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete bookmark", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Bookmark not found", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getBookmarksHandler lists the chirps the caller bookmarked, most recently
+// bookmarked first.
+func (cfg *apiConfig) getBookmarksHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	chirps, err := cfg.dbQueries.GetBookmarkedChirps(r.Context(), userId)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get bookmarks", err)
+		return
+	}
+	respondWithList(w, http.StatusOK, cfg.newChirps(r.Context(), chirps), cfg.wantsEnvelope(r))
+}
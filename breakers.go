@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/breaker"
+)
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// routeBreaker returns the circuit breaker for a route, shared by all API
+// prefixes the route is mounted under.
+func (cfg *apiConfig) routeBreaker(route string) *breaker.Breaker {
+	if cfg.routeBreakers == nil {
+		cfg.routeBreakers = map[string]*breaker.Breaker{}
+	}
+	b, ok := cfg.routeBreakers[route]
+	if !ok {
+		b = breaker.New(cfg.breakerThreshold, cfg.breakerCooldown)
+		cfg.routeBreakers[route] = b
+	}
+	return b
+}
+
+// middlewareBreaker fails fast with a 503 once a route keeps failing, which
+// mostly means Postgres is struggling, instead of piling more requests on
+// it. Any 5xx response or panic counts as a failure.
+func (cfg *apiConfig) middlewareBreaker(route string, next http.Handler) http.Handler {
+	b := cfg.routeBreaker(route)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := b.Allow()
+		if err != nil {
+			retryAfter := int(b.RetryAfter().Round(time.Second).Seconds())
+			w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+			respondWithError(w, http.StatusServiceUnavailable, "Service temporarily unavailable", err)
+			return
+		}
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		completed := false
+		defer func() {
+			b.Record(completed && rec.status < 500)
+		}()
+		next.ServeHTTP(rec, r)
+		completed = true
+	})
+}
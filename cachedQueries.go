@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+
+	"github.com/fkl13/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// getChirp and getUser read through the in-memory caches. Every path that
+// changes a chirp or user must update or invalidate the cached copy.
+func (cfg *apiConfig) getChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	if chirp, ok := cfg.chirpCache.Get(id); ok {
+		return chirp, nil
+	}
+	chirp, err := cfg.dbQueries.GetChirp(ctx, id)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	cfg.chirpCache.Put(id, chirp)
+	return chirp, nil
+}
+
+func (cfg *apiConfig) getUser(ctx context.Context, id uuid.UUID) (database.User, error) {
+	if user, ok := cfg.userCache.Get(id); ok {
+		return user, nil
+	}
+	user, err := cfg.dbQueries.GetUser(ctx, id)
+	if err != nil {
+		return database.User{}, err
+	}
+	cfg.userCache.Put(id, user)
+	return user, nil
+}
+
+// getUsers is getUser for many users at once: the ones that aren't cached
+// are read in a single query. Users that don't exist are left out.
+func (cfg *apiConfig) getUsers(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]database.User, error) {
+	users := make(map[uuid.UUID]database.User, len(ids))
+	missing := []uuid.UUID{}
+	for _, id := range ids {
+		if _, ok := users[id]; ok {
+			continue
+		}
+		if user, ok := cfg.userCache.Get(id); ok {
+			users[id] = user
+			continue
+		}
+		missing = append(missing, id)
+	}
+	if len(missing) == 0 {
+		return users, nil
+	}
+	found, err := cfg.dbQueries.GetUsersByIDs(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range found {
+		cfg.userCache.Put(user.ID, user)
+		users[user.ID] = user
+	}
+	return users, nil
+}
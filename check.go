@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/auth"
+	"github.com/fkl13/chirpy/internal/config"
+	"github.com/fkl13/chirpy/internal/errreport"
+	"github.com/fkl13/chirpy/internal/mail"
+	"github.com/fkl13/chirpy/internal/publicid"
+	"github.com/fkl13/chirpy/internal/search"
+	"github.com/google/uuid"
+)
+
+//go:embed sql/schema/*.sql
+var migrations embed.FS
+
+type preflightCheck struct {
+	name string
+	run  func(ctx context.Context) (string, error)
+}
+
+// runCheck validates everything the server needs before it can serve
+// traffic and prints a report. It returns the process exit code.
+func runCheck(out io.Writer) int {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(out, "FAIL config: %v\n", err)
+		return 1
+	}
+	fmt.Fprintln(out, "ok   config")
+
+	dbConn, err := sql.Open("postgres", cfg.DBURL)
+	if err != nil {
+		fmt.Fprintf(out, "FAIL database: %v\n", err)
+		return 1
+	}
+	defer dbConn.Close()
+
+	checks := []preflightCheck{
+		{"database", func(ctx context.Context) (string, error) {
+			return "reachable", dbConn.PingContext(ctx)
+		}},
+		{"migrations", func(ctx context.Context) (string, error) {
+			return checkMigrations(ctx, dbConn)
+		}},
+		{"jwt secret", func(ctx context.Context) (string, error) {
+			return checkJWTSecret(cfg.JWTSecret)
+		}},
+		{"mail", func(ctx context.Context) (string, error) {
+			return checkMail(ctx, cfg)
+		}},
+		{"public ids", func(ctx context.Context) (string, error) {
+			return checkPublicIDs(cfg.PublicIDKey)
+		}},
+		{"error reporting", func(ctx context.Context) (string, error) {
+			return checkErrorReporting(cfg.ErrorReporting)
+		}},
+		{"search", func(ctx context.Context) (string, error) {
+			return checkSearch(ctx, cfg.Search)
+		}},
+		{"storage", func(ctx context.Context) (string, error) {
+			return checkStorage()
+		}},
+	}
+
+	failed := false
+	for _, check := range checks {
+		detail, err := check.run(ctx)
+		if err != nil {
+			failed = true
+			fmt.Fprintf(out, "FAIL %s: %v\n", check.name, err)
+			continue
+		}
+		fmt.Fprintf(out, "ok   %s: %s\n", check.name, detail)
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+func latestMigration() (int, error) {
+	entries, err := fs.ReadDir(migrations, "sql/schema")
+	if err != nil {
+		return 0, err
+	}
+	latest := 0
+	for _, entry := range entries {
+		prefix, _, _ := strings.Cut(entry.Name(), "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return 0, fmt.Errorf("migration %s has no version prefix", entry.Name())
+		}
+		latest = max(latest, version)
+	}
+	return latest, nil
+}
+
+func schemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var version sql.NullInt64
+	err := db.QueryRowContext(ctx, "SELECT max(version_id) FROM goose_db_version WHERE is_applied").Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't read goose version: %w", err)
+	}
+	return int(version.Int64), nil
+}
+
+func checkMigrations(ctx context.Context, db *sql.DB) (string, error) {
+	latest, err := latestMigration()
+	if err != nil {
+		return "", err
+	}
+	current, err := schemaVersion(ctx, db)
+	if err != nil {
+		return "", err
+	}
+	if current < latest {
+		return "", fmt.Errorf("schema at version %d, latest is %d", current, latest)
+	}
+	return fmt.Sprintf("schema at version %d", current), nil
+}
+
+func checkJWTSecret(secret string) (string, error) {
+	const minSecretLength = 32
+	if len(secret) < minSecretLength {
+		return "", fmt.Errorf("secret is %d bytes, need at least %d", len(secret), minSecretLength)
+	}
+	userID := uuid.New()
+	token, err := auth.MakeJWT(userID, secret, time.Minute)
+	if err != nil {
+		return "", err
+	}
+	got, err := auth.ValidateJWT(token, secret)
+	if err != nil {
+		return "", err
+	}
+	if got != userID {
+		return "", fmt.Errorf("token round trip returned the wrong user")
+	}
+	return "signs and validates tokens", nil
+}
+
+func checkMail(ctx context.Context, cfg config.Config) (string, error) {
+	_, err := mail.NewRenderer(cfg.MailTemplateDir)
+	if err != nil {
+		return "", err
+	}
+
+	addr := ""
+	switch cfg.Mail.Provider {
+	case "smtp":
+		addr = cfg.Mail.SMTPAddr
+	case "ses":
+		addr = mail.SESAddr(cfg.Mail.SESRegion)
+	default:
+		_, err := mail.New(cfg.Mail)
+		if err != nil {
+			return "", err
+		}
+		provider := cfg.Mail.Provider
+		if provider == "" {
+			provider = "log"
+		}
+		return provider + " provider needs no server", nil
+	}
+
+	dialer := net.Dialer{Timeout: 5 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	conn.Close()
+	return addr + " reachable", nil
+}
+
+func checkPublicIDs(key string) (string, error) {
+	if key == "" {
+		return "off", nil
+	}
+	codec, err := publicid.New(key)
+	if err != nil {
+		return "", err
+	}
+	id := uuid.New()
+	got, err := codec.Decode(codec.Encode(id))
+	if err != nil {
+		return "", err
+	}
+	if got != id {
+		return "", fmt.Errorf("id round trip returned the wrong id")
+	}
+	return "encodes and decodes ids", nil
+}
+
+func checkErrorReporting(cfg errreport.Config) (string, error) {
+	switch {
+	case cfg.SentryDSN != "":
+		_, err := errreport.NewSentrySender(cfg.SentryDSN, cfg.Environment)
+		if err != nil {
+			return "", err
+		}
+		return "reports to sentry", nil
+	case cfg.WebhookURL != "":
+		u, err := url.Parse(cfg.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "", fmt.Errorf("invalid webhook url %q", cfg.WebhookURL)
+		}
+		return "reports to " + u.Host, nil
+	}
+	return "off", nil
+}
+
+// checkSearch validates the search settings and, for meilisearch, asks the
+// server whether it's healthy.
+func checkSearch(ctx context.Context, cfg search.Config) (string, error) {
+	backend, err := search.New(cfg, nil)
+	if err != nil {
+		return "", err
+	}
+	if backend == nil {
+		return "off", nil
+	}
+	if cfg.Backend != "meilisearch" {
+		return "postgres full-text search", nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimSuffix(cfg.URL, "/")+"/health", nil)
+	if err != nil {
+		return "", err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("meilisearch health check returned %s", res.Status)
+	}
+	return cfg.URL + " healthy", nil
+}
+
+func checkStorage() (string, error) {
+	path := filepath.Join(filepathRoot, "index.html")
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	f.Close()
+	return path + " readable", nil
+}
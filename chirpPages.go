@@ -0,0 +1,95 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultChirpPageSize = 50
+	maxChirpPageSize     = 100
+)
+
+// encodeChirpCursor returns an opaque cursor for the page after chirp.
+func encodeChirpCursor(chirp database.Chirp) string {
+	raw := strconv.FormatInt(chirp.CreatedAt.UnixMicro(), 10) + "." + chirp.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeChirpCursor(cursor string) (time.Time, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+	micros, id, ok := strings.Cut(string(raw), ".")
+	if !ok {
+		return time.Time{}, uuid.Nil, errors.New("malformed cursor")
+	}
+	n, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+	chirpID, err := uuid.Parse(id)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+	return time.UnixMicro(n).UTC(), chirpID, nil
+}
+
+// getChirpsPageHandler is the candidate for GET /chirps behind the
+// chirps.cursor rollout. It returns at most ?limit= chirps and links to the
+// next page with a cursor instead of listing every chirp at once.
+func (cfg *apiConfig) getChirpsPageHandler(w http.ResponseWriter, r *http.Request) {
+	author, sort, ok := cfg.chirpListQuery(w, r)
+	if !ok {
+		return
+	}
+
+	limit := defaultChirpPageSize
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxChirpPageSize {
+			respondWithError(w, http.StatusBadRequest, "Limit must be between 1 and "+strconv.Itoa(maxChirpPageSize), err)
+			return
+		}
+		limit = n
+	}
+
+	params := database.GetChirpsPageParams{
+		AuthorID: author,
+		ViewerID: cfg.viewerID(r),
+		Sort:     sort,
+		PageSize: int32(limit + 1),
+	}
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		createdAt, id, err := decodeChirpCursor(cursor)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid cursor", err)
+			return
+		}
+		params.AfterCreatedAt = sql.NullTime{Time: createdAt, Valid: true}
+		params.AfterID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	chirps, err := cfg.dbQueries.GetChirpsPage(r.Context(), params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get chirps", err)
+		return
+	}
+	page := listPage{Limit: limit}
+	if len(chirps) > limit {
+		chirps = chirps[:limit]
+		page.HasMore = true
+		page.NextCursor = encodeChirpCursor(chirps[limit-1])
+	}
+
+	respondWithPage(w, r, cfg.newChirps(r.Context(), chirps), page, "cursor", cfg.wantsEnvelope(r))
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/fkl13/chirpy/internal/auth"
+	"github.com/fkl13/chirpy/internal/markdown"
+	"github.com/google/uuid"
+)
+
+// mentionPattern matches @username where a username could start, so email
+// addresses aren't read as mentions.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@.])@([A-Za-z0-9_]{3,15})\b`)
+
+// extractMentions returns the normalized usernames mentioned in a chirp
+// body, each once, in order of appearance.
+func extractMentions(body string) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		name := normalizeUsername(match[1])
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// Mention is a username found in a previewed chirp. UserID is nil when no
+// active account has that username.
+type Mention struct {
+	Username string  `json:"username"`
+	UserID   *string `json:"user_id"`
+}
+
+// formattedBody is a chirp body as it would be stored and shown.
+type formattedBody struct {
+	Body           string
+	NormalizedBody string
+	BodyHTML       string
+	Masked         bool
+}
+
+// formatChirpBody validates a chirp body and cleans, normalizes and renders
+// it the way posting and showing it would.
+func (cfg *apiConfig) formatChirpBody(body string) (formattedBody, error) {
+	cleaned, err := validateChirp(body, cfg.settings().badWords)
+	if err != nil {
+		return formattedBody{}, err
+	}
+	normalized := normalizeBody(cleaned, cfg.normalization())
+	return formattedBody{
+		Body:           cleaned,
+		NormalizedBody: normalized,
+		BodyHTML:       markdown.Render(normalized),
+		Masked:         cleaned != body,
+	}, nil
+}
+
+// previewChirpHandler runs a chirp body through the same checks and
+// cleaning as posting it and shows the result, without storing anything.
+// Anything that would change or stop the chirp, such as masked words or a
+// used-up quota, is returned as a warning.
+func (cfg *apiConfig) previewChirpHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+	type response struct {
+		Body           string    `json:"body"`
+		NormalizedBody string    `json:"normalized_body"`
+		BodyHTML       string    `json:"body_html"`
+		Hashtags       []string  `json:"hashtags"`
+		Mentions       []Mention `json:"mentions"`
+		Warnings       []string  `json:"warnings"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	params := parameters{}
+	err = decodeJSONBody(w, r, &params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	formatted, err := cfg.formatChirpBody(params.Body)
+	if err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+
+	user, err := cfg.getUser(r.Context(), userId)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+	res := response{
+		Body:           formatted.Body,
+		NormalizedBody: formatted.NormalizedBody,
+		BodyHTML:       formatted.BodyHTML,
+		Hashtags:       extractHashtags(formatted.Body),
+		Mentions:       []Mention{},
+		Warnings:       []string{},
+	}
+	if formatted.Masked {
+		res.Warnings = append(res.Warnings, "Some words will be masked")
+	}
+	if reason := cfg.cannotPost(user); reason != "" {
+		res.Warnings = append(res.Warnings, reason)
+	}
+	used, limit, err := cfg.chirpQuotaUsed(r.Context(), user)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check chirp quota", err)
+		return
+	}
+	if limit > 0 && used >= int64(limit) {
+		res.Warnings = append(res.Warnings, "You've used up today's chirp quota")
+	}
+
+	names := extractMentions(formatted.Body)
+	if len(names) > 0 {
+		users, err := cfg.dbQueries.GetUsersByUsernames(r.Context(), names)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't look up mentions", err)
+			return
+		}
+		ids := map[string]uuid.UUID{}
+		for _, u := range users {
+			ids[strings.ToLower(u.Username.String)] = u.ID
+		}
+		for _, name := range names {
+			mention := Mention{Username: name}
+			if id, ok := ids[name]; ok {
+				userID := cfg.exposedID(id)
+				mention.UserID = &userID
+			} else {
+				res.Warnings = append(res.Warnings, "No user is called @"+name)
+			}
+			res.Mentions = append(res.Mentions, mention)
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+// deactivateUserHandler hides the user and their chirps and ends all their
+// sessions. The account can be reactivated until the grace period is over,
+// after which purgeDeactivatedUsers deletes it.
+func (cfg *apiConfig) deactivateUserHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't deactivate user", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	// Deactivating again keeps the original time, so the grace period
+	// can't be extended.
+	err = qtx.DeactivateUser(r.Context(), userId)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't deactivate user", err)
+		return
+	}
+	err = endSessions(r.Context(), qtx, userId)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke sessions", err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't deactivate user", err)
+		return
+	}
+	cfg.userCache.Invalidate(userId)
+	// The user's chirps are hidden by the queries now, drop any cached copy.
+	cfg.chirpCache.Clear()
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// checkActive reports whether the user's account is active. Deactivation
+// ends the account's sessions, but another instance may accept an access
+// token until its cached user expires. If not, it has already written the
+// response.
+func (cfg *apiConfig) checkActive(w http.ResponseWriter, r *http.Request, userID uuid.UUID) bool {
+	user, err := cfg.getUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+		return false
+	}
+	if user.DeactivatedAt.Valid {
+		respondWithError(w, http.StatusForbidden, "This account is deactivated", nil)
+		return false
+	}
+	return true
+}
+
+func (cfg *apiConfig) reactivateUserHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Password string `json:"password"`
+		Email    string `json:"email"`
+	}
+	type response struct {
+		User
+	}
+
+	params := parameters{}
+	err := decodeJSONBody(w, r, &params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	user, err := cfg.dbQueries.GetUserByEmail(r.Context(), normalizeEmail(params.Email, cfg.stripEmailPlusTags))
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
+		return
+	}
+	err = auth.CheckPasswordHash(params.Password, user.HashedPassword)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
+		return
+	}
+
+	if !user.DeactivatedAt.Valid {
+		respondWithError(w, http.StatusBadRequest, "Account isn't deactivated", nil)
+		return
+	}
+	if time.Since(user.DeactivatedAt.Time) > cfg.deactivationGracePeriod {
+		respondWithError(w, http.StatusGone, "Account can no longer be reactivated", nil)
+		return
+	}
+
+	user, err = cfg.dbQueries.ReactivateUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't reactivate user", err)
+		return
+	}
+	cfg.userCache.Put(user.ID, user)
+
+	respondWithJSON(w, http.StatusOK, response{
+		User: cfg.newUser(user),
+	})
+}
+
+// purgeDeactivatedUsers runs deleteExpiredUsers every interval.
+func (cfg *apiConfig) purgeDeactivatedUsers(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		n, err := cfg.deleteExpiredUsers(context.Background())
+		if err != nil {
+			log.Printf("Couldn't delete deactivated users: %v", err)
+			continue
+		}
+		if n > 0 {
+			log.Printf("Deleted %d deactivated users", n)
+		}
+	}
+}
+
+// deleteExpiredUsers deletes accounts whose grace period is over, each with
+// deleteUser in its own transaction.
+func (cfg *apiConfig) deleteExpiredUsers(ctx context.Context) (int64, error) {
+	before := time.Now().UTC().Add(-cfg.deactivationGracePeriod)
+	userIds, err := cfg.dbQueries.GetExpiredDeactivatedUserIDs(ctx, sql.NullTime{Time: before, Valid: true})
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	defer func() {
+		if n > 0 {
+			cfg.userCache.Clear()
+			cfg.chirpCache.Clear()
+			cfg.wakeOutboxRelay()
+		}
+	}()
+	for _, userId := range userIds {
+		err = cfg.deleteExpiredUser(ctx, userId)
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (cfg *apiConfig) deleteExpiredUser(ctx context.Context, userId uuid.UUID) error {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	err = deleteUser(ctx, cfg.dbQueries.WithTx(tx), userId)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/auth"
+	"github.com/fkl13/chirpy/internal/database"
+	"github.com/fkl13/chirpy/internal/validate"
+	"github.com/google/uuid"
+)
+
+// Chirp statuses. Only published chirps are visible to anyone but their
+// author; scheduled chirps are published once their publish_at passes.
+const (
+	chirpDraft     = "draft"
+	chirpScheduled = "scheduled"
+	chirpPublished = "published"
+)
+
+// chirpStatus works out the status of a new chirp from the status and
+// publish_at sent by the client.
+func chirpStatus(status string, publishAt *time.Time, now time.Time) (string, sql.NullTime, error) {
+	v := validate.Validator{}
+	switch status {
+	case "", chirpPublished:
+		if publishAt != nil {
+			if !publishAt.After(now) {
+				v.Add("publish_at", validate.CodeInvalid, "must be in the future")
+				return "", sql.NullTime{}, v.Err()
+			}
+			return chirpScheduled, sql.NullTime{Time: publishAt.UTC(), Valid: true}, nil
+		}
+		return chirpPublished, sql.NullTime{}, nil
+	case chirpDraft:
+		if publishAt != nil {
+			v.Add("publish_at", validate.CodeInvalid, "can't be set on a draft")
+			return "", sql.NullTime{}, v.Err()
+		}
+		return chirpDraft, sql.NullTime{}, nil
+	default:
+		v.Add("status", validate.CodeInvalid, "must be draft or published")
+		return "", sql.NullTime{}, v.Err()
+	}
+}
+
+// publishChirp does what a chirp needs once it's public: index its
+// hashtags, count it as a reply and announce it. It runs in the
+// transaction that stored or published the chirp.
+func (cfg *apiConfig) publishChirp(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	err := storeHashtags(ctx, q, chirp)
+	if err != nil {
+		return fmt.Errorf("couldn't store hashtags: %w", err)
+	}
+	if chirp.ParentChirpID.Valid {
+		err = q.AddChirpReplies(ctx, database.AddChirpRepliesParams{Delta: 1, ID: chirp.ParentChirpID.UUID})
+		if err != nil {
+			return fmt.Errorf("couldn't count reply: %w", err)
+		}
+	}
+	err = addOutboxEvent(ctx, q, eventChirpCreated, newChirpCreatedEvent(chirp))
+	if err != nil {
+		return fmt.Errorf("couldn't store chirp event: %w", err)
+	}
+	return nil
+}
+
+// publishDraft publishes one of the author's drafts or scheduled chirps.
+// It returns sql.ErrNoRows if the chirp was published in the meantime.
+func (cfg *apiConfig) publishDraft(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	chirp, err := qtx.PublishChirp(ctx, id)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	err = cfg.publishChirp(ctx, qtx, chirp)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	cfg.chirpCache.Put(chirp.ID, chirp)
+	if chirp.ParentChirpID.Valid {
+		cfg.chirpCache.Invalidate(chirp.ParentChirpID.UUID)
+	}
+	return chirp, nil
+}
+
+// publishScheduledChirps runs publishDueChirps every interval.
+func (cfg *apiConfig) publishScheduledChirps(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		n, err := cfg.publishDueChirps(context.Background())
+		if err != nil {
+			log.Printf("Couldn't publish scheduled chirps: %v", err)
+		}
+		if n > 0 {
+			log.Printf("Published %d scheduled chirps", n)
+		}
+	}
+}
+
+// publishDueChirps publishes the scheduled chirps whose time has come.
+// Chirps of accounts that moved wait until the move is undone.
+func (cfg *apiConfig) publishDueChirps(ctx context.Context) (int, error) {
+	const batchSize = 100
+
+	published := 0
+	defer func() {
+		if published > 0 {
+			cfg.wakeOutboxRelay()
+		}
+	}()
+	for {
+		due, err := cfg.dbQueries.GetDueChirps(ctx, database.GetDueChirpsParams{
+			PublishAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+			Limit:     batchSize,
+		})
+		if err != nil {
+			return published, err
+		}
+		for _, chirp := range due {
+			_, err := cfg.publishDraft(ctx, chirp.ID)
+			if errors.Is(err, sql.ErrNoRows) {
+				// Another instance got there first.
+				continue
+			}
+			if err != nil {
+				return published, err
+			}
+			published++
+		}
+		if len(due) < batchSize {
+			return published, nil
+		}
+	}
+}
+
+// getDraftsHandler lists the caller's drafts and scheduled chirps, the
+// next to be published first.
+func (cfg *apiConfig) getDraftsHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	drafts, err := cfg.dbQueries.GetDrafts(r.Context(), userId)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get drafts", err)
+		return
+	}
+	respondWithList(w, http.StatusOK, cfg.newChirps(r.Context(), drafts), cfg.wantsEnvelope(r))
+}
+
+// publishDraftHandler publishes a draft or scheduled chirp right away.
+func (cfg *apiConfig) publishDraftHandler(w http.ResponseWriter, r *http.Request) {
+	draft, ok := cfg.authorDraft(w, r)
+	if !ok {
+		return
+	}
+	if !cfg.checkCanPost(w, r, draft.UserID) {
+		return
+	}
+
+	chirp, err := cfg.publishDraft(r.Context(), draft.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			draftPolicy.notFound(w, err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't publish draft", err)
+		return
+	}
+	cfg.wakeOutboxRelay()
+
+	respondWithJSON(w, http.StatusOK, cfg.newChirp(r.Context(), chirp))
+}
+
+// deleteDraftHandler throws away a draft or cancels a scheduled chirp.
+func (cfg *apiConfig) deleteDraftHandler(w http.ResponseWriter, r *http.Request) {
+	draft, ok := cfg.authorDraft(w, r)
+	if !ok {
+		return
+	}
+
+	_, err := cfg.dbQueries.DeleteChirp(r.Context(), draft.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete draft", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorDraft looks up the draft in the path for its author. If it can't,
+// it has already written the response.
+func (cfg *apiConfig) authorDraft(w http.ResponseWriter, r *http.Request) (database.Chirp, bool) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return database.Chirp{}, false
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return database.Chirp{}, false
+	}
+
+	chirpId, err := cfg.parseID(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return database.Chirp{}, false
+	}
+	draft, err := cfg.dbQueries.GetDraft(r.Context(), database.GetDraftParams{
+		ID:     chirpId,
+		UserID: userId,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			draftPolicy.notFound(w, err)
+			return database.Chirp{}, false
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get draft", err)
+		return database.Chirp{}, false
+	}
+	return draft, true
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/auth"
+	"github.com/fkl13/chirpy/internal/database"
+)
+
+const (
+	// emailVerificationTTL is how long a verification link works.
+	emailVerificationTTL = 48 * time.Hour
+	// verificationEmailLimit is how many verification emails a user can
+	// ask for per hour.
+	verificationEmailLimit = 3
+	// verificationPage is the page, served from /app, that confirms the
+	// address by calling POST /api/v1/users/verify with the token.
+	verificationPage = "/app/verify.html"
+)
+
+// verificationLink points at the verification page of the web app at
+// publicURL.
+func verificationLink(publicURL, token string) string {
+	return publicURL + verificationPage + "?" + url.Values{"token": {token}}.Encode()
+}
+
+// sendVerificationEmail mails the user a link to confirm their current
+// address. Links sent earlier stop working. It does nothing when email
+// verification is off.
+func (cfg *apiConfig) sendVerificationEmail(ctx context.Context, user database.User) error {
+	if cfg.publicURL == "" {
+		return nil
+	}
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return err
+	}
+	err = cfg.dbQueries.DeleteStaleEmailVerifications(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	err = cfg.dbQueries.CreateEmailVerification(ctx, database.CreateEmailVerificationParams{
+		Token:     token,
+		UserID:    user.ID,
+		Email:     user.Email,
+		ExpiresAt: time.Now().UTC().Add(emailVerificationTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	msg, err := cfg.mailTemplates.Render("verification", user.Email, map[string]any{
+		"Link": verificationLink(cfg.publicURL, token),
+	})
+	if err != nil {
+		return err
+	}
+	go func() {
+		err := cfg.mailer.Send(context.Background(), msg)
+		if err != nil {
+			log.Printf("Couldn't send verification email: %v", err)
+		}
+	}()
+	return nil
+}
+
+// verifyEmailHandler confirms an address with the token from a
+// verification link. The token only works for the address it was sent to.
+func (cfg *apiConfig) verifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token string `json:"token"`
+	}
+	type response struct {
+		User
+	}
+
+	params := parameters{}
+	err := decodeJSONBody(w, r, &params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	verification, err := qtx.TakeEmailVerification(r.Context(), params.Token)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired token", err)
+		return
+	}
+	user, err := qtx.MarkEmailVerified(r.Context(), database.MarkEmailVerifiedParams{
+		ID:    verification.UserID,
+		Email: verification.Email,
+	})
+	if err != nil {
+		// The address changed since the link was sent.
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired token", err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't verify email", err)
+		return
+	}
+	cfg.userCache.Put(user.ID, user)
+
+	respondWithJSON(w, http.StatusOK, response{
+		User: cfg.newUser(user),
+	})
+}
+
+// resendVerificationHandler sends the caller a new verification link.
+func (cfg *apiConfig) resendVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	if cfg.publicURL == "" {
+		respondWithError(w, http.StatusNotImplemented, "Email verification isn't enabled", nil)
+		return
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	result := cfg.verificationLimiter.Allow(userId.String())
+	if !result.Allowed {
+		resetIn := max(int(time.Until(result.Reset).Round(time.Second).Seconds()), 0)
+		w.Header().Set("Retry-After", strconv.Itoa(resetIn))
+		respondWithError(w, http.StatusTooManyRequests, "Too many verification emails", nil)
+		return
+	}
+
+	user, err := cfg.getUser(r.Context(), userId)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+	if user.EmailVerifiedAt.Valid {
+		respondWithError(w, http.StatusBadRequest, "Email is already verified", nil)
+		return
+	}
+	err = cfg.sendVerificationEmail(r.Context(), user)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't send verification email", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/markdown"
+)
+
+// The embed widget lets other sites show a chirp: widget/embed.js turns
+// marked-up elements into iframes pointing at /embed/chirp/{chirpID}.
+
+var (
+	//go:embed widget/embed.js
+	embedScript []byte
+	//go:embed widget/chirp.css
+	embedCSS string
+	//go:embed widget/chirp.html
+	embedPage string
+
+	embedTemplate = template.Must(template.New("chirp").Parse(embedPage))
+	// embedCSP allows nothing but the page's own inline stylesheet, so a
+	// chirp body can never load or run anything.
+	embedCSP = fmt.Sprintf(
+		"default-src 'none'; style-src 'sha256-%s'; base-uri 'none'; form-action 'none'; frame-ancestors *",
+		base64.StdEncoding.EncodeToString(sha256Sum(embedCSS)),
+	)
+)
+
+const (
+	embedScriptMaxAge = time.Hour
+	embedPageMaxAge   = 5 * time.Minute
+)
+
+func sha256Sum(s string) []byte {
+	sum := sha256.Sum256([]byte(s))
+	return sum[:]
+}
+
+func embedTheme(r *http.Request) string {
+	if r.URL.Query().Get("theme") == "dark" {
+		return "dark"
+	}
+	return "light"
+}
+
+func (cfg *apiConfig) embedScriptHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(embedScriptMaxAge.Seconds())))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write(embedScript)
+}
+
+// embedChirpPageHandler renders a chirp as a standalone page for the embed
+// iframe. It needs no token: only chirps anyone can read are shown.
+func (cfg *apiConfig) embedChirpPageHandler(w http.ResponseWriter, r *http.Request) {
+	chirpId, err := cfg.parseID(r.PathValue("chirpID"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	chirp, err := cfg.getChirp(r.Context(), chirpId)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	theme := embedTheme(r)
+	etag := fmt.Sprintf(`"%s-%d-%s"`, chirp.ID, chirp.UpdatedAt.UnixNano(), theme)
+	h := w.Header()
+	h.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(embedPageMaxAge.Seconds())))
+	h.Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	var buf bytes.Buffer
+	err = embedTemplate.Execute(&buf, struct {
+		CSS       template.CSS
+		Theme     string
+		Body      template.HTML
+		CreatedAt time.Time
+	}{
+		CSS:       template.CSS(embedCSS),
+		Theme:     theme,
+		Body:      template.HTML(markdown.Render(chirp.Body)),
+		CreatedAt: chirp.CreatedAt.UTC(),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't render chirp", err)
+		return
+	}
+
+	h.Set("Content-Type", "text/html; charset=utf-8")
+	h.Set("Content-Security-Policy", embedCSP)
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Referrer-Policy", "no-referrer")
+	w.Write(buf.Bytes())
+}
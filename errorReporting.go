@@ -0,0 +1,110 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+
+	"github.com/fkl13/chirpy/internal/auth"
+	"github.com/fkl13/chirpy/internal/errreport"
+	"github.com/google/uuid"
+)
+
+// requestWriter carries the request through to respondWithError, which only
+// gets the ResponseWriter, so failed requests can be reported with context.
+type requestWriter struct {
+	http.ResponseWriter
+	r         *http.Request
+	requestID string
+	cfg       *apiConfig
+}
+
+func (w *requestWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// middlewareRequestID tags every request with an ID, reusing a sane
+// X-Request-ID from a proxy, and echoes it in the response.
+func (cfg *apiConfig) middlewareRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(&requestWriter{ResponseWriter: w, r: r, requestID: id, cfg: cfg}, r)
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// reportError sends a 5xx response to the error reporter, if one is set up.
+func reportError(w http.ResponseWriter, code int, msg string, err error) {
+	rw := findRequestWriter(w)
+	if rw == nil || rw.cfg.errorReporter == nil {
+		return
+	}
+
+	event := errreport.Event{
+		Message:   msg,
+		Status:    code,
+		RequestID: rw.requestID,
+		Method:    rw.r.Method,
+		Route:     rw.r.Pattern,
+		UserHash:  rw.cfg.userHash(rw.r),
+	}
+	if event.Route == "" {
+		event.Route = rw.r.URL.Path
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	var panicErr *panicError
+	if errors.As(err, &panicErr) {
+		event.Stack = string(panicErr.stack)
+	}
+	rw.cfg.errorReporter.Report(event)
+}
+
+func findRequestWriter(w http.ResponseWriter) *requestWriter {
+	for {
+		switch v := w.(type) {
+		case *requestWriter:
+			return v
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = v.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
+
+// userHash identifies the user behind a request in error reports without
+// sending their ID to a third party. It's keyed with the server secret, like
+// the analytics visitor hash, so the reporter can't hash known IDs to find
+// out whose it is.
+func (cfg *apiConfig) userHash(r *http.Request) string {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return ""
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte("errors:"+cfg.jwtSecret))
+	mac.Write(userId[:])
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/auth"
+)
+
+// csvCell keeps a value from being read as a formula when the export is
+// opened in a spreadsheet.
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// respondWithCSV sends rows as a CSV file download.
+func respondWithCSV(w http.ResponseWriter, filename string, header []string, rows [][]string) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	cw.Write(header)
+	for _, row := range rows {
+		for i := range row {
+			row[i] = csvCell(row[i])
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		log.Printf("Error writing CSV: %s", err)
+	}
+}
+
+// exportLikesHandler downloads the caller's likes as CSV, oldest first.
+func (cfg *apiConfig) exportLikesHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	likes, err := cfg.dbQueries.ExportLikes(r.Context(), userId)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't export likes", err)
+		return
+	}
+	rows := make([][]string, 0, len(likes))
+	for _, like := range likes {
+		rows = append(rows, []string{
+			like.LikedAt.Format(time.RFC3339),
+			like.ID.String(),
+			like.UserID.String(),
+			like.CreatedAt.Format(time.RFC3339),
+			like.Body,
+		})
+	}
+	respondWithCSV(w, "chirpy-likes.csv", []string{"liked_at", "chirp_id", "author_id", "chirp_created_at", "body"}, rows)
+}
+
+// exportBookmarksHandler downloads the caller's bookmarks as CSV, oldest
+// first.
+func (cfg *apiConfig) exportBookmarksHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	bookmarks, err := cfg.dbQueries.ExportBookmarks(r.Context(), userId)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't export bookmarks", err)
+		return
+	}
+	rows := make([][]string, 0, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		rows = append(rows, []string{
+			bookmark.BookmarkedAt.Format(time.RFC3339),
+			bookmark.ID.String(),
+			bookmark.UserID.String(),
+			bookmark.CreatedAt.Format(time.RFC3339),
+			bookmark.Body,
+		})
+	}
+	respondWithCSV(w, "chirpy-bookmarks.csv", []string{"bookmarked_at", "chirp_id", "author_id", "chirp_created_at", "body"}, rows)
+}
+
+type opmlOutline struct {
+	Type    string `xml:"type,attr"`
+	Text    string `xml:"text,attr"`
+	URL     string `xml:"url,attr"`
+	Created string `xml:"created,attr"`
+}
+
+type opmlDocument struct {
+	XMLName xml.Name      `xml:"opml"`
+	Version string        `xml:"version,attr"`
+	Title   string        `xml:"head>title"`
+	Created string        `xml:"head>dateCreated"`
+	Outline []opmlOutline `xml:"body>outline"`
+}
+
+// writeOPML writes an OPML 2.0 document. Dates use RFC 822 as the format
+// requires.
+func writeOPML(w io.Writer, doc opmlDocument) error {
+	doc.Version = "2.0"
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	err = enc.Encode(doc)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// exportFollowingHandler downloads the accounts the caller follows, as CSV
+// or, with ?format=opml, as an OPML list linking to each account's chirps.
+func (cfg *apiConfig) exportFollowingHandler(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "csv" && format != "opml" {
+		respondWithError(w, http.StatusBadRequest, "Format must be csv or opml", nil)
+		return
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	following, err := cfg.dbQueries.GetFollowing(r.Context(), userId)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't export followed users", err)
+		return
+	}
+
+	if format != "opml" {
+		rows := make([][]string, 0, len(following))
+		for _, f := range following {
+			rows = append(rows, []string{
+				f.UserID.String(),
+				cfg.publicID(f.UserID),
+				f.CreatedAt.Format(time.RFC3339),
+			})
+		}
+		respondWithCSV(w, "chirpy-following.csv", []string{"user_id", "public_id", "followed_at"}, rows)
+		return
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	doc := opmlDocument{
+		Title:   "Chirpy following",
+		Created: time.Now().UTC().Format(time.RFC1123Z),
+	}
+	for _, f := range following {
+		name := cfg.publicID(f.UserID)
+		if name == "" {
+			name = f.UserID.String()
+		}
+		chirps := url.URL{
+			Scheme:   scheme,
+			Host:     r.Host,
+			Path:     "/api/v1/chirps",
+			RawQuery: url.Values{"author_id": {name}, "sort": {"desc"}}.Encode(),
+		}
+		doc.Outline = append(doc.Outline, opmlOutline{
+			Type:    "link",
+			Text:    name,
+			URL:     chirps.String(),
+			Created: f.CreatedAt.UTC().Format(time.RFC1123Z),
+		})
+	}
+
+	w.Header().Set("Content-Type", "text/x-opml; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="chirpy-following.opml"`)
+	w.WriteHeader(http.StatusOK)
+	err = writeOPML(w, doc)
+	if err != nil {
+		log.Printf("Error writing OPML: %s", err)
+	}
+}
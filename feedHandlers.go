@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/fkl13/chirpy/internal/auth"
+	"github.com/fkl13/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// getFeedHandler is the caller's home timeline: chirps of the accounts they
+// follow, newest first. To get the next page, pass the ID of the last chirp
+// as ?before=; it's also the next_cursor of the page.
+func (cfg *apiConfig) getFeedHandler(w http.ResponseWriter, r *http.Request) {
+	const defaultLimit = 20
+	const maxLimit = 100
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	limit := defaultLimit
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		n, err := strconv.Atoi(limitParam)
+		if err != nil || n <= 0 {
+			respondWithError(w, http.StatusBadRequest, "Invalid limit", err)
+			return
+		}
+		limit = min(n, maxLimit)
+	}
+	before := uuid.NullUUID{}
+	if beforeParam := r.URL.Query().Get("before"); beforeParam != "" {
+		id, err := cfg.parseID(beforeParam)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid before", err)
+			return
+		}
+		before = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	chirps, err := cfg.dbQueries.GetFeed(r.Context(), database.GetFeedParams{
+		UserID:     userId,
+		Before:     before,
+		MaxResults: int32(limit + 1),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get feed", err)
+		return
+	}
+	page := listPage{Limit: limit}
+	if len(chirps) > limit {
+		chirps = chirps[:limit]
+		page.HasMore = true
+		page.NextCursor = cfg.exposedID(chirps[limit-1].ID)
+	}
+	respondWithPage(w, r, cfg.newChirps(r.Context(), chirps), page, "before", cfg.wantsEnvelope(r))
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/auth"
+	"github.com/fkl13/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Follow is one entry of a follower or following list. It only names the
+// other account; emails stay private.
+type Follow struct {
+	UserID     string    `json:"user_id"`
+	PublicID   string    `json:"public_id,omitempty"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+// followUserHandler follows the user in the path. Following someone twice
+// is not an error. Users can't follow each other while either one blocks
+// the other.
+func (cfg *apiConfig) followUserHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.setFollow(w, r, true)
+}
+
+func (cfg *apiConfig) unfollowUserHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.setFollow(w, r, false)
+}
+
+func (cfg *apiConfig) setFollow(w http.ResponseWriter, r *http.Request, follow bool) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	targetId, err := cfg.parseID(r.PathValue("userID"))
+	if err != nil {
+		userPolicy.notFound(w, err)
+		return
+	}
+	if targetId == userId {
+		respondWithError(w, http.StatusBadRequest, "You can't follow yourself", nil)
+		return
+	}
+
+	params := database.FollowUserParams{FollowerID: userId, FolloweeID: targetId}
+	if follow {
+		if !cfg.checkActive(w, r, userId) {
+			return
+		}
+		target, err := cfg.getUser(r.Context(), targetId)
+		if err != nil || target.DeactivatedAt.Valid {
+			userPolicy.notFound(w, err)
+			return
+		}
+		blocking, err := cfg.dbQueries.IsBlocked(r.Context(), database.IsBlockedParams{BlockerID: userId, BlockedID: targetId})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't check blocks", err)
+			return
+		}
+		if blocking {
+			respondWithError(w, http.StatusForbidden, "Unblock this user to follow them", nil)
+			return
+		}
+		blocked, err := cfg.dbQueries.IsBlocked(r.Context(), database.IsBlockedParams{BlockerID: targetId, BlockedID: userId})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't check blocks", err)
+			return
+		}
+		if blocked {
+			userPolicy.deny(w, "You can't follow this user", nil)
+			return
+		}
+		_, err = cfg.dbQueries.FollowUser(r.Context(), params)
+	} else {
+		_, err = cfg.dbQueries.UnfollowUser(r.Context(), database.UnfollowUserParams(params))
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save follow", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getFollowersHandler lists who follows the user in the path, most recent
+// followers first.
+func (cfg *apiConfig) getFollowersHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := cfg.activeUserFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := cfg.dbQueries.GetFollowers(r.Context(), userId)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get followers", err)
+		return
+	}
+	follows := make([]Follow, 0, len(rows))
+	for _, row := range rows {
+		follows = append(follows, cfg.newFollow(row.UserID, row.CreatedAt))
+	}
+	respondWithList(w, http.StatusOK, follows, cfg.wantsEnvelope(r))
+}
+
+// getFollowingHandler lists who the user in the path follows, most recently
+// followed first.
+func (cfg *apiConfig) getFollowingHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := cfg.activeUserFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := cfg.dbQueries.GetFollowing(r.Context(), userId)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get followed users", err)
+		return
+	}
+	follows := make([]Follow, 0, len(rows))
+	for _, row := range rows {
+		follows = append(follows, cfg.newFollow(row.UserID, row.CreatedAt))
+	}
+	respondWithList(w, http.StatusOK, follows, cfg.wantsEnvelope(r))
+}
+
+func (cfg *apiConfig) newFollow(userID uuid.UUID, followedAt time.Time) Follow {
+	return Follow{
+		UserID:     cfg.exposedID(userID),
+		PublicID:   cfg.publicID(userID),
+		FollowedAt: followedAt,
+	}
+}
+
+// activeUserFromPath resolves the userID path value to an account that
+// isn't deactivated. If it can't, it has already written the response.
+func (cfg *apiConfig) activeUserFromPath(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userId, err := cfg.parseID(r.PathValue("userID"))
+	if err != nil {
+		userPolicy.notFound(w, err)
+		return uuid.Nil, false
+	}
+	user, err := cfg.getUser(r.Context(), userId)
+	if err != nil || user.DeactivatedAt.Valid {
+		userPolicy.notFound(w, err)
+		return uuid.Nil, false
+	}
+	return user.ID, true
+}
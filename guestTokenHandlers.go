@@ -0,0 +1,110 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultGuestTokenLifetime = time.Hour
+	maxGuestTokenLifetime     = 24 * time.Hour
+)
+
+func guestChirpResource(id uuid.UUID) string {
+	return "chirp:" + id.String()
+}
+
+// createGuestTokenHandler lets operators mint a short-lived token that
+// allows reading a single chirp through the embed endpoint, e.g. from a
+// widget on another site.
+func (cfg *apiConfig) createGuestTokenHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		ChirpID          string `json:"chirp_id"`
+		ExpiresInSeconds int    `json:"expires_in_seconds"`
+	}
+	type response struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+		URL       string    `json:"url"`
+	}
+
+	params := parameters{}
+	err := decodeJSONBody(w, r, &params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	expiresIn := defaultGuestTokenLifetime
+	if params.ExpiresInSeconds != 0 {
+		expiresIn = time.Duration(params.ExpiresInSeconds) * time.Second
+	}
+	if expiresIn <= 0 || expiresIn > maxGuestTokenLifetime {
+		respondWithError(w, http.StatusBadRequest, "expires_in_seconds must be between 1 and 86400", nil)
+		return
+	}
+
+	chirpId, err := cfg.parseID(params.ChirpID)
+	if err != nil {
+		chirpPolicy.notFound(w, err)
+		return
+	}
+	chirp, err := cfg.getChirp(r.Context(), chirpId)
+	if err != nil {
+		chirpPolicy.notFound(w, err)
+		return
+	}
+
+	token, err := auth.MakeGuestToken(guestChirpResource(chirp.ID), cfg.jwtSecret, expiresIn)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create guest token", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, response{
+		Token:     token,
+		ExpiresAt: time.Now().UTC().Add(expiresIn),
+		URL:       "/api/v1/embed/chirps/" + cfg.exposedID(chirp.ID) + "?token=" + token,
+	})
+}
+
+// embedChirpHandler serves a chirp to holders of a guest token for it. The
+// token can be passed as ?token= since embeds often can't set headers.
+func (cfg *apiConfig) embedChirpHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		var err error
+		token, err = auth.GetBearerToken(r.Header)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, "No guest token provided", err)
+			return
+		}
+	}
+	resource, err := auth.ValidateGuestToken(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate guest token", err)
+		return
+	}
+
+	chirpId, err := cfg.parseID(r.PathValue("chirpID"))
+	if err != nil {
+		chirpPolicy.notFound(w, err)
+		return
+	}
+	if resource != guestChirpResource(chirpId) {
+		respondWithError(w, http.StatusForbidden, "Guest token isn't valid for this chirp", nil)
+		return
+	}
+	chirp, err := cfg.getChirp(r.Context(), chirpId)
+	if err != nil {
+		chirpPolicy.notFound(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, cfg.newChirp(r.Context(), chirp))
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/database"
+)
+
+const (
+	maxHashtagLength = 100
+	trendingWindow   = 24 * time.Hour
+)
+
+// hashtagPattern matches #tag at the start of a chirp or after a character
+// that can't be part of a word, so "C#" and "&#39;" aren't tags. The same
+// pattern backfilled existing chirps in the chirp_hashtags migration.
+var hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&])#(\p{L}[\p{L}\p{N}_]*)`)
+
+// extractHashtags returns the lowercased hashtags of a chirp body, each
+// once, in order of appearance.
+func extractHashtags(body string) []string {
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, match := range hashtagPattern.FindAllStringSubmatch(body, -1) {
+		tag := strings.ToLower(match[1])
+		if len([]rune(tag)) > maxHashtagLength || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// storeHashtags indexes the hashtags of a chirp. It runs in the transaction
+// that stores the chirp.
+func storeHashtags(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	tags := extractHashtags(chirp.Body)
+	if len(tags) == 0 {
+		return nil
+	}
+	return q.AddChirpHashtags(ctx, database.AddChirpHashtagsParams{
+		ChirpID: chirp.ID,
+		Tags:    tags,
+	})
+}
+
+// getHashtagChirpsHandler lists the newest chirps tagged with a hashtag.
+func (cfg *apiConfig) getHashtagChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	const maxChirps = 100
+
+	tag := strings.ToLower(strings.TrimPrefix(r.PathValue("tag"), "#"))
+	if tag == "" {
+		respondWithError(w, http.StatusBadRequest, "Missing hashtag", nil)
+		return
+	}
+	chirps, err := cfg.dbQueries.GetChirpsByHashtag(r.Context(), database.GetChirpsByHashtagParams{
+		Tag:      tag,
+		Limit:    maxChirps,
+		ViewerID: cfg.viewerID(r),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get chirps", err)
+		return
+	}
+	respondWithList(w, http.StatusOK, cfg.newChirps(r.Context(), chirps), cfg.wantsEnvelope(r))
+}
+
+// getTrendingHashtagsHandler lists the hashtags used in the most chirps
+// over the last day.
+func (cfg *apiConfig) getTrendingHashtagsHandler(w http.ResponseWriter, r *http.Request) {
+	const maxTags = 10
+
+	type trendingHashtag struct {
+		Tag    string `json:"tag"`
+		Chirps int64  `json:"chirps"`
+	}
+
+	tags, err := cfg.dbQueries.GetTrendingHashtags(r.Context(), database.GetTrendingHashtagsParams{
+		CreatedAt: time.Now().UTC().Add(-trendingWindow),
+		Limit:     maxTags,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get trending hashtags", err)
+		return
+	}
+	res := make([]trendingHashtag, 0, len(tags))
+	for _, tag := range tags {
+		res = append(res, trendingHashtag{Tag: tag.Tag, Chirps: tag.Chirps})
+	}
+	respondWithList(w, http.StatusOK, res, cfg.wantsEnvelope(r))
+}
@@ -14,7 +14,8 @@ import (
 )
 
 const (
-	TokenIssuer string = "chirpy"
+	TokenIssuer      string = "chirpy"
+	GuestTokenIssuer string = "chirpy-guest"
 )
 
 func HashPassword(password string) (string, error) {
@@ -43,6 +44,14 @@ func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (str
 }
 
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+	id, _, err := ValidateJWTIssuedAt(tokenString, tokenSecret)
+	return id, err
+}
+
+// ValidateJWTIssuedAt is ValidateJWT that also returns when the token was
+// issued, so tokens from before a logout can be refused. Tokens without an
+// issue time report the zero time.
+func ValidateJWTIssuedAt(tokenString, tokenSecret string) (uuid.UUID, time.Time, error) {
 	claim := jwt.RegisteredClaims{}
 	token, err := jwt.ParseWithClaims(
 		tokenString,
@@ -50,28 +59,66 @@ func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
 		func(token *jwt.Token) (interface{}, error) { return []byte(tokenSecret), nil },
 	)
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, time.Time{}, err
 	}
 
 	userIDString, err := token.Claims.GetSubject()
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, time.Time{}, err
 	}
 
 	issuer, err := token.Claims.GetIssuer()
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, time.Time{}, err
 	}
 
 	if issuer != TokenIssuer {
-		return uuid.Nil, fmt.Errorf("invalid issuer")
+		return uuid.Nil, time.Time{}, fmt.Errorf("invalid issuer")
 	}
 
 	id, err := uuid.Parse(userIDString)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("invalid user ID: %w", err)
+		return uuid.Nil, time.Time{}, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	var issuedAt time.Time
+	if claim.IssuedAt != nil {
+		issuedAt = claim.IssuedAt.Time
+	}
+	return id, issuedAt, nil
+}
+
+// MakeGuestToken signs a read-only token for a single resource such as
+// "chirp:<id>". Guest tokens use their own issuer so ValidateJWT never
+// accepts them as user tokens.
+func MakeGuestToken(resource, tokenSecret string, expiresIn time.Duration) (string, error) {
+	claim := &jwt.RegisteredClaims{
+		Issuer:    GuestTokenIssuer,
+		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
+		Subject:   resource,
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claim)
+	return token.SignedString([]byte(tokenSecret))
+}
+
+// ValidateGuestToken returns the resource a guest token grants access to.
+func ValidateGuestToken(tokenString, tokenSecret string) (string, error) {
+	claim := jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(
+		tokenString,
+		&claim,
+		func(token *jwt.Token) (interface{}, error) { return []byte(tokenSecret), nil },
+		jwt.WithIssuer(GuestTokenIssuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return "", err
+	}
+	if claim.Subject == "" {
+		return "", fmt.Errorf("guest token has no resource")
 	}
-	return id, nil
+	return claim.Subject, nil
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
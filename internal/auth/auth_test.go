@@ -2,6 +2,7 @@ package auth
 
 import (
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -110,6 +111,40 @@ func TestValidateJWT(t *testing.T) {
 	}
 }
 
+func TestValidateGuestToken(t *testing.T) {
+	guestToken, _ := MakeGuestToken("chirp:1", "secret", time.Hour)
+	expiredToken, _ := MakeGuestToken("chirp:1", "secret", -time.Hour)
+	userToken, _ := MakeJWT(uuid.New(), "secret", time.Hour)
+
+	tests := []struct {
+		name         string
+		tokenString  string
+		wantResource string
+		wantErr      bool
+	}{
+		{name: "Valid token", tokenString: guestToken, wantResource: "chirp:1"},
+		{name: "Expired token", tokenString: expiredToken, wantErr: true},
+		{name: "User token", tokenString: userToken, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotResource, err := ValidateGuestToken(tt.tokenString, "secret")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateGuestToken() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if gotResource != tt.wantResource {
+				t.Errorf("ValidateGuestToken() gotResource = %v, want %v", gotResource, tt.wantResource)
+			}
+		})
+	}
+
+	if _, err := ValidateJWT(guestToken, "secret"); err == nil {
+		t.Error("ValidateJWT() accepted a guest token")
+	}
+}
+
 func TestGetBearerToken(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -150,3 +185,74 @@ func TestGetBearerToken(t *testing.T) {
 		})
 	}
 }
+
+func FuzzGetBearerToken(f *testing.F) {
+	f.Add("Bearer Token")
+	f.Add("Bearer ")
+	f.Add("Bearer Bearer Token")
+	f.Add("ApiKey Token")
+	f.Add("")
+	f.Fuzz(func(t *testing.T, value string) {
+		token, err := GetBearerToken(http.Header{"Authorization": {value}})
+		if err != nil {
+			return
+		}
+		if token != strings.TrimSpace(token) {
+			t.Errorf("GetBearerToken(%q) = %q, not trimmed", value, token)
+		}
+		if !strings.Contains(value, "Bearer ") {
+			t.Errorf("GetBearerToken(%q) accepted a header without Bearer", value)
+		}
+	})
+}
+
+func FuzzGetAPIKey(f *testing.F) {
+	f.Add("ApiKey Key")
+	f.Add("ApiKey ")
+	f.Add("Bearer Key")
+	f.Add("")
+	f.Fuzz(func(t *testing.T, value string) {
+		key, err := GetAPIKey(http.Header{"Authorization": {value}})
+		if err != nil {
+			return
+		}
+		if key != strings.TrimSpace(key) {
+			t.Errorf("GetAPIKey(%q) = %q, not trimmed", value, key)
+		}
+		if !strings.Contains(value, "ApiKey ") {
+			t.Errorf("GetAPIKey(%q) accepted a header without ApiKey", value)
+		}
+	})
+}
+
+func FuzzValidateJWT(f *testing.F) {
+	validToken, _ := MakeJWT(uuid.New(), "secret", time.Hour)
+	f.Add(validToken)
+	f.Add("invalid.token.string")
+	f.Add("")
+	f.Fuzz(func(t *testing.T, token string) {
+		id, err := ValidateJWT(token, "secret")
+		if err != nil && id != uuid.Nil {
+			t.Errorf("ValidateJWT(%q) returned %v with error %v", token, id, err)
+		}
+	})
+}
+
+func TestValidateJWTIssuedAt(t *testing.T) {
+	userID := uuid.New()
+	before := time.Now().Truncate(time.Second)
+	token, err := MakeJWT(userID, "secret", time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT() error = %v", err)
+	}
+	gotUserID, issuedAt, err := ValidateJWTIssuedAt(token, "secret")
+	if err != nil {
+		t.Fatalf("ValidateJWTIssuedAt() error = %v", err)
+	}
+	if gotUserID != userID {
+		t.Errorf("ValidateJWTIssuedAt() gotUserID = %v, want %v", gotUserID, userID)
+	}
+	if issuedAt.Before(before) || issuedAt.After(time.Now()) {
+		t.Errorf("ValidateJWTIssuedAt() issuedAt = %v, want about %v", issuedAt, before)
+	}
+}
@@ -0,0 +1,81 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := New(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	call := func(success bool) error {
+		err := b.Allow()
+		if err != nil {
+			return err
+		}
+		b.Record(success)
+		return nil
+	}
+
+	if err := call(false); err != nil {
+		t.Fatalf("first failure: Allow() error = %v", err)
+	}
+	if got := b.State(); got != Closed {
+		t.Errorf("after one failure State() = %v, want closed", got)
+	}
+	call(false)
+	if got := b.State(); got != Open {
+		t.Fatalf("after two failures State() = %v, want open", got)
+	}
+	if err := b.Allow(); err != ErrOpen {
+		t.Errorf("open breaker: Allow() error = %v, want ErrOpen", err)
+	}
+	if got := b.RetryAfter(); got != time.Minute {
+		t.Errorf("RetryAfter() = %v, want 1m", got)
+	}
+
+	now = now.Add(time.Minute)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("probe: Allow() error = %v", err)
+	}
+	if err := b.Allow(); err != ErrOpen {
+		t.Errorf("second call during probe: Allow() error = %v, want ErrOpen", err)
+	}
+	b.Record(false)
+	if got := b.State(); got != Open {
+		t.Fatalf("failed probe: State() = %v, want open", got)
+	}
+
+	now = now.Add(time.Minute)
+	if err := call(true); err != nil {
+		t.Fatalf("second probe: Allow() error = %v", err)
+	}
+	if got := b.State(); got != Closed {
+		t.Errorf("successful probe: State() = %v, want closed", got)
+	}
+}
+
+func TestSuccessResetsFailures(t *testing.T) {
+	b := New(2, time.Minute)
+	b.Allow()
+	b.Record(false)
+	b.Allow()
+	b.Record(true)
+	b.Allow()
+	b.Record(false)
+	if got := b.State(); got != Closed {
+		t.Errorf("State() = %v, want closed", got)
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	b := New(0, time.Minute)
+	for range 10 {
+		if err := b.Allow(); err != nil {
+			t.Fatalf("Allow() error = %v", err)
+		}
+		b.Record(false)
+	}
+}
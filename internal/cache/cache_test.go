@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCacheGetPut(t *testing.T) {
+	c := New[string, int](2, time.Minute)
+
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("Get() on empty cache reported a hit")
+	}
+	c.Put("a", 1)
+	c.Put("b", 2)
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("Get(a) = %v, %v, want 1, true", v, ok)
+	}
+
+	// "b" is now the least recently used entry and gets evicted.
+	c.Put("c", 3)
+	if _, ok := c.Get("b"); ok {
+		t.Error("Get(b) after eviction reported a hit")
+	}
+	if v, ok := c.Get("c"); !ok || v != 3 {
+		t.Errorf("Get(c) = %v, %v, want 3, true", v, ok)
+	}
+
+	c.Invalidate("a")
+	if _, ok := c.Get("a"); ok {
+		t.Error("Get(a) after Invalidate() reported a hit")
+	}
+
+	stats := c.Stats()
+	if stats.Hits != 2 || stats.Misses != 3 || stats.Entries != 1 {
+		t.Errorf("Stats() = %+v, want 2 hits, 3 misses, 1 entry", stats)
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	now := time.Now()
+	c := New[string, int](10, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Put("a", 1)
+	now = now.Add(2 * time.Minute)
+	if _, ok := c.Get("a"); ok {
+		t.Error("Get() returned an expired entry")
+	}
+	if stats := c.Stats(); stats.Entries != 0 {
+		t.Errorf("expired entry wasn't removed, %d entries left", stats.Entries)
+	}
+}
+
+func TestCacheDisabled(t *testing.T) {
+	c := New[string, int](0, time.Minute)
+	c.Put("a", 1)
+	if _, ok := c.Get("a"); ok {
+		t.Error("disabled cache reported a hit")
+	}
+}
+
+func TestStatsHitRate(t *testing.T) {
+	tests := []struct {
+		stats Stats
+		want  float64
+	}{
+		{Stats{}, 0},
+		{Stats{Hits: 3, Misses: 1}, 0.75},
+	}
+	for _, tt := range tests {
+		if got := tt.stats.HitRate(); got != tt.want {
+			t.Errorf("%+v.HitRate() = %v, want %v", tt.stats, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package captcha
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("secret") != "secret" || r.Form.Get("response") != "good" {
+			w.Write([]byte(`{"success": false, "error-codes": ["invalid-input-response"]}`))
+			return
+		}
+		w.Write([]byte(`{"success": true}`))
+	}))
+	defer server.Close()
+
+	if New(server.URL, "") != nil {
+		t.Error("New() without a secret should return nil")
+	}
+	v := New(server.URL, "secret")
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "Valid token", token: "good"},
+		{name: "Rejected token", token: "bad", wantErr: ErrFailed},
+		{name: "Missing token", token: "", wantErr: ErrFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Verify(ctx, tt.token, "192.0.2.1")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,444 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/errreport"
+	"github.com/fkl13/chirpy/internal/mail"
+	"github.com/fkl13/chirpy/internal/search"
+)
+
+type Config struct {
+	DBURL     string
+	Platform  string
+	JWTSecret string
+	PolkaKey  string
+	AdminKey  string
+	// AdminKeys maps the names of admins to their API keys, read from
+	// ADMIN_KEYS as name=key pairs. The name is recorded in the audit log
+	// for what they do; ADMIN_KEY is the key of an admin called "admin".
+	AdminKeys map[string]string
+
+	// CountryHeader names a header set by a trusted proxy that carries the
+	// client's country code, e.g. CF-IPCountry.
+	CountryHeader string
+	// ClientIPHeader names a header set by a trusted proxy that carries the
+	// client's IP address, e.g. X-Forwarded-For or CF-Connecting-IP. Left
+	// empty, the address of the connection is used.
+	ClientIPHeader    string
+	EnvelopeResponses bool
+
+	// StripEmailPlusTags makes foo+tag@example.com and foo@example.com the
+	// same account. Stored addresses aren't rewritten, so turn it on before
+	// users with plus tags sign up.
+	StripEmailPlusTags bool
+
+	Mail            mail.Config
+	MailTemplateDir string
+
+	Search search.Config
+
+	RuntimeConfigFile string
+
+	LogLevel  slog.Level
+	LogFormat string
+
+	// ListenAddr is a TCP address or "unix:<path>". It's ignored when
+	// systemd passes in a socket.
+	ListenAddr string
+
+	// CacheSize bounds the chirp and user caches; 0 disables them.
+	CacheSize int
+	CacheTTL  time.Duration
+
+	// RateLimit is the number of requests a client may make per
+	// RateLimitWindow; 0 disables rate limiting.
+	RateLimit       int
+	RateLimitWindow time.Duration
+
+	// BreakerThreshold is how many consecutive failures open a route's
+	// circuit breaker for BreakerCooldown; 0 disables the breakers.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+
+	// DeactivationGracePeriod is how long a deactivated account can still be
+	// reactivated before it's deleted.
+	DeactivationGracePeriod time.Duration
+
+	// AccessTokenTTL is how long a JWT is valid and RefreshTokenTTL how long
+	// a refresh token is, counted from its last use.
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	// EventBusURL points at a NATS server, e.g. nats://localhost:4222, that
+	// receives chirpy events under EventSubjectPrefix. Empty disables it.
+	EventBusURL        string
+	EventSubjectPrefix string
+
+	// ErrorReporting sends 5xx responses and panics to Sentry or a webhook.
+	ErrorReporting errreport.Config
+
+	// PublicIDKey turns on obfuscated public IDs for chirps and users.
+	PublicIDKey string
+
+	// SecurityContact is a comma-separated list of contact URIs, e.g.
+	// mailto:security@example.com, published in /.well-known/security.txt.
+	// Empty leaves security.txt unserved.
+	SecurityContact   string
+	SecurityPolicyURL string
+
+	// CaptchaSecret turns on the public abuse report endpoint, which checks
+	// a captcha with CaptchaVerifyURL (Cloudflare Turnstile by default).
+	CaptchaSecret    string
+	CaptchaVerifyURL string
+	// AbuseReportLimit is how many abuse reports a signed-in user may send
+	// per hour. AnonymousAbuseReportLimit is the stricter limit per client
+	// IP for reports without an account, so signing out doesn't get around
+	// the reporter reputation of an account. It defaults to half of
+	// AbuseReportLimit, rounded up, which leaves room for a few reporters
+	// sharing an address behind NAT.
+	AbuseReportLimit          int
+	AnonymousAbuseReportLimit int
+
+	// AnalyticsRetention is how long anonymized analytics are kept before
+	// they're purged; 0 turns analytics off entirely.
+	AnalyticsRetention time.Duration
+
+	// WebAuthnRPID is the domain passkeys are registered for, e.g.
+	// chirpy.example; empty turns passkeys off. WebAuthnOrigins lists the
+	// origins the WebAuthn API may be called from and defaults to
+	// https://<WebAuthnRPID>.
+	WebAuthnRPID    string
+	WebAuthnOrigins []string
+
+	// PublicURL is where the web app is served, e.g. https://chirpy.example.
+	// Links in emails point there; empty turns email verification off.
+	PublicURL string
+
+	// RecordingDir is where the dev-only request recorder also writes its
+	// recordings. Empty keeps them in memory only.
+	RecordingDir string
+}
+
+// Load reads the configuration from the environment. Every setting NAME can
+// instead be read from a file given as NAME_FILE, and values of the form
+// "vault:<path>#<key>" are fetched from Vault when VAULT_ADDR is set.
+func Load() (Config, error) {
+	l, err := newLoader(os.Getenv, os.ReadFile)
+	if err != nil {
+		return Config{}, err
+	}
+	return l.load()
+}
+
+func (l *loader) load() (Config, error) {
+	cfg := Config{}
+	required := []struct {
+		name string
+		dst  *string
+	}{
+		{"DB_URL", &cfg.DBURL},
+		{"PLATFORM", &cfg.Platform},
+		{"JWT_SECRET", &cfg.JWTSecret},
+		{"POLKA_KEY", &cfg.PolkaKey},
+	}
+	for _, setting := range required {
+		v, err := l.get(setting.name)
+		if err != nil {
+			return Config{}, err
+		}
+		if v == "" {
+			return Config{}, fmt.Errorf("%s must be set", setting.name)
+		}
+		*setting.dst = v
+	}
+
+	optional := []struct {
+		name string
+		dst  *string
+	}{
+		{"ADMIN_KEY", &cfg.AdminKey},
+		{"COUNTRY_HEADER", &cfg.CountryHeader},
+		{"CLIENT_IP_HEADER", &cfg.ClientIPHeader},
+		{"MAIL_PROVIDER", &cfg.Mail.Provider},
+		{"MAIL_FROM", &cfg.Mail.From},
+		{"SMTP_ADDR", &cfg.Mail.SMTPAddr},
+		{"SMTP_USERNAME", &cfg.Mail.SMTPUsername},
+		{"SMTP_PASSWORD", &cfg.Mail.SMTPPassword},
+		{"SES_REGION", &cfg.Mail.SESRegion},
+		{"MAIL_TEMPLATE_DIR", &cfg.MailTemplateDir},
+		{"SEARCH_BACKEND", &cfg.Search.Backend},
+		{"SEARCH_URL", &cfg.Search.URL},
+		{"SEARCH_API_KEY", &cfg.Search.APIKey},
+		{"SEARCH_INDEX", &cfg.Search.Index},
+		{"RUNTIME_CONFIG_FILE", &cfg.RuntimeConfigFile},
+		{"LISTEN_ADDR", &cfg.ListenAddr},
+		{"PUBLIC_ID_KEY", &cfg.PublicIDKey},
+		{"SENTRY_DSN", &cfg.ErrorReporting.SentryDSN},
+		{"ERROR_WEBHOOK_URL", &cfg.ErrorReporting.WebhookURL},
+		{"EVENT_BUS_URL", &cfg.EventBusURL},
+		{"EVENT_SUBJECT_PREFIX", &cfg.EventSubjectPrefix},
+		{"SECURITY_CONTACT", &cfg.SecurityContact},
+		{"SECURITY_POLICY_URL", &cfg.SecurityPolicyURL},
+		{"CAPTCHA_SECRET", &cfg.CaptchaSecret},
+		{"CAPTCHA_VERIFY_URL", &cfg.CaptchaVerifyURL},
+		{"RECORDING_DIR", &cfg.RecordingDir},
+		{"WEBAUTHN_RP_ID", &cfg.WebAuthnRPID},
+		{"PUBLIC_URL", &cfg.PublicURL},
+	}
+	for _, setting := range optional {
+		v, err := l.get(setting.name)
+		if err != nil {
+			return Config{}, err
+		}
+		*setting.dst = v
+	}
+
+	cfg.AdminKeys = map[string]string{}
+	adminKeys, err := l.get("ADMIN_KEYS")
+	if err != nil {
+		return Config{}, err
+	}
+	for _, pair := range strings.Split(adminKeys, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, key, ok := strings.Cut(pair, "=")
+		name, key = strings.TrimSpace(name), strings.TrimSpace(key)
+		if !ok || name == "" || key == "" {
+			return Config{}, fmt.Errorf("invalid ADMIN_KEYS entry for %q, want name=key", name)
+		}
+		if _, dup := cfg.AdminKeys[name]; dup {
+			return Config{}, fmt.Errorf("ADMIN_KEYS names %q twice", name)
+		}
+		cfg.AdminKeys[name] = key
+	}
+	if cfg.AdminKey != "" {
+		if _, dup := cfg.AdminKeys["admin"]; dup {
+			return Config{}, fmt.Errorf("ADMIN_KEYS names \"admin\", which is ADMIN_KEY")
+		}
+		cfg.AdminKeys["admin"] = cfg.AdminKey
+	}
+
+	envelope, err := l.get("ENVELOPE_RESPONSES")
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.EnvelopeResponses = envelope == "true"
+
+	stripPlusTags, err := l.get("EMAIL_STRIP_PLUS_TAGS")
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.StripEmailPlusTags = stripPlusTags == "true"
+
+	origins, err := l.get("WEBAUTHN_ORIGINS")
+	if err != nil {
+		return Config{}, err
+	}
+	for _, origin := range strings.Split(origins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			cfg.WebAuthnOrigins = append(cfg.WebAuthnOrigins, origin)
+		}
+	}
+	if cfg.WebAuthnRPID != "" && len(cfg.WebAuthnOrigins) == 0 {
+		cfg.WebAuthnOrigins = []string{"https://" + cfg.WebAuthnRPID}
+	}
+
+	if cfg.PublicURL != "" {
+		u, err := url.Parse(cfg.PublicURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+			return Config{}, fmt.Errorf("invalid PUBLIC_URL %q", cfg.PublicURL)
+		}
+		cfg.PublicURL = strings.TrimSuffix(cfg.PublicURL, "/")
+	}
+
+	if cfg.ListenAddr == "" {
+		cfg.ListenAddr = ":8080"
+	}
+	cfg.ErrorReporting.Environment = cfg.Platform
+	if cfg.EventSubjectPrefix == "" {
+		cfg.EventSubjectPrefix = "chirpy."
+	}
+
+	cfg.CacheSize = 10000
+	cacheSize, err := l.get("CACHE_SIZE")
+	if err != nil {
+		return Config{}, err
+	}
+	if cacheSize != "" {
+		cfg.CacheSize, err = strconv.Atoi(cacheSize)
+		if err != nil || cfg.CacheSize < 0 {
+			return Config{}, fmt.Errorf("invalid CACHE_SIZE %q", cacheSize)
+		}
+	}
+
+	cfg.CacheTTL = 5 * time.Minute
+	cacheTTL, err := l.get("CACHE_TTL")
+	if err != nil {
+		return Config{}, err
+	}
+	if cacheTTL != "" {
+		cfg.CacheTTL, err = time.ParseDuration(cacheTTL)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid CACHE_TTL: %w", err)
+		}
+	}
+
+	cfg.RateLimit = 600
+	rateLimit, err := l.get("RATE_LIMIT")
+	if err != nil {
+		return Config{}, err
+	}
+	if rateLimit != "" {
+		cfg.RateLimit, err = strconv.Atoi(rateLimit)
+		if err != nil || cfg.RateLimit < 0 {
+			return Config{}, fmt.Errorf("invalid RATE_LIMIT %q", rateLimit)
+		}
+	}
+
+	cfg.RateLimitWindow = time.Minute
+	rateLimitWindow, err := l.get("RATE_LIMIT_WINDOW")
+	if err != nil {
+		return Config{}, err
+	}
+	if rateLimitWindow != "" {
+		cfg.RateLimitWindow, err = time.ParseDuration(rateLimitWindow)
+		if err != nil || cfg.RateLimitWindow <= 0 {
+			return Config{}, fmt.Errorf("invalid RATE_LIMIT_WINDOW %q", rateLimitWindow)
+		}
+	}
+
+	cfg.BreakerThreshold = 5
+	breakerThreshold, err := l.get("BREAKER_THRESHOLD")
+	if err != nil {
+		return Config{}, err
+	}
+	if breakerThreshold != "" {
+		cfg.BreakerThreshold, err = strconv.Atoi(breakerThreshold)
+		if err != nil || cfg.BreakerThreshold < 0 {
+			return Config{}, fmt.Errorf("invalid BREAKER_THRESHOLD %q", breakerThreshold)
+		}
+	}
+
+	cfg.BreakerCooldown = 30 * time.Second
+	breakerCooldown, err := l.get("BREAKER_COOLDOWN")
+	if err != nil {
+		return Config{}, err
+	}
+	if breakerCooldown != "" {
+		cfg.BreakerCooldown, err = time.ParseDuration(breakerCooldown)
+		if err != nil || cfg.BreakerCooldown <= 0 {
+			return Config{}, fmt.Errorf("invalid BREAKER_COOLDOWN %q", breakerCooldown)
+		}
+	}
+
+	cfg.DeactivationGracePeriod = 30 * 24 * time.Hour
+	gracePeriod, err := l.get("DEACTIVATION_GRACE_PERIOD")
+	if err != nil {
+		return Config{}, err
+	}
+	if gracePeriod != "" {
+		cfg.DeactivationGracePeriod, err = time.ParseDuration(gracePeriod)
+		if err != nil || cfg.DeactivationGracePeriod < 0 {
+			return Config{}, fmt.Errorf("invalid DEACTIVATION_GRACE_PERIOD %q", gracePeriod)
+		}
+	}
+
+	cfg.AccessTokenTTL = time.Hour
+	accessTokenTTL, err := l.get("ACCESS_TOKEN_TTL")
+	if err != nil {
+		return Config{}, err
+	}
+	if accessTokenTTL != "" {
+		cfg.AccessTokenTTL, err = time.ParseDuration(accessTokenTTL)
+		if err != nil || cfg.AccessTokenTTL <= 0 {
+			return Config{}, fmt.Errorf("invalid ACCESS_TOKEN_TTL %q", accessTokenTTL)
+		}
+	}
+
+	cfg.RefreshTokenTTL = 60 * 24 * time.Hour
+	refreshTokenTTL, err := l.get("REFRESH_TOKEN_TTL")
+	if err != nil {
+		return Config{}, err
+	}
+	if refreshTokenTTL != "" {
+		cfg.RefreshTokenTTL, err = time.ParseDuration(refreshTokenTTL)
+		if err != nil || cfg.RefreshTokenTTL <= 0 {
+			return Config{}, fmt.Errorf("invalid REFRESH_TOKEN_TTL %q", refreshTokenTTL)
+		}
+	}
+	if cfg.RefreshTokenTTL < cfg.AccessTokenTTL {
+		return Config{}, fmt.Errorf("REFRESH_TOKEN_TTL %v is shorter than ACCESS_TOKEN_TTL %v", cfg.RefreshTokenTTL, cfg.AccessTokenTTL)
+	}
+
+	cfg.AbuseReportLimit = 5
+	abuseReportLimit, err := l.get("ABUSE_REPORT_LIMIT")
+	if err != nil {
+		return Config{}, err
+	}
+	if abuseReportLimit != "" {
+		cfg.AbuseReportLimit, err = strconv.Atoi(abuseReportLimit)
+		if err != nil || cfg.AbuseReportLimit < 0 {
+			return Config{}, fmt.Errorf("invalid ABUSE_REPORT_LIMIT %q", abuseReportLimit)
+		}
+	}
+
+	cfg.AnonymousAbuseReportLimit = (cfg.AbuseReportLimit + 1) / 2
+	anonymousAbuseReportLimit, err := l.get("ANONYMOUS_ABUSE_REPORT_LIMIT")
+	if err != nil {
+		return Config{}, err
+	}
+	if anonymousAbuseReportLimit != "" {
+		cfg.AnonymousAbuseReportLimit, err = strconv.Atoi(anonymousAbuseReportLimit)
+		if err != nil || cfg.AnonymousAbuseReportLimit < 0 {
+			return Config{}, fmt.Errorf("invalid ANONYMOUS_ABUSE_REPORT_LIMIT %q", anonymousAbuseReportLimit)
+		}
+	}
+	if cfg.AbuseReportLimit > 0 && (cfg.AnonymousAbuseReportLimit == 0 || cfg.AnonymousAbuseReportLimit > cfg.AbuseReportLimit) {
+		return Config{}, fmt.Errorf("ANONYMOUS_ABUSE_REPORT_LIMIT %d is looser than ABUSE_REPORT_LIMIT %d", cfg.AnonymousAbuseReportLimit, cfg.AbuseReportLimit)
+	}
+
+	cfg.AnalyticsRetention = 90 * 24 * time.Hour
+	analyticsRetention, err := l.get("ANALYTICS_RETENTION")
+	if err != nil {
+		return Config{}, err
+	}
+	if analyticsRetention != "" {
+		cfg.AnalyticsRetention, err = time.ParseDuration(analyticsRetention)
+		if err != nil || cfg.AnalyticsRetention < 0 {
+			return Config{}, fmt.Errorf("invalid ANALYTICS_RETENTION %q", analyticsRetention)
+		}
+	}
+
+	level, err := l.get("LOG_LEVEL")
+	if err != nil {
+		return Config{}, err
+	}
+	if level != "" {
+		err = cfg.LogLevel.UnmarshalText([]byte(level))
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid LOG_LEVEL: %w", err)
+		}
+	}
+
+	cfg.LogFormat, err = l.get("LOG_FORMAT")
+	if err != nil {
+		return Config{}, err
+	}
+	switch cfg.LogFormat {
+	case "":
+		cfg.LogFormat = "text"
+	case "text", "json":
+	default:
+		return Config{}, fmt.Errorf("LOG_FORMAT must be text or json, got %q", cfg.LogFormat)
+	}
+
+	return cfg, nil
+}
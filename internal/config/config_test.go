@@ -0,0 +1,330 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func fakeEnv(env map[string]string) func(string) string {
+	return func(name string) string { return env[name] }
+}
+
+func fakeFiles(files map[string]string) func(string) ([]byte, error) {
+	return func(path string) ([]byte, error) {
+		content, ok := files[path]
+		if !ok {
+			return nil, fmt.Errorf("%s: no such file", path)
+		}
+		return []byte(content), nil
+	}
+}
+
+func TestLoaderGet(t *testing.T) {
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/chirpy":
+			fmt.Fprint(w, `{"data": {"data": {"jwt_secret": "from-vault-v2"}}}`)
+		case "/v1/kv/chirpy":
+			fmt.Fprint(w, `{"data": {"jwt_secret": "from-vault-v1"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer vault.Close()
+
+	files := fakeFiles(map[string]string{
+		"/run/secrets/jwt":   "from-file\n",
+		"/run/secrets/token": "root\n",
+	})
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "Plain env var",
+			env:  map[string]string{"JWT_SECRET": "plain"},
+			want: "plain",
+		},
+		{
+			name: "File wins over env var",
+			env:  map[string]string{"JWT_SECRET": "plain", "JWT_SECRET_FILE": "/run/secrets/jwt"},
+			want: "from-file",
+		},
+		{
+			name:    "Missing file",
+			env:     map[string]string{"JWT_SECRET_FILE": "/run/secrets/missing"},
+			wantErr: true,
+		},
+		{
+			name: "Vault KV v2",
+			env:  map[string]string{"VAULT_ADDR": vault.URL, "VAULT_TOKEN": "root", "JWT_SECRET": "vault:secret/data/chirpy#jwt_secret"},
+			want: "from-vault-v2",
+		},
+		{
+			name: "Vault KV v1 with token from file",
+			env:  map[string]string{"VAULT_ADDR": vault.URL, "VAULT_TOKEN_FILE": "/run/secrets/token", "JWT_SECRET": "vault:kv/chirpy#jwt_secret"},
+			want: "from-vault-v1",
+		},
+		{
+			name:    "Vault missing key",
+			env:     map[string]string{"VAULT_ADDR": vault.URL, "VAULT_TOKEN": "root", "JWT_SECRET": "vault:secret/data/chirpy#nope"},
+			wantErr: true,
+		},
+		{
+			name: "Vault reference without Vault configured is literal",
+			env:  map[string]string{"JWT_SECRET": "vault:secret/data/chirpy#jwt_secret"},
+			want: "vault:secret/data/chirpy#jwt_secret",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := newLoader(fakeEnv(tt.env), files)
+			if err != nil {
+				t.Fatalf("newLoader() error = %v", err)
+			}
+			got, err := l.get("JWT_SECRET")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("get() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("get() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRequiresSettings(t *testing.T) {
+	env := map[string]string{
+		"DB_URL":     "postgres://localhost/chirpy",
+		"PLATFORM":   "dev",
+		"JWT_SECRET": "secret",
+	}
+	l, err := newLoader(fakeEnv(env), fakeFiles(nil))
+	if err != nil {
+		t.Fatalf("newLoader() error = %v", err)
+	}
+	_, err = l.load()
+	if err == nil {
+		t.Fatal("load() without POLKA_KEY should fail")
+	}
+
+	env["POLKA_KEY"] = "polka"
+	cfg, err := l.load()
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if cfg.JWTSecret != "secret" || cfg.Mail.Provider != "" {
+		t.Errorf("load() = %+v", cfg)
+	}
+	if cfg.LogLevel != slog.LevelInfo || cfg.LogFormat != "text" {
+		t.Errorf("load() logging = %v/%v, want INFO/text", cfg.LogLevel, cfg.LogFormat)
+	}
+
+	env["LOG_LEVEL"] = "debug"
+	env["LOG_FORMAT"] = "json"
+	cfg, err = l.load()
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if cfg.LogLevel != slog.LevelDebug || cfg.LogFormat != "json" {
+		t.Errorf("load() logging = %v/%v, want DEBUG/json", cfg.LogLevel, cfg.LogFormat)
+	}
+
+	env["LOG_FORMAT"] = "xml"
+	_, err = l.load()
+	if err == nil {
+		t.Error("load() with LOG_FORMAT=xml should fail")
+	}
+}
+
+func TestLoadWebAuthnOrigins(t *testing.T) {
+	env := map[string]string{
+		"DB_URL":         "postgres://localhost/chirpy",
+		"PLATFORM":       "dev",
+		"JWT_SECRET":     "secret",
+		"POLKA_KEY":      "polka",
+		"WEBAUTHN_RP_ID": "chirpy.example",
+	}
+	l, err := newLoader(fakeEnv(env), fakeFiles(nil))
+	if err != nil {
+		t.Fatalf("newLoader() error = %v", err)
+	}
+	cfg, err := l.load()
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if !reflect.DeepEqual(cfg.WebAuthnOrigins, []string{"https://chirpy.example"}) {
+		t.Errorf("default origins = %v", cfg.WebAuthnOrigins)
+	}
+
+	env["WEBAUTHN_ORIGINS"] = "https://chirpy.example, https://app.chirpy.example"
+	cfg, err = l.load()
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if !reflect.DeepEqual(cfg.WebAuthnOrigins, []string{"https://chirpy.example", "https://app.chirpy.example"}) {
+		t.Errorf("origins = %v", cfg.WebAuthnOrigins)
+	}
+}
+
+func TestLoadTokenTTLs(t *testing.T) {
+	env := map[string]string{
+		"DB_URL":     "postgres://localhost/chirpy",
+		"PLATFORM":   "dev",
+		"JWT_SECRET": "secret",
+		"POLKA_KEY":  "polka",
+	}
+	l, err := newLoader(fakeEnv(env), fakeFiles(nil))
+	if err != nil {
+		t.Fatalf("newLoader() error = %v", err)
+	}
+	cfg, err := l.load()
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if cfg.AccessTokenTTL != time.Hour || cfg.RefreshTokenTTL != 60*24*time.Hour {
+		t.Errorf("default TTLs = %v/%v, want 1h/1440h", cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
+	}
+
+	env["ACCESS_TOKEN_TTL"] = "15m"
+	env["REFRESH_TOKEN_TTL"] = "168h"
+	cfg, err = l.load()
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if cfg.AccessTokenTTL != 15*time.Minute || cfg.RefreshTokenTTL != 7*24*time.Hour {
+		t.Errorf("TTLs = %v/%v, want 15m/168h", cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
+	}
+
+	for name, ttls := range map[string][2]string{
+		"Zero access TTL":       {"0s", "168h"},
+		"Invalid refresh TTL":   {"15m", "a week"},
+		"Refresh before access": {"2h", "1h"},
+	} {
+		env["ACCESS_TOKEN_TTL"] = ttls[0]
+		env["REFRESH_TOKEN_TTL"] = ttls[1]
+		if _, err := l.load(); err == nil {
+			t.Errorf("%s: load() succeeded, want an error", name)
+		}
+	}
+}
+
+func TestLoadPublicURL(t *testing.T) {
+	env := map[string]string{
+		"DB_URL":     "postgres://localhost/chirpy",
+		"PLATFORM":   "dev",
+		"JWT_SECRET": "secret",
+		"POLKA_KEY":  "polka",
+		"PUBLIC_URL": "https://chirpy.example/",
+	}
+	l, err := newLoader(fakeEnv(env), fakeFiles(nil))
+	if err != nil {
+		t.Fatalf("newLoader() error = %v", err)
+	}
+	cfg, err := l.load()
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if cfg.PublicURL != "https://chirpy.example" {
+		t.Errorf("PublicURL = %q, want it without the trailing slash", cfg.PublicURL)
+	}
+
+	for _, invalid := range []string{"chirpy.example", "ftp://chirpy.example", "https://chirpy.example/?a=1"} {
+		env["PUBLIC_URL"] = invalid
+		if _, err := l.load(); err == nil {
+			t.Errorf("load() with PUBLIC_URL=%q succeeded, want an error", invalid)
+		}
+	}
+}
+
+func TestLoadAnonymousAbuseReportLimit(t *testing.T) {
+	env := map[string]string{
+		"DB_URL":             "postgres://localhost/chirpy",
+		"PLATFORM":           "dev",
+		"JWT_SECRET":         "secret",
+		"POLKA_KEY":          "polka",
+		"ABUSE_REPORT_LIMIT": "10",
+	}
+	l, err := newLoader(fakeEnv(env), fakeFiles(nil))
+	if err != nil {
+		t.Fatalf("newLoader() error = %v", err)
+	}
+	cfg, err := l.load()
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if cfg.AnonymousAbuseReportLimit != 5 {
+		t.Errorf("AnonymousAbuseReportLimit = %d, want half of ABUSE_REPORT_LIMIT", cfg.AnonymousAbuseReportLimit)
+	}
+
+	env["ABUSE_REPORT_LIMIT"] = "1"
+	cfg, err = l.load()
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if cfg.AnonymousAbuseReportLimit != 1 {
+		t.Errorf("AnonymousAbuseReportLimit = %d with ABUSE_REPORT_LIMIT=1, want 1", cfg.AnonymousAbuseReportLimit)
+	}
+
+	env["ABUSE_REPORT_LIMIT"] = "10"
+
+	for _, looser := range []string{"0", "11"} {
+		env["ANONYMOUS_ABUSE_REPORT_LIMIT"] = looser
+		if _, err := l.load(); err == nil {
+			t.Errorf("load() with ANONYMOUS_ABUSE_REPORT_LIMIT=%s succeeded, want an error", looser)
+		}
+	}
+
+	env["ABUSE_REPORT_LIMIT"] = "0"
+	delete(env, "ANONYMOUS_ABUSE_REPORT_LIMIT")
+	cfg, err = l.load()
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if cfg.AnonymousAbuseReportLimit != 0 {
+		t.Errorf("AnonymousAbuseReportLimit = %d with abuse report limits off, want 0", cfg.AnonymousAbuseReportLimit)
+	}
+}
+
+func TestLoadAdminKeys(t *testing.T) {
+	env := map[string]string{
+		"DB_URL":     "postgres://localhost/chirpy",
+		"PLATFORM":   "dev",
+		"JWT_SECRET": "secret",
+		"POLKA_KEY":  "polka",
+		"ADMIN_KEY":  "shared",
+		"ADMIN_KEYS": "alice=a-key, bob=b-key",
+	}
+	l, err := newLoader(fakeEnv(env), fakeFiles(nil))
+	if err != nil {
+		t.Fatalf("newLoader() error = %v", err)
+	}
+	cfg, err := l.load()
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	want := map[string]string{"admin": "shared", "alice": "a-key", "bob": "b-key"}
+	if !reflect.DeepEqual(cfg.AdminKeys, want) {
+		t.Errorf("AdminKeys = %v, want %v", cfg.AdminKeys, want)
+	}
+
+	for _, invalid := range []string{"alice", "alice=", "=a-key", "alice=a,alice=b", "admin=a-key"} {
+		env["ADMIN_KEYS"] = invalid
+		if _, err := l.load(); err == nil {
+			t.Errorf("load() with ADMIN_KEYS=%q succeeded, want an error", invalid)
+		}
+	}
+}
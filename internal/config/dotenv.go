@@ -0,0 +1,25 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+
+	"github.com/joho/godotenv"
+)
+
+// DefaultEnvFile is read at startup when no other file is given.
+const DefaultEnvFile = ".env"
+
+// LoadEnvFile copies the variables in a dotenv file into the environment.
+// Variables that are already set keep their value. A missing file is only
+// an error when required is set; loaded reports whether the file was read.
+func LoadEnvFile(path string, required bool) (loaded bool, err error) {
+	err = godotenv.Load(path)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadEnvFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.env")
+	err := os.WriteFile(path, []byte("CHIRPY_TEST_FROM_FILE=file\nCHIRPY_TEST_PRESET=file\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CHIRPY_TEST_PRESET", "env")
+	t.Setenv("CHIRPY_TEST_FROM_FILE", "")
+	os.Unsetenv("CHIRPY_TEST_FROM_FILE")
+
+	loaded, err := LoadEnvFile(path, true)
+	if err != nil || !loaded {
+		t.Fatalf("LoadEnvFile() = %v, %v, want true, nil", loaded, err)
+	}
+	if got := os.Getenv("CHIRPY_TEST_FROM_FILE"); got != "file" {
+		t.Errorf("CHIRPY_TEST_FROM_FILE = %q, want file", got)
+	}
+	if got := os.Getenv("CHIRPY_TEST_PRESET"); got != "env" {
+		t.Errorf("CHIRPY_TEST_PRESET = %q, want the environment to win", got)
+	}
+
+	missing := filepath.Join(dir, "missing.env")
+	loaded, err = LoadEnvFile(missing, false)
+	if err != nil || loaded {
+		t.Errorf("LoadEnvFile(missing, false) = %v, %v, want false, nil", loaded, err)
+	}
+	_, err = LoadEnvFile(missing, true)
+	if err == nil {
+		t.Error("LoadEnvFile(missing, true) should fail")
+	}
+}
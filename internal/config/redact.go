@@ -0,0 +1,168 @@
+package config
+
+import (
+	"log/slog"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// LogValue describes the configuration for logs. Secrets only show whether
+// they're set, and passwords are stripped from URLs and DSNs. Every field is
+// listed, so a new setting needs a line here too.
+func (c Config) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("platform", c.Platform),
+		slog.String("listen_addr", c.ListenAddr),
+		slog.String("public_url", c.PublicURL),
+		slog.String("db_url", redactDSN(c.DBURL)),
+		slog.String("jwt_secret", redact(c.JWTSecret)),
+		slog.String("polka_key", redact(c.PolkaKey)),
+		slog.String("admin_key", redact(c.AdminKey)),
+		slog.Any("admins", slices.Sorted(maps.Keys(c.AdminKeys))),
+		slog.String("public_id_key", redact(c.PublicIDKey)),
+		slog.String("country_header", c.CountryHeader),
+		slog.String("client_ip_header", c.ClientIPHeader),
+		slog.String("log_level", c.LogLevel.String()),
+		slog.String("log_format", c.LogFormat),
+		slog.String("runtime_config_file", c.RuntimeConfigFile),
+		slog.Bool("envelope_responses", c.EnvelopeResponses),
+		slog.Bool("strip_email_plus_tags", c.StripEmailPlusTags),
+		slog.Group("mail",
+			slog.String("provider", c.Mail.Provider),
+			slog.String("from", c.Mail.From),
+			slog.String("smtp_addr", c.Mail.SMTPAddr),
+			slog.String("smtp_username", c.Mail.SMTPUsername),
+			slog.String("smtp_password", redact(c.Mail.SMTPPassword)),
+			slog.String("ses_region", c.Mail.SESRegion),
+			slog.String("template_dir", c.MailTemplateDir),
+		),
+		slog.Group("search",
+			slog.String("backend", c.Search.Backend),
+			slog.String("url", redactURL(c.Search.URL)),
+			slog.String("api_key", redact(c.Search.APIKey)),
+			slog.String("index", c.Search.Index),
+		),
+		slog.Group("cache",
+			slog.Int("size", c.CacheSize),
+			slog.Duration("ttl", c.CacheTTL),
+		),
+		slog.Group("rate_limit",
+			slog.Int("limit", c.RateLimit),
+			slog.Duration("window", c.RateLimitWindow),
+		),
+		slog.Group("breaker",
+			slog.Int("threshold", c.BreakerThreshold),
+			slog.Duration("cooldown", c.BreakerCooldown),
+		),
+		slog.Group("tokens",
+			slog.Duration("access_ttl", c.AccessTokenTTL),
+			slog.Duration("refresh_ttl", c.RefreshTokenTTL),
+		),
+		slog.Duration("deactivation_grace_period", c.DeactivationGracePeriod),
+		slog.Group("event_bus",
+			slog.String("url", redactURL(c.EventBusURL)),
+			slog.String("subject_prefix", c.EventSubjectPrefix),
+		),
+		slog.Group("error_reporting",
+			slog.String("sentry_dsn", redact(c.ErrorReporting.SentryDSN)),
+			slog.String("webhook_url", redact(c.ErrorReporting.WebhookURL)),
+			slog.String("environment", c.ErrorReporting.Environment),
+		),
+		slog.Group("security_txt",
+			slog.String("contact", c.SecurityContact),
+			slog.String("policy_url", c.SecurityPolicyURL),
+		),
+		slog.Group("abuse_reports",
+			slog.String("captcha_secret", redact(c.CaptchaSecret)),
+			slog.String("captcha_verify_url", c.CaptchaVerifyURL),
+			slog.Int("limit", c.AbuseReportLimit),
+			slog.Int("anonymous_limit", c.AnonymousAbuseReportLimit),
+		),
+		slog.Duration("analytics_retention", c.AnalyticsRetention),
+		slog.Group("webauthn",
+			slog.String("rp_id", c.WebAuthnRPID),
+			slog.Any("origins", c.WebAuthnOrigins),
+		),
+		slog.String("recording_dir", c.RecordingDir),
+	)
+}
+
+const redactedPassword = "xxxxx"
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "[redacted]"
+}
+
+// redactURL keeps a URL readable but drops its password, whether it's in
+// the userinfo or a password query parameter. URLs that can't be parsed are
+// redacted completely.
+func redactURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "[redacted]"
+	}
+	if q := u.Query(); q.Has("password") {
+		q.Set("password", redactedPassword)
+		u.RawQuery = q.Encode()
+	}
+	return u.Redacted()
+}
+
+// redactDSN drops the password from a Postgres connection string, which
+// lib/pq takes either as a URL or as key=value pairs.
+func redactDSN(raw string) string {
+	if strings.HasPrefix(raw, "postgres://") || strings.HasPrefix(raw, "postgresql://") {
+		return redactURL(raw)
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(raw); {
+		eq := strings.IndexByte(raw[i:], '=')
+		if eq == -1 {
+			b.WriteString(raw[i:])
+			break
+		}
+		key := strings.TrimSpace(raw[i : i+eq])
+		b.WriteString(raw[i : i+eq+1])
+		i += eq + 1
+		for i < len(raw) && isSpace(raw[i]) {
+			b.WriteByte(raw[i])
+			i++
+		}
+
+		// A value is quoted, with backslash escapes, or runs to the next
+		// space.
+		start := i
+		if i < len(raw) && raw[i] == '\'' {
+			for i++; i < len(raw) && raw[i] != '\''; i++ {
+				if raw[i] == '\\' {
+					i++
+				}
+			}
+			i = min(i+1, len(raw))
+		} else {
+			for i < len(raw) && !isSpace(raw[i]) {
+				i++
+			}
+		}
+		if key == "password" {
+			b.WriteString(redactedPassword)
+		} else {
+			b.WriteString(raw[start:i])
+		}
+	}
+	return b.String()
+}
+
+func isSpace(c byte) bool {
+	return unicode.IsSpace(rune(c))
+}
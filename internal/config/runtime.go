@@ -0,0 +1,161 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Runtime holds the settings that can change while the server is running.
+// They're read from the JSON file named by RUNTIME_CONFIG_FILE at startup
+// and again on every reload.
+type Runtime struct {
+	BannedWords []string `json:"banned_words"`
+	// MatchConfusables also bans words spelled with lookalike characters,
+	// such as a Cyrillic "е" or a zero for an "o".
+	MatchConfusables bool            `json:"match_confusables"`
+	FeatureFlags     map[string]bool `json:"feature_flags"`
+	// LogLevel, when set, replaces the current log level on reload.
+	LogLevel string `json:"log_level"`
+	// Quotas maps a membership tier ("free" or "red") to its daily limits.
+	Quotas map[string]Quota `json:"quotas"`
+	// Rollouts gradually switch endpoints to a new implementation, keyed
+	// by the name the route was registered with.
+	Rollouts map[string]Rollout `json:"rollouts"`
+	// Normalization tidies up chirp bodies for display.
+	Normalization Normalization `json:"normalization"`
+	// Onboarding is applied to every account that signs up.
+	Onboarding Onboarding `json:"onboarding"`
+	// RateLimit, when set, replaces RATE_LIMIT and RATE_LIMIT_WINDOW.
+	RateLimit *RateLimit `json:"rate_limit"`
+}
+
+// RateLimit is the number of requests a client may make per Window; a
+// Limit of 0 disables rate limiting. The window is written as a duration
+// such as "1m".
+type RateLimit struct {
+	Limit  int
+	Window time.Duration
+}
+
+// DefaultRateLimit is the rate limit from RATE_LIMIT and RATE_LIMIT_WINDOW,
+// used while the runtime config doesn't set one.
+func (c Config) DefaultRateLimit() RateLimit {
+	return RateLimit{Limit: c.RateLimit, Window: c.RateLimitWindow}
+}
+
+func (rl *RateLimit) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Limit  int    `json:"limit"`
+		Window string `json:"window"`
+	}
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+	if raw.Limit < 0 {
+		return fmt.Errorf("rate_limit: limit must not be negative")
+	}
+	window, err := time.ParseDuration(raw.Window)
+	if err != nil || window <= 0 {
+		return fmt.Errorf("rate_limit: invalid window %q", raw.Window)
+	}
+	*rl = RateLimit{Limit: raw.Limit, Window: window}
+	return nil
+}
+
+// Onboarding gives new users something to read right away.
+type Onboarding struct {
+	// Follow lists accounts every new user follows.
+	Follow []uuid.UUID `json:"follow"`
+	// WelcomeChirp, when set, is posted once by WelcomeAuthor, whom new
+	// users should follow to see it. It's the same for everyone, so it
+	// can't name the new user.
+	WelcomeChirp  string    `json:"welcome_chirp"`
+	WelcomeAuthor uuid.UUID `json:"welcome_author"`
+}
+
+// Normalization controls how normalized_body and body_html are derived from
+// a chirp body. The body itself is stored as written. Line endings always
+// become "\n".
+type Normalization struct {
+	// TrimSpace removes trailing whitespace from every line and leading and
+	// trailing blank lines from the chirp.
+	TrimSpace bool `json:"trim_space"`
+	// CollapseSpaces turns runs of spaces and tabs into a single space.
+	CollapseSpaces bool `json:"collapse_spaces"`
+	// MaxBlankLines limits consecutive blank lines. A negative value keeps
+	// them all.
+	MaxBlankLines int `json:"max_blank_lines"`
+}
+
+// Rollout picks the users who get the new implementation: everyone in
+// UserIDs plus Percent of the other signed-in users.
+type Rollout struct {
+	Percent int         `json:"percent"`
+	UserIDs []uuid.UUID `json:"user_ids"`
+}
+
+// Quota limits what a user of one membership tier can do per UTC day.
+// A zero limit means unlimited.
+type Quota struct {
+	ChirpsPerDay   int `json:"chirps_per_day"`
+	APICallsPerDay int `json:"api_calls_per_day"`
+}
+
+func DefaultRuntime() Runtime {
+	return Runtime{
+		BannedWords:      []string{"kerfuffle", "sharbert", "fornax"},
+		MatchConfusables: true,
+		FeatureFlags:     map[string]bool{},
+		Quotas: map[string]Quota{
+			"free": {ChirpsPerDay: 100, APICallsPerDay: 10000},
+			"red":  {},
+		},
+		Normalization: Normalization{
+			TrimSpace:      true,
+			CollapseSpaces: true,
+			MaxBlankLines:  1,
+		},
+	}
+}
+
+// LoadRuntime reads the runtime settings file. Settings missing from the
+// file keep their defaults; an empty path yields the defaults.
+func LoadRuntime(path string) (Runtime, error) {
+	rt := DefaultRuntime()
+	if path == "" {
+		return rt, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Runtime{}, fmt.Errorf("couldn't read runtime config: %w", err)
+	}
+	err = json.Unmarshal(data, &rt)
+	if err != nil {
+		return Runtime{}, fmt.Errorf("couldn't parse runtime config: %w", err)
+	}
+	if rt.FeatureFlags == nil {
+		rt.FeatureFlags = map[string]bool{}
+	}
+	if rt.Quotas == nil {
+		rt.Quotas = map[string]Quota{}
+	}
+	if rt.Onboarding.WelcomeChirp != "" && rt.Onboarding.WelcomeAuthor == uuid.Nil {
+		return Runtime{}, fmt.Errorf("onboarding: welcome_chirp needs a welcome_author")
+	}
+	if strings.Contains(rt.Onboarding.WelcomeChirp, "{handle}") {
+		return Runtime{}, fmt.Errorf("onboarding: welcome_chirp is posted once for everyone and can't contain {handle}")
+	}
+	for name, rollout := range rt.Rollouts {
+		if rollout.Percent < 0 || rollout.Percent > 100 {
+			return Runtime{}, fmt.Errorf("rollout %q: percent must be between 0 and 100", name)
+		}
+	}
+	return rt, nil
+}
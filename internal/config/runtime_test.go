@@ -0,0 +1,172 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestLoadRuntime(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		err := os.WriteFile(path, []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		want    Runtime
+		wantErr bool
+	}{
+		{
+			name: "No file uses defaults",
+			path: "",
+			want: DefaultRuntime(),
+		},
+		{
+			name: "Overrides banned words only",
+			path: write("words.json", `{"banned_words": ["darn"]}`),
+			want: Runtime{BannedWords: []string{"darn"}, MatchConfusables: true, FeatureFlags: map[string]bool{}, Quotas: DefaultRuntime().Quotas, Normalization: DefaultRuntime().Normalization},
+		},
+		{
+			name: "Feature flags",
+			path: write("flags.json", `{"feature_flags": {"new_feed": true}}`),
+			want: Runtime{BannedWords: DefaultRuntime().BannedWords, MatchConfusables: true, FeatureFlags: map[string]bool{"new_feed": true}, Quotas: DefaultRuntime().Quotas, Normalization: DefaultRuntime().Normalization},
+		},
+		{
+			name: "Quotas replace a tier and keep the others",
+			path: write("quotas.json", `{"quotas": {"free": {"chirps_per_day": 10}}}`),
+			want: Runtime{
+				BannedWords:      DefaultRuntime().BannedWords,
+				MatchConfusables: true,
+				FeatureFlags:     map[string]bool{},
+				Quotas: map[string]Quota{
+					"free": {ChirpsPerDay: 10},
+					"red":  {},
+				},
+				Normalization: DefaultRuntime().Normalization,
+			},
+		},
+		{
+			name: "Rollouts",
+			path: write("rollouts.json", `{"rollouts": {"chirps.cursor": {"percent": 10, "user_ids": ["6f1c5b0e-2f43-4c3a-9a3e-3c1f2f1b7d10"]}}}`),
+			want: Runtime{
+				BannedWords:      DefaultRuntime().BannedWords,
+				MatchConfusables: true,
+				FeatureFlags:     map[string]bool{},
+				Quotas:           DefaultRuntime().Quotas,
+				Rollouts: map[string]Rollout{
+					"chirps.cursor": {Percent: 10, UserIDs: []uuid.UUID{uuid.MustParse("6f1c5b0e-2f43-4c3a-9a3e-3c1f2f1b7d10")}},
+				},
+				Normalization: DefaultRuntime().Normalization,
+			},
+		},
+		{
+			name: "Normalization keeps unset rules",
+			path: write("normalization.json", `{"normalization": {"max_blank_lines": -1}}`),
+			want: Runtime{
+				BannedWords:      DefaultRuntime().BannedWords,
+				MatchConfusables: true,
+				FeatureFlags:     map[string]bool{},
+				Quotas:           DefaultRuntime().Quotas,
+				Normalization: Normalization{
+					TrimSpace:      true,
+					CollapseSpaces: true,
+					MaxBlankLines:  -1,
+				},
+			},
+		},
+		{
+			name: "Confusable matching off",
+			path: write("confusables.json", `{"match_confusables": false}`),
+			want: Runtime{
+				BannedWords:   DefaultRuntime().BannedWords,
+				FeatureFlags:  map[string]bool{},
+				Quotas:        DefaultRuntime().Quotas,
+				Normalization: DefaultRuntime().Normalization,
+			},
+		},
+		{
+			name: "Onboarding",
+			path: write("onboarding.json", `{"onboarding": {"follow": ["6f1c5b0e-2f43-4c3a-9a3e-3c1f2f1b7d10"], "welcome_chirp": "Welcome to Chirpy!", "welcome_author": "6f1c5b0e-2f43-4c3a-9a3e-3c1f2f1b7d10"}}`),
+			want: Runtime{
+				BannedWords:      DefaultRuntime().BannedWords,
+				MatchConfusables: true,
+				FeatureFlags:     map[string]bool{},
+				Quotas:           DefaultRuntime().Quotas,
+				Normalization:    DefaultRuntime().Normalization,
+				Onboarding: Onboarding{
+					Follow:        []uuid.UUID{uuid.MustParse("6f1c5b0e-2f43-4c3a-9a3e-3c1f2f1b7d10")},
+					WelcomeChirp:  "Welcome to Chirpy!",
+					WelcomeAuthor: uuid.MustParse("6f1c5b0e-2f43-4c3a-9a3e-3c1f2f1b7d10"),
+				},
+			},
+		},
+		{
+			name: "Rate limit",
+			path: write("rate-limit.json", `{"rate_limit": {"limit": 100, "window": "30s"}}`),
+			want: Runtime{
+				BannedWords:      DefaultRuntime().BannedWords,
+				MatchConfusables: true,
+				FeatureFlags:     map[string]bool{},
+				Quotas:           DefaultRuntime().Quotas,
+				Normalization:    DefaultRuntime().Normalization,
+				RateLimit:        &RateLimit{Limit: 100, Window: 30 * time.Second},
+			},
+		},
+		{
+			name:    "Rate limit without window",
+			path:    write("bad-rate-limit.json", `{"rate_limit": {"limit": 100}}`),
+			wantErr: true,
+		},
+		{
+			name:    "Welcome chirp without author",
+			path:    write("bad-onboarding.json", `{"onboarding": {"welcome_chirp": "Welcome!"}}`),
+			wantErr: true,
+		},
+		{
+			name:    "Welcome chirp naming the new user",
+			path:    write("handle-onboarding.json", `{"onboarding": {"welcome_chirp": "Welcome, {handle}!", "welcome_author": "6f1c5b0e-2f43-4c3a-9a3e-3c1f2f1b7d10"}}`),
+			wantErr: true,
+		},
+		{
+			name:    "Rollout percent out of range",
+			path:    write("bad-rollout.json", `{"rollouts": {"chirps.cursor": {"percent": 150}}}`),
+			wantErr: true,
+		},
+		{
+			name:    "Invalid JSON",
+			path:    write("broken.json", `{"banned_words": [`),
+			wantErr: true,
+		},
+		{
+			name:    "Missing file",
+			path:    filepath.Join(dir, "missing.json"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadRuntime(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadRuntime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadRuntime() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
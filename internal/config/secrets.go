@@ -0,0 +1,132 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SecretSource resolves a reference to a secret kept in a secret manager.
+type SecretSource interface {
+	Secret(ctx context.Context, ref string) (string, error)
+}
+
+type loader struct {
+	getenv   func(string) string
+	readFile func(string) ([]byte, error)
+	sources  map[string]SecretSource
+}
+
+func newLoader(getenv func(string) string, readFile func(string) ([]byte, error)) (*loader, error) {
+	l := &loader{
+		getenv:   getenv,
+		readFile: readFile,
+		sources:  map[string]SecretSource{},
+	}
+
+	vaultAddr, err := l.get("VAULT_ADDR")
+	if err != nil {
+		return nil, err
+	}
+	if vaultAddr != "" {
+		token, err := l.get("VAULT_TOKEN")
+		if err != nil {
+			return nil, err
+		}
+		l.sources["vault"] = &VaultSource{
+			Addr:   strings.TrimRight(vaultAddr, "/"),
+			Token:  token,
+			Client: &http.Client{Timeout: 10 * time.Second},
+		}
+	}
+	return l, nil
+}
+
+// get returns the setting name, preferring the contents of the file named
+// by name_FILE and resolving secret manager references.
+func (l *loader) get(name string) (string, error) {
+	value := l.getenv(name)
+	if path := l.getenv(name + "_FILE"); path != "" {
+		data, err := l.readFile(path)
+		if err != nil {
+			return "", fmt.Errorf("couldn't read %s_FILE: %w", name, err)
+		}
+		value = strings.TrimRight(string(data), "\r\n")
+	}
+
+	scheme, ref, ok := strings.Cut(value, ":")
+	if !ok {
+		return value, nil
+	}
+	source, ok := l.sources[scheme]
+	if !ok {
+		return value, nil
+	}
+	secret, err := source.Secret(context.Background(), ref)
+	if err != nil {
+		return "", fmt.Errorf("couldn't load %s from %s: %w", name, scheme, err)
+	}
+	return secret, nil
+}
+
+// VaultSource reads secrets from a HashiCorp Vault KV engine. References
+// look like "secret/data/chirpy#jwt_secret"; both KV v1 and v2 mounts work.
+type VaultSource struct {
+	Addr   string
+	Token  string
+	Client *http.Client
+}
+
+func (v *VaultSource) Secret(ctx context.Context, ref string) (string, error) {
+	path, key, ok := strings.Cut(ref, "#")
+	if !ok || key == "" {
+		return "", fmt.Errorf("vault reference %q has no #key", ref)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.Addr+"/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+
+	resp, err := v.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault responded with %s", resp.Status)
+	}
+
+	var payload struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&payload)
+	if err != nil {
+		return "", err
+	}
+
+	data := payload.Data
+	if nested, ok := payload.Data["data"]; ok {
+		// KV v2 wraps the secret in another data object.
+		data = map[string]json.RawMessage{}
+		err = json.Unmarshal(nested, &data)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	raw, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("vault secret %s has no key %q", path, key)
+	}
+	var value string
+	err = json.Unmarshal(raw, &value)
+	if err != nil {
+		return "", fmt.Errorf("vault secret %s key %q isn't a string", path, key)
+	}
+	return value, nil
+}
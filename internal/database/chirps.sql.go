@@ -7,29 +7,94 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+const addChirpReplies = `-- name: AddChirpReplies :exec
+UPDATE chirps
+SET reply_count = reply_count + $1
+WHERE id = $2
+`
+
+type AddChirpRepliesParams struct {
+	Delta int32
+	ID    uuid.UUID
+}
+
+func (q *Queries) AddChirpReplies(ctx context.Context, arg AddChirpRepliesParams) error {
+	_, err := q.db.ExecContext(ctx, addChirpReplies, arg.Delta, arg.ID)
+	return err
+}
+
+const countChirps = `-- name: CountChirps :one
+SELECT count(*)
+FROM chirps
+WHERE user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
+AND deleted_at IS NULL
+AND status = 'published'
+`
+
+func (q *Queries) CountChirps(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countChirps)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countChirpsByUserSince = `-- name: CountChirpsByUserSince :one
+SELECT count(*)
+FROM chirps
+WHERE user_id = $1
+AND created_at >= $2
+`
+
+type CountChirpsByUserSinceParams struct {
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+func (q *Queries) CountChirpsByUserSince(ctx context.Context, arg CountChirpsByUserSinceParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countChirpsByUserSince, arg.UserID, arg.CreatedAt)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, parent_chirp_id, place_name, latitude, longitude, status, publish_at)
 VALUES (
-	gen_random_uuid(),
+	$1,
 	NOW(),
 	NOW(),
-	$1,
-	$2
+	$2,
+	$3,
+	$4,
+	$5,
+	$6,
+	$7,
+	$8,
+	$9
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, reply_count, place_name, latitude, longitude, likes_count, rechirp_count, status, publish_at
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	ID            uuid.UUID
+	Body          string
+	UserID        uuid.UUID
+	ParentChirpID uuid.NullUUID
+	PlaceName     sql.NullString
+	Latitude      sql.NullFloat64
+	Longitude     sql.NullFloat64
+	Status        string
+	PublishAt     sql.NullTime
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp, arg.ID, arg.Body, arg.UserID, arg.ParentChirpID, arg.PlaceName, arg.Latitude, arg.Longitude, arg.Status, arg.PublishAt)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,23 +102,42 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ParentChirpID,
+		&i.DeletedAt,
+		&i.ReplyCount,
+		&i.PlaceName,
+		&i.Latitude,
+		&i.Longitude,
+		&i.LikesCount,
+		&i.RechirpCount,
+		&i.Status,
+		&i.PublishAt,
 	)
 	return i, err
 }
 
-const deleteChirp = `-- name: DeleteChirp :exec
-DELETE FROM chirps WHERE id = $1
+const deleteChirp = `-- name: DeleteChirp :execrows
+UPDATE chirps
+SET deleted_at = NOW()
+WHERE id = $1
+AND deleted_at IS NULL
 `
 
-func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, deleteChirp, id)
-	return err
+func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteChirp, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 const getChirp = `-- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id
+SELECT id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, reply_count, place_name, latitude, longitude, likes_count, rechirp_count, status, publish_at
 FROM chirps
 WHERE id = $1
+AND user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
+AND deleted_at IS NULL
+AND status = 'published'
 `
 
 func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
@@ -65,20 +149,115 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ParentChirpID,
+		&i.DeletedAt,
+		&i.ReplyCount,
+		&i.PlaceName,
+		&i.Latitude,
+		&i.Longitude,
+		&i.LikesCount,
+		&i.RechirpCount,
+		&i.Status,
+		&i.PublishAt,
 	)
 	return i, err
 }
 
+const getChirpCreatedAt = `-- name: GetChirpCreatedAt :one
+SELECT created_at
+FROM chirps
+WHERE id = $1
+`
+
+func (q *Queries) GetChirpCreatedAt(ctx context.Context, id uuid.UUID) (time.Time, error) {
+	row := q.db.QueryRowContext(ctx, getChirpCreatedAt, id)
+	var createdAt time.Time
+	err := row.Scan(&createdAt)
+	return createdAt, err
+}
+
+const getChirpReplies = `-- name: GetChirpReplies :many
+SELECT id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, reply_count, place_name, latitude, longitude, likes_count, rechirp_count, status, publish_at
+FROM chirps
+WHERE parent_chirp_id = $1
+AND user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
+AND deleted_at IS NULL
+AND status = 'published'
+AND user_id NOT IN (
+	SELECT blocked_id FROM blocks WHERE blocker_id = $2
+	UNION ALL
+	SELECT muted_id FROM mutes WHERE muter_id = $2
+)
+ORDER BY created_at asc
+`
+
+type GetChirpRepliesParams struct {
+	ParentChirpID uuid.NullUUID
+	ViewerID      uuid.NullUUID
+}
+
+func (q *Queries) GetChirpReplies(ctx context.Context, arg GetChirpRepliesParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpReplies, arg.ParentChirpID, arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentChirpID,
+			&i.DeletedAt,
+			&i.ReplyCount,
+			&i.PlaceName,
+			&i.Latitude,
+			&i.Longitude,
+			&i.LikesCount,
+			&i.RechirpCount,
+			&i.Status,
+			&i.PublishAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getChirps = `-- name: GetChirps :many
-SELECT id, created_at, updated_at, body, user_id
+SELECT id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, reply_count, place_name, latitude, longitude, likes_count, rechirp_count, status, publish_at
 FROM chirps
+WHERE user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
+AND deleted_at IS NULL
+AND status = 'published'
+AND user_id NOT IN (
+	SELECT blocked_id FROM blocks WHERE blocker_id = $1
+	UNION ALL
+	SELECT muted_id FROM mutes WHERE muter_id = $1
+)
 ORDER BY
-  CASE WHEN $1::text = 'asc' THEN created_at END asc,
-  CASE WHEN $1 = 'desc' THEN created_at END desc
+  CASE WHEN $2::text = 'asc' THEN created_at END asc,
+  CASE WHEN $2 = 'desc' THEN created_at END desc
 `
 
-func (q *Queries) GetChirps(ctx context.Context, sort string) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirps, sort)
+type GetChirpsParams struct {
+	Sort     string
+	ViewerID uuid.NullUUID
+}
+
+func (q *Queries) GetChirps(ctx context.Context, arg GetChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirps, arg.Sort, arg.ViewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -92,6 +271,16 @@ func (q *Queries) GetChirps(ctx context.Context, sort string) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ParentChirpID,
+			&i.DeletedAt,
+			&i.ReplyCount,
+			&i.PlaceName,
+			&i.Latitude,
+			&i.Longitude,
+			&i.LikesCount,
+			&i.RechirpCount,
+			&i.Status,
+			&i.PublishAt,
 		); err != nil {
 			return nil, err
 		}
@@ -107,21 +296,293 @@ func (q *Queries) GetChirps(ctx context.Context, sort string) ([]Chirp, error) {
 }
 
 const getChirpsByAuthor = `-- name: GetChirpsByAuthor :many
-SELECT id, created_at, updated_at, body, user_id
+SELECT id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, reply_count, place_name, latitude, longitude, likes_count, rechirp_count, status, publish_at
 FROM chirps
 WHERE user_id = $1
+AND user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
+AND deleted_at IS NULL
+AND status = 'published'
+AND user_id NOT IN (
+	SELECT blocked_id FROM blocks WHERE blocker_id = $2
+	UNION ALL
+	SELECT muted_id FROM mutes WHERE muter_id = $2
+)
 ORDER BY
-  CASE WHEN $2::text = 'asc' THEN created_at END asc,
-  CASE WHEN $2 = 'desc' THEN created_at END desc
+  CASE WHEN $3::text = 'asc' THEN created_at END asc,
+  CASE WHEN $3 = 'desc' THEN created_at END desc
 `
 
 type GetChirpsByAuthorParams struct {
-	UserID uuid.UUID
-	Sort   string
+	UserID   uuid.UUID
+	Sort     string
+	ViewerID uuid.NullUUID
 }
 
 func (q *Queries) GetChirpsByAuthor(ctx context.Context, arg GetChirpsByAuthorParams) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirpsByAuthor, arg.UserID, arg.Sort)
+	rows, err := q.db.QueryContext(ctx, getChirpsByAuthor, arg.UserID, arg.Sort, arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentChirpID,
+			&i.DeletedAt,
+			&i.ReplyCount,
+			&i.PlaceName,
+			&i.Latitude,
+			&i.Longitude,
+			&i.LikesCount,
+			&i.RechirpCount,
+			&i.Status,
+			&i.PublishAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsCreatedAfter = `-- name: GetChirpsCreatedAfter :many
+SELECT id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, reply_count, place_name, latitude, longitude, likes_count, rechirp_count, status, publish_at
+FROM chirps
+WHERE created_at > $1
+AND user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
+AND deleted_at IS NULL
+AND status = 'published'
+AND user_id NOT IN (
+	SELECT blocked_id FROM blocks WHERE blocker_id = $2
+	UNION ALL
+	SELECT muted_id FROM mutes WHERE muter_id = $2
+)
+ORDER BY created_at asc
+`
+
+type GetChirpsCreatedAfterParams struct {
+	CreatedAt time.Time
+	ViewerID  uuid.NullUUID
+}
+
+func (q *Queries) GetChirpsCreatedAfter(ctx context.Context, arg GetChirpsCreatedAfterParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsCreatedAfter, arg.CreatedAt, arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentChirpID,
+			&i.DeletedAt,
+			&i.ReplyCount,
+			&i.PlaceName,
+			&i.Latitude,
+			&i.Longitude,
+			&i.LikesCount,
+			&i.RechirpCount,
+			&i.Status,
+			&i.PublishAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsPage = `-- name: GetChirpsPage :many
+SELECT id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, reply_count, place_name, latitude, longitude, likes_count, rechirp_count, status, publish_at
+FROM chirps
+WHERE ($1::uuid IS NULL OR user_id = $1)
+AND user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
+AND deleted_at IS NULL
+AND status = 'published'
+AND user_id NOT IN (
+	SELECT blocked_id FROM blocks WHERE blocker_id = $2
+	UNION ALL
+	SELECT muted_id FROM mutes WHERE muter_id = $2
+)
+AND (
+	$3::timestamp IS NULL
+	OR ($4::text = 'asc' AND (created_at, id) > ($3, $5::uuid))
+	OR ($4 = 'desc' AND (created_at, id) < ($3, $5::uuid))
+)
+ORDER BY
+  CASE WHEN $4 = 'asc' THEN created_at END asc,
+  CASE WHEN $4 = 'asc' THEN id END asc,
+  CASE WHEN $4 = 'desc' THEN created_at END desc,
+  CASE WHEN $4 = 'desc' THEN id END desc
+LIMIT $6
+`
+
+type GetChirpsPageParams struct {
+	AuthorID       uuid.NullUUID
+	ViewerID       uuid.NullUUID
+	AfterCreatedAt sql.NullTime
+	Sort           string
+	AfterID        uuid.NullUUID
+	PageSize       int32
+}
+
+func (q *Queries) GetChirpsPage(ctx context.Context, arg GetChirpsPageParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsPage, arg.AuthorID, arg.ViewerID, arg.AfterCreatedAt, arg.Sort, arg.AfterID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentChirpID,
+			&i.DeletedAt,
+			&i.ReplyCount,
+			&i.PlaceName,
+			&i.Latitude,
+			&i.Longitude,
+			&i.LikesCount,
+			&i.RechirpCount,
+			&i.Status,
+			&i.PublishAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPlaces = `-- name: GetPlaces :many
+SELECT place_name::text AS place_name,
+	round(avg(latitude)::numeric, 1)::double precision AS latitude,
+	round(avg(longitude)::numeric, 1)::double precision AS longitude,
+	count(*) AS chirps
+FROM chirps
+WHERE lower(place_name) LIKE lower($1) || '%'
+AND deleted_at IS NULL
+AND status = 'published'
+GROUP BY place_name
+ORDER BY count(*) DESC, place_name
+LIMIT $2
+`
+
+type GetPlacesParams struct {
+	Prefix     string
+	MaxResults int32
+}
+
+type GetPlacesRow struct {
+	PlaceName string
+	Latitude  float64
+	Longitude float64
+	Chirps    int64
+}
+
+func (q *Queries) GetPlaces(ctx context.Context, arg GetPlacesParams) ([]GetPlacesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPlaces, arg.Prefix, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPlacesRow
+	for rows.Next() {
+		var i GetPlacesRow
+		if err := rows.Scan(
+			&i.PlaceName,
+			&i.Latitude,
+			&i.Longitude,
+			&i.Chirps,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const hasPublishedChirp = `-- name: HasPublishedChirp :one
+SELECT EXISTS (
+	SELECT 1 FROM chirps
+	WHERE user_id = $1
+	AND body = $2
+	AND status = 'published'
+	AND deleted_at IS NULL
+) AS posted
+`
+
+type HasPublishedChirpParams struct {
+	UserID uuid.UUID
+	Body   string
+}
+
+func (q *Queries) HasPublishedChirp(ctx context.Context, arg HasPublishedChirpParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, hasPublishedChirp, arg.UserID, arg.Body)
+	var posted bool
+	err := row.Scan(&posted)
+	return posted, err
+}
+
+const listChirpsAfterID = `-- name: ListChirpsAfterID :many
+SELECT id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, reply_count, place_name, latitude, longitude, likes_count, rechirp_count, status, publish_at
+FROM chirps
+WHERE id > $1
+AND user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
+AND deleted_at IS NULL
+AND status = 'published'
+ORDER BY id
+LIMIT $2
+`
+
+type ListChirpsAfterIDParams struct {
+	ID    uuid.UUID
+	Limit int32
+}
+
+func (q *Queries) ListChirpsAfterID(ctx context.Context, arg ListChirpsAfterIDParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsAfterID, arg.ID, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -135,6 +596,16 @@ func (q *Queries) GetChirpsByAuthor(ctx context.Context, arg GetChirpsByAuthorPa
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ParentChirpID,
+			&i.DeletedAt,
+			&i.ReplyCount,
+			&i.PlaceName,
+			&i.Latitude,
+			&i.Longitude,
+			&i.LikesCount,
+			&i.RechirpCount,
+			&i.Status,
+			&i.PublishAt,
 		); err != nil {
 			return nil, err
 		}
@@ -148,3 +619,158 @@ func (q *Queries) GetChirpsByAuthor(ctx context.Context, arg GetChirpsByAuthorPa
 	}
 	return items, nil
 }
+
+const lockUser = `-- name: LockUser :exec
+SELECT id FROM users
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) LockUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, lockUser, id)
+	return err
+}
+
+const redraftChirp = `-- name: RedraftChirp :one
+UPDATE chirps
+SET deleted_at = NOW()
+WHERE id = $1
+AND user_id = $2
+AND deleted_at IS NULL
+RETURNING id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, reply_count, place_name, latitude, longitude, likes_count, rechirp_count, status, publish_at
+`
+
+type RedraftChirpParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) RedraftChirp(ctx context.Context, arg RedraftChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, redraftChirp, arg.ID, arg.UserID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ParentChirpID,
+		&i.DeletedAt,
+		&i.ReplyCount,
+		&i.PlaceName,
+		&i.Latitude,
+		&i.Longitude,
+		&i.LikesCount,
+		&i.RechirpCount,
+		&i.Status,
+		&i.PublishAt,
+	)
+	return i, err
+}
+
+const restoreChirp = `-- name: RestoreChirp :one
+UPDATE chirps
+SET deleted_at = NULL
+WHERE id = $1
+AND deleted_at IS NOT NULL
+AND status = 'published'
+RETURNING id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, reply_count, place_name, latitude, longitude, likes_count, rechirp_count, status, publish_at
+`
+
+func (q *Queries) RestoreChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, restoreChirp, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ParentChirpID,
+		&i.DeletedAt,
+		&i.ReplyCount,
+		&i.PlaceName,
+		&i.Latitude,
+		&i.Longitude,
+		&i.LikesCount,
+		&i.RechirpCount,
+		&i.Status,
+		&i.PublishAt,
+	)
+	return i, err
+}
+
+const searchChirps = `-- name: SearchChirps :many
+SELECT id
+FROM chirps
+WHERE to_tsvector('english', body) @@ websearch_to_tsquery('english', $1)
+AND user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
+AND deleted_at IS NULL
+AND status = 'published'
+ORDER BY ts_rank(to_tsvector('english', body), websearch_to_tsquery('english', $1)) DESC, created_at DESC
+LIMIT $2
+`
+
+type SearchChirpsParams struct {
+	Query      string
+	MaxResults int32
+}
+
+func (q *Queries) SearchChirps(ctx context.Context, arg SearchChirpsParams) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, searchChirps, arg.Query, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateChirp = `-- name: UpdateChirp :one
+UPDATE chirps
+SET body = $2, updated_at = NOW()
+WHERE id = $1
+AND deleted_at IS NULL
+RETURNING id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, reply_count, place_name, latitude, longitude, likes_count, rechirp_count, status, publish_at
+`
+
+type UpdateChirpParams struct {
+	ID   uuid.UUID
+	Body string
+}
+
+func (q *Queries) UpdateChirp(ctx context.Context, arg UpdateChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirp, arg.ID, arg.Body)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ParentChirpID,
+		&i.DeletedAt,
+		&i.ReplyCount,
+		&i.PlaceName,
+		&i.Latitude,
+		&i.Longitude,
+		&i.LikesCount,
+		&i.RechirpCount,
+		&i.Status,
+		&i.PublishAt,
+	)
+	return i, err
+}
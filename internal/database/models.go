@@ -6,17 +6,172 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type AbuseReport struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	Url          string
+	Reason       string
+	Details      string
+	ContactEmail string
+	ChirpID      uuid.NullUUID
+	ResolvedAt   sql.NullTime
+	ReporterID   uuid.NullUUID
+	Outcome      sql.NullString
+}
+
+type AllowedWord struct {
+	Word      string
+	CreatedAt time.Time
+	Reason    string
+}
+
+type AnalyticsEvent struct {
+	Day      time.Time
+	Route    string
+	Country  string
+	Visitor  string
+	Requests int64
+}
+
+type ApiUsage struct {
+	UserID uuid.UUID
+	Day    time.Time
+	Calls  int64
+}
+
+type AuditLog struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	Actor     string
+	Action    string
+	UserID    uuid.NullUUID
+	Reason    string
+}
+
+type Block struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
+type Bookmark struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpHashtag struct {
+	ChirpID   uuid.UUID
+	Tag       string
+	CreatedAt time.Time
+}
+
+type ChirpLike struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpShare struct {
+	Code          string
+	CreatedAt     time.Time
+	ChirpID       uuid.UUID
+	RedirectCount int32
+}
+
 type Chirp struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Body          string
+	UserID        uuid.UUID
+	ParentChirpID uuid.NullUUID
+	DeletedAt     sql.NullTime
+	ReplyCount    int32
+	PlaceName     sql.NullString
+	Latitude      sql.NullFloat64
+	Longitude     sql.NullFloat64
+	LikesCount    int32
+	RechirpCount  int32
+	Status        string
+	PublishAt     sql.NullTime
+}
+
+type EmailVerification struct {
+	Token     string
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	Email     string
+	ExpiresAt time.Time
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type LoginEvent struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UserID     uuid.UUID
+	IpAddress  string
+	UserAgent  string
+	Country    sql.NullString
+	ReportedAt sql.NullTime
+}
+
+type Marker struct {
+	UserID     uuid.UUID
+	Timeline   string
+	LastReadID uuid.UUID
+	Version    int32
+	UpdatedAt  time.Time
+}
+
+type Mute struct {
+	MuterID   uuid.UUID
+	MutedID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type OutboxEvent struct {
+	ID          int64
+	CreatedAt   time.Time
+	EventType   string
+	Payload     json.RawMessage
+	PublishedAt sql.NullTime
+}
+
+type PasskeyChallenge struct {
+	ID        uuid.UUID
+	Challenge []byte
+	UserID    uuid.NullUUID
+	ExpiresAt time.Time
+}
+
+type Passkey struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UserID       uuid.UUID
+	Name         string
+	CredentialID []byte
+	PublicKey    []byte
+	SignCount    int64
+	LastUsedAt   sql.NullTime
+}
+
+type Rechirp struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
-	UpdatedAt time.Time
-	Body      string
 	UserID    uuid.UUID
+	ChirpID   uuid.UUID
 }
 
 type RefreshToken struct {
@@ -26,13 +181,32 @@ type RefreshToken struct {
 	UserID    uuid.UUID
 	ExpiresAt time.Time
 	RevokedAt sql.NullTime
+	FamilyID  uuid.UUID
+	RotatedAt sql.NullTime
+}
+
+type UserEmailDuplicate struct {
+	UserID     uuid.UUID
+	KeptUserID uuid.UUID
+	Email      string
 }
 
 type User struct {
-	ID             uuid.UUID
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
-	IsChirpyRed    bool
+	ID                 uuid.UUID
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	Email              string
+	HashedPassword     string
+	IsChirpyRed        bool
+	DeactivatedAt      sql.NullTime
+	LocationEnabled    bool
+	PreciseLocation    bool
+	MovedTo            sql.NullString
+	MovedAt            sql.NullTime
+	Username           sql.NullString
+	DisplayName        sql.NullString
+	Bio                sql.NullString
+	AvatarUrl          sql.NullString
+	EmailVerifiedAt    sql.NullTime
+	SessionsValidAfter sql.NullTime
 }
@@ -7,29 +7,49 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const countUserChirps = `-- name: CountUserChirps :one
+SELECT count(*)
+FROM chirps
+WHERE user_id = $1
+AND deleted_at IS NULL
+AND status = 'published'
+`
+
+func (q *Queries) CountUserChirps(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUserChirps, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, username)
 VALUES (
-	gen_random_uuid(),
+	$1,
 	NOW(),
 	NOW(),
-	$1,
-	$2
+	$2,
+	$3,
+	$4
 )
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, deactivated_at, location_enabled, precise_location, moved_to, moved_at, username, display_name, bio, avatar_url, email_verified_at, sessions_valid_after
 `
 
 type CreateUserParams struct {
+	ID             uuid.UUID
 	Email          string
 	HashedPassword string
+	Username       sql.NullString
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser, arg.ID, arg.Email, arg.HashedPassword, arg.Username)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -38,10 +58,32 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.DeactivatedAt,
+		&i.LocationEnabled,
+		&i.PreciseLocation,
+		&i.MovedTo,
+		&i.MovedAt,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.EmailVerifiedAt,
+		&i.SessionsValidAfter,
 	)
 	return i, err
 }
 
+const deactivateUser = `-- name: DeactivateUser :exec
+UPDATE users
+SET deactivated_at = COALESCE(deactivated_at, NOW()), updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) DeactivateUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deactivateUser, id)
+	return err
+}
+
 const deleteUsers = `-- name: DeleteUsers :exec
 DELETE FROM users
 `
@@ -51,8 +93,76 @@ func (q *Queries) DeleteUsers(ctx context.Context) error {
 	return err
 }
 
+const endUserSessions = `-- name: EndUserSessions :exec
+UPDATE users
+SET sessions_valid_after = date_trunc('second', NOW()), updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) EndUserSessions(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, endUserSessions, id)
+	return err
+}
+
+const getExpiredDeactivatedUserIDs = `-- name: GetExpiredDeactivatedUserIDs :many
+SELECT id FROM users
+WHERE deactivated_at < $1
+`
+
+func (q *Queries) GetExpiredDeactivatedUserIDs(ctx context.Context, deactivatedAt sql.NullTime) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getExpiredDeactivatedUserIDs, deactivatedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUser = `-- name: GetUser :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, deactivated_at, location_enabled, precise_location, moved_to, moved_at, username, display_name, bio, avatar_url, email_verified_at, sessions_valid_after FROM users WHERE id = $1
+`
+
+func (q *Queries) GetUser(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DeactivatedAt,
+		&i.LocationEnabled,
+		&i.PreciseLocation,
+		&i.MovedTo,
+		&i.MovedAt,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.EmailVerifiedAt,
+		&i.SessionsValidAfter,
+	)
+	return i, err
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red FROM users WHERE email = $1
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, deactivated_at, location_enabled, precise_location, moved_to, moved_at, username, display_name, bio, avatar_url, email_verified_at, sessions_valid_after FROM users WHERE lower(email) = lower($1)
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -65,6 +175,179 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.DeactivatedAt,
+		&i.LocationEnabled,
+		&i.PreciseLocation,
+		&i.MovedTo,
+		&i.MovedAt,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.EmailVerifiedAt,
+		&i.SessionsValidAfter,
+	)
+	return i, err
+}
+
+const getUsersByIDs = `-- name: GetUsersByIDs :many
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, deactivated_at, location_enabled, precise_location, moved_to, moved_at, username, display_name, bio, avatar_url, email_verified_at, sessions_valid_after FROM users
+WHERE id = ANY($1::uuid[])
+`
+
+func (q *Queries) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.HashedPassword,
+			&i.IsChirpyRed,
+			&i.DeactivatedAt,
+			&i.LocationEnabled,
+			&i.PreciseLocation,
+			&i.MovedTo,
+			&i.MovedAt,
+			&i.Username,
+			&i.DisplayName,
+			&i.Bio,
+			&i.AvatarUrl,
+			&i.EmailVerifiedAt,
+			&i.SessionsValidAfter,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUsersByUsernames = `-- name: GetUsersByUsernames :many
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, deactivated_at, location_enabled, precise_location, moved_to, moved_at, username, display_name, bio, avatar_url, email_verified_at, sessions_valid_after FROM users
+WHERE lower(username) = ANY($1::text[]) AND deactivated_at IS NULL
+`
+
+func (q *Queries) GetUsersByUsernames(ctx context.Context, usernames []string) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersByUsernames, pq.Array(usernames))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.HashedPassword,
+			&i.IsChirpyRed,
+			&i.DeactivatedAt,
+			&i.LocationEnabled,
+			&i.PreciseLocation,
+			&i.MovedTo,
+			&i.MovedAt,
+			&i.Username,
+			&i.DisplayName,
+			&i.Bio,
+			&i.AvatarUrl,
+			&i.EmailVerifiedAt,
+			&i.SessionsValidAfter,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const reactivateUser = `-- name: ReactivateUser :one
+UPDATE users
+SET deactivated_at = NULL, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, deactivated_at, location_enabled, precise_location, moved_to, moved_at, username, display_name, bio, avatar_url, email_verified_at, sessions_valid_after
+`
+
+func (q *Queries) ReactivateUser(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, reactivateUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DeactivatedAt,
+		&i.LocationEnabled,
+		&i.PreciseLocation,
+		&i.MovedTo,
+		&i.MovedAt,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.EmailVerifiedAt,
+		&i.SessionsValidAfter,
+	)
+	return i, err
+}
+
+const setLocationSettings = `-- name: SetLocationSettings :one
+UPDATE users
+SET location_enabled = $2, precise_location = $3, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, deactivated_at, location_enabled, precise_location, moved_to, moved_at, username, display_name, bio, avatar_url, email_verified_at, sessions_valid_after
+`
+
+type SetLocationSettingsParams struct {
+	ID              uuid.UUID
+	LocationEnabled bool
+	PreciseLocation bool
+}
+
+func (q *Queries) SetLocationSettings(ctx context.Context, arg SetLocationSettingsParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setLocationSettings, arg.ID, arg.LocationEnabled, arg.PreciseLocation)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DeactivatedAt,
+		&i.LocationEnabled,
+		&i.PreciseLocation,
+		&i.MovedTo,
+		&i.MovedAt,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.EmailVerifiedAt,
+		&i.SessionsValidAfter,
 	)
 	return i, err
 }
@@ -73,7 +356,7 @@ const setUserMembership = `-- name: SetUserMembership :one
 UPDATE users
 SET is_chirpy_red = TRUE, updated_at = NOW()
 WHERE id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, deactivated_at, location_enabled, precise_location, moved_to, moved_at, username, display_name, bio, avatar_url, email_verified_at, sessions_valid_after
 `
 
 func (q *Queries) SetUserMembership(ctx context.Context, id uuid.UUID) (User, error) {
@@ -86,25 +369,122 @@ func (q *Queries) SetUserMembership(ctx context.Context, id uuid.UUID) (User, er
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.DeactivatedAt,
+		&i.LocationEnabled,
+		&i.PreciseLocation,
+		&i.MovedTo,
+		&i.MovedAt,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.EmailVerifiedAt,
+		&i.SessionsValidAfter,
+	)
+	return i, err
+}
+
+const setUserMovedTo = `-- name: SetUserMovedTo :one
+UPDATE users
+SET moved_to = $2,
+	moved_at = CASE WHEN $2::text IS NULL THEN NULL ELSE NOW() END,
+	updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, deactivated_at, location_enabled, precise_location, moved_to, moved_at, username, display_name, bio, avatar_url, email_verified_at, sessions_valid_after
+`
+
+type SetUserMovedToParams struct {
+	ID      uuid.UUID
+	MovedTo sql.NullString
+}
+
+func (q *Queries) SetUserMovedTo(ctx context.Context, arg SetUserMovedToParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserMovedTo, arg.ID, arg.MovedTo)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DeactivatedAt,
+		&i.LocationEnabled,
+		&i.PreciseLocation,
+		&i.MovedTo,
+		&i.MovedAt,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.EmailVerifiedAt,
+		&i.SessionsValidAfter,
 	)
 	return i, err
 }
 
 const updateUser = `-- name: UpdateUser :one
 UPDATE users
-SET email = $1, hashed_password = $2, updated_at = NOW()
+SET email = $1, hashed_password = $2,
+	email_verified_at = CASE WHEN email = $1 THEN email_verified_at END,
+	username = COALESCE($4, username),
+	display_name = NULLIF(COALESCE($5, display_name), ''),
+	bio = NULLIF(COALESCE($6, bio), ''),
+	avatar_url = NULLIF(COALESCE($7, avatar_url), ''),
+	updated_at = NOW()
 WHERE id = $3
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, deactivated_at, location_enabled, precise_location, moved_to, moved_at, username, display_name, bio, avatar_url, email_verified_at, sessions_valid_after
 `
 
 type UpdateUserParams struct {
 	Email          string
 	HashedPassword string
 	ID             uuid.UUID
+	Username       sql.NullString
+	DisplayName    sql.NullString
+	Bio            sql.NullString
+	AvatarUrl      sql.NullString
 }
 
 func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, updateUser, arg.Email, arg.HashedPassword, arg.ID)
+	row := q.db.QueryRowContext(ctx, updateUser, arg.Email, arg.HashedPassword, arg.ID, arg.Username, arg.DisplayName, arg.Bio, arg.AvatarUrl)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DeactivatedAt,
+		&i.LocationEnabled,
+		&i.PreciseLocation,
+		&i.MovedTo,
+		&i.MovedAt,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.EmailVerifiedAt,
+		&i.SessionsValidAfter,
+	)
+	return i, err
+}
+
+const updateUserMembership = `-- name: UpdateUserMembership :one
+UPDATE users
+SET is_chirpy_red = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, deactivated_at, location_enabled, precise_location, moved_to, moved_at, username, display_name, bio, avatar_url, email_verified_at, sessions_valid_after
+`
+
+type UpdateUserMembershipParams struct {
+	ID          uuid.UUID
+	IsChirpyRed bool
+}
+
+func (q *Queries) UpdateUserMembership(ctx context.Context, arg UpdateUserMembershipParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserMembership, arg.ID, arg.IsChirpyRed)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -113,6 +493,17 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.DeactivatedAt,
+		&i.LocationEnabled,
+		&i.PreciseLocation,
+		&i.MovedTo,
+		&i.MovedAt,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.EmailVerifiedAt,
+		&i.SessionsValidAfter,
 	)
 	return i, err
 }
@@ -0,0 +1,93 @@
+package errreport
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Event describes a failed request.
+type Event struct {
+	Timestamp time.Time
+	Message   string
+	Error     string
+	Stack     string
+	Status    int
+	RequestID string
+	Method    string
+	Route     string
+	// UserHash identifies the user without revealing their ID.
+	UserHash string
+}
+
+type Sender interface {
+	Send(ctx context.Context, event Event) error
+}
+
+type Config struct {
+	SentryDSN   string
+	WebhookURL  string
+	Environment string
+}
+
+// Reporter sends events in the background so reporting never slows down
+// or fails a request. Events are dropped while the queue is full.
+type Reporter struct {
+	sender Sender
+	events chan Event
+}
+
+// New returns a reporter for the configured destination, or nil when error
+// reporting is off.
+func New(cfg Config) (*Reporter, error) {
+	var sender Sender
+	switch {
+	case cfg.SentryDSN != "":
+		s, err := NewSentrySender(cfg.SentryDSN, cfg.Environment)
+		if err != nil {
+			return nil, err
+		}
+		sender = s
+	case cfg.WebhookURL != "":
+		sender = NewWebhookSender(cfg.WebhookURL)
+	default:
+		return nil, nil
+	}
+	return NewReporter(sender, 100), nil
+}
+
+func NewReporter(sender Sender, queueSize int) *Reporter {
+	r := &Reporter{
+		sender: sender,
+		events: make(chan Event, queueSize),
+	}
+	go r.run()
+	return r
+}
+
+func (r *Reporter) Report(event Event) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+	select {
+	case r.events <- event:
+	default:
+		log.Printf("Error report queue is full, dropping report for %s %s", event.Method, event.Route)
+	}
+}
+
+func (r *Reporter) run() {
+	for event := range r.events {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err := r.sender.Send(ctx, event)
+		cancel()
+		if err != nil {
+			log.Printf("Couldn't report error: %v", err)
+		}
+	}
+}
+
+func statusError(status int, body string) error {
+	return fmt.Errorf("unexpected status %d: %s", status, body)
+}
@@ -0,0 +1,87 @@
+package errreport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewSentrySender(t *testing.T) {
+	tests := []struct {
+		dsn     string
+		want    string
+		wantErr bool
+	}{
+		{dsn: "https://abc@o1.ingest.sentry.io/42", want: "https://o1.ingest.sentry.io/api/42/store/"},
+		{dsn: "http://abc@sentry.local/sub/7", want: "http://sentry.local/sub/api/7/store/"},
+		{dsn: "https://o1.ingest.sentry.io/42", wantErr: true},
+		{dsn: "https://abc@o1.ingest.sentry.io/", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dsn, func(t *testing.T) {
+			s, err := NewSentrySender(tt.dsn, "test")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewSentrySender() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && s.storeURL != tt.want {
+				t.Errorf("storeURL = %q, want %q", s.storeURL, tt.want)
+			}
+		})
+	}
+}
+
+func TestSentrySend(t *testing.T) {
+	var auth string
+	var payload map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("X-Sentry-Auth")
+		json.NewDecoder(r.Body).Decode(&payload)
+	}))
+	defer server.Close()
+
+	s, err := NewSentrySender(strings.Replace(server.URL, "://", "://key@", 1)+"/1", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Send(context.Background(), Event{Message: "Couldn't get chirps", Route: "GET /api/v1/chirps", UserHash: "u1"})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if !strings.Contains(auth, "sentry_key=key") {
+		t.Errorf("X-Sentry-Auth = %q", auth)
+	}
+	if payload["message"] != "Couldn't get chirps" || payload["user"].(map[string]any)["id"] != "u1" {
+		t.Errorf("payload = %v", payload)
+	}
+}
+
+func TestReporterWebhook(t *testing.T) {
+	received := make(chan webhookPayload, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p webhookPayload
+		json.NewDecoder(r.Body).Decode(&p)
+		received <- p
+	}))
+	defer server.Close()
+
+	r, err := New(Config{WebhookURL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Report(Event{Status: 500, RequestID: "req-1", Route: "GET /api/v1/chirps"})
+
+	got := <-received
+	if got.RequestID != "req-1" || got.Status != 500 || got.Timestamp.IsZero() {
+		t.Errorf("webhook got %+v", got)
+	}
+}
+
+func TestNewOff(t *testing.T) {
+	r, err := New(Config{})
+	if err != nil || r != nil {
+		t.Errorf("New() = %v, %v, want nil, nil", r, err)
+	}
+}
@@ -0,0 +1,88 @@
+package errreport
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SentrySender reports events to a Sentry project through its store
+// endpoint.
+type SentrySender struct {
+	storeURL    string
+	key         string
+	environment string
+	client      *http.Client
+}
+
+// NewSentrySender parses a DSN like https://<key>@o1.ingest.sentry.io/<project>.
+func NewSentrySender(dsn, environment string) (*SentrySender, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sentry dsn: %w", err)
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return nil, fmt.Errorf("sentry dsn has no public key")
+	}
+	projectPath, project := path.Split(strings.TrimSuffix(u.Path, "/"))
+	if project == "" {
+		return nil, fmt.Errorf("sentry dsn has no project id")
+	}
+
+	return &SentrySender{
+		storeURL:    fmt.Sprintf("%s://%s%sapi/%s/store/", u.Scheme, u.Host, projectPath, project),
+		key:         u.User.Username(),
+		environment: environment,
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (s *SentrySender) Send(ctx context.Context, event Event) error {
+	id := make([]byte, 16)
+	_, err := rand.Read(id)
+	if err != nil {
+		return err
+	}
+
+	message := event.Message
+	if event.Error != "" {
+		message += ": " + event.Error
+	}
+	payload := map[string]any{
+		"event_id":    hex.EncodeToString(id),
+		"timestamp":   event.Timestamp.Format(time.RFC3339),
+		"level":       "error",
+		"platform":    "go",
+		"logger":      "chirpy",
+		"environment": s.environment,
+		"message":     message,
+		"transaction": event.Method + " " + event.Route,
+		"tags": map[string]string{
+			"request_id": event.RequestID,
+			"route":      event.Route,
+			"status":     strconv.Itoa(event.Status),
+		},
+		"extra": map[string]string{
+			"stack": event.Stack,
+		},
+	}
+	if event.UserHash != "" {
+		payload["user"] = map[string]string{"id": event.UserHash}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return post(ctx, s.client, s.storeURL, body, map[string]string{
+		"X-Sentry-Auth": "Sentry sentry_version=7, sentry_client=chirpy/1.0, sentry_key=" + s.key,
+	})
+}
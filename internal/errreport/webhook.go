@@ -0,0 +1,64 @@
+package errreport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+)
+
+// WebhookSender posts every event as JSON to a URL.
+type WebhookSender struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookSender(url string) *WebhookSender {
+	return &WebhookSender{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type webhookPayload struct {
+	Timestamp time.Time `json:"timestamp"`
+	Message   string    `json:"message"`
+	Error     string    `json:"error,omitempty"`
+	Stack     string    `json:"stack,omitempty"`
+	Status    int       `json:"status"`
+	RequestID string    `json:"request_id"`
+	Method    string    `json:"method"`
+	Route     string    `json:"route"`
+	UserHash  string    `json:"user_hash,omitempty"`
+}
+
+func (s *WebhookSender) Send(ctx context.Context, event Event) error {
+	body, err := json.Marshal(webhookPayload(event))
+	if err != nil {
+		return err
+	}
+	return post(ctx, s.client, s.url, body, nil)
+}
+
+func post(ctx context.Context, client *http.Client, url string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return statusError(res.StatusCode, string(msg))
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package eventbus
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	natsDialTimeout = 5 * time.Second
+	natsIdleTimeout = 5 * time.Minute
+)
+
+// NATSPublisher speaks just enough of the NATS client protocol to publish
+// and subscribe.
+// Every publish is followed by a PING, and the PONG confirms the server
+// processed the message. A broken connection is redialed on the next
+// publish.
+type NATSPublisher struct {
+	mu     sync.Mutex
+	addr   string
+	user   string
+	pass   string
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func NewNATSPublisher(u *url.URL) *NATSPublisher {
+	p := &NATSPublisher{addr: u.Host}
+	if u.Port() == "" {
+		p.addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	if u.User != nil {
+		p.user = u.User.Username()
+		p.pass, _ = u.User.Password()
+	}
+	return p
+}
+
+func (p *NATSPublisher) Publish(ctx context.Context, subject string, data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn == nil {
+		err := p.connect(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := p.publish(ctx, subject, data)
+	if err != nil {
+		p.closeConn()
+		return err
+	}
+	return nil
+}
+
+func (p *NATSPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closeConn()
+}
+
+func (p *NATSPublisher) closeConn() error {
+	if p.conn == nil {
+		return nil
+	}
+	err := p.conn.Close()
+	p.conn = nil
+	p.reader = nil
+	return err
+}
+
+func (p *NATSPublisher) connect(ctx context.Context) error {
+	conn, reader, err := p.dial(ctx)
+	if err != nil {
+		return err
+	}
+	p.conn = conn
+	p.reader = reader
+	return nil
+}
+
+// dial opens a connection and completes the handshake.
+func (p *NATSPublisher) dial(ctx context.Context) (net.Conn, *bufio.Reader, error) {
+	dialer := net.Dialer{Timeout: natsDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't connect to nats: %w", err)
+	}
+	reader := bufio.NewReader(conn)
+	setDeadline(conn, ctx)
+
+	line, err := reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return nil, nil, fmt.Errorf("nats server didn't send INFO: %q %v", line, err)
+	}
+
+	options := map[string]any{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     "chirpy",
+		"lang":     "go",
+		"version":  "1",
+	}
+	switch {
+	case p.user != "" && p.pass != "":
+		options["user"] = p.user
+		options["pass"] = p.pass
+	case p.user != "":
+		options["auth_token"] = p.user
+	}
+	connect, err := json.Marshal(options)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	_, err = fmt.Fprintf(conn, "CONNECT %s\r\nPING\r\n", connect)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	err = waitForPong(conn, reader)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return conn, reader, nil
+}
+
+func (p *NATSPublisher) publish(ctx context.Context, subject string, data []byte) error {
+	setDeadline(p.conn, ctx)
+	_, err := fmt.Fprintf(p.conn, "PUB %s %d\r\n%s\r\nPING\r\n", subject, len(data), data)
+	if err != nil {
+		return err
+	}
+	return waitForPong(p.conn, p.reader)
+}
+
+// Subscribe reads the messages on subject from a connection of its own,
+// so it neither blocks nor is blocked by Publish. The server PINGs idle
+// clients every few minutes; a connection that stays silent for longer
+// than natsIdleTimeout is considered dead.
+func (p *NATSPublisher) Subscribe(ctx context.Context, subject string, handle func(data []byte)) error {
+	conn, reader, err := p.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	setDeadline(conn, ctx)
+	_, err = fmt.Fprintf(conn, "SUB %s 1\r\nPING\r\n", subject)
+	if err != nil {
+		return err
+	}
+	err = waitForPong(conn, reader)
+	if err != nil {
+		return err
+	}
+
+	for {
+		conn.SetDeadline(time.Now().Add(natsIdleTimeout))
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "MSG "):
+			// MSG <subject> <sid> [reply-to] <#bytes>
+			fields := strings.Fields(line)
+			size, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil || size < 0 {
+				return fmt.Errorf("nats: malformed %q", line)
+			}
+			data := make([]byte, size+2)
+			_, err = io.ReadFull(reader, data)
+			if err != nil {
+				return err
+			}
+			handle(data[:size])
+		case line == "PING":
+			_, err := conn.Write([]byte("PONG\r\n"))
+			if err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("nats: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+// waitForPong reads until the server answers our PING, replying to the
+// server's own PINGs on the way.
+func waitForPong(conn net.Conn, reader *bufio.Reader) error {
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			_, err := conn.Write([]byte("PONG\r\n"))
+			if err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("nats: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+func setDeadline(conn net.Conn, ctx context.Context) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(natsDialTimeout)
+	}
+	conn.SetDeadline(deadline)
+}
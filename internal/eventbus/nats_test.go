@@ -0,0 +1,165 @@
+package eventbus
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeNATS accepts one connection and records every published message.
+func fakeNATS(t *testing.T, published chan<- string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		fmt.Fprint(conn, "INFO {\"server_id\":\"test\"}\r\n")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimSpace(line)
+			switch {
+			case line == "PING":
+				fmt.Fprint(conn, "PONG\r\n")
+			case strings.HasPrefix(line, "PUB "):
+				payload, _ := r.ReadString('\n')
+				published <- line + " " + strings.TrimSpace(payload)
+			}
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestNATSPublisher(t *testing.T) {
+	published := make(chan string, 2)
+	addr := fakeNATS(t, published)
+
+	p := NewNATSPublisher(&url.URL{Scheme: "nats", Host: addr})
+	defer p.Close()
+
+	for _, body := range []string{`{"id":1}`, `{"id":2}`} {
+		err := p.Publish(context.Background(), "chirpy.chirp.created", []byte(body))
+		if err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+		want := fmt.Sprintf("PUB chirpy.chirp.created %d %s", len(body), body)
+		if got := <-published; got != want {
+			t.Errorf("server got %q, want %q", got, want)
+		}
+	}
+}
+
+func TestNATSSubscribe(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// The server delivers two messages once the client subscribed, with a
+	// PING of its own in between.
+	pong := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		fmt.Fprint(conn, "INFO {\"server_id\":\"test\"}\r\n")
+		subscribed := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimSpace(line)
+			switch {
+			case strings.HasPrefix(line, "SUB "):
+				subscribed = line == "SUB chirpy.chirp.created 1"
+			case line == "PING":
+				fmt.Fprint(conn, "PONG\r\n")
+				if subscribed {
+					fmt.Fprint(conn, "MSG chirpy.chirp.created 1 8\r\n{\"id\":1}\r\nPING\r\n")
+					fmt.Fprint(conn, "MSG chirpy.chirp.created 1 _INBOX.x 8\r\n{\"id\":2}\r\n")
+					subscribed = false
+				}
+			case line == "PONG":
+				pong <- line
+			}
+		}
+	}()
+
+	p := NewNATSPublisher(&url.URL{Scheme: "nats", Host: ln.Addr().String()})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var got []string
+	err = p.Subscribe(ctx, "chirpy.chirp.created", func(data []byte) {
+		got = append(got, string(data))
+		if len(got) == 2 {
+			cancel()
+		}
+	})
+	if err != context.Canceled {
+		t.Errorf("Subscribe() error = %v, want %v", err, context.Canceled)
+	}
+	want := []string{`{"id":1}`, `{"id":2}`}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("got messages %q, want %q", got, want)
+	}
+	select {
+	case <-pong:
+	case <-time.After(time.Second):
+		t.Error("client didn't answer the server's PING")
+	}
+}
+
+func TestNATSPublisherUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	p := NewNATSPublisher(&url.URL{Scheme: "nats", Host: addr})
+	if err := p.Publish(context.Background(), "chirpy.test", []byte("{}")); err == nil {
+		t.Error("Publish() to a closed port succeeded")
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{url: "nats://localhost:4222"},
+		{url: "kafka://localhost:9092", wantErr: true},
+		{url: "redis://localhost", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			_, err := New(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
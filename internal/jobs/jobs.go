@@ -0,0 +1,153 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnknownJob     = errors.New("unknown job")
+	ErrAlreadyRunning = errors.New("job is already running")
+)
+
+// Func runs a job. It calls progress as work completes; total is 0 when
+// it isn't known up front.
+type Func func(ctx context.Context, progress func(done, total int)) error
+
+type State string
+
+const (
+	Running   State = "running"
+	Succeeded State = "succeeded"
+	Failed    State = "failed"
+)
+
+type Status struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	State      State      `json:"state"`
+	Done       int        `json:"done"`
+	Total      int        `json:"total"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Runner runs registered maintenance jobs in the background, one run per
+// job at a time, and remembers the most recent runs.
+type Runner struct {
+	mu      sync.Mutex
+	jobs    map[string]Func
+	runs    []*Status
+	nextID  int
+	keep    int
+	timeout time.Duration
+}
+
+func NewRunner(keep int, timeout time.Duration) *Runner {
+	return &Runner{
+		jobs:    map[string]Func{},
+		keep:    keep,
+		timeout: timeout,
+	}
+}
+
+func (r *Runner) Register(name string, f Func) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs[name] = f
+}
+
+// Names lists the registered jobs.
+func (r *Runner) Names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, 0, len(r.jobs))
+	for name := range r.jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Start kicks off a run of the named job and returns right away.
+func (r *Runner) Start(name string) (Status, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, ok := r.jobs[name]
+	if !ok {
+		return Status{}, fmt.Errorf("%w %q", ErrUnknownJob, name)
+	}
+	for _, run := range r.runs {
+		if run.Name == name && run.State == Running {
+			return Status{}, ErrAlreadyRunning
+		}
+	}
+
+	r.nextID++
+	run := &Status{
+		ID:        r.nextID,
+		Name:      name,
+		State:     Running,
+		StartedAt: time.Now().UTC(),
+	}
+	r.runs = append(r.runs, run)
+	r.trim()
+
+	go r.run(run, f)
+	return *run, nil
+}
+
+func (r *Runner) run(run *Status, f Func) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	err := f(ctx, func(done, total int) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		run.Done = done
+		run.Total = total
+	})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	finished := time.Now().UTC()
+	run.FinishedAt = &finished
+	run.State = Succeeded
+	if err != nil {
+		run.State = Failed
+		run.Error = err.Error()
+		log.Printf("Job %s #%d failed: %v", run.Name, run.ID, err)
+	}
+}
+
+// trim forgets the oldest finished runs beyond keep.
+func (r *Runner) trim() {
+	for len(r.runs) > r.keep {
+		i := 0
+		for i < len(r.runs) && r.runs[i].State == Running {
+			i++
+		}
+		if i == len(r.runs) {
+			return
+		}
+		r.runs = append(r.runs[:i], r.runs[i+1:]...)
+	}
+}
+
+// List returns the remembered runs, newest first.
+func (r *Runner) List() []Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := make([]Status, 0, len(r.runs))
+	for i := len(r.runs) - 1; i >= 0; i-- {
+		list = append(list, *r.runs[i])
+	}
+	return list
+}
@@ -0,0 +1,81 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func waitFor(t *testing.T, r *Runner, id int) Status {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		for _, s := range r.List() {
+			if s.ID == id && s.State != Running {
+				return s
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("job %d didn't finish", id)
+	return Status{}
+}
+
+func TestRunner(t *testing.T) {
+	r := NewRunner(10, time.Minute)
+	release := make(chan struct{})
+	r.Register("count", func(ctx context.Context, progress func(done, total int)) error {
+		<-release
+		progress(3, 3)
+		return nil
+	})
+	r.Register("broken", func(ctx context.Context, progress func(done, total int)) error {
+		return errors.New("broken")
+	})
+
+	if _, err := r.Start("missing"); !errors.Is(err, ErrUnknownJob) {
+		t.Errorf("Start(missing) error = %v, want ErrUnknownJob", err)
+	}
+
+	run, err := r.Start("count")
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if _, err := r.Start("count"); !errors.Is(err, ErrAlreadyRunning) {
+		t.Errorf("second Start() error = %v, want ErrAlreadyRunning", err)
+	}
+	close(release)
+	got := waitFor(t, r, run.ID)
+	if got.State != Succeeded || got.Done != 3 || got.Total != 3 || got.FinishedAt == nil {
+		t.Errorf("finished run = %+v", got)
+	}
+
+	run, _ = r.Start("broken")
+	got = waitFor(t, r, run.ID)
+	if got.State != Failed || got.Error != "broken" {
+		t.Errorf("failed run = %+v", got)
+	}
+
+	if list := r.List(); len(list) != 2 || list[0].Name != "broken" {
+		t.Errorf("List() = %+v, want newest first", list)
+	}
+}
+
+func TestRunnerKeepsRecentRuns(t *testing.T) {
+	r := NewRunner(2, time.Minute)
+	r.Register("noop", func(ctx context.Context, progress func(done, total int)) error {
+		return nil
+	})
+	for range 4 {
+		run, err := r.Start("noop")
+		if err != nil {
+			t.Fatal(err)
+		}
+		waitFor(t, r, run.ID)
+	}
+	list := r.List()
+	if len(list) != 2 || list[0].ID != 4 {
+		t.Errorf("List() = %+v, want runs 4 and 3", list)
+	}
+}
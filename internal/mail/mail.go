@@ -0,0 +1,242 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrHeaderInjection is returned for a sender or recipient address with a
+// line break, which could otherwise add headers to the message.
+var ErrHeaderInjection = errors.New("line break in mail header")
+
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+type Config struct {
+	// Provider is one of "log", "smtp", "ses" or "capture".
+	Provider string
+	From     string
+
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string
+
+	// SESRegion selects the regional SES SMTP endpoint. SES credentials
+	// are the SMTP credentials generated for the IAM user.
+	SESRegion string
+}
+
+func New(cfg Config) (Sender, error) {
+	switch cfg.Provider {
+	case "", "log":
+		return &LogSender{}, nil
+	case "capture":
+		return &CaptureSender{}, nil
+	case "smtp":
+		if cfg.SMTPAddr == "" {
+			return nil, fmt.Errorf("smtp mail provider needs an address")
+		}
+		return NewSMTPSender(cfg.SMTPAddr, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From), nil
+	case "ses":
+		if cfg.SESRegion == "" {
+			return nil, fmt.Errorf("ses mail provider needs a region")
+		}
+		return NewSESSender(cfg.SESRegion, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From), nil
+	}
+	return nil, fmt.Errorf("unknown mail provider %q", cfg.Provider)
+}
+
+// LogSender only logs outgoing mail. It's the default so local setups don't
+// need a mail server.
+type LogSender struct{}
+
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	log.Printf("mail to %s: %s\n%s", msg.To, msg.Subject, msg.Text)
+	return nil
+}
+
+// CaptureSender keeps every message in memory so tests can assert on them.
+type CaptureSender struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func (s *CaptureSender) Send(ctx context.Context, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+func (s *CaptureSender) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// DefaultSMTPTimeout bounds how long delivering one message may take.
+const DefaultSMTPTimeout = 30 * time.Second
+
+type SMTPSender struct {
+	Addr string
+	From string
+	Auth smtp.Auth
+	// Timeout bounds each delivery, unless the context ends earlier.
+	Timeout time.Duration
+}
+
+func NewSMTPSender(addr, username, password, from string) *SMTPSender {
+	s := &SMTPSender{
+		Addr:    addr,
+		From:    from,
+		Timeout: DefaultSMTPTimeout,
+	}
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		s.Auth = smtp.PlainAuth("", username, password, host)
+	}
+	return s
+}
+
+// NewSESSender sends through the Amazon SES SMTP interface of the region.
+func NewSESSender(region, username, password, from string) *SMTPSender {
+	return NewSMTPSender(SESAddr(region), username, password, from)
+}
+
+// SESAddr is the address of the Amazon SES SMTP interface of the region.
+func SESAddr(region string) string {
+	return fmt.Sprintf("email-smtp.%s.amazonaws.com:587", region)
+}
+
+// Send delivers msg like smtp.SendMail, but gives up once the context ends
+// or the timeout passes, so a slow mail server can't hold on to the caller.
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	body, err := buildMessage(s.From, msg)
+	if err != nil {
+		return err
+	}
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		err = conn.SetDeadline(deadline)
+		if err != nil {
+			return err
+		}
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	host, _, _ := net.SplitHostPort(s.Addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		err = c.StartTLS(&tls.Config{ServerName: host})
+		if err != nil {
+			return err
+		}
+	}
+	if s.Auth != nil {
+		if ok, _ := c.Extension("AUTH"); ok {
+			err = c.Auth(s.Auth)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	err = c.Mail(s.From)
+	if err != nil {
+		return err
+	}
+	err = c.Rcpt(msg.To)
+	if err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// buildMessage writes msg as a MIME message. The subject is Q-encoded, which
+// takes care of line breaks in it; the addresses are written as they are, so
+// line breaks in them are refused.
+func buildMessage(from string, msg Message) ([]byte, error) {
+	if strings.ContainsAny(from+msg.To, "\r\n") {
+		return nil, ErrHeaderInjection
+	}
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	}
+	for _, p := range parts {
+		if p.content == "" {
+			continue
+		}
+		pw, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {p.contentType}})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := pw.Write([]byte(p.content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,323 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	renderer, err := NewRenderer("")
+	if err != nil {
+		t.Fatalf("NewRenderer() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		template    string
+		data        any
+		wantSubject string
+		wantText    string
+		wantHTML    string
+		wantErr     bool
+	}{
+		{
+			name:        "Verification",
+			template:    "verification",
+			data:        map[string]string{"Link": "https://chirpy.example/verify?token=abc&x=1"},
+			wantSubject: "Confirm your Chirpy email address",
+			wantText:    "https://chirpy.example/verify?token=abc&x=1",
+			wantHTML:    "https://chirpy.example/verify?token=abc&amp;x=1",
+		},
+		{
+			name:        "Digest escapes chirps in HTML",
+			template:    "digest",
+			data:        map[string][]string{"Chirps": {"<b>hi</b>"}},
+			wantSubject: "Your Chirpy digest",
+			wantText:    "- <b>hi</b>",
+			wantHTML:    "&lt;b&gt;hi&lt;/b&gt;",
+		},
+		{
+			name:     "Unknown template",
+			template: "missing",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := renderer.Render(tt.template, "user@example.com", tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if msg.Subject != tt.wantSubject {
+				t.Errorf("Render() subject = %q, want %q", msg.Subject, tt.wantSubject)
+			}
+			if !strings.Contains(msg.Text, tt.wantText) {
+				t.Errorf("Render() text = %q, want it to contain %q", msg.Text, tt.wantText)
+			}
+			if !strings.Contains(msg.HTML, tt.wantHTML) {
+				t.Errorf("Render() html = %q, want it to contain %q", msg.HTML, tt.wantHTML)
+			}
+		})
+	}
+}
+
+func TestRenderOverrides(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"verification.txt": `{{define "subject"}}Welcome to Acme{{end}}{{define "text"}}{{.Link}}{{end}}`,
+		"reset.txt":        `{{define "subject"}}broken`,
+		"digest.html":      `{{define "text"}}no html block{{end}}`,
+	}
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	renderer, err := NewRenderer(dir)
+	if err != nil {
+		t.Fatalf("NewRenderer() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		template    string
+		wantSubject string
+		wantHTML    string
+	}{
+		{
+			name:        "Override replaces default",
+			template:    "verification",
+			wantSubject: "Welcome to Acme",
+			wantHTML:    "please confirm your email address",
+		},
+		{
+			name:        "Broken override falls back",
+			template:    "reset",
+			wantSubject: "Reset your Chirpy password",
+			wantHTML:    "reset the password",
+		},
+		{
+			name:        "Incomplete override falls back",
+			template:    "digest",
+			wantSubject: "Your Chirpy digest",
+			wantHTML:    "what you missed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := renderer.Render(tt.template, "user@example.com", map[string]any{"Link": "x"})
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if msg.Subject != tt.wantSubject {
+				t.Errorf("Render() subject = %q, want %q", msg.Subject, tt.wantSubject)
+			}
+			if !strings.Contains(msg.HTML, tt.wantHTML) {
+				t.Errorf("Render() html = %q, want it to contain %q", msg.HTML, tt.wantHTML)
+			}
+		})
+	}
+}
+
+func TestRenderOverrideFallsBackOnExecuteError(t *testing.T) {
+	dir := t.TempDir()
+	override := `{{define "subject"}}Welcome to Acme{{end}}{{define "text"}}{{.Lnk}}{{end}}`
+	err := os.WriteFile(filepath.Join(dir, "verification.txt"), []byte(override), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	renderer, err := NewRenderer(dir)
+	if err != nil {
+		t.Fatalf("NewRenderer() error = %v", err)
+	}
+
+	msg, err := renderer.Render("verification", "user@example.com", map[string]any{"Link": "https://chirpy.example/verify"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if msg.Subject == "Welcome to Acme" || !strings.Contains(msg.Text, "https://chirpy.example/verify") {
+		t.Errorf("Render() = %+v, want the default template", msg)
+	}
+}
+
+func TestCaptureSender(t *testing.T) {
+	sender, err := New(Config{Provider: "capture"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	err = sender.Send(context.Background(), Message{To: "user@example.com", Subject: "Hi"})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	messages := sender.(*CaptureSender).Messages()
+	if len(messages) != 1 || messages[0].To != "user@example.com" {
+		t.Errorf("Messages() = %v, want the sent message", messages)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "Default is log", cfg: Config{}},
+		{name: "SMTP", cfg: Config{Provider: "smtp", SMTPAddr: "localhost:25"}},
+		{name: "SMTP without address", cfg: Config{Provider: "smtp"}, wantErr: true},
+		{name: "SES", cfg: Config{Provider: "ses", SESRegion: "eu-west-1"}},
+		{name: "SES without region", cfg: Config{Provider: "ses"}, wantErr: true},
+		{name: "Unknown provider", cfg: Config{Provider: "pigeon"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuildMessage(t *testing.T) {
+	body, err := buildMessage("chirpy@example.com", Message{
+		To:      "user@example.com",
+		Subject: "Grüße",
+		Text:    "plain",
+		HTML:    "<p>html</p>",
+	})
+	if err != nil {
+		t.Fatalf("buildMessage() error = %v", err)
+	}
+	for _, want := range []string{
+		"To: user@example.com\r\n",
+		"Subject: =?utf-8?q?Gr=C3=BC=C3=9Fe?=\r\n",
+		"Content-Type: text/plain; charset=utf-8",
+		"<p>html</p>",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("buildMessage() = %q, want it to contain %q", body, want)
+		}
+	}
+}
+
+func TestBuildMessageHeaderInjection(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		msg  Message
+	}{
+		{name: "To", from: "chirpy@example.com", msg: Message{To: "user@example.com\r\nBcc: victim@example.com", Text: "plain"}},
+		{name: "From", from: "chirpy@example.com\nBcc: victim@example.com", msg: Message{To: "user@example.com", Text: "plain"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := buildMessage(tt.from, tt.msg)
+			if !errors.Is(err, ErrHeaderInjection) {
+				t.Errorf("buildMessage() error = %v, want %v", err, ErrHeaderInjection)
+			}
+		})
+	}
+
+	body, err := buildMessage("chirpy@example.com", Message{To: "user@example.com", Subject: "Hi\r\nBcc: victim@example.com", Text: "plain"})
+	if err != nil {
+		t.Fatalf("buildMessage() error = %v", err)
+	}
+	if strings.Contains(string(body), "\r\nBcc:") {
+		t.Errorf("buildMessage() = %q, want the subject's line break encoded", body)
+	}
+}
+
+func TestSMTPSenderSend(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		tp.PrintfLine("220 test ESMTP")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			switch strings.ToUpper(strings.Fields(line)[0]) {
+			case "EHLO", "MAIL", "RCPT":
+				tp.PrintfLine("250 OK")
+			case "DATA":
+				tp.PrintfLine("354 Go ahead")
+				data, err := tp.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				received <- string(data)
+				tp.PrintfLine("250 Queued")
+			case "QUIT":
+				tp.PrintfLine("221 Bye")
+				return
+			default:
+				tp.PrintfLine("502 Unknown command")
+			}
+		}
+	}()
+
+	s := NewSMTPSender(ln.Addr().String(), "", "", "chirpy@example.com")
+	err = s.Send(context.Background(), Message{To: "user@example.com", Subject: "Hi", Text: "hello"})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if data := <-received; !strings.Contains(data, "hello") {
+		t.Errorf("server received %q, want the message", data)
+	}
+}
+
+func TestSMTPSenderTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		// Accept and never greet, like a stuck mail server.
+		conn, err := ln.Accept()
+		if err == nil {
+			defer conn.Close()
+			io.Copy(io.Discard, conn)
+		}
+	}()
+
+	s := NewSMTPSender(ln.Addr().String(), "", "", "chirpy@example.com")
+	s.Timeout = 100 * time.Millisecond
+	start := time.Now()
+	err = s.Send(context.Background(), Message{To: "user@example.com", Subject: "Hi", Text: "hello"})
+	if err == nil {
+		t.Fatal("Send() succeeded against a server that never answered")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Send() gave up after %v, want about the timeout", elapsed)
+	}
+}
@@ -0,0 +1,145 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"log"
+	"maps"
+	"os"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var defaultTemplates embed.FS
+
+// Renderer turns a named template plus data into a Message. Every email
+// has a <name>.txt template defining "subject" and "text" and a
+// <name>.html template defining "html".
+type Renderer struct {
+	text map[string]*texttemplate.Template
+	html map[string]*htmltemplate.Template
+	// defaults renders the embedded templates when an override fails to.
+	defaults *Renderer
+}
+
+// NewRenderer loads the embedded default templates. If overrideDir is set,
+// templates found there replace the defaults with the same file name; an
+// override that doesn't parse is logged and the default is kept. Overrides
+// also fail on data they ask for but don't get, and then the default is
+// rendered instead.
+func NewRenderer(overrideDir string) (*Renderer, error) {
+	defaults := &Renderer{
+		text: map[string]*texttemplate.Template{},
+		html: map[string]*htmltemplate.Template{},
+	}
+	embedded, err := fs.Sub(defaultTemplates, "templates")
+	if err != nil {
+		return nil, err
+	}
+	err = defaults.load(embedded)
+	if err != nil {
+		return nil, err
+	}
+
+	if overrideDir == "" {
+		return defaults, nil
+	}
+	r := &Renderer{
+		text:     maps.Clone(defaults.text),
+		html:     maps.Clone(defaults.html),
+		defaults: defaults,
+	}
+	overrides := os.DirFS(overrideDir)
+	entries, err := fs.ReadDir(overrides, ".")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read mail template overrides: %w", err)
+	}
+	for _, entry := range entries {
+		err := r.parse(overrides, entry.Name(), "missingkey=error")
+		if err != nil {
+			log.Printf("Ignoring mail template override %s: %v", entry.Name(), err)
+		}
+	}
+	return r, nil
+}
+
+func (r *Renderer) load(fsys fs.FS) error {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		err := r.parse(fsys, entry.Name())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Renderer) parse(fsys fs.FS, file string, options ...string) error {
+	name, ext, _ := strings.Cut(file, ".")
+	switch ext {
+	case "txt":
+		t, err := texttemplate.New(file).Option(options...).ParseFS(fsys, file)
+		if err != nil {
+			return err
+		}
+		if t.Lookup("subject") == nil || t.Lookup("text") == nil {
+			return fmt.Errorf("%s must define \"subject\" and \"text\"", file)
+		}
+		r.text[name] = t
+	case "html":
+		t, err := htmltemplate.New(file).Option(options...).ParseFS(fsys, file)
+		if err != nil {
+			return err
+		}
+		if t.Lookup("html") == nil {
+			return fmt.Errorf("%s must define \"html\"", file)
+		}
+		r.html[name] = t
+	}
+	return nil
+}
+
+func (r *Renderer) Render(name, to string, data any) (Message, error) {
+	msg, err := r.render(name, to, data)
+	if err != nil && r.defaults != nil {
+		log.Printf("Couldn't render mail template override %s, using the default: %v", name, err)
+		return r.defaults.render(name, to, data)
+	}
+	return msg, err
+}
+
+func (r *Renderer) render(name, to string, data any) (Message, error) {
+	text, ok := r.text[name]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown mail template %q", name)
+	}
+
+	msg := Message{To: to}
+	var buf bytes.Buffer
+	if err := text.ExecuteTemplate(&buf, "subject", data); err != nil {
+		return Message{}, err
+	}
+	msg.Subject = strings.TrimSpace(buf.String())
+
+	buf.Reset()
+	if err := text.ExecuteTemplate(&buf, "text", data); err != nil {
+		return Message{}, err
+	}
+	msg.Text = buf.String()
+
+	if html, ok := r.html[name]; ok {
+		buf.Reset()
+		if err := html.ExecuteTemplate(&buf, "html", data); err != nil {
+			return Message{}, err
+		}
+		msg.HTML = buf.String()
+	}
+	return msg, nil
+}
@@ -0,0 +1,148 @@
+// Package markdown renders the small markdown subset chirps support to
+// HTML that is safe to insert into a page as is.
+package markdown
+
+import (
+	"html"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Render converts **bold**, *italic* or _italic_, `code` and
+// [text](url) links to HTML. Links must be http, https or mailto. Every
+// other character is escaped, line breaks become <br>, and a backslash
+// makes the next markup character literal.
+func Render(src string) string {
+	var b strings.Builder
+	render(&b, src, true)
+	return b.String()
+}
+
+func render(b *strings.Builder, s string, links bool) {
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte("\\`*_[]()", s[i+1]) >= 0:
+			b.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+			continue
+
+		case c == '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end > 0 {
+				b.WriteString("<code>")
+				b.WriteString(html.EscapeString(s[i+1 : i+1+end]))
+				b.WriteString("</code>")
+				i += end + 2
+				continue
+			}
+
+		case strings.HasPrefix(s[i:], "**"):
+			if end := strings.Index(s[i+2:], "**"); end > 0 && isTight(s[i+2:i+2+end]) {
+				b.WriteString("<strong>")
+				render(b, s[i+2:i+2+end], links)
+				b.WriteString("</strong>")
+				i += end + 4
+				continue
+			}
+
+		case c == '*' || c == '_':
+			if end := closingEmphasis(s, i); end > 0 {
+				b.WriteString("<em>")
+				render(b, s[i+1:end], links)
+				b.WriteString("</em>")
+				i = end + 1
+				continue
+			}
+
+		case c == '[' && links:
+			if text, href, n, ok := parseLink(s[i:]); ok {
+				b.WriteString(`<a href="`)
+				b.WriteString(html.EscapeString(href))
+				b.WriteString(`" rel="nofollow noopener ugc">`)
+				render(b, text, false)
+				b.WriteString("</a>")
+				i += n
+				continue
+			}
+
+		case c == '\n':
+			b.WriteString("<br>")
+			i++
+			continue
+		}
+
+		_, size := utf8.DecodeRuneInString(s[i:])
+		b.WriteString(html.EscapeString(s[i : i+size]))
+		i += size
+	}
+}
+
+// isTight reports whether emphasized text doesn't start or end with a
+// space, so "2 * 3 * 4" stays plain.
+func isTight(s string) bool {
+	return s != "" && !unicode.IsSpace(rune(s[0])) && !unicode.IsSpace(rune(s[len(s)-1]))
+}
+
+// closingEmphasis returns the index of the delimiter closing the one at
+// start, or -1. Underscores only count at word boundaries, so snake_case
+// identifiers stay plain.
+func closingEmphasis(s string, start int) int {
+	delim := s[start]
+	if delim == '_' && start > 0 && isWordByte(s[start-1]) {
+		return -1
+	}
+	for end := start + 1; end < len(s); end++ {
+		if s[end] == '\n' {
+			return -1
+		}
+		if s[end] != delim || (delim == '*' && end+1 < len(s) && s[end+1] == '*') {
+			continue
+		}
+		if delim == '_' && end+1 < len(s) && isWordByte(s[end+1]) {
+			continue
+		}
+		if isTight(s[start+1 : end]) {
+			return end
+		}
+		return -1
+	}
+	return -1
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= 0x80 || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+}
+
+// parseLink parses [text](url) at the start of s and returns the text, the
+// URL and the length of the markup.
+func parseLink(s string) (text, href string, n int, ok bool) {
+	mid := strings.Index(s, "](")
+	if mid <= 1 || strings.ContainsAny(s[1:mid], "[\n") {
+		return "", "", 0, false
+	}
+	end := strings.IndexByte(s[mid+2:], ')')
+	if end <= 0 {
+		return "", "", 0, false
+	}
+	href = s[mid+2 : mid+2+end]
+	if strings.ContainsAny(href, " \n\"<>") || !safeURL(href) {
+		return "", "", 0, false
+	}
+	return s[1:mid], href, mid + 3 + end, true
+}
+
+func safeURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return u.Host != ""
+	case "mailto":
+		return u.Opaque != ""
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package markdown
+
+import "testing"
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{name: "Plain text", src: "just chirping", want: "just chirping"},
+		{name: "HTML is escaped", src: `<script>alert("x")</script>`, want: "&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;"},
+		{name: "Bold", src: "so **loud**", want: "so <strong>loud</strong>"},
+		{name: "Italic with stars", src: "*soft* voice", want: "<em>soft</em> voice"},
+		{name: "Italic with underscores", src: "_soft_ voice", want: "<em>soft</em> voice"},
+		{name: "Bold around italic", src: "**very _loud_**", want: "<strong>very <em>loud</em></strong>"},
+		{name: "Snake case stays plain", src: "call my_func_name", want: "call my_func_name"},
+		{name: "Loose stars stay plain", src: "2 * 3 * 4", want: "2 * 3 * 4"},
+		{name: "Unclosed markup stays plain", src: "**half and `open", want: "**half and `open"},
+		{name: "Code is not formatted", src: "`**x** <b>`", want: "<code>**x** &lt;b&gt;</code>"},
+		{name: "Escaped star", src: `\*not italic\*`, want: "*not italic*"},
+		{name: "Link", src: "see [the docs](https://example.com/a?b=1&c=2)", want: `see <a href="https://example.com/a?b=1&amp;c=2" rel="nofollow noopener ugc">the docs</a>`},
+		{name: "Formatted link text", src: "[**docs**](https://example.com)", want: `<a href="https://example.com" rel="nofollow noopener ugc"><strong>docs</strong></a>`},
+		{name: "Mailto link", src: "[mail](mailto:a@example.com)", want: `<a href="mailto:a@example.com" rel="nofollow noopener ugc">mail</a>`},
+		{name: "Javascript link is dropped", src: "[x](javascript:alert(1))", want: "[x](javascript:alert(1))"},
+		{name: "Relative link is dropped", src: "[x](/admin)", want: "[x](/admin)"},
+		{name: "Quote in URL is dropped", src: `[x](https://example.com/"onmouseover)`, want: "[x](https://example.com/&#34;onmouseover)"},
+		{name: "Line breaks", src: "one\ntwo", want: "one<br>two"},
+		{name: "Unicode", src: "*héllo* wörld", want: "<em>héllo</em> wörld"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.src); got != tt.want {
+				t.Errorf("Render(%q) = %q, want %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
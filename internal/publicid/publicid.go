@@ -0,0 +1,83 @@
+package publicid
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// encodedLength is the number of base62 digits needed for 128 bits.
+const encodedLength = 22
+
+// Codec maps internal UUIDs to the identifiers shown in URLs and responses.
+type Codec interface {
+	Encode(id uuid.UUID) string
+	Decode(s string) (uuid.UUID, error)
+}
+
+// New returns a codec that encrypts IDs with key.
+func New(key string) (Codec, error) {
+	if key == "" {
+		return nil, fmt.Errorf("public id key is empty")
+	}
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return &encrypted{block: block}, nil
+}
+
+// encrypted runs the 16 byte UUID through a single AES block and prints the
+// result in base62. AES is a permutation, so every UUID gets a distinct
+// 22 character ID that reveals neither creation order nor version, and
+// can't be guessed without the key.
+type encrypted struct {
+	block cipher.Block
+}
+
+func (e *encrypted) Encode(id uuid.UUID) string {
+	var out [16]byte
+	e.block.Encrypt(out[:], id[:])
+
+	n := new(big.Int).SetBytes(out[:])
+	base := big.NewInt(int64(len(alphabet)))
+	digits := make([]byte, encodedLength)
+	mod := new(big.Int)
+	for i := encodedLength - 1; i >= 0; i-- {
+		n.DivMod(n, base, mod)
+		digits[i] = alphabet[mod.Int64()]
+	}
+	return string(digits)
+}
+
+func (e *encrypted) Decode(s string) (uuid.UUID, error) {
+	if len(s) != encodedLength {
+		return uuid.Nil, fmt.Errorf("invalid public id length %d", len(s))
+	}
+	n := new(big.Int)
+	base := big.NewInt(int64(len(alphabet)))
+	for i := 0; i < len(s); i++ {
+		digit := strings.IndexByte(alphabet, s[i])
+		if digit < 0 {
+			return uuid.Nil, fmt.Errorf("invalid public id character %q", s[i])
+		}
+		n.Mul(n, base)
+		n.Add(n, big.NewInt(int64(digit)))
+	}
+	if n.BitLen() > 128 {
+		return uuid.Nil, fmt.Errorf("public id out of range")
+	}
+
+	var in, id [16]byte
+	n.FillBytes(in[:])
+	e.block.Decrypt(id[:], in[:])
+	return uuid.UUID(id), nil
+}
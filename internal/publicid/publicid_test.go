@@ -0,0 +1,73 @@
+package publicid
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func mustNew(t *testing.T, key string) Codec {
+	t.Helper()
+	codec, err := New(key)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return codec
+}
+
+func TestRoundTrip(t *testing.T) {
+	codec := mustNew(t, "secret")
+	ids := []uuid.UUID{uuid.Nil, uuid.Max, uuid.New(), uuid.Must(uuid.NewV7())}
+	for _, id := range ids {
+		encoded := codec.Encode(id)
+		if len(encoded) != encodedLength {
+			t.Errorf("Encode(%v) = %q, want %d characters", id, encoded, encodedLength)
+		}
+		got, err := codec.Decode(encoded)
+		if err != nil {
+			t.Fatalf("Decode(%q) error = %v", encoded, err)
+		}
+		if got != id {
+			t.Errorf("Decode(Encode(%v)) = %v", id, got)
+		}
+	}
+}
+
+func TestKeysDiffer(t *testing.T) {
+	id := uuid.New()
+	a := mustNew(t, "secret").Encode(id)
+	b := mustNew(t, "other secret").Encode(id)
+	if a == b {
+		t.Errorf("different keys produced the same public id %q", a)
+	}
+	got, err := mustNew(t, "other secret").Decode(a)
+	if err == nil && got == id {
+		t.Error("a different key decoded the public id")
+	}
+}
+
+func TestNewEmptyKey(t *testing.T) {
+	if _, err := New(""); err == nil {
+		t.Error("New(\"\") succeeded")
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	codec := mustNew(t, "secret")
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "Too short", input: "abc"},
+		{name: "Invalid character", input: "000000000000000000000-"},
+		{name: "Out of range", input: "zzzzzzzzzzzzzzzzzzzzzz"},
+		{name: "UUID", input: uuid.NewString()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := codec.Decode(tt.input); err == nil {
+				t.Errorf("Decode(%q) succeeded", tt.input)
+			}
+		})
+	}
+}
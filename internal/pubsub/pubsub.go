@@ -0,0 +1,47 @@
+package pubsub
+
+import "sync"
+
+// Hub fans out published values to every current subscriber. Publishing
+// never blocks: a subscriber that isn't keeping up misses values rather
+// than stalling the publisher.
+type Hub[T any] struct {
+	mu   sync.Mutex
+	subs map[chan T]struct{}
+}
+
+func NewHub[T any]() *Hub[T] {
+	return &Hub[T]{
+		subs: map[chan T]struct{}{},
+	}
+}
+
+// Subscribe registers a new subscriber. The returned function must be
+// called to unsubscribe once the caller is done listening.
+func (h *Hub[T]) Subscribe(buffer int) (<-chan T, func()) {
+	ch := make(chan T, buffer)
+
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subs, ch)
+			h.mu.Unlock()
+		})
+	}
+}
+
+func (h *Hub[T]) Publish(v T) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs {
+		select {
+		case ch <- v:
+		default:
+		}
+	}
+}
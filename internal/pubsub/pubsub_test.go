@@ -0,0 +1,44 @@
+package pubsub
+
+import (
+	"testing"
+)
+
+func TestHubPublish(t *testing.T) {
+	hub := NewHub[int]()
+	first, unsubFirst := hub.Subscribe(1)
+	second, unsubSecond := hub.Subscribe(1)
+	defer unsubSecond()
+
+	hub.Publish(1)
+	if got := <-first; got != 1 {
+		t.Errorf("first subscriber got %v, want 1", got)
+	}
+	if got := <-second; got != 1 {
+		t.Errorf("second subscriber got %v, want 1", got)
+	}
+
+	unsubFirst()
+	unsubFirst()
+	hub.Publish(2)
+	select {
+	case v := <-first:
+		t.Errorf("unsubscribed channel received %v", v)
+	default:
+	}
+	if got := <-second; got != 2 {
+		t.Errorf("second subscriber got %v, want 2", got)
+	}
+}
+
+func TestHubPublishDoesNotBlock(t *testing.T) {
+	hub := NewHub[int]()
+	ch, unsub := hub.Subscribe(1)
+	defer unsub()
+
+	hub.Publish(1)
+	hub.Publish(2)
+	if got := <-ch; got != 1 {
+		t.Errorf("got %v, want 1", got)
+	}
+}
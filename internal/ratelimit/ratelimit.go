@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter allows each key a fixed number of requests per window. Windows
+// start at a key's first request, which keeps the bookkeeping to one
+// counter per client.
+type Limiter struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	clients   map[string]*client
+	nextSweep time.Time
+	now       func() time.Time
+}
+
+type client struct {
+	count int
+	reset time.Time
+}
+
+// Result describes a key's quota after a request was counted.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// New returns a limiter allowing limit requests per window. A limit of 0
+// allows everything.
+func New(limit int, window time.Duration) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		window:  window,
+		clients: map[string]*client{},
+		now:     time.Now,
+	}
+}
+
+func (l *Limiter) Enabled() bool {
+	return l.limit > 0
+}
+
+// Limit is the number of requests allowed per window.
+func (l *Limiter) Limit() int {
+	return l.limit
+}
+
+// Window is the length of each rate limit window.
+func (l *Limiter) Window() time.Duration {
+	return l.window
+}
+
+// Allow counts a request for key and reports whether it's within the limit.
+func (l *Limiter) Allow(key string) Result {
+	now := l.now()
+	if !l.Enabled() {
+		return Result{Allowed: true, Reset: now}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.After(l.nextSweep) {
+		for k, c := range l.clients {
+			if !now.Before(c.reset) {
+				delete(l.clients, k)
+			}
+		}
+		l.nextSweep = now.Add(l.window)
+	}
+
+	c, ok := l.clients[key]
+	if !ok || !now.Before(c.reset) {
+		c = &client{reset: now.Add(l.window)}
+		l.clients[key] = c
+	}
+	c.count++
+
+	return Result{
+		Allowed:   c.count <= l.limit,
+		Limit:     l.limit,
+		Remaining: max(l.limit-c.count, 0),
+		Reset:     c.reset,
+	}
+}
@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllow(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := New(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	tests := []struct {
+		name          string
+		key           string
+		advance       time.Duration
+		wantAllowed   bool
+		wantRemaining int
+	}{
+		{name: "First request", key: "a", wantAllowed: true, wantRemaining: 1},
+		{name: "Last allowed request", key: "a", wantAllowed: true, wantRemaining: 0},
+		{name: "Over the limit", key: "a", wantAllowed: false, wantRemaining: 0},
+		{name: "Other key", key: "b", wantAllowed: true, wantRemaining: 1},
+		{name: "Next window", key: "a", advance: time.Minute, wantAllowed: true, wantRemaining: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.advance)
+			got := l.Allow(tt.key)
+			if got.Allowed != tt.wantAllowed {
+				t.Errorf("Allowed = %v, want %v", got.Allowed, tt.wantAllowed)
+			}
+			if got.Remaining != tt.wantRemaining {
+				t.Errorf("Remaining = %d, want %d", got.Remaining, tt.wantRemaining)
+			}
+			if got.Limit != 2 {
+				t.Errorf("Limit = %d, want 2", got.Limit)
+			}
+			if !got.Reset.After(now) {
+				t.Errorf("Reset = %v, want after %v", got.Reset, now)
+			}
+		})
+	}
+}
+
+func TestSweepsExpiredClients(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := New(1, time.Minute)
+	l.now = func() time.Time { return now }
+
+	l.Allow("a")
+	l.Allow("b")
+	now = now.Add(2 * time.Minute)
+	l.Allow("c")
+
+	if len(l.clients) != 1 {
+		t.Errorf("len(clients) = %d, want 1", len(l.clients))
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	l := New(0, time.Minute)
+	for range 10 {
+		if !l.Allow("a").Allowed {
+			t.Fatal("disabled limiter rejected a request")
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Meilisearch keeps chirps in a Meilisearch index. Writes are queued as
+// tasks by Meilisearch and show up in results shortly after.
+type Meilisearch struct {
+	baseURL string
+	apiKey  string
+	index   string
+	client  *http.Client
+}
+
+func NewMeilisearch(baseURL, apiKey, index string) *Meilisearch {
+	return &Meilisearch{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+		index:   index,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type meiliDocument struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Body      string    `json:"body"`
+	CreatedAt int64     `json:"created_at"`
+}
+
+func (m *Meilisearch) Index(ctx context.Context, docs []Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	payload := make([]meiliDocument, len(docs))
+	for i, doc := range docs {
+		payload[i] = meiliDocument{
+			ID:        doc.ID,
+			UserID:    doc.UserID,
+			Body:      doc.Body,
+			CreatedAt: doc.CreatedAt.Unix(),
+		}
+	}
+	return m.do(ctx, http.MethodPost, "/documents?primaryKey=id", payload, nil)
+}
+
+func (m *Meilisearch) Delete(ctx context.Context, id uuid.UUID) error {
+	return m.do(ctx, http.MethodDelete, "/documents/"+id.String(), nil, nil)
+}
+
+func (m *Meilisearch) DeleteAll(ctx context.Context) error {
+	return m.do(ctx, http.MethodDelete, "/documents", nil, nil)
+}
+
+func (m *Meilisearch) Search(ctx context.Context, query string, limit int) ([]uuid.UUID, error) {
+	request := struct {
+		Q                    string   `json:"q"`
+		Limit                int      `json:"limit"`
+		AttributesToRetrieve []string `json:"attributesToRetrieve"`
+	}{
+		Q:                    query,
+		Limit:                limit,
+		AttributesToRetrieve: []string{"id"},
+	}
+	response := struct {
+		Hits []struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"hits"`
+	}{}
+
+	err := m.do(ctx, http.MethodPost, "/search", request, &response)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uuid.UUID, len(response.Hits))
+	for i, hit := range response.Hits {
+		ids[i] = hit.ID
+	}
+	return ids, nil
+}
+
+func (m *Meilisearch) do(ctx context.Context, method, path string, body, dst any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	endpoint := m.baseURL + "/indexes/" + url.PathEscape(m.index) + path
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if m.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+m.apiKey)
+	}
+
+	res, err := m.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("meilisearch: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("meilisearch: %s %s: %s: %s", method, path, res.Status, strings.TrimSpace(string(msg)))
+	}
+	if dst == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(dst)
+}
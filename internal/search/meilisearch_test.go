@@ -0,0 +1,78 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestMeilisearch(t *testing.T) {
+	id := uuid.New()
+	var indexed []meiliDocument
+	var deleted string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/indexes/chirps/documents":
+			json.NewDecoder(r.Body).Decode(&indexed)
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == http.MethodDelete && r.URL.Path == "/indexes/chirps/documents/"+id.String():
+			deleted = id.String()
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == http.MethodPost && r.URL.Path == "/indexes/chirps/search":
+			var req struct {
+				Q string `json:"q"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.Q != "breakfast" {
+				w.Write([]byte(`{"hits": []}`))
+				return
+			}
+			w.Write([]byte(`{"hits": [{"id": "` + id.String() + `"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	m := NewMeilisearch(server.URL+"/", "key", "chirps")
+
+	err := m.Index(ctx, []Document{{ID: id, UserID: uuid.New(), Body: "breakfast", CreatedAt: time.Unix(100, 0)}})
+	if err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+	if len(indexed) != 1 || indexed[0].ID != id || indexed[0].CreatedAt != 100 {
+		t.Errorf("Index() sent %+v", indexed)
+	}
+
+	ids, err := m.Search(ctx, "breakfast", 10)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(ids) != 1 || ids[0] != id {
+		t.Errorf("Search() = %v, want [%v]", ids, id)
+	}
+
+	err = m.Delete(ctx, id)
+	if err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if deleted != id.String() {
+		t.Errorf("Delete() didn't delete %v", id)
+	}
+
+	err = NewMeilisearch(server.URL, "wrong", "chirps").DeleteAll(ctx)
+	if err == nil {
+		t.Error("DeleteAll() with a wrong key succeeded")
+	}
+}
@@ -0,0 +1,39 @@
+package search
+
+import (
+	"context"
+
+	"github.com/fkl13/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Postgres searches chirps with Postgres full-text search. The chirps
+// table keeps a generated tsvector column with a GIN index, so the index
+// is always up to date and there is nothing to write to it.
+type Postgres struct {
+	q *database.Queries
+}
+
+func NewPostgres(q *database.Queries) *Postgres {
+	return &Postgres{q: q}
+}
+
+func (p *Postgres) Index(ctx context.Context, docs []Document) error {
+	return nil
+}
+
+func (p *Postgres) Delete(ctx context.Context, id uuid.UUID) error {
+	return nil
+}
+
+func (p *Postgres) DeleteAll(ctx context.Context) error {
+	return nil
+}
+
+// Search takes web search syntax: quoted phrases, "or" and -excluded words.
+func (p *Postgres) Search(ctx context.Context, query string, limit int) ([]uuid.UUID, error) {
+	return p.q.SearchChirps(ctx, database.SearchChirpsParams{
+		Query:      query,
+		MaxResults: int32(limit),
+	})
+}
@@ -0,0 +1,58 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Document is the searchable part of a chirp.
+type Document struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Body      string
+	CreatedAt time.Time
+}
+
+// Backend indexes chirps and answers full-text queries with chirp IDs,
+// best match first.
+type Backend interface {
+	Index(ctx context.Context, docs []Document) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	// DeleteAll empties the index before a full reindex.
+	DeleteAll(ctx context.Context) error
+	Search(ctx context.Context, query string, limit int) ([]uuid.UUID, error)
+}
+
+type Config struct {
+	// Backend is "postgres" (the default), "meilisearch", or "off".
+	Backend string
+	URL     string
+	APIKey  string
+	// Index defaults to "chirps".
+	Index string
+}
+
+// New returns the configured backend, or nil when search is off. The
+// postgres backend searches through q.
+func New(cfg Config, q *database.Queries) (Backend, error) {
+	index := cfg.Index
+	if index == "" {
+		index = "chirps"
+	}
+	switch cfg.Backend {
+	case "off":
+		return nil, nil
+	case "", "postgres":
+		return NewPostgres(q), nil
+	case "meilisearch":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("meilisearch search backend needs a url")
+		}
+		return NewMeilisearch(cfg.URL, cfg.APIKey, index), nil
+	}
+	return nil, fmt.Errorf("unknown search backend %q", cfg.Backend)
+}
@@ -0,0 +1,37 @@
+package search
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		backend string
+		want    string
+		wantErr bool
+	}{
+		{backend: "", want: "*search.Postgres"},
+		{backend: "postgres", want: "*search.Postgres"},
+		{backend: "off", want: "<nil>"},
+		{backend: "meilisearch", wantErr: true},
+		{backend: "elastic", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.backend, func(t *testing.T) {
+			backend, err := New(Config{Backend: tt.backend}, nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fmt.Sprintf("%T", backend); got != tt.want {
+				t.Errorf("backend = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
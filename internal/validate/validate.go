@@ -0,0 +1,111 @@
+// Package validate collects every problem with a request's fields so
+// clients can fix them all at once instead of one round trip per field.
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+)
+
+// Violation codes.
+const (
+	CodeRequired     = "required"
+	CodeInvalidEmail = "invalid_email"
+	CodeTooShort     = "too_short"
+	CodeTooLong      = "too_long"
+	CodeInvalid      = "invalid"
+)
+
+// Violation describes one invalid field. Field is a path such as "email"
+// or "chirps[2].body".
+type Violation struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error is returned when at least one field is invalid.
+type Error struct {
+	Violations []Violation
+}
+
+func (e *Error) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.Field + ": " + v.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validator accumulates violations. The zero value is ready to use.
+type Validator struct {
+	violations []Violation
+}
+
+func (v *Validator) Add(field, code, message string) {
+	v.violations = append(v.violations, Violation{Field: field, Code: code, Message: message})
+}
+
+// Required reports whether value is non-blank and records a violation if
+// it isn't.
+func (v *Validator) Required(field, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		v.Add(field, CodeRequired, "is required")
+		return false
+	}
+	return true
+}
+
+// Email checks that a required value is a plain email address.
+func (v *Validator) Email(field, value string) {
+	if !v.Required(field, value) {
+		return
+	}
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Address != strings.TrimSpace(value) {
+		v.Add(field, CodeInvalidEmail, "must be an email address")
+	}
+}
+
+// MinLength checks that value has at least n characters.
+func (v *Validator) MinLength(field, value string, n int) {
+	if utf8.RuneCountInString(value) < n {
+		v.Add(field, CodeTooShort, fmt.Sprintf("must be at least %d characters", n))
+	}
+}
+
+// MaxLength checks that value has at most n characters.
+func (v *Validator) MaxLength(field, value string, n int) {
+	if utf8.RuneCountInString(value) > n {
+		v.Add(field, CodeTooLong, fmt.Sprintf("must be at most %d characters", n))
+	}
+}
+
+// Merge adds the violations of err, an *Error from validating a nested
+// value, with their fields prefixed by path. Other errors are recorded as
+// an invalid path.
+func (v *Validator) Merge(path string, err error) {
+	if err == nil {
+		return
+	}
+	var verr *Error
+	if !errors.As(err, &verr) {
+		v.Add(path, CodeInvalid, err.Error())
+		return
+	}
+	for _, violation := range verr.Violations {
+		violation.Field = path + "." + violation.Field
+		v.violations = append(v.violations, violation)
+	}
+}
+
+// Err returns an *Error with all violations, or nil if there are none.
+func (v *Validator) Err() error {
+	if len(v.violations) == 0 {
+		return nil
+	}
+	return &Error{Violations: v.violations}
+}
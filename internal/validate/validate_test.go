@@ -0,0 +1,66 @@
+package validate
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidator(t *testing.T) {
+	v := Validator{}
+	v.Email("email", "not-an-address")
+	v.MinLength("password", "short", 8)
+	v.MaxLength("name", "ok", 10)
+
+	nested := Validator{}
+	nested.Required("body", " ")
+	v.Merge("chirps[1]", nested.Err())
+
+	var verr *Error
+	if !errors.As(v.Err(), &verr) {
+		t.Fatalf("Err() = %v, want *Error", v.Err())
+	}
+	got := []string{}
+	for _, violation := range verr.Violations {
+		got = append(got, violation.Field+"/"+violation.Code)
+	}
+	want := []string{"email/invalid_email", "password/too_short", "chirps[1].body/required"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("violations = %v, want %v", got, want)
+	}
+}
+
+func TestEmail(t *testing.T) {
+	tests := []struct {
+		email string
+		code  string
+	}{
+		{email: "walt@example.com"},
+		{email: "", code: CodeRequired},
+		{email: "walt", code: CodeInvalidEmail},
+		{email: "Walt <walt@example.com>", code: CodeInvalidEmail},
+	}
+	for _, tt := range tests {
+		v := Validator{}
+		v.Email("email", tt.email)
+		err := v.Err()
+		if tt.code == "" {
+			if err != nil {
+				t.Errorf("Email(%q) = %v, want no violation", tt.email, err)
+			}
+			continue
+		}
+		var verr *Error
+		if !errors.As(err, &verr) || verr.Violations[0].Code != tt.code {
+			t.Errorf("Email(%q) = %v, want code %s", tt.email, err, tt.code)
+		}
+	}
+}
+
+func TestErrNil(t *testing.T) {
+	v := Validator{}
+	v.Required("email", "walt@example.com")
+	if err := v.Err(); err != nil {
+		t.Errorf("Err() = %v, want nil", err)
+	}
+}
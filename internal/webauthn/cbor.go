@@ -0,0 +1,141 @@
+package webauthn
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// maxCBORDepth bounds how deeply arrays and maps may nest. Attestation
+// objects and COSE keys never go beyond two levels.
+const maxCBORDepth = 8
+
+var errCBORShort = errors.New("cbor: unexpected end of data")
+
+// decodeCBOR decodes the first CBOR item in b and returns the bytes after
+// it. Only what WebAuthn uses is supported: integers (as int64), byte
+// strings, text strings, arrays, maps and the simple values false, true and
+// null, all of definite length. Tags are dropped.
+func decodeCBOR(b []byte) (any, []byte, error) {
+	return decodeCBORItem(b, 0)
+}
+
+func decodeCBORItem(b []byte, depth int) (any, []byte, error) {
+	if depth > maxCBORDepth {
+		return nil, nil, errors.New("cbor: nested too deeply")
+	}
+	if len(b) == 0 {
+		return nil, nil, errCBORShort
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+	b = b[1:]
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, b, nil
+		case 21:
+			return true, b, nil
+		case 22, 23:
+			return nil, b, nil
+		}
+		return nil, nil, fmt.Errorf("cbor: unsupported simple value %d", info)
+	}
+
+	n, b, err := readCBORArgument(info, b)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch major {
+	case 0:
+		if n > math.MaxInt64 {
+			return nil, nil, errors.New("cbor: integer overflows int64")
+		}
+		return int64(n), b, nil
+	case 1:
+		if n > math.MaxInt64 {
+			return nil, nil, errors.New("cbor: integer overflows int64")
+		}
+		return -1 - int64(n), b, nil
+	case 2, 3:
+		if n > uint64(len(b)) {
+			return nil, nil, errCBORShort
+		}
+		if major == 3 {
+			return string(b[:n]), b[n:], nil
+		}
+		return b[:n:n], b[n:], nil
+	case 4:
+		// Every item takes at least one byte, which keeps a bogus length
+		// from allocating much.
+		if n > uint64(len(b)) {
+			return nil, nil, errCBORShort
+		}
+		items := make([]any, 0, n)
+		for range n {
+			var item any
+			item, b, err = decodeCBORItem(b, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+		}
+		return items, b, nil
+	case 5:
+		if n > uint64(len(b))/2 {
+			return nil, nil, errCBORShort
+		}
+		m := make(map[any]any, n)
+		for range n {
+			var key, value any
+			key, b, err = decodeCBORItem(b, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, fmt.Errorf("cbor: unsupported map key type %T", key)
+			}
+			value, b, err = decodeCBORItem(b, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			if _, ok := m[key]; ok {
+				return nil, nil, fmt.Errorf("cbor: duplicate map key %v", key)
+			}
+			m[key] = value
+		}
+		return m, b, nil
+	case 6:
+		return decodeCBORItem(b, depth)
+	}
+	return nil, nil, fmt.Errorf("cbor: unsupported major type %d", major)
+}
+
+func readCBORArgument(info byte, b []byte) (uint64, []byte, error) {
+	var size int
+	switch {
+	case info < 24:
+		return uint64(info), b, nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	case info == 31:
+		return 0, nil, errors.New("cbor: indefinite lengths aren't supported")
+	default:
+		return 0, nil, fmt.Errorf("cbor: invalid additional information %d", info)
+	}
+	if len(b) < size {
+		return 0, nil, errCBORShort
+	}
+	var n uint64
+	for _, c := range b[:size] {
+		n = n<<8 | uint64(c)
+	}
+	return n, b[size:], nil
+}
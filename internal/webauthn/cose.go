@@ -0,0 +1,130 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// COSE algorithm identifiers of the signatures chirpy accepts.
+const (
+	AlgES256 = -7
+	AlgEdDSA = -8
+	AlgRS256 = -257
+)
+
+// Algorithms lists the accepted algorithms in order of preference, for
+// the pubKeyCredParams of a registration.
+var Algorithms = []int{AlgES256, AlgEdDSA, AlgRS256}
+
+const minRSABits = 2048
+
+// COSE key parameters, see RFC 9053.
+const (
+	coseKty = 1
+	coseAlg = 3
+	// coseCrv is also the RSA modulus; coseX the RSA exponent.
+	coseCrv = -1
+	coseX   = -2
+	coseY   = -3
+
+	coseKtyOKP = 1
+	coseKtyEC2 = 2
+	coseKtyRSA = 3
+
+	coseCrvP256    = 1
+	coseCrvEd25519 = 6
+)
+
+// publicKey is a credential public key decoded from its COSE form.
+type publicKey struct {
+	alg int
+	key crypto.PublicKey
+}
+
+// parsePublicKey decodes a COSE_Key with one of the accepted algorithms.
+func parsePublicKey(b []byte) (publicKey, error) {
+	v, rest, err := decodeCBOR(b)
+	if err != nil {
+		return publicKey{}, err
+	}
+	if len(rest) != 0 {
+		return publicKey{}, errors.New("webauthn: trailing data after public key")
+	}
+	m, ok := v.(map[any]any)
+	if !ok {
+		return publicKey{}, errors.New("webauthn: public key isn't a map")
+	}
+	kty, _ := m[int64(coseKty)].(int64)
+	alg, _ := m[int64(coseAlg)].(int64)
+	crv, _ := m[int64(coseCrv)].(int64)
+
+	switch {
+	case kty == coseKtyEC2 && alg == AlgES256 && crv == coseCrvP256:
+		x, _ := m[int64(coseX)].([]byte)
+		y, _ := m[int64(coseY)].([]byte)
+		if len(x) != 32 || len(y) != 32 {
+			return publicKey{}, errors.New("webauthn: invalid P-256 coordinates")
+		}
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return publicKey{}, fmt.Errorf("webauthn: invalid P-256 key: %w", err)
+		}
+		return publicKey{alg: AlgES256, key: &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}}, nil
+	case kty == coseKtyOKP && alg == AlgEdDSA && crv == coseCrvEd25519:
+		x, _ := m[int64(coseX)].([]byte)
+		if len(x) != ed25519.PublicKeySize {
+			return publicKey{}, errors.New("webauthn: invalid Ed25519 key")
+		}
+		return publicKey{alg: AlgEdDSA, key: ed25519.PublicKey(x)}, nil
+	case kty == coseKtyRSA && alg == AlgRS256:
+		n, _ := m[int64(coseCrv)].([]byte)
+		e, _ := m[int64(coseX)].([]byte)
+		if len(e) == 0 || len(e) > 4 {
+			return publicKey{}, errors.New("webauthn: invalid RSA exponent")
+		}
+		exp := 0
+		for _, c := range e {
+			exp = exp<<8 | int(c)
+		}
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exp}
+		if key.N.BitLen() < minRSABits || exp < 3 || exp%2 == 0 {
+			return publicKey{}, errors.New("webauthn: weak RSA key")
+		}
+		return publicKey{alg: AlgRS256, key: key}, nil
+	}
+	return publicKey{}, fmt.Errorf("webauthn: unsupported key type %d with algorithm %d", kty, alg)
+}
+
+func (k publicKey) verify(data, sig []byte) error {
+	switch key := k.key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		if !ecdsa.VerifyASN1(key, digest[:], sig) {
+			return ErrSignature
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, data, sig) {
+			return ErrSignature
+		}
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(data)
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) != nil {
+			return ErrSignature
+		}
+	default:
+		return ErrSignature
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+// Package webauthn verifies passkey registrations and logins. It covers
+// what a relying party that asks for no attestation needs: the client data,
+// the authenticator data, the credential public key and the signature.
+// Attestation statements aren't checked, and every ceremony must verify the
+// user.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ChallengeSize is the length of a challenge in bytes.
+const ChallengeSize = 32
+
+var (
+	ErrSignature = errors.New("webauthn: invalid signature")
+	// ErrUserVerification means the authenticator only checked that someone
+	// was present, not who.
+	ErrUserVerification = errors.New("webauthn: user wasn't verified")
+	// ErrSignCount means the authenticator's counter didn't go up, which
+	// suggests the credential was cloned.
+	ErrSignCount = errors.New("webauthn: signature counter went backwards")
+)
+
+// authenticator data flags
+const (
+	flagUserPresent   = 0x01
+	flagUserVerified  = 0x04
+	flagAttestedData  = 0x40
+	flagExtensionData = 0x80
+)
+
+// Bytes is binary data that travels as unpadded base64url in JSON, like
+// the WebAuthn browser API's JSON form.
+type Bytes []byte
+
+func (b Bytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (b *Bytes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	// Some clients pad or use the standard alphabet.
+	decoded, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		decoded, err = base64.URLEncoding.DecodeString(s)
+	}
+	if err != nil {
+		decoded, err = base64.StdEncoding.DecodeString(s)
+	}
+	if err != nil {
+		return fmt.Errorf("webauthn: invalid base64url: %w", err)
+	}
+	*b = decoded
+	return nil
+}
+
+// RelyingParty is the site passkeys are registered for. ID is its domain
+// and Origins the origins, like https://chirpy.example, pages calling the
+// WebAuthn API are served from.
+type RelyingParty struct {
+	ID      string
+	Origins []string
+}
+
+// Credential is a registered passkey.
+type Credential struct {
+	ID []byte
+	// PublicKey is the COSE_Key the authenticator sent.
+	PublicKey []byte
+	SignCount uint32
+}
+
+// NewChallenge returns a random challenge.
+func NewChallenge() ([]byte, error) {
+	challenge := make([]byte, ChallengeSize)
+	_, err := rand.Read(challenge)
+	if err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+func (rp RelyingParty) checkClientData(raw []byte, typ string, challenge []byte) error {
+	var cd clientData
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return fmt.Errorf("webauthn: invalid client data: %w", err)
+	}
+	if cd.Type != typ {
+		return fmt.Errorf("webauthn: client data type is %q, want %q", cd.Type, typ)
+	}
+	got, err := base64.RawURLEncoding.DecodeString(cd.Challenge)
+	if err != nil || subtle.ConstantTimeCompare(got, challenge) != 1 {
+		return errors.New("webauthn: challenge doesn't match")
+	}
+	if !slices.Contains(rp.Origins, cd.Origin) {
+		return fmt.Errorf("webauthn: unexpected origin %q", cd.Origin)
+	}
+	return nil
+}
+
+type authenticatorData struct {
+	flags     byte
+	signCount uint32
+	// credentialID and publicKey are only set during registration.
+	credentialID []byte
+	publicKey    []byte
+}
+
+func (rp RelyingParty) parseAuthenticatorData(b []byte) (authenticatorData, error) {
+	const headerSize = 32 + 1 + 4
+	if len(b) < headerSize {
+		return authenticatorData{}, errors.New("webauthn: authenticator data too short")
+	}
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(b[:32], rpIDHash[:]) {
+		return authenticatorData{}, errors.New("webauthn: credential is for another relying party")
+	}
+	ad := authenticatorData{
+		flags:     b[32],
+		signCount: binary.BigEndian.Uint32(b[33:37]),
+	}
+	if ad.flags&flagUserPresent == 0 {
+		return authenticatorData{}, errors.New("webauthn: user wasn't present")
+	}
+	// Passkeys replace the password, so the authenticator must have checked
+	// a PIN or biometric too.
+	if ad.flags&flagUserVerified == 0 {
+		return authenticatorData{}, ErrUserVerification
+	}
+	rest := b[headerSize:]
+
+	if ad.flags&flagAttestedData != 0 {
+		// AAGUID, then the length of the credential ID.
+		if len(rest) < 16+2 {
+			return authenticatorData{}, errors.New("webauthn: attested credential data too short")
+		}
+		n := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if len(rest) < n || n == 0 || n > 1023 {
+			return authenticatorData{}, errors.New("webauthn: invalid credential ID")
+		}
+		ad.credentialID = rest[:n:n]
+		rest = rest[n:]
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return authenticatorData{}, fmt.Errorf("webauthn: invalid public key: %w", err)
+		}
+		ad.publicKey = rest[: len(rest)-len(after) : len(rest)-len(after)]
+		rest = after
+	}
+	if ad.flags&flagExtensionData != 0 {
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return authenticatorData{}, fmt.Errorf("webauthn: invalid extensions: %w", err)
+		}
+		rest = after
+	}
+	if len(rest) != 0 {
+		return authenticatorData{}, errors.New("webauthn: trailing data after authenticator data")
+	}
+	return ad, nil
+}
+
+// VerifyRegistration checks the response to navigator.credentials.create
+// for challenge and returns the new credential.
+func (rp RelyingParty) VerifyRegistration(challenge, clientDataJSON, attestationObject []byte) (Credential, error) {
+	err := rp.checkClientData(clientDataJSON, "webauthn.create", challenge)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	v, rest, err := decodeCBOR(attestationObject)
+	if err != nil {
+		return Credential{}, fmt.Errorf("webauthn: invalid attestation object: %w", err)
+	}
+	att, ok := v.(map[any]any)
+	if !ok || len(rest) != 0 {
+		return Credential{}, errors.New("webauthn: invalid attestation object")
+	}
+	authData, ok := att["authData"].([]byte)
+	if !ok {
+		return Credential{}, errors.New("webauthn: attestation object has no authenticator data")
+	}
+	ad, err := rp.parseAuthenticatorData(authData)
+	if err != nil {
+		return Credential{}, err
+	}
+	if ad.flags&flagAttestedData == 0 {
+		return Credential{}, errors.New("webauthn: no credential in authenticator data")
+	}
+	_, err = parsePublicKey(ad.publicKey)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	return Credential{
+		ID:        ad.credentialID,
+		PublicKey: ad.publicKey,
+		SignCount: ad.signCount,
+	}, nil
+}
+
+// VerifyAssertion checks the response to navigator.credentials.get for
+// challenge against a registered credential and returns the new signature
+// counter. Authenticators that don't count always send 0.
+func (rp RelyingParty) VerifyAssertion(challenge []byte, cred Credential, clientDataJSON, authenticatorData, signature []byte) (uint32, error) {
+	err := rp.checkClientData(clientDataJSON, "webauthn.get", challenge)
+	if err != nil {
+		return 0, err
+	}
+	ad, err := rp.parseAuthenticatorData(authenticatorData)
+	if err != nil {
+		return 0, err
+	}
+	key, err := parsePublicKey(cred.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(slices.Clip(authenticatorData), clientDataHash[:]...)
+	err = key.verify(signed, signature)
+	if err != nil {
+		return 0, err
+	}
+
+	if (ad.signCount != 0 || cred.SignCount != 0) && ad.signCount <= cred.SignCount {
+		return 0, ErrSignCount
+	}
+	return ad.signCount, nil
+}
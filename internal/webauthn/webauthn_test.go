@@ -0,0 +1,290 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// A minimal CBOR encoder for building authenticator responses.
+
+func cborHead(major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n < 1<<8:
+		return []byte{major<<5 | 24, byte(n)}
+	case n < 1<<16:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+	}
+}
+
+func cborInt(n int64) []byte {
+	if n < 0 {
+		return cborHead(1, uint64(-1-n))
+	}
+	return cborHead(0, uint64(n))
+}
+
+func cborBytes(b []byte) []byte {
+	return append(cborHead(2, uint64(len(b))), b...)
+}
+
+func cborText(s string) []byte {
+	return append(cborHead(3, uint64(len(s))), s...)
+}
+
+// cborMap encodes alternating, already encoded keys and values.
+func cborMap(items ...[]byte) []byte {
+	out := cborHead(5, uint64(len(items)/2))
+	for _, item := range items {
+		out = append(out, item...)
+	}
+	return out
+}
+
+type testAuthenticator struct {
+	credentialID []byte
+	cose         []byte
+	sign         func(data []byte) []byte
+}
+
+func newES256Authenticator(t *testing.T) testAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, y := make([]byte, 32), make([]byte, 32)
+	key.X.FillBytes(x)
+	key.Y.FillBytes(y)
+	return testAuthenticator{
+		credentialID: []byte("es256-credential"),
+		cose: cborMap(
+			cborInt(coseKty), cborInt(coseKtyEC2),
+			cborInt(coseAlg), cborInt(AlgES256),
+			cborInt(coseCrv), cborInt(coseCrvP256),
+			cborInt(coseX), cborBytes(x),
+			cborInt(coseY), cborBytes(y),
+		),
+		sign: func(data []byte) []byte {
+			digest := sha256.Sum256(data)
+			sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			return sig
+		},
+	}
+}
+
+func newEd25519Authenticator(t *testing.T) testAuthenticator {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testAuthenticator{
+		credentialID: []byte("ed25519-credential"),
+		cose: cborMap(
+			cborInt(coseKty), cborInt(coseKtyOKP),
+			cborInt(coseAlg), cborInt(AlgEdDSA),
+			cborInt(coseCrv), cborInt(coseCrvEd25519),
+			cborInt(coseX), cborBytes(pub),
+		),
+		sign: func(data []byte) []byte {
+			return ed25519.Sign(priv, data)
+		},
+	}
+}
+
+func authData(rpID string, flags byte, signCount uint32, attested []byte) []byte {
+	hash := sha256.Sum256([]byte(rpID))
+	out := append(hash[:], flags)
+	out = binary.BigEndian.AppendUint32(out, signCount)
+	return append(out, attested...)
+}
+
+func (a testAuthenticator) attestationObject(rpID string) []byte {
+	attested := make([]byte, 16)
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(attested, a.credentialID...)
+	attested = append(attested, a.cose...)
+	return cborMap(
+		cborText("fmt"), cborText("none"),
+		cborText("attStmt"), cborMap(),
+		cborText("authData"), cborBytes(authData(rpID, flagUserPresent|flagUserVerified|flagAttestedData, 0, attested)),
+	)
+}
+
+func clientDataJSON(t *testing.T, typ string, challenge []byte, origin string) []byte {
+	t.Helper()
+	b, err := json.Marshal(map[string]string{
+		"type":      typ,
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":    origin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	rp := RelyingParty{ID: "chirpy.example", Origins: []string{"https://chirpy.example"}}
+	for name, a := range map[string]testAuthenticator{
+		"ES256":   newES256Authenticator(t),
+		"Ed25519": newEd25519Authenticator(t),
+	} {
+		t.Run(name, func(t *testing.T) {
+			challenge, err := NewChallenge()
+			if err != nil {
+				t.Fatal(err)
+			}
+			cred, err := rp.VerifyRegistration(challenge,
+				clientDataJSON(t, "webauthn.create", challenge, "https://chirpy.example"),
+				a.attestationObject(rp.ID))
+			if err != nil {
+				t.Fatalf("VerifyRegistration() error = %v", err)
+			}
+			if string(cred.ID) != string(a.credentialID) {
+				t.Errorf("credential ID = %q, want %q", cred.ID, a.credentialID)
+			}
+
+			challenge, err = NewChallenge()
+			if err != nil {
+				t.Fatal(err)
+			}
+			cd := clientDataJSON(t, "webauthn.get", challenge, "https://chirpy.example")
+			ad := authData(rp.ID, flagUserPresent|flagUserVerified, 5, nil)
+			cdHash := sha256.Sum256(cd)
+			sig := a.sign(append(append([]byte{}, ad...), cdHash[:]...))
+
+			count, err := rp.VerifyAssertion(challenge, cred, cd, ad, sig)
+			if err != nil {
+				t.Fatalf("VerifyAssertion() error = %v", err)
+			}
+			if count != 5 {
+				t.Errorf("sign count = %d, want 5", count)
+			}
+
+			cred.SignCount = count
+			_, err = rp.VerifyAssertion(challenge, cred, cd, ad, sig)
+			if !errors.Is(err, ErrSignCount) {
+				t.Errorf("replayed assertion error = %v, want %v", err, ErrSignCount)
+			}
+
+			cred.SignCount = 0
+			sig[len(sig)-1] ^= 1
+			_, err = rp.VerifyAssertion(challenge, cred, cd, ad, sig)
+			if !errors.Is(err, ErrSignature) {
+				t.Errorf("tampered signature error = %v, want %v", err, ErrSignature)
+			}
+		})
+	}
+}
+
+func TestVerifyRegistrationRejects(t *testing.T) {
+	rp := RelyingParty{ID: "chirpy.example", Origins: []string{"https://chirpy.example"}}
+	a := newES256Authenticator(t)
+	challenge := []byte("0123456789abcdef0123456789abcdef")
+	tests := []struct {
+		name       string
+		clientData []byte
+		attObject  []byte
+	}{
+		{
+			name:       "Wrong origin",
+			clientData: clientDataJSON(t, "webauthn.create", challenge, "https://evil.example"),
+			attObject:  a.attestationObject(rp.ID),
+		},
+		{
+			name:       "Wrong challenge",
+			clientData: clientDataJSON(t, "webauthn.create", []byte("another challenge"), "https://chirpy.example"),
+			attObject:  a.attestationObject(rp.ID),
+		},
+		{
+			name:       "Wrong type",
+			clientData: clientDataJSON(t, "webauthn.get", challenge, "https://chirpy.example"),
+			attObject:  a.attestationObject(rp.ID),
+		},
+		{
+			name:       "Wrong relying party",
+			clientData: clientDataJSON(t, "webauthn.create", challenge, "https://chirpy.example"),
+			attObject:  a.attestationObject("evil.example"),
+		},
+		{
+			name:       "Truncated attestation object",
+			clientData: clientDataJSON(t, "webauthn.create", challenge, "https://chirpy.example"),
+			attObject:  a.attestationObject(rp.ID)[:40],
+		},
+		{
+			name:       "No credential",
+			clientData: clientDataJSON(t, "webauthn.create", challenge, "https://chirpy.example"),
+			attObject: cborMap(
+				cborText("fmt"), cborText("none"),
+				cborText("authData"), cborBytes(authData(rp.ID, flagUserPresent|flagUserVerified, 0, nil)),
+			),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := rp.VerifyRegistration(challenge, tt.clientData, tt.attObject)
+			if err == nil {
+				t.Error("VerifyRegistration() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	v, rest, err := decodeCBOR(append(cborMap(cborInt(-3), cborBytes([]byte{1, 2}), cborText("a"), cborInt(1000)), 0xff))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, ok := v.(map[any]any)
+	if !ok || len(m) != 2 || m[int64(-3)].([]byte)[1] != 2 || m["a"] != int64(1000) {
+		t.Errorf("decodeCBOR() = %#v", v)
+	}
+	if len(rest) != 1 {
+		t.Errorf("rest = %v, want the trailing byte", rest)
+	}
+
+	for name, b := range map[string][]byte{
+		"Empty":             {},
+		"Truncated string":  {0x45, 1, 2},
+		"Indefinite length": {0x5f, 0x41, 1, 0xff},
+		"Huge array":        {0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		"Duplicate key":     cborMap(cborInt(1), cborInt(1), cborInt(1), cborInt(2)),
+		"Too deep":          {0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x00},
+	} {
+		if _, _, err := decodeCBOR(b); err == nil {
+			t.Errorf("%s: decodeCBOR() succeeded, want an error", name)
+		}
+	}
+}
+
+func TestBytesJSON(t *testing.T) {
+	b, err := json.Marshal(Bytes{0xfb, 0xff})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `"-_8"` {
+		t.Errorf("Marshal = %s, want unpadded base64url", b)
+	}
+	var got Bytes
+	for _, s := range []string{`"-_8"`, `"-_8="`, `"+/8="`} {
+		if err := json.Unmarshal([]byte(s), &got); err != nil || string(got) != "\xfb\xff" {
+			t.Errorf("Unmarshal(%s) = %v, %v", s, got, err)
+		}
+	}
+}
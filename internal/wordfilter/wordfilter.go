@@ -0,0 +1,221 @@
+// Package wordfilter masks banned words in chirps. Words match regardless
+// of case and of the punctuation around or inside them, and optionally
+// when they're spelled with characters that look like the real letters.
+package wordfilter
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Mask replaces every banned word.
+const Mask = "****"
+
+// Filter is an immutable set of banned words.
+type Filter struct {
+	words       map[string]struct{}
+	allowed     map[string]struct{}
+	confusables bool
+}
+
+// New returns a filter for words. With confusables set, lookalike letters
+// such as Cyrillic "е", fullwidth "Ｅ", "é" or the digit "0" count as the
+// Latin letters they resemble.
+func New(words []string, confusables bool) *Filter {
+	f := &Filter{
+		words:       make(map[string]struct{}, len(words)),
+		confusables: confusables,
+	}
+	for _, word := range words {
+		if key := f.key(word); key != "" {
+			f.words[key] = struct{}{}
+		}
+	}
+	return f
+}
+
+// WithAllowed returns a copy of the filter that leaves the given words
+// alone. An allowed word is matched as written, punctuation included, so
+// allowing "he'll" keeps "he'll" without allowing "hell". Each call
+// replaces the previous allowlist.
+func (f *Filter) WithAllowed(words []string) *Filter {
+	if f == nil {
+		return nil
+	}
+	next := &Filter{
+		words:       f.words,
+		allowed:     make(map[string]struct{}, len(words)),
+		confusables: f.confusables,
+	}
+	for _, word := range words {
+		if key := f.key(word); key != "" {
+			next.allowed[key] = struct{}{}
+		}
+	}
+	return next
+}
+
+// Contains reports whether word is banned.
+func (f *Filter) Contains(word string) bool {
+	if f == nil {
+		return false
+	}
+	_, ok := f.words[f.key(word)]
+	return ok
+}
+
+// Len returns the number of banned words.
+func (f *Filter) Len() int {
+	if f == nil {
+		return 0
+	}
+	return len(f.words)
+}
+
+// Apply masks the banned words in text. Only the words change: whitespace
+// and the punctuation around a word are kept, so "kerfuffle!" becomes
+// "****!".
+func (f *Filter) Apply(text string) string {
+	if f.Len() == 0 {
+		return text
+	}
+	notSpace := func(r rune) bool { return !unicode.IsSpace(r) }
+
+	var b strings.Builder
+	b.Grow(len(text))
+	for text != "" {
+		end := strings.IndexFunc(text, unicode.IsSpace)
+		if end == -1 {
+			end = len(text)
+		}
+		b.WriteString(f.applyToken(text[:end]))
+		text = text[end:]
+
+		end = strings.IndexFunc(text, notSpace)
+		if end == -1 {
+			end = len(text)
+		}
+		b.WriteString(text[:end])
+		text = text[end:]
+	}
+	return b.String()
+}
+
+// span is a run of letters and digits in a token, as byte offsets.
+type span struct{ start, end int }
+
+// applyToken masks a token without whitespace. If its letters spell a
+// banned word once the punctuation in between is dropped ("k.e.r.f.u.f.f.l.e"),
+// everything from the first to the last letter is masked. Otherwise each
+// run of letters is checked on its own, so "fornax/sharbert" masks both.
+func (f *Filter) applyToken(token string) string {
+	var spans []span
+	start := -1
+	for i, r := range token {
+		if isWordRune(r) {
+			if start == -1 {
+				start = i
+			}
+			continue
+		}
+		if start != -1 {
+			spans = append(spans, span{start, i})
+			start = -1
+		}
+	}
+	if start != -1 {
+		spans = append(spans, span{start, len(token)})
+	}
+	if len(spans) == 0 {
+		return token
+	}
+
+	first, last := spans[0].start, spans[len(spans)-1].end
+	if f.isAllowed(token[first:last]) {
+		return token
+	}
+	if len(spans) > 1 {
+		var joined strings.Builder
+		for _, s := range spans {
+			joined.WriteString(token[s.start:s.end])
+		}
+		if f.Contains(joined.String()) {
+			return token[:first] + Mask + token[last:]
+		}
+	}
+
+	var b strings.Builder
+	prev := 0
+	for _, s := range spans {
+		word := token[s.start:s.end]
+		if f.Contains(word) && !f.isAllowed(word) {
+			b.WriteString(token[prev:s.start])
+			b.WriteString(Mask)
+			prev = s.end
+		}
+	}
+	if prev == 0 {
+		return token
+	}
+	b.WriteString(token[prev:])
+	return b.String()
+}
+
+func (f *Filter) isAllowed(word string) bool {
+	_, ok := f.allowed[f.key(word)]
+	return ok
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+// key is the form words are compared in: lowercase, and with lookalikes
+// folded to Latin letters when the filter matches confusables.
+func (f *Filter) key(word string) string {
+	var b strings.Builder
+	b.Grow(len(word))
+	for _, r := range strings.ToLower(word) {
+		if f.confusables {
+			if unicode.Is(unicode.Mn, r) {
+				continue
+			}
+			if folded, ok := fold(r); ok {
+				r = folded
+			}
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// fold maps a lowercase lookalike to the ASCII letter it resembles.
+func fold(r rune) (rune, bool) {
+	switch {
+	case r >= 'ａ' && r <= 'ｚ':
+		return 'a' + r - 'ａ', true
+	case r >= '０' && r <= '９':
+		r = '0' + r - '０'
+	}
+	folded, ok := confusables[r]
+	return folded, ok
+}
+
+var confusables = map[rune]rune{
+	// Digits used as letters.
+	'0': 'o', '1': 'l', '3': 'e', '4': 'a', '5': 's', '7': 't',
+	// Cyrillic.
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'һ': 'h', 'і': 'i', 'ї': 'i',
+	'ј': 'j', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p', 'с': 'c',
+	'т': 't', 'у': 'y', 'х': 'x', 'ѕ': 's', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w',
+	// Greek.
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v',
+	'ο': 'o', 'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x',
+	// Latin letters with diacritics and other Latin lookalikes.
+	'à': 'a', 'á': 'a', 'â': 'a', 'ã': 'a', 'ä': 'a', 'å': 'a', 'ā': 'a',
+	'ç': 'c', 'è': 'e', 'é': 'e', 'ê': 'e', 'ë': 'e', 'ē': 'e',
+	'ì': 'i', 'í': 'i', 'î': 'i', 'ï': 'i', 'ı': 'i', 'ñ': 'n',
+	'ò': 'o', 'ó': 'o', 'ô': 'o', 'õ': 'o', 'ö': 'o', 'ø': 'o', 'ō': 'o',
+	'ù': 'u', 'ú': 'u', 'û': 'u', 'ü': 'u', 'ū': 'u', 'ý': 'y', 'ÿ': 'y',
+	'ɡ': 'g', 'ſ': 's',
+}
@@ -0,0 +1,88 @@
+package wordfilter
+
+import "testing"
+
+func TestApply(t *testing.T) {
+	banned := []string{"kerfuffle", "sharbert", "Fornax"}
+	tests := []struct {
+		name        string
+		text        string
+		confusables bool
+		want        string
+	}{
+		{name: "Clean text", text: "I had something interesting for breakfast", want: "I had something interesting for breakfast"},
+		{name: "Plain word", text: "what a kerfuffle today", want: "what a **** today"},
+		{name: "Case", text: "KERFUFFLE Sharbert fOrNaX", want: "**** **** ****"},
+		{name: "Trailing punctuation", text: "kerfuffle!", want: "****!"},
+		{name: "Surrounding punctuation", text: `("sharbert"), fornax...`, want: `("****"), ****...`},
+		{name: "Possessive", text: "the kerfuffle's end", want: "the ****'s end"},
+		{name: "Two words around a slash", text: "fornax/sharbert", want: "****/****"},
+		{name: "Punctuation inside a word", text: "k.e.r.f.u.f.f.l.e!", want: "****!"},
+		{name: "Hyphen inside a word", text: "ker-fuffle", want: "****"},
+		{name: "Zero width space inside a word", text: "ker\u200bfuffle", want: "****"},
+		{name: "Substring stays", text: "kerfuffles sharberts unfornax", want: "kerfuffles sharberts unfornax"},
+		{name: "Hyphenated compound", text: "fornax-like", want: "****-like"},
+		{name: "Whitespace is kept", text: "  kerfuffle\n\tsharbert\r\n", want: "  ****\n\t****\r\n"},
+		{name: "Only punctuation", text: "?! ... --", want: "?! ... --"},
+		{name: "Empty", text: "", want: ""},
+		{name: "Cyrillic lookalikes unmatched when off", text: "kеrfufflе", want: "kеrfufflе"},
+		{name: "Cyrillic lookalikes", text: "kеrfufflе", confusables: true, want: "****"},
+		{name: "Greek lookalikes", text: "fοrnaχ", confusables: true, want: "****"},
+		{name: "Fullwidth letters", text: "ＳＨＡＲＢＥＲＴ!", confusables: true, want: "****!"},
+		{name: "Digits for letters", text: "f0rn4x", confusables: true, want: "****"},
+		{name: "Fullwidth digits", text: "f０rnax", confusables: true, want: "****"},
+		{name: "Diacritics", text: "kérfüfflé", confusables: true, want: "****"},
+		{name: "Combining marks", text: "ke\u0301rfuffle", confusables: true, want: "****"},
+		{name: "Lookalikes with punctuation", text: "(ѕharbеrt)", confusables: true, want: "(****)"},
+		{name: "Numbers stay", text: "call 555-0100", confusables: true, want: "call 555-0100"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := New(banned, tt.confusables)
+			if got := f.Apply(tt.text); got != tt.want {
+				t.Errorf("Apply(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithAllowed(t *testing.T) {
+	f := New([]string{"hell", "fornax"}, true).WithAllowed([]string{"He'll", "fornax-like"})
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "he'll be late", want: "he'll be late"},
+		{text: "HE'LL!", want: "HE'LL!"},
+		{text: "hell no", want: "**** no"},
+		{text: "h.e.l.l", want: "****"},
+		{text: "fornax-like", want: "fornax-like"},
+		{text: "fοrnax-like", want: "fοrnax-like"},
+	}
+	for _, tt := range tests {
+		if got := f.Apply(tt.text); got != tt.want {
+			t.Errorf("Apply(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+	if got := f.WithAllowed(nil).Apply("he'll"); got != "****" {
+		t.Errorf("Apply(he'll) = %q after clearing the allowlist, want ****", got)
+	}
+}
+
+func TestContains(t *testing.T) {
+	f := New([]string{"Fornax", "fоrnax"}, true)
+	if f.Len() != 1 {
+		t.Errorf("Len() = %d, want lookalike spellings to count once", f.Len())
+	}
+	if !f.Contains("FORNAX") {
+		t.Error("Contains(FORNAX) = false, want true")
+	}
+	if f.Contains("fornax!") {
+		t.Error("Contains(fornax!) = true, want punctuation to be part of the word")
+	}
+
+	var empty *Filter
+	if empty.Contains("fornax") || empty.Apply("fornax") != "fornax" {
+		t.Error("nil filter banned a word")
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/fkl13/chirpy/internal/jobs"
+)
+
+// registerJobs sets up the maintenance jobs operators can start through
+// POST /admin/jobs/{name}.
+func (cfg *apiConfig) registerJobs() {
+	if cfg.search != nil {
+		cfg.jobs.Register("reindex", func(ctx context.Context, progress func(done, total int)) error {
+			total, err := cfg.dbQueries.CountChirps(ctx)
+			if err != nil {
+				return err
+			}
+			progress(0, int(total))
+			_, err = reindexChirps(ctx, cfg.dbQueries, cfg.search, func(indexed int) {
+				progress(indexed, int(total))
+			})
+			return err
+		})
+	}
+	if cfg.analytics != nil {
+		cfg.jobs.Register("purge-analytics", func(ctx context.Context, progress func(done, total int)) error {
+			n, err := cfg.purgeAnalytics(ctx)
+			if err != nil {
+				return err
+			}
+			progress(int(n), int(n))
+			return nil
+		})
+	}
+	cfg.jobs.Register("purge-deactivated-users", func(ctx context.Context, progress func(done, total int)) error {
+		n, err := cfg.deleteExpiredUsers(ctx)
+		if err != nil {
+			return err
+		}
+		progress(int(n), int(n))
+		return nil
+	})
+}
+
+func (cfg *apiConfig) startJobHandler(w http.ResponseWriter, r *http.Request) {
+	run, err := cfg.jobs.Start(r.PathValue("name"))
+	if errors.Is(err, jobs.ErrUnknownJob) {
+		respondWithError(w, http.StatusNotFound, fmt.Sprintf("Unknown job, available jobs: %v", cfg.jobs.Names()), err)
+		return
+	}
+	if errors.Is(err, jobs.ErrAlreadyRunning) {
+		respondWithError(w, http.StatusConflict, "Job is already running", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start job", err)
+		return
+	}
+	respondWithJSON(w, http.StatusAccepted, run)
+}
+
+func (cfg *apiConfig) getJobsHandler(w http.ResponseWriter, r *http.Request) {
+	respondWithList(w, http.StatusOK, cfg.jobs.List(), cfg.wantsEnvelope(r))
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/fkl13/chirpy/internal/auth"
+	"github.com/fkl13/chirpy/internal/database"
+)
+
+// likeChirpHandler likes a chirp for the caller. Liking a chirp twice is
+// not an error and counts once.
+func (cfg *apiConfig) likeChirpHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.setChirpLike(w, r, true)
+}
+
+func (cfg *apiConfig) unlikeChirpHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.setChirpLike(w, r, false)
+}
+
+// setChirpLike adds or removes the caller's like and answers with the chirp
+// and its new like count.
+func (cfg *apiConfig) setChirpLike(w http.ResponseWriter, r *http.Request, liked bool) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	if liked && !cfg.checkActive(w, r, userId) {
+		return
+	}
+
+	chirpId, err := cfg.parseID(r.PathValue("chirpID"))
+	if err != nil {
+		chirpPolicy.notFound(w, err)
+		return
+	}
+	chirp, err := cfg.getChirp(r.Context(), chirpId)
+	if err != nil {
+		chirpPolicy.notFound(w, err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	like := database.LikeChirpParams{UserID: userId, ChirpID: chirpId}
+	var changed int64
+	delta := int32(1)
+	if liked {
+		changed, err = qtx.LikeChirp(r.Context(), like)
+	} else {
+		changed, err = qtx.UnlikeChirp(r.Context(), database.UnlikeChirpParams(like))
+		delta = -1
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save like", err)
+		return
+	}
+	if changed > 0 {
+		chirp, err = qtx.AddChirpLikes(r.Context(), database.AddChirpLikesParams{Delta: delta, ID: chirpId})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't count like", err)
+			return
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save like", err)
+		return
+	}
+	if changed > 0 {
+		cfg.chirpCache.Put(chirp.ID, chirp)
+	}
+
+	respondWithJSON(w, http.StatusOK, cfg.newChirp(r.Context(), chirp))
+}
+
+// getLikedChirpsHandler lists the chirps the caller liked, most recently
+// liked first.
+func (cfg *apiConfig) getLikedChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	chirps, err := cfg.dbQueries.GetLikedChirps(r.Context(), userId)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get liked chirps", err)
+		return
+	}
+	respondWithList(w, http.StatusOK, cfg.newChirps(r.Context(), chirps), cfg.wantsEnvelope(r))
+}
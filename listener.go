@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// systemdListenFDsStart is the first file descriptor systemd passes to a
+// socket-activated service.
+const systemdListenFDsStart = 3
+
+// listen returns the listener to serve on. A socket inherited from a
+// previous process or through systemd socket activation always wins;
+// otherwise addr is either a TCP address like ":8080" or
+// "unix:/path/to/socket".
+func listen(addr string) (net.Listener, error) {
+	ln, err := handedOverListener()
+	if err != nil || ln != nil {
+		return ln, err
+	}
+	ln, err = systemdListener()
+	if err != nil || ln != nil {
+		return ln, err
+	}
+
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		// A socket file left behind by a previous run would make the bind fail.
+		err := os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		return net.Listen("unix", path)
+	}
+	return net.Listen("tcp", addr)
+}
+
+func handedOverListener() (net.Listener, error) {
+	fd, err := strconv.Atoi(os.Getenv(inheritedListenFDEnv))
+	if err != nil {
+		return nil, nil
+	}
+	os.Unsetenv(inheritedListenFDEnv)
+	return fileListener(uintptr(fd))
+}
+
+func systemdListener() (net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	fds, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || fds < 1 {
+		return nil, nil
+	}
+	if fds > 1 {
+		return nil, fmt.Errorf("expected one inherited socket, got %d", fds)
+	}
+
+	// Don't hand the sockets down to child processes a second time.
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	return fileListener(systemdListenFDsStart)
+}
+
+func fileListener(fd uintptr) (net.Listener, error) {
+	f := os.NewFile(fd, "inherited-listener")
+	defer f.Close()
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't use inherited socket: %w", err)
+	}
+	return ln, nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type loadtestStats struct {
+	mu        sync.Mutex
+	latencies map[string][]time.Duration
+	failures  map[string]int
+}
+
+func (s *loadtestStats) record(op string, d time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.failures[op]++
+		return
+	}
+	s.latencies[op] = append(s.latencies[op], d)
+}
+
+func (s *loadtestStats) report(w io.Writer, elapsed time.Duration) {
+	fmt.Fprintf(w, "%-10s %8s %8s %10s %10s %10s\n", "op", "ok", "failed", "p50", "p95", "p99")
+	ops := []string{"signup", "login", "chirp", "timeline"}
+	for _, op := range ops {
+		l := s.latencies[op]
+		slices.Sort(l)
+		fmt.Fprintf(w, "%-10s %8d %8d %10s %10s %10s\n", op, len(l), s.failures[op],
+			percentile(l, 0.50), percentile(l, 0.95), percentile(l, 0.99))
+	}
+	total := 0
+	for _, l := range s.latencies {
+		total += len(l)
+	}
+	fmt.Fprintf(w, "%d requests in %s (%.1f req/s)\n", total, elapsed.Round(time.Millisecond), float64(total)/elapsed.Seconds())
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(float64(len(sorted)-1) * p)
+	return sorted[i].Round(time.Microsecond)
+}
+
+type loadtestClient struct {
+	baseURL string
+	client  *http.Client
+	stats   *loadtestStats
+}
+
+func (c *loadtestClient) do(op, method, path, token string, body, out any) error {
+	var payload io.Reader
+	if body != nil {
+		dat, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(dat)
+	}
+	req, err := http.NewRequest(method, c.baseURL+path, payload)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	if err == nil {
+		defer resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			err = fmt.Errorf("%s %s: %s", method, path, resp.Status)
+		} else if out != nil {
+			err = json.NewDecoder(resp.Body).Decode(out)
+		} else {
+			_, err = io.Copy(io.Discard, resp.Body)
+		}
+	}
+	c.stats.record(op, time.Since(start), err)
+	return err
+}
+
+// runUser plays one user: sign up, log in, then alternate between posting
+// chirps and reading the timeline until the deadline.
+func (c *loadtestClient) runUser(deadline time.Time) {
+	email := fmt.Sprintf("loadtest-%s@chirpy.example", uuid.NewString())
+	password := "loadtest-" + uuid.NewString()
+	credentials := map[string]string{"email": email, "password": password}
+
+	err := c.do("signup", http.MethodPost, "/api/v1/users", "", credentials, nil)
+	if err != nil {
+		return
+	}
+	var login struct {
+		Token string `json:"token"`
+	}
+	err = c.do("login", http.MethodPost, "/api/v1/login", "", credentials, &login)
+	if err != nil {
+		return
+	}
+
+	for i := 0; time.Now().Before(deadline); i++ {
+		body := map[string]string{"body": fmt.Sprintf("Load test chirp number %d", i)}
+		c.do("chirp", http.MethodPost, "/api/v1/chirps", login.Token, body, nil)
+		c.do("timeline", http.MethodGet, "/api/v1/chirps?sort=desc", login.Token, nil, nil)
+	}
+}
+
+// runLoadtest exercises the signup, login, chirp and timeline flows against
+// a running instance and prints latency percentiles. It returns the process
+// exit code.
+func runLoadtest(args []string, out io.Writer) int {
+	flags := flag.NewFlagSet("loadtest", flag.ContinueOnError)
+	flags.SetOutput(out)
+	baseURL := flags.String("url", "http://localhost:8080", "base URL of the chirpy instance")
+	users := flags.Int("users", 10, "number of concurrent simulated users")
+	duration := flags.Duration("duration", 30*time.Second, "how long each user keeps sending requests")
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	client := &loadtestClient{
+		baseURL: *baseURL,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		stats: &loadtestStats{
+			latencies: map[string][]time.Duration{},
+			failures:  map[string]int{},
+		},
+	}
+
+	start := time.Now()
+	deadline := start.Add(*duration)
+	var wg sync.WaitGroup
+	for range *users {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.runUser(deadline)
+		}()
+	}
+	wg.Wait()
+
+	client.stats.report(out, time.Since(start))
+	for _, failures := range client.stats.failures {
+		if failures > 0 {
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/fkl13/chirpy/internal/auth"
+	"github.com/fkl13/chirpy/internal/database"
+	"github.com/fkl13/chirpy/internal/validate"
+)
+
+const (
+	// coarseLocationScale rounds coordinates to 0.1 degrees, roughly 10km,
+	// unless the author opted in to precise locations.
+	coarseLocationScale = 10
+	maxPlaceNameLength  = 100
+	earthRadiusKm       = 6371
+)
+
+// ChirpLocation is where a chirp was posted from. Coordinates are coarse
+// unless the author opted in to precise locations.
+type ChirpLocation struct {
+	PlaceName string  `json:"place_name,omitempty"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+func newChirpLocation(chirp database.Chirp) *ChirpLocation {
+	if !chirp.Latitude.Valid || !chirp.Longitude.Valid {
+		return nil
+	}
+	return &ChirpLocation{
+		PlaceName: chirp.PlaceName.String,
+		Latitude:  chirp.Latitude.Float64,
+		Longitude: chirp.Longitude.Float64,
+	}
+}
+
+func roundCoordinate(v float64) float64 {
+	return math.Round(v*coarseLocationScale) / coarseLocationScale
+}
+
+// locationParams checks a location sent with a new chirp and turns it into
+// columns, rounding the coordinates unless precise is set.
+func locationParams(loc ChirpLocation, precise bool) (sql.NullString, sql.NullFloat64, sql.NullFloat64, error) {
+	v := validate.Validator{}
+	if loc.Latitude < -90 || loc.Latitude > 90 {
+		v.Add("location.latitude", validate.CodeInvalid, "must be between -90 and 90")
+	}
+	if loc.Longitude < -180 || loc.Longitude > 180 {
+		v.Add("location.longitude", validate.CodeInvalid, "must be between -180 and 180")
+	}
+	loc.PlaceName = strings.TrimSpace(loc.PlaceName)
+	v.MaxLength("location.place_name", loc.PlaceName, maxPlaceNameLength)
+	if err := v.Err(); err != nil {
+		return sql.NullString{}, sql.NullFloat64{}, sql.NullFloat64{}, err
+	}
+
+	if !precise {
+		loc.Latitude = roundCoordinate(loc.Latitude)
+		loc.Longitude = roundCoordinate(loc.Longitude)
+	}
+	return sql.NullString{String: loc.PlaceName, Valid: loc.PlaceName != ""},
+		sql.NullFloat64{Float64: loc.Latitude, Valid: true},
+		sql.NullFloat64{Float64: loc.Longitude, Valid: true},
+		nil
+}
+
+// distanceKm is the great-circle distance between two points.
+func distanceKm(lat1, lng1, lat2, lng2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// parseNear reads a "lat,lng" query parameter.
+func parseNear(s string) (lat, lng float64, err error) {
+	latParam, lngParam, ok := strings.Cut(s, ",")
+	if !ok {
+		return 0, 0, errors.New("near must be lat,lng")
+	}
+	lat, err = strconv.ParseFloat(strings.TrimSpace(latParam), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return 0, 0, fmt.Errorf("invalid latitude %q", latParam)
+	}
+	lng, err = strconv.ParseFloat(strings.TrimSpace(lngParam), 64)
+	if err != nil || lng < -180 || lng > 180 {
+		return 0, 0, fmt.Errorf("invalid longitude %q", lngParam)
+	}
+	return lat, lng, nil
+}
+
+type locationSettings struct {
+	Enabled bool `json:"enabled"`
+	Precise bool `json:"precise"`
+}
+
+func (cfg *apiConfig) getLocationSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	user, err := cfg.getUser(r.Context(), userId)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, locationSettings{
+		Enabled: user.LocationEnabled,
+		Precise: user.PreciseLocation,
+	})
+}
+
+// setLocationSettingsHandler turns location tagging on or off. Location
+// tagging is off for new accounts, and precise coordinates are only kept
+// when tagging is on as well.
+func (cfg *apiConfig) setLocationSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	params := locationSettings{}
+	err = decodeJSONBody(w, r, &params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	user, err := cfg.dbQueries.SetLocationSettings(r.Context(), database.SetLocationSettingsParams{
+		ID:              userId,
+		LocationEnabled: params.Enabled,
+		PreciseLocation: params.Enabled && params.Precise,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save location settings", err)
+		return
+	}
+	cfg.userCache.Put(user.ID, user)
+
+	respondWithJSON(w, http.StatusOK, locationSettings{
+		Enabled: user.LocationEnabled,
+		Precise: user.PreciseLocation,
+	})
+}
+
+// getPlacesHandler suggests place names chirps were tagged with, most used
+// first, for ?q= as a prefix. Coordinates are averaged and always coarse.
+func (cfg *apiConfig) getPlacesHandler(w http.ResponseWriter, r *http.Request) {
+	const maxPlaces = 20
+
+	type place struct {
+		Name      string  `json:"name"`
+		Latitude  float64 `json:"latitude"`
+		Longitude float64 `json:"longitude"`
+		Chirps    int64   `json:"chirps"`
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		respondWithError(w, http.StatusBadRequest, "Missing place query", nil)
+		return
+	}
+	escaper := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+	places, err := cfg.dbQueries.GetPlaces(r.Context(), database.GetPlacesParams{
+		Prefix:     escaper.Replace(query),
+		MaxResults: maxPlaces,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get places", err)
+		return
+	}
+	res := make([]place, 0, len(places))
+	for _, p := range places {
+		res = append(res, place{
+			Name:      p.PlaceName,
+			Latitude:  p.Latitude,
+			Longitude: p.Longitude,
+			Chirps:    p.Chirps,
+		})
+	}
+	respondWithList(w, http.StatusOK, res, cfg.wantsEnvelope(r))
+}
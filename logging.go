@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+func newLogger(w io.Writer, format string, level *slog.LevelVar) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+	if format == "json" {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// setLogLevelHandler changes the log level of the running server until the
+// next restart or settings reload.
+func (cfg *apiConfig) setLogLevelHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Level string `json:"level"`
+	}
+	type response struct {
+		Level string `json:"level"`
+	}
+
+	params := parameters{}
+	err := decodeJSONBody(w, r, &params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	var level slog.Level
+	err = level.UnmarshalText([]byte(params.Level))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid log level", err)
+		return
+	}
+
+	cfg.logLevel.Set(level)
+	slog.Info("Changed log level", "level", level)
+	respondWithJSON(w, http.StatusOK, response{
+		Level: level.String(),
+	})
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/auth"
+	"github.com/fkl13/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type LoginEvent struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	IPAddress  string     `json:"ip_address"`
+	UserAgent  string     `json:"user_agent"`
+	Country    string     `json:"country,omitempty"`
+	ReportedAt *time.Time `json:"reported_at,omitempty"`
+}
+
+// clientIP returns the address of the client. Behind a proxy that's the one
+// in CLIENT_IP_HEADER; for X-Forwarded-For that's the last entry, which the
+// proxy added itself, since clients can send the header with any value.
+func (cfg *apiConfig) clientIP(r *http.Request) string {
+	if cfg.clientIPHeader != "" {
+		values := strings.Split(strings.Join(r.Header.Values(cfg.clientIPHeader), ","), ",")
+		ip := strings.TrimSpace(values[len(values)-1])
+		if net.ParseIP(ip) != nil {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// recordLogin stores where a successful login came from and mails the user
+// when it's from a device or country we haven't seen for them before.
+// Failures are only logged, they must never block the login itself.
+func (cfg *apiConfig) recordLogin(r *http.Request, user database.User) {
+	country := sql.NullString{}
+	if cfg.countryHeader != "" {
+		code := r.Header.Get(cfg.countryHeader)
+		country = sql.NullString{String: code, Valid: code != ""}
+	}
+	userAgent := r.UserAgent()
+
+	history, err := cfg.dbQueries.GetLoginHistorySummary(r.Context(), database.GetLoginHistorySummaryParams{
+		UserID:    user.ID,
+		UserAgent: userAgent,
+		Country:   country,
+	})
+	if err != nil {
+		log.Printf("Couldn't get login history: %v", err)
+		return
+	}
+
+	event, err := cfg.dbQueries.CreateLoginEvent(r.Context(), database.CreateLoginEventParams{
+		UserID:    user.ID,
+		IpAddress: cfg.clientIP(r),
+		UserAgent: userAgent,
+		Country:   country,
+	})
+	if err != nil {
+		log.Printf("Couldn't record login: %v", err)
+		return
+	}
+
+	if history.Logins == 0 {
+		return
+	}
+	newDevice := history.SameDevice == 0
+	newCountry := country.Valid && history.SameCountry == 0
+	if !newDevice && !newCountry {
+		return
+	}
+
+	msg, err := cfg.mailTemplates.Render("new_login", user.Email, map[string]any{
+		"Time":      event.CreatedAt.Format(time.RFC1123),
+		"IPAddress": event.IpAddress,
+		"UserAgent": event.UserAgent,
+		"Country":   event.Country.String,
+	})
+	if err != nil {
+		log.Printf("Couldn't render login alert: %v", err)
+		return
+	}
+	go func() {
+		err := cfg.mailer.Send(context.Background(), msg)
+		if err != nil {
+			log.Printf("Couldn't send login alert: %v", err)
+		}
+	}()
+}
+
+func newLoginEvent(event database.LoginEvent) LoginEvent {
+	e := LoginEvent{
+		ID:        event.ID,
+		CreatedAt: event.CreatedAt,
+		IPAddress: event.IpAddress,
+		UserAgent: event.UserAgent,
+		Country:   event.Country.String,
+	}
+	if event.ReportedAt.Valid {
+		e.ReportedAt = &event.ReportedAt.Time
+	}
+	return e
+}
+
+func (cfg *apiConfig) getLoginEventsHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	events, err := cfg.dbQueries.GetRecentLoginEvents(r.Context(), userId)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get logins", err)
+		return
+	}
+
+	payload := []LoginEvent{}
+	for _, event := range events {
+		payload = append(payload, newLoginEvent(event))
+	}
+	respondWithList(w, http.StatusOK, payload, cfg.wantsEnvelope(r))
+}
+
+// reportLoginEventHandler is the "this wasn't me" action: it flags the login
+// and ends every session of the account, access tokens included.
+func (cfg *apiConfig) reportLoginEventHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	loginId, err := uuid.Parse(r.PathValue("loginID"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Login not found", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't report login", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	_, err = qtx.ReportLoginEvent(r.Context(), database.ReportLoginEventParams{
+		ID:     loginId,
+		UserID: userId,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "Login not found", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't report login", err)
+		return
+	}
+
+	err = endSessions(r.Context(), qtx, userId)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke sessions", err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't report login", err)
+		return
+	}
+	cfg.userCache.Invalidate(userId)
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
@@ -1,103 +1,325 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
+	"unicode"
 
 	"github.com/fkl13/chirpy/internal/auth"
+	"github.com/fkl13/chirpy/internal/breaker"
+	"github.com/fkl13/chirpy/internal/cache"
+	"github.com/fkl13/chirpy/internal/captcha"
+	"github.com/fkl13/chirpy/internal/config"
 	"github.com/fkl13/chirpy/internal/database"
+	"github.com/fkl13/chirpy/internal/errreport"
+	"github.com/fkl13/chirpy/internal/eventbus"
+	"github.com/fkl13/chirpy/internal/jobs"
+	"github.com/fkl13/chirpy/internal/mail"
+	"github.com/fkl13/chirpy/internal/markdown"
+	"github.com/fkl13/chirpy/internal/publicid"
+	"github.com/fkl13/chirpy/internal/pubsub"
+	"github.com/fkl13/chirpy/internal/ratelimit"
+	"github.com/fkl13/chirpy/internal/search"
+	"github.com/fkl13/chirpy/internal/validate"
+	"github.com/fkl13/chirpy/internal/webauthn"
+	"github.com/fkl13/chirpy/internal/wordfilter"
 	"github.com/google/uuid"
-	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
 type apiConfig struct {
+	db             *sql.DB
 	dbQueries      *database.Queries
 	platform       string
 	jwtSecret      string
 	polkaKey       string
+	adminKeys      map[string]string
+	countryHeader  string
+	clientIPHeader string
 	fileserverHits atomic.Int32
+
+	envelopeResponses  bool
+	stripEmailPlusTags bool
+
+	chirpHub   *pubsub.Hub[Chirp]
+	outboxWake chan struct{}
+
+	jobs           *jobs.Runner
+	apiUsage       *usageCounter
+	webhookMetrics *webhookMetrics
+	rolloutMetrics *rolloutMetrics
+
+	// errorReporter is nil unless SENTRY_DSN or ERROR_WEBHOOK_URL is set.
+	errorReporter *errreport.Reporter
+
+	// search is nil when SEARCH_BACKEND is off.
+	search search.Backend
+
+	// eventBus is nil unless EVENT_BUS_URL is set.
+	eventBus           eventbus.Bus
+	eventSubjectPrefix string
+
+	mailer        mail.Sender
+	mailTemplates *mail.Renderer
+
+	runtimeConfigFile string
+	runtime           atomic.Pointer[runtimeSettings]
+	logLevel          *slog.LevelVar
+	// reloadMu serializes settings reloads and allowlist changes so
+	// neither overwrites the other's result.
+	reloadMu sync.Mutex
+
+	chirpCache *cache.Cache[uuid.UUID, database.Chirp]
+	userCache  *cache.Cache[uuid.UUID, database.User]
+
+	// rateLimit is the rate limit from the environment, which the runtime
+	// settings can replace.
+	rateLimit config.RateLimit
+
+	breakerThreshold int
+	breakerCooldown  time.Duration
+	routeBreakers    map[string]*breaker.Breaker
+
+	deactivationGracePeriod time.Duration
+
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+
+	// publicIDs is nil unless PUBLIC_ID_KEY is set.
+	publicIDs publicid.Codec
+
+	securityContacts  []string
+	securityPolicyURL string
+
+	// captcha is nil unless CAPTCHA_SECRET is set, which leaves abuse
+	// reporting off.
+	captcha      *captcha.Verifier
+	abuseLimiter *ratelimit.Limiter
+	// anonymousAbuseLimiter is stricter, so reporters can't sign out to
+	// get around their reputation.
+	anonymousAbuseLimiter *ratelimit.Limiter
+
+	recorder *recorder
+
+	// analytics is nil when ANALYTICS_RETENTION is 0.
+	analytics *analyticsCounter
+
+	// passkeys is nil unless WEBAUTHN_RP_ID is set.
+	passkeys *webauthn.RelyingParty
+
+	// publicURL is empty unless PUBLIC_URL is set, which leaves email
+	// verification off and share links redirecting within this host.
+	publicURL           string
+	verificationLimiter *ratelimit.Limiter
 }
 
+const filepathRoot = "."
+
 func main() {
-	const port = "8080"
-	const filepathRoot = "."
+	envFile := flag.String("env-file", "", "dotenv file to load (default "+config.DefaultEnvFile+" if present)")
+	flag.Parse()
+	args := flag.Args()
 
-	err := godotenv.Load()
+	if len(args) > 0 && args[0] == "loadtest" {
+		os.Exit(runLoadtest(args[1:], os.Stdout))
+	}
+
+	envPath := *envFile
+	if envPath == "" {
+		envPath = config.DefaultEnvFile
+	}
+	loaded, err := config.LoadEnvFile(envPath, *envFile != "")
 	if err != nil {
-		log.Fatalf("couldn't load .env: %v", err)
+		log.Fatalf("couldn't load %s: %v", envPath, err)
+	}
+	if !loaded {
+		slog.Info("No env file found, using the environment only", "path", envPath)
+	}
+
+	if len(args) > 0 && args[0] == "check" {
+		os.Exit(runCheck(os.Stdout))
+	}
+	if len(args) > 0 && args[0] == "reindex" {
+		os.Exit(runReindex(os.Stdout))
 	}
 
-	dbURL := os.Getenv("DB_URL")
-	if dbURL == "" {
-		log.Fatal("DB_URL must be set")
+	config, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(config.LogLevel)
+	slog.SetDefault(newLogger(os.Stderr, config.LogFormat, logLevel))
+	if len(config.AdminKeys) == 0 {
+		log.Println("Neither ADMIN_KEY nor ADMIN_KEYS is set, admin endpoints are disabled")
 	}
 
-	dbConn, err := sql.Open("postgres", dbURL)
+	dbConn, err := sql.Open("postgres", config.DBURL)
 	if err != nil {
 		log.Fatalf("couldn't open db: %v", err)
 	}
 	defer dbConn.Close()
 
-	platform := os.Getenv("PLATFORM")
-	if platform == "" {
-		log.Fatal("PLATFORM must be set")
+	mailer, err := mail.New(config.Mail)
+	if err != nil {
+		log.Fatalf("couldn't set up mail: %v", err)
+	}
+	mailTemplates, err := mail.NewRenderer(config.MailTemplateDir)
+	if err != nil {
+		log.Fatalf("couldn't load mail templates: %v", err)
 	}
 
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		log.Fatal("JWT_SECRET environment variable is not set")
+	var publicIDs publicid.Codec
+	if config.PublicIDKey != "" {
+		publicIDs, err = publicid.New(config.PublicIDKey)
+		if err != nil {
+			log.Fatalf("couldn't set up public ids: %v", err)
+		}
 	}
 
-	polkaKey := os.Getenv("POLKA_KEY")
-	if polkaKey == "" {
-		log.Fatal("POLKA_KEY environment variable is not set")
+	errorReporter, err := errreport.New(config.ErrorReporting)
+	if err != nil {
+		log.Fatalf("couldn't set up error reporting: %v", err)
+	}
+
+	var eventBus eventbus.Bus
+	if config.EventBusURL != "" {
+		eventBus, err = eventbus.New(config.EventBusURL)
+		if err != nil {
+			log.Fatalf("couldn't set up event bus: %v", err)
+		}
+		defer eventBus.Close()
 	}
 
 	dbQueries := database.New(dbConn)
+	searchBackend, err := search.New(config.Search, dbQueries)
+	if err != nil {
+		log.Fatalf("couldn't set up search: %v", err)
+	}
+
 	apiConfig := apiConfig{
-		dbQueries:      dbQueries,
-		fileserverHits: atomic.Int32{},
-		platform:       platform,
-		jwtSecret:      jwtSecret,
-		polkaKey:       polkaKey,
+		db:                      dbConn,
+		dbQueries:               dbQueries,
+		fileserverHits:          atomic.Int32{},
+		platform:                config.Platform,
+		jwtSecret:               config.JWTSecret,
+		polkaKey:                config.PolkaKey,
+		adminKeys:               config.AdminKeys,
+		countryHeader:           config.CountryHeader,
+		clientIPHeader:          config.ClientIPHeader,
+		envelopeResponses:       config.EnvelopeResponses,
+		stripEmailPlusTags:      config.StripEmailPlusTags,
+		chirpHub:                pubsub.NewHub[Chirp](),
+		outboxWake:              make(chan struct{}, 1),
+		eventBus:                eventBus,
+		eventSubjectPrefix:      config.EventSubjectPrefix,
+		search:                  searchBackend,
+		errorReporter:           errorReporter,
+		jobs:                    jobs.NewRunner(50, time.Hour),
+		apiUsage:                newUsageCounter(),
+		analytics:               newAnalyticsCounter(config.JWTSecret, config.AnalyticsRetention),
+		webhookMetrics:          newWebhookMetrics(),
+		rolloutMetrics:          newRolloutMetrics(),
+		mailer:                  mailer,
+		mailTemplates:           mailTemplates,
+		runtimeConfigFile:       config.RuntimeConfigFile,
+		logLevel:                logLevel,
+		chirpCache:              cache.New[uuid.UUID, database.Chirp](config.CacheSize, config.CacheTTL),
+		userCache:               cache.New[uuid.UUID, database.User](config.CacheSize, config.CacheTTL),
+		rateLimit:               config.DefaultRateLimit(),
+		deactivationGracePeriod: config.DeactivationGracePeriod,
+		accessTokenTTL:          config.AccessTokenTTL,
+		refreshTokenTTL:         config.RefreshTokenTTL,
+		breakerThreshold:        config.BreakerThreshold,
+		breakerCooldown:         config.BreakerCooldown,
+		publicIDs:               publicIDs,
+		securityContacts:        splitList(config.SecurityContact),
+		securityPolicyURL:       config.SecurityPolicyURL,
+		captcha:                 captcha.New(config.CaptchaVerifyURL, config.CaptchaSecret),
+		abuseLimiter:            ratelimit.New(config.AbuseReportLimit, time.Hour),
+		anonymousAbuseLimiter:   ratelimit.New(config.AnonymousAbuseReportLimit, time.Hour),
+		recorder:                newRecorder(config.RecordingDir),
+		passkeys:                newRelyingParty(config.WebAuthnRPID, config.WebAuthnOrigins),
+		publicURL:               config.PublicURL,
+		verificationLimiter:     ratelimit.New(verificationEmailLimit, time.Hour),
+	}
+	err = apiConfig.reloadSettings()
+	if err != nil {
+		log.Fatal(err)
 	}
+	apiConfig.watchReloadSignal()
+	go apiConfig.purgeDeactivatedUsers(time.Hour)
+	go apiConfig.publishScheduledChirps(10 * time.Second)
+	go apiConfig.relayOutbox(time.Second)
+	if apiConfig.eventBus != nil {
+		go apiConfig.announceChirpsFromBus(5 * time.Second)
+	}
+	apiConfig.registerJobs()
+	go apiConfig.flushAPIUsageEvery(30 * time.Second)
+	go apiConfig.flushAnalyticsEvery(30 * time.Second)
 
 	mux := http.NewServeMux()
 
 	mux.Handle("/app/", apiConfig.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(filepathRoot)))))
-	mux.Handle("GET /api/healthz", http.HandlerFunc(healthzHandler))
-	mux.HandleFunc("POST /api/users", apiConfig.createUserHandler)
-	mux.HandleFunc("PUT /api/users", apiConfig.updateUserHandler)
-
-	mux.HandleFunc("POST /api/login", apiConfig.loginHandler)
-	mux.HandleFunc("POST /api/refresh", apiConfig.refreshHandler)
-	mux.HandleFunc("POST /api/revoke", apiConfig.revokeHandler)
+	apiConfig.registerAPIRoutes(mux, "/api/v1", nil)
+	apiConfig.registerAPIRoutes(mux, "/api", deprecatedAlias("/api", "/api/v1"))
 
-	mux.HandleFunc("POST /api/chirps", apiConfig.createChirpHandler)
-	mux.HandleFunc("GET /api/chirps", apiConfig.getAllChirpsHandler)
-	mux.HandleFunc("GET /api/chirps/{chirpID}", apiConfig.getChirpHandler)
-	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiConfig.deleteChirpHandler)
-
-	mux.HandleFunc("POST /api/polka/webhooks", apiConfig.addUserSubscribtionHandler)
+	mux.HandleFunc("GET /s/{code}", apiConfig.shareRedirectHandler)
+	mux.HandleFunc("GET /embed.js", apiConfig.embedScriptHandler)
+	mux.HandleFunc("GET /embed/chirp/{chirpID}", apiConfig.embedChirpPageHandler)
+	mux.HandleFunc("GET /.well-known/security.txt", apiConfig.securityTxtHandler)
 
 	mux.Handle("GET /admin/metrics", http.HandlerFunc(apiConfig.getMetricHandler))
 	mux.Handle("POST /admin/reset", http.HandlerFunc(apiConfig.resetMetricHandler))
+	mux.Handle("POST /admin/loglevel", apiConfig.middlewareAdminOnly(http.HandlerFunc(apiConfig.setLogLevelHandler)))
+	mux.Handle("GET /admin/mail/{template}/preview", apiConfig.middlewareAdminOnly(http.HandlerFunc(apiConfig.previewMailHandler)))
+	mux.Handle("POST /admin/guest-tokens", apiConfig.middlewareAdminOnly(http.HandlerFunc(apiConfig.createGuestTokenHandler)))
+	mux.Handle("PATCH /admin/users/{userID}/membership", apiConfig.middlewareAdminOnly(http.HandlerFunc(apiConfig.setUserMembershipHandler)))
+	mux.Handle("POST /admin/users/{userID}/merge", apiConfig.middlewareAdminOnly(http.HandlerFunc(apiConfig.mergeUsersHandler)))
+	mux.Handle("POST /admin/chirps/{chirpID}/restore", apiConfig.middlewareAdminOnly(http.HandlerFunc(apiConfig.restoreChirpHandler)))
+	mux.Handle("GET /admin/search", apiConfig.middlewareAdminOnly(http.HandlerFunc(apiConfig.adminSearchHandler)))
+	mux.Handle("GET /admin/audit-log", apiConfig.middlewareAdminOnly(http.HandlerFunc(apiConfig.getAuditLogHandler)))
+	mux.Handle("PUT /admin/recording", apiConfig.middlewareAdminOnly(http.HandlerFunc(apiConfig.setRecordingHandler)))
+	mux.Handle("GET /admin/recordings", apiConfig.middlewareAdminOnly(http.HandlerFunc(apiConfig.getRecordingsHandler)))
+	mux.Handle("DELETE /admin/recordings", apiConfig.middlewareAdminOnly(http.HandlerFunc(apiConfig.clearRecordingsHandler)))
+	mux.Handle("GET /admin/allowed-words", apiConfig.middlewareAdminOnly(http.HandlerFunc(apiConfig.getAllowedWordsHandler)))
+	mux.Handle("PUT /admin/allowed-words/{word}", apiConfig.middlewareAdminOnly(http.HandlerFunc(apiConfig.addAllowedWordHandler)))
+	mux.Handle("DELETE /admin/allowed-words/{word}", apiConfig.middlewareAdminOnly(http.HandlerFunc(apiConfig.deleteAllowedWordHandler)))
+	mux.Handle("GET /admin/stats", apiConfig.middlewareAdminOnly(http.HandlerFunc(apiConfig.getStatsHandler)))
+	mux.Handle("GET /admin/usage", apiConfig.middlewareAdminOnly(http.HandlerFunc(apiConfig.getUsageStatsHandler)))
+	mux.Handle("GET /admin/abuse-reports", apiConfig.middlewareAdminOnly(http.HandlerFunc(apiConfig.getAbuseReportsHandler)))
+	mux.Handle("POST /admin/abuse-reports/{reportID}/resolve", apiConfig.middlewareAdminOnly(http.HandlerFunc(apiConfig.resolveAbuseReportHandler)))
+	mux.Handle("GET /admin/jobs", apiConfig.middlewareAdminOnly(http.HandlerFunc(apiConfig.getJobsHandler)))
+	mux.Handle("POST /admin/jobs/{name}", apiConfig.middlewareAdminOnly(http.HandlerFunc(apiConfig.startJobHandler)))
 
 	srv := &http.Server{
-		Addr:    ":" + port,
-		Handler: mux,
+		Handler: apiConfig.middlewareRequestID(middlewareRecover(apiConfig.middlewareRecord(apiConfig.middlewareRateLimit(mux)))),
+	}
+
+	ln, err := listen(config.ListenAddr)
+	if err != nil {
+		log.Fatalf("couldn't listen: %v", err)
 	}
 
-	log.Printf("Serving on port: %s\n", port)
-	log.Fatal(srv.ListenAndServe())
+	apiConfig.logStartup(config, ln.Addr())
+	err = serve(srv, ln)
+	apiConfig.flushAPIUsage(context.Background())
+	apiConfig.flushAnalytics(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
 }
 
 func healthzHandler(w http.ResponseWriter, r *http.Request) {
@@ -118,13 +340,34 @@ func (cfg *apiConfig) getMetricHandler(w http.ResponseWriter, r *http.Request) {
 <body>
     <h1>Welcome, Chirpy Admin</h1>
     <p>Chirpy has been visited %d times!</p>
+    <p>%d chirps have been shared, and share links were followed %d times.</p>
+    <h2>Caches</h2>
+    <table>
+        <tr><th>Cache</th><th>Entries</th><th>Hits</th><th>Misses</th><th>Hit rate</th></tr>
+        <tr><td>Chirps</td><td>%d</td><td>%d</td><td>%d</td><td>%.1f%%</td></tr>
+        <tr><td>Users</td><td>%d</td><td>%d</td><td>%d</td><td>%.1f%%</td></tr>
+    </table>
 </body>
 </html>
 `
 
+	shares, err := cfg.dbQueries.GetChirpShareStats(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get share stats", err)
+		return
+	}
+
+	chirpStats := cfg.chirpCache.Stats()
+	userStats := cfg.userCache.Stats()
+
 	w.Header().Add("Content-Type", "text/html")
 	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, template, cfg.fileserverHits.Load())
+	fmt.Fprintf(w, template,
+		cfg.fileserverHits.Load(),
+		shares.SharedChirps, shares.Redirects,
+		chirpStats.Entries, chirpStats.Hits, chirpStats.Misses, chirpStats.HitRate()*100,
+		userStats.Entries, userStats.Hits, userStats.Misses, userStats.HitRate()*100,
+	)
 }
 
 func (cfg *apiConfig) resetMetricHandler(w http.ResponseWriter, r *http.Request) {
@@ -138,21 +381,83 @@ func (cfg *apiConfig) resetMetricHandler(w http.ResponseWriter, r *http.Request)
 		respondWithError(w, http.StatusInternalServerError, "couldn't delete users", err)
 		return
 	}
+	cfg.userCache.Clear()
+	cfg.chirpCache.Clear()
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Hits reset to 0"))
 }
 
 type Chirp struct {
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	ID        uuid.UUID `json:"id"`
-	UserId    uuid.UUID `json:"user_id"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	Body           string         `json:"body"`
+	NormalizedBody string         `json:"normalized_body"`
+	BodyHTML       string         `json:"body_html"`
+	ID             string         `json:"id"`
+	PublicID       string         `json:"public_id,omitempty"`
+	UserId         string         `json:"user_id"`
+	AuthorHandle   string         `json:"author_handle"`
+	ParentChirpID  *string        `json:"parent_chirp_id,omitempty"`
+	ReplyCount     int32          `json:"reply_count"`
+	LikesCount     int32          `json:"likes_count"`
+	RechirpCount   int32          `json:"rechirp_count"`
+	Location       *ChirpLocation `json:"location,omitempty"`
+	Status         string         `json:"status"`
+	PublishAt      *time.Time     `json:"publish_at,omitempty"`
+}
+
+// newChirp shapes a single chirp for a response. Lists go through
+// newChirps, which looks the authors up together.
+func (cfg *apiConfig) newChirp(ctx context.Context, chirp database.Chirp) Chirp {
+	return cfg.chirpWithHandle(chirp, cfg.authorHandle(ctx, chirp.UserID))
+}
+
+// newChirps shapes a list of chirps, reading their authors' handles in one
+// query.
+func (cfg *apiConfig) newChirps(ctx context.Context, chirps []database.Chirp) []Chirp {
+	handles := cfg.authorHandles(ctx, chirps)
+	payload := make([]Chirp, 0, len(chirps))
+	for _, chirp := range chirps {
+		payload = append(payload, cfg.chirpWithHandle(chirp, handles[chirp.UserID]))
+	}
+	return payload
+}
+
+func (cfg *apiConfig) chirpWithHandle(chirp database.Chirp, authorHandle string) Chirp {
+	normalized := normalizeBody(chirp.Body, cfg.normalization())
+	c := Chirp{
+		ID:             cfg.exposedID(chirp.ID),
+		PublicID:       cfg.publicID(chirp.ID),
+		CreatedAt:      chirp.CreatedAt,
+		UpdatedAt:      chirp.UpdatedAt,
+		Body:           chirp.Body,
+		NormalizedBody: normalized,
+		BodyHTML:       markdown.Render(normalized),
+		UserId:         cfg.exposedID(chirp.UserID),
+		AuthorHandle:   authorHandle,
+	}
+	c.ReplyCount = chirp.ReplyCount
+	c.LikesCount = chirp.LikesCount
+	c.RechirpCount = chirp.RechirpCount
+	c.Location = newChirpLocation(chirp)
+	c.Status = chirp.Status
+	if chirp.PublishAt.Valid {
+		c.PublishAt = &chirp.PublishAt.Time
+	}
+	if chirp.ParentChirpID.Valid {
+		parentID := cfg.exposedID(chirp.ParentChirpID.UUID)
+		c.ParentChirpID = &parentID
+	}
+	return c
 }
 
 func (cfg *apiConfig) createChirpHandler(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
-		Body string `json:"body"`
+		Body          string         `json:"body"`
+		ParentChirpID string         `json:"parent_chirp_id"`
+		Location      *ChirpLocation `json:"location"`
+		Status        string         `json:"status"`
+		PublishAt     *time.Time     `json:"publish_at"`
 	}
 
 	token, err := auth.GetBearerToken(r.Header)
@@ -166,87 +471,220 @@ func (cfg *apiConfig) createChirpHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
-	err = decoder.Decode(&params)
+	err = decodeJSONBody(w, r, &params)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
 		return
 	}
 
-	cleaned, err := validateChirp(params.Body)
+	cleaned, err := validateChirp(params.Body, cfg.settings().badWords)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error(), err)
+		respondWithValidationError(w, err)
 		return
 	}
+	status, publishAt, err := chirpStatus(params.Status, params.PublishAt, time.Now())
+	if err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+	parent := uuid.NullUUID{}
+	if params.ParentChirpID != "" {
+		var parentChirp database.Chirp
+		parentId, err := cfg.parseID(params.ParentChirpID)
+		if err == nil {
+			parentChirp, err = cfg.getChirp(r.Context(), parentId)
+		}
+		if err != nil {
+			v := validate.Validator{}
+			v.Add("parent_chirp_id", validate.CodeInvalid, "must be an existing chirp")
+			respondWithValidationError(w, v.Err())
+			return
+		}
+		blocked, err := cfg.dbQueries.IsBlocked(r.Context(), database.IsBlockedParams{
+			BlockerID: parentChirp.UserID,
+			BlockedID: userId,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't check blocks", err)
+			return
+		}
+		if blocked {
+			chirpPolicy.deny(w, "You can't reply to this user", nil)
+			return
+		}
+		parent = uuid.NullUUID{UUID: parentId, Valid: true}
+	}
+	var placeName sql.NullString
+	var latitude, longitude sql.NullFloat64
+	if params.Location != nil {
+		user, err := cfg.getUser(r.Context(), userId)
+		if err != nil {
+			respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+			return
+		}
+		if !user.LocationEnabled {
+			v := validate.Validator{}
+			v.Add("location", validate.CodeInvalid, "location tagging is turned off for this account")
+			respondWithValidationError(w, v.Err())
+			return
+		}
+		placeName, latitude, longitude, err = locationParams(*params.Location, user.PreciseLocation)
+		if err != nil {
+			respondWithValidationError(w, err)
+			return
+		}
+	}
 
-	chirp, err := cfg.dbQueries.CreateChirp(r.Context(), database.CreateChirpParams{
-		Body:   cleaned,
-		UserID: userId,
+	if !cfg.checkCanPost(w, r, userId) {
+		return
+	}
+	if !cfg.checkChirpQuota(w, r, userId, 1) {
+		return
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create chirp ID", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	chirp, err := qtx.CreateChirp(r.Context(), database.CreateChirpParams{
+		ID:            id,
+		Body:          cleaned,
+		UserID:        userId,
+		ParentChirpID: parent,
+		PlaceName:     placeName,
+		Latitude:      latitude,
+		Longitude:     longitude,
+		Status:        status,
+		PublishAt:     publishAt,
 	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't store user", err)
 		return
 	}
+	if status == chirpPublished {
+		err = cfg.publishChirp(r.Context(), qtx, chirp)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't publish chirp", err)
+			return
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't store chirp", err)
+		return
+	}
+	if status == chirpPublished {
+		cfg.chirpCache.Put(chirp.ID, chirp)
+		if parent.Valid {
+			cfg.chirpCache.Invalidate(parent.UUID)
+		}
+		cfg.wakeOutboxRelay()
+	}
 
-	respondWithJSON(w, http.StatusCreated, Chirp{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserId:    chirp.UserID,
-	})
+	respondWithJSON(w, http.StatusCreated, cfg.newChirp(r.Context(), chirp))
 }
 
-func validateChirp(body string) (string, error) {
+func validateChirp(body string, badWords *wordfilter.Filter) (string, error) {
 	const maxChirpLength = 140
 	if len(body) > maxChirpLength {
-		return "", fmt.Errorf("Chirp is too long")
+		v := validate.Validator{}
+		v.Add("body", validate.CodeTooLong, "Chirp is too long")
+		return "", v.Err()
 	}
 
-	badWords := map[string]struct{}{
-		"kerfuffle": {},
-		"sharbert":  {},
-		"fornax":    {},
-	}
-	cleaned := cleanRequestBody(body, badWords)
-	return cleaned, nil
+	return badWords.Apply(body), nil
 }
 
-func cleanRequestBody(body string, badWords map[string]struct{}) string {
-	words := strings.Split(body, " ")
-	for i, word := range words {
-		lowercaseWord := strings.ToLower(word)
-		if _, ok := badWords[lowercaseWord]; ok {
-			words[i] = "****"
+// normalizeBody applies the configured normalization rules to a chirp body
+// for display.
+func normalizeBody(body string, rules config.Normalization) string {
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	body = strings.ReplaceAll(body, "\r", "\n")
+
+	lines := strings.Split(body, "\n")
+	kept := make([]string, 0, len(lines))
+	blank := 0
+	for _, line := range lines {
+		if rules.CollapseSpaces {
+			line = strings.Join(strings.FieldsFunc(line, func(r rune) bool {
+				return r == ' ' || r == '\t'
+			}), " ")
 		}
+		if rules.TrimSpace {
+			line = strings.TrimRightFunc(line, unicode.IsSpace)
+		}
+		if strings.TrimSpace(line) == "" {
+			blank++
+			if rules.MaxBlankLines >= 0 && blank > rules.MaxBlankLines {
+				continue
+			}
+		} else {
+			blank = 0
+		}
+		kept = append(kept, line)
 	}
 
-	cleaned := strings.Join(words, " ")
-	return cleaned
+	normalized := strings.Join(kept, "\n")
+	if rules.TrimSpace {
+		normalized = strings.TrimSpace(normalized)
+	}
+	return normalized
+}
+
+// chirpListQuery reads the author_id and sort parameters of GET /chirps. If
+// they're invalid, it has already written the response.
+func (cfg *apiConfig) chirpListQuery(w http.ResponseWriter, r *http.Request) (author uuid.NullUUID, sort string, ok bool) {
+	sort = r.URL.Query().Get("sort")
+	switch sort {
+	case "":
+		sort = "asc"
+	case "asc", "desc":
+	default:
+		respondWithError(w, http.StatusBadRequest, "Sort must be asc or desc", nil)
+		return uuid.NullUUID{}, "", false
+	}
+
+	if authorId := r.URL.Query().Get("author_id"); authorId != "" {
+		id, err := cfg.parseID(authorId)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid author id", err)
+			return uuid.NullUUID{}, "", false
+		}
+		author = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	return author, sort, true
 }
 
 func (cfg *apiConfig) getAllChirpsHandler(w http.ResponseWriter, r *http.Request) {
-	authorId := r.URL.Query().Get("author_id")
-	sortParam := r.URL.Query().Get("sort")
-	sort := "asc"
-	if sortParam == "desc" {
-		sort = "desc"
+	author, sort, ok := cfg.chirpListQuery(w, r)
+	if !ok {
+		return
 	}
 
+	viewer := cfg.viewerID(r)
 	var err error
 	var chirps []database.Chirp
-	if authorId == "" {
-		chirps, err = cfg.dbQueries.GetChirps(r.Context(), sort)
+	if !author.Valid {
+		chirps, err = cfg.dbQueries.GetChirps(r.Context(), database.GetChirpsParams{
+			Sort:     sort,
+			ViewerID: viewer,
+		})
 	} else {
-		id, err := uuid.Parse(authorId)
-		if err != nil {
-			respondWithError(w, http.StatusBadRequest, "Invalid author id", err)
-			return
-		}
 		chirps, err = cfg.dbQueries.GetChirpsByAuthor(r.Context(), database.GetChirpsByAuthorParams{
-			UserID: id,
-			Sort:   sort,
+			UserID:   author.UUID,
+			Sort:     sort,
+			ViewerID: viewer,
 		})
 	}
 
@@ -255,39 +693,72 @@ func (cfg *apiConfig) getAllChirpsHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	payload := []Chirp{}
-	for _, chirp := range chirps {
-		c := Chirp{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserId:    chirp.UserID,
-		}
-		payload = append(payload, c)
+	respondWithList(w, http.StatusOK, cfg.newChirps(r.Context(), chirps), cfg.wantsEnvelope(r))
+}
+
+// getChirpRepliesHandler lists the direct replies to a chirp, oldest first.
+func (cfg *apiConfig) getChirpRepliesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := cfg.parseID(r.PathValue("chirpID"))
+	if err != nil {
+		chirpPolicy.notFound(w, err)
+		return
+	}
+	viewer := cfg.viewerID(r)
+	_, ok := cfg.getVisibleChirp(w, r, viewer, id)
+	if !ok {
+		return
+	}
+
+	replies, err := cfg.dbQueries.GetChirpReplies(r.Context(), database.GetChirpRepliesParams{
+		ParentChirpID: uuid.NullUUID{UUID: id, Valid: true},
+		ViewerID:      viewer,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get replies", err)
+		return
 	}
-	respondWithJSON(w, http.StatusOK, payload)
+	respondWithList(w, http.StatusOK, cfg.newChirps(r.Context(), replies), cfg.wantsEnvelope(r))
 }
 
 func (cfg *apiConfig) getChirpHandler(w http.ResponseWriter, r *http.Request) {
-	id, err := uuid.Parse(r.PathValue("chirpID"))
+	id, err := cfg.parseID(r.PathValue("chirpID"))
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, "invalid uuid", err)
+		chirpPolicy.notFound(w, err)
 		return
 	}
-	chirp, err := cfg.dbQueries.GetChirp(r.Context(), id)
-	if err != nil {
-		respondWithError(w, http.StatusNotFound, "chirp not found", err)
+	chirp, ok := cfg.getVisibleChirp(w, r, cfg.viewerID(r), id)
+	if !ok {
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, Chirp{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserId:    chirp.UserID,
-	})
+	respondWithJSON(w, http.StatusOK, cfg.newChirp(r.Context(), chirp))
+}
+
+// getVisibleChirp looks up a chirp unless it's hidden from the viewer, see
+// hiddenFrom. Drafts are visible to their author only. If it can't, it has
+// already written the response.
+func (cfg *apiConfig) getVisibleChirp(w http.ResponseWriter, r *http.Request, viewer uuid.NullUUID, id uuid.UUID) (database.Chirp, bool) {
+	chirp, err := cfg.getChirp(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) && viewer.Valid {
+		chirp, err = cfg.dbQueries.GetDraft(r.Context(), database.GetDraftParams{
+			ID:     id,
+			UserID: viewer.UUID,
+		})
+	}
+	if err != nil {
+		chirpPolicy.notFound(w, err)
+		return database.Chirp{}, false
+	}
+	hidden, err := cfg.hiddenFrom(r.Context(), viewer, chirp.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get chirp", err)
+		return database.Chirp{}, false
+	}
+	if hidden {
+		chirpPolicy.notFound(w, nil)
+		return database.Chirp{}, false
+	}
+	return chirp, true
 }
 
 func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
@@ -295,21 +766,15 @@ func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 		Password string `json:"password"`
 		Email    string `json:"email"`
 	}
-	type response struct {
-		User
-		Token        string `json:"token"`
-		RefreshToken string `json:"refresh_token"`
-	}
 
-	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
-	err := decoder.Decode(&params)
+	err := decodeJSONBody(w, r, &params)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
 		return
 	}
 
-	user, err := cfg.dbQueries.GetUserByEmail(r.Context(), params.Email)
+	user, err := cfg.dbQueries.GetUserByEmail(r.Context(), normalizeEmail(params.Email, cfg.stripEmailPlusTags))
 	if err != nil {
 		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
 		return
@@ -320,43 +785,63 @@ func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
 		return
 	}
+	if user.DeactivatedAt.Valid {
+		respondWithError(w, http.StatusForbidden, "Account is deactivated", nil)
+		return
+	}
+
+	cfg.respondWithLogin(w, r, user)
+}
 
-	token, err := auth.MakeJWT(user.ID, cfg.jwtSecret, time.Hour)
+// respondWithLogin starts a session for a user who just proved who they
+// are, with a password or a passkey, and sends its tokens.
+func (cfg *apiConfig) respondWithLogin(w http.ResponseWriter, r *http.Request, user database.User) {
+	type response struct {
+		User
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	token, err := auth.MakeJWT(user.ID, cfg.jwtSecret, cfg.accessTokenTTL)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't create access token", err)
+		return
 	}
 
 	refreshToken, err := auth.MakeRefreshToken()
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't create refresh token", err)
+		return
 	}
 
 	_, err = cfg.dbQueries.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
 		Token:     refreshToken,
 		UserID:    user.ID,
-		ExpiresAt: time.Now().UTC().AddDate(0, 0, 60),
+		ExpiresAt: time.Now().UTC().Add(cfg.refreshTokenTTL),
+		FamilyID:  uuid.New(),
 	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't save refresh token", err)
 		return
 	}
 
+	cfg.recordLogin(r, user)
+
 	respondWithJSON(w, http.StatusOK, response{
-		User: User{
-			ID:          user.ID,
-			CreatedAt:   user.CreatedAt,
-			UpdatedAt:   user.UpdatedAt,
-			Email:       user.Email,
-			IsChirpyRed: user.IsChirpyRed,
-		},
+		User:         cfg.newUser(user),
 		Token:        token,
 		RefreshToken: refreshToken,
 	})
 }
 
+// refreshHandler trades a refresh token for an access token and a new
+// refresh token of the same family. Each refresh token works once: when one
+// is presented again, someone else holds a copy, so the whole family is
+// revoked and the user has to log in again.
 func (cfg *apiConfig) refreshHandler(w http.ResponseWriter, r *http.Request) {
 	type response struct {
-		Token string `json:"token"`
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
 	}
 
 	refreshToken, err := auth.GetBearerToken(r.Header)
@@ -365,22 +850,86 @@ func (cfg *apiConfig) refreshHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := cfg.dbQueries.GetUserByRefreshToken(r.Context(), refreshToken)
+	stored, err := cfg.dbQueries.GetRefreshToken(r.Context(), refreshToken)
 	if err != nil {
 		respondWithError(w, http.StatusUnauthorized, "Couldn't get user for refresh token", err)
 		return
 	}
+	if stored.RevokedAt.Valid || !stored.ExpiresAt.After(time.Now().UTC()) {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get user for refresh token", nil)
+		return
+	}
+	if stored.RotatedAt.Valid {
+		cfg.revokeRefreshTokenFamily(r.Context(), stored)
+		respondWithError(w, http.StatusUnauthorized, "Refresh token was already used", nil)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	rotated, err := qtx.RotateRefreshToken(r.Context(), refreshToken)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't rotate refresh token", err)
+		return
+	}
+	if rotated == 0 {
+		// A concurrent request with the same token got there first.
+		tx.Rollback()
+		cfg.revokeRefreshTokenFamily(r.Context(), stored)
+		respondWithError(w, http.StatusUnauthorized, "Refresh token was already used", nil)
+		return
+	}
 
-	accessToken, err := auth.MakeJWT(user.ID, cfg.jwtSecret, time.Hour)
+	newRefreshToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create refresh token", err)
+		return
+	}
+	_, err = qtx.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
+		Token:     newRefreshToken,
+		UserID:    stored.UserID,
+		ExpiresAt: time.Now().UTC().Add(cfg.refreshTokenTTL),
+		FamilyID:  stored.FamilyID,
+	})
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Couldn't create access token", err)
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save refresh token", err)
+		return
+	}
+
+	accessToken, err := auth.MakeJWT(stored.UserID, cfg.jwtSecret, cfg.accessTokenTTL)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create access token", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't rotate refresh token", err)
+		return
 	}
 
 	respondWithJSON(w, http.StatusOK, response{
-		Token: accessToken,
+		Token:        accessToken,
+		RefreshToken: newRefreshToken,
 	})
 }
 
+// revokeRefreshTokenFamily ends every session descending from the login
+// token was issued for, after token turned up a second time.
+func (cfg *apiConfig) revokeRefreshTokenFamily(ctx context.Context, token database.RefreshToken) {
+	log.Printf("Refresh token reused for user %s, revoking family %s", token.UserID, token.FamilyID)
+	err := cfg.dbQueries.RevokeRefreshTokenFamily(ctx, token.FamilyID)
+	if err != nil {
+		log.Printf("Couldn't revoke refresh token family %s: %v", token.FamilyID, err)
+	}
+}
+
 func (cfg *apiConfig) revokeHandler(w http.ResponseWriter, r *http.Request) {
 	refreshToken, err := auth.GetBearerToken(r.Header)
 	if err != nil {
@@ -397,6 +946,101 @@ func (cfg *apiConfig) revokeHandler(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusNoContent, nil)
 }
 
+// revokeAllHandler logs the caller out everywhere: every refresh token of
+// the account is revoked and access tokens issued until now are refused by
+// middlewareSessions, including the caller's own. With delete_passkeys the
+// account's passkeys are removed as well, for when a device was lost.
+func (cfg *apiConfig) revokeAllHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		DeletePasskeys bool `json:"delete_passkeys"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	params := parameters{}
+	err = decodeJSONBody(w, r, &params)
+	if err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke sessions", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	err = endSessions(r.Context(), qtx, userId)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke sessions", err)
+		return
+	}
+	if params.DeletePasskeys {
+		_, err = qtx.DeleteUserPasskeys(r.Context(), userId)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't delete passkeys", err)
+			return
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke sessions", err)
+		return
+	}
+	cfg.userCache.Invalidate(userId)
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// endSessions revokes every refresh token of the user and refuses the
+// access tokens issued so far. Invalidate the cached user once the
+// transaction commits.
+func endSessions(ctx context.Context, q *database.Queries, userId uuid.UUID) error {
+	err := q.RevokeAllUserTokens(ctx, userId)
+	if err != nil {
+		return err
+	}
+	return q.EndUserSessions(ctx, userId)
+}
+
+// middlewareSessions refuses access tokens issued before the user last
+// logged out everywhere. Requests without a valid access token are left for
+// the handler to turn away. Other instances read the user from their own
+// cache, so there they stop working within CACHE_TTL.
+func (cfg *apiConfig) middlewareSessions(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		userId, issuedAt, err := auth.ValidateJWTIssuedAt(token, cfg.jwtSecret)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		user, err := cfg.getUser(r.Context(), userId)
+		if err == nil && user.SessionsValidAfter.Valid && issuedAt.Before(user.SessionsValidAfter.Time) {
+			respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", errors.New("session was revoked"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// deleteChirpHandler only marks the chirp deleted; support can bring it
+// back through /admin/chirps/{chirpID}/restore.
 func (cfg *apiConfig) deleteChirpHandler(w http.ResponseWriter, r *http.Request) {
 	token, err := auth.GetBearerToken(r.Header)
 	if err != nil {
@@ -409,28 +1053,188 @@ func (cfg *apiConfig) deleteChirpHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	chirpId, err := uuid.Parse(r.PathValue("chirpID"))
+	chirpId, err := cfg.parseID(r.PathValue("chirpID"))
 	if err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
 		return
 	}
 
-	chirp, err := cfg.dbQueries.GetChirp(r.Context(), chirpId)
+	chirp, err := cfg.getChirp(r.Context(), chirpId)
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, "Couldn't get chirp", err)
+		chirpPolicy.notFound(w, err)
 		return
 	}
 
 	if chirp.UserID != userId {
-		respondWithError(w, http.StatusForbidden, "You can't delete this chirp", err)
+		chirpPolicy.deny(w, "You can't delete this chirp", nil)
 		return
 	}
 
-	err = cfg.dbQueries.DeleteChirp(r.Context(), chirpId)
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	deleted, err := qtx.DeleteChirp(r.Context(), chirpId)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete chirp", err)
+		return
+	}
+	if deleted == 0 {
+		// Another request deleted it since it was cached.
+		cfg.chirpCache.Invalidate(chirpId)
+		chirpPolicy.notFound(w, sql.ErrNoRows)
+		return
+	}
+	if chirp.ParentChirpID.Valid {
+		err = qtx.AddChirpReplies(r.Context(), database.AddChirpRepliesParams{Delta: -1, ID: chirp.ParentChirpID.UUID})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't count reply", err)
+			return
+		}
+	}
+	err = addOutboxEvent(r.Context(), qtx, eventChirpDeleted, chirpDeletedEvent{ID: chirp.ID, UserId: chirp.UserID})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't store chirp event", err)
+		return
+	}
+	err = tx.Commit()
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't delete chirp", err)
 		return
 	}
+	cfg.chirpCache.Invalidate(chirpId)
+	if chirp.ParentChirpID.Valid {
+		cfg.chirpCache.Invalidate(chirp.ParentChirpID.UUID)
+	}
+	cfg.wakeOutboxRelay()
 
 	respondWithJSON(w, http.StatusNoContent, nil)
 }
+
+// updateChirpHandler lets the author change the body of a chirp. The new
+// body goes through the same checks as a new chirp.
+func (cfg *apiConfig) updateChirpHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	chirpId, err := cfg.parseID(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	params := parameters{}
+	err = decodeJSONBody(w, r, &params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	cleaned, err := validateChirp(params.Body, cfg.settings().badWords)
+	if err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+
+	chirp, err := cfg.getChirp(r.Context(), chirpId)
+	if err != nil {
+		chirpPolicy.notFound(w, err)
+		return
+	}
+	if chirp.UserID != userId {
+		chirpPolicy.deny(w, "You can't edit this chirp", nil)
+		return
+	}
+	if !cfg.checkCanPost(w, r, userId) {
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	chirp, err = qtx.UpdateChirp(r.Context(), database.UpdateChirpParams{
+		ID:   chirpId,
+		Body: cleaned,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update chirp", err)
+		return
+	}
+	err = qtx.DeleteChirpHashtags(r.Context(), chirp.ID)
+	if err == nil {
+		err = storeHashtags(r.Context(), qtx, chirp)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't store hashtags", err)
+		return
+	}
+	err = addOutboxEvent(r.Context(), qtx, eventChirpUpdated, chirpUpdatedEvent{
+		ID:        chirp.ID,
+		UserId:    chirp.UserID,
+		Body:      chirp.Body,
+		CreatedAt: chirp.CreatedAt,
+		UpdatedAt: chirp.UpdatedAt,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't store chirp event", err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update chirp", err)
+		return
+	}
+	cfg.chirpCache.Put(chirp.ID, chirp)
+	cfg.wakeOutboxRelay()
+
+	respondWithJSON(w, http.StatusOK, cfg.newChirp(r.Context(), chirp))
+}
+
+// renderChirpHandler previews how a chirp body will be formatted, without
+// storing anything. POST /chirps/preview does the same for a signed-in
+// user and also checks whether they can post it.
+func (cfg *apiConfig) renderChirpHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+	type response struct {
+		Body     string `json:"body"`
+		BodyHTML string `json:"body_html"`
+	}
+
+	params := parameters{}
+	err := decodeJSONBody(w, r, &params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	formatted, err := cfg.formatChirpBody(params.Body)
+	if err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, response{
+		Body:     formatted.Body,
+		BodyHTML: formatted.BodyHTML,
+	})
+}
@@ -0,0 +1,2634 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/auth"
+	"github.com/fkl13/chirpy/internal/cache"
+	"github.com/fkl13/chirpy/internal/config"
+	"github.com/fkl13/chirpy/internal/database"
+	"github.com/fkl13/chirpy/internal/errreport"
+	"github.com/fkl13/chirpy/internal/markdown"
+	"github.com/fkl13/chirpy/internal/publicid"
+	"github.com/fkl13/chirpy/internal/pubsub"
+	"github.com/fkl13/chirpy/internal/ratelimit"
+	"github.com/fkl13/chirpy/internal/search"
+	"github.com/fkl13/chirpy/internal/validate"
+	"github.com/fkl13/chirpy/internal/wordfilter"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func benchmarkChirps(n int) []Chirp {
+	chirps := make([]Chirp, n)
+	for i := range chirps {
+		chirps[i] = Chirp{
+			ID:        uuid.NewString(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			Body:      "I had something interesting for breakfast and I'm going to chirp about it",
+			UserId:    uuid.NewString(),
+		}
+	}
+	return chirps
+}
+
+func BenchmarkValidateChirp(b *testing.B) {
+	badWords := newRuntimeSettings(config.DefaultRuntime()).badWords
+	body := "I hear Mastodon is better than Chirpy. sharbert I need to migrate kerfuffle and Fornax!"
+	b.ReportAllocs()
+	for range b.N {
+		_, err := validateChirp(body, badWords)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRespondWithJSON(b *testing.B) {
+	chirps := benchmarkChirps(100)
+	b.ReportAllocs()
+	for range b.N {
+		w := httptest.NewRecorder()
+		respondWithJSON(w, http.StatusOK, chirps)
+	}
+}
+
+func BenchmarkRespondWithListEnvelope(b *testing.B) {
+	chirps := benchmarkChirps(100)
+	b.ReportAllocs()
+	for range b.N {
+		w := httptest.NewRecorder()
+		respondWithList(w, http.StatusOK, chirps, true)
+	}
+}
+
+func BenchmarkHealthzHandler(b *testing.B) {
+	b.ReportAllocs()
+	for range b.N {
+		w := httptest.NewRecorder()
+		healthzHandler(w, httptest.NewRequest(http.MethodGet, "/api/v1/healthz", nil))
+	}
+}
+
+// BenchmarkGetChirpHandlerCached measures the hot read path when the chirp
+// is served from the cache, so no database is needed.
+func BenchmarkGetChirpHandlerCached(b *testing.B) {
+	chirp := database.Chirp{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Body:      "Cached chirp",
+		UserID:    uuid.New(),
+	}
+	cfg := &apiConfig{
+		chirpCache: cache.New[uuid.UUID, database.Chirp](10, time.Hour),
+	}
+	cfg.chirpCache.Put(chirp.ID, chirp)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/chirps/{chirpID}", cfg.getChirpHandler)
+	path := "/api/v1/chirps/" + chirp.ID.String()
+
+	b.ReportAllocs()
+	for range b.N {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			b.Fatalf("status = %d, body = %s", w.Code, w.Body)
+		}
+	}
+}
+
+func FuzzValidateChirp(f *testing.F) {
+	badWords := newRuntimeSettings(config.DefaultRuntime()).badWords
+	f.Add("I had something interesting for breakfast")
+	f.Add("I hear Mastodon is better than Chirpy. sharbert I need to migrate")
+	f.Add("Kerfuffle  Fornax! ")
+	f.Add(strings.Repeat("a", 141))
+	f.Fuzz(func(t *testing.T, body string) {
+		cleaned, err := validateChirp(body, badWords)
+		if err != nil {
+			if len(body) <= 140 {
+				t.Errorf("validateChirp(%q) rejected a short chirp: %v", body, err)
+			}
+			return
+		}
+		if len(body) > 140 {
+			t.Errorf("validateChirp(%q) accepted a long chirp", body)
+		}
+		if got, want := len(strings.Split(cleaned, " ")), len(strings.Split(body, " ")); got != want {
+			t.Errorf("validateChirp(%q) = %q, word count %d, want %d", body, cleaned, got, want)
+		}
+	})
+}
+
+func FuzzDecodeJSONBody(f *testing.F) {
+	f.Add(`{"body": "hello"}`)
+	f.Add(`{"chirps": [{"body": "a", "publish_at": "2026-01-01T00:00:00Z"}]}`)
+	f.Add(`{"body": "hello"} trailing`)
+	f.Add(`{"chirps": "nope"}`)
+	f.Add(`[`)
+	f.Fuzz(func(t *testing.T, body string) {
+		type item struct {
+			Body      string     `json:"body"`
+			PublishAt *time.Time `json:"publish_at"`
+		}
+		params := struct {
+			Body   string `json:"body"`
+			Chirps []item `json:"chirps"`
+		}{}
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/chirps", strings.NewReader(body))
+		decodeJSONBody(httptest.NewRecorder(), r, &params)
+	})
+}
+
+func TestMiddlewareRecover(t *testing.T) {
+	handler := middlewareRecover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	if !strings.Contains(w.Body.String(), `"error"`) {
+		t.Errorf("body = %q, want a JSON error", w.Body.String())
+	}
+}
+
+func TestMiddlewareRateLimit(t *testing.T) {
+	cfg := &apiConfig{}
+	rt := config.DefaultRuntime()
+	rt.RateLimit = &config.RateLimit{Limit: 1, Window: time.Minute}
+	cfg.runtime.Store(newRuntimeSettings(rt))
+	handler := cfg.middlewareRateLimit(http.HandlerFunc(healthzHandler))
+
+	tests := []struct {
+		name          string
+		wantStatus    int
+		wantRemaining string
+	}{
+		{name: "Allowed", wantStatus: http.StatusOK, wantRemaining: "0"},
+		{name: "Limited", wantStatus: http.StatusTooManyRequests, wantRemaining: "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/healthz", nil))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			for _, header := range []string{"X-RateLimit-Remaining", "RateLimit-Remaining"} {
+				if got := w.Header().Get(header); got != tt.wantRemaining {
+					t.Errorf("%s = %q, want %q", header, got, tt.wantRemaining)
+				}
+			}
+			if got := w.Header().Get("X-RateLimit-Limit"); got != "1" {
+				t.Errorf("X-RateLimit-Limit = %q, want \"1\"", got)
+			}
+		})
+	}
+}
+
+func TestMiddlewareBreaker(t *testing.T) {
+	cfg := &apiConfig{breakerThreshold: 2, breakerCooldown: time.Minute}
+	calls := 0
+	handler := cfg.middlewareBreaker("GET /chirps", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get chirps", nil)
+	}))
+
+	wantStatus := []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusServiceUnavailable}
+	for i, want := range wantStatus {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/chirps", nil))
+		if w.Code != want {
+			t.Errorf("request %d: status = %d, want %d", i, w.Code, want)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("handler called %d times, want 2", calls)
+	}
+}
+
+type chanSender chan errreport.Event
+
+func (s chanSender) Send(ctx context.Context, event errreport.Event) error {
+	s <- event
+	return nil
+}
+
+func TestErrorReporting(t *testing.T) {
+	events := make(chanSender, 1)
+	cfg := &apiConfig{errorReporter: errreport.NewReporter(events, 1)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/chirps/{chirpID}", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	handler := cfg.middlewareRequestID(middlewareRecover(mux))
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/chirps/123", nil)
+	r.Header.Set("X-Request-ID", "req-1")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if got := w.Header().Get("X-Request-ID"); got != "req-1" {
+		t.Errorf("X-Request-ID = %q, want req-1", got)
+	}
+	event := <-events
+	if event.RequestID != "req-1" || event.Status != http.StatusInternalServerError {
+		t.Errorf("event = %+v", event)
+	}
+	if event.Route != "GET /api/v1/chirps/{chirpID}" {
+		t.Errorf("event.Route = %q", event.Route)
+	}
+	if event.Error != "panic: boom" || event.Stack == "" {
+		t.Errorf("event.Error = %q, stack empty: %v", event.Error, event.Stack == "")
+	}
+}
+
+func TestRunLoadtestBadFlag(t *testing.T) {
+	var out strings.Builder
+	if code := runLoadtest([]string{"-nope"}, &out); code != 2 {
+		t.Errorf("runLoadtest() = %d, want 2", code)
+	}
+	if !strings.Contains(out.String(), "flag provided but not defined") {
+		t.Errorf("runLoadtest() output = %q", out.String())
+	}
+}
+
+func TestRunLoadtestReportsFailures(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	var out strings.Builder
+	code := runLoadtest([]string{"-url", srv.URL, "-users", "2", "-duration", "10ms"}, &out)
+	if code != 1 {
+		t.Errorf("runLoadtest() = %d, want 1", code)
+	}
+	if !strings.Contains(out.String(), "signup") {
+		t.Errorf("runLoadtest() output = %q", out.String())
+	}
+}
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		name          string
+		email         string
+		stripPlusTags bool
+		want          string
+	}{
+		{name: "Lowercase", email: "Foo@Bar.com", want: "foo@bar.com"},
+		{name: "Trim", email: "  foo@bar.com\n", want: "foo@bar.com"},
+		{name: "Keep plus tag", email: "foo+chirpy@bar.com", want: "foo+chirpy@bar.com"},
+		{name: "Strip plus tag", email: "Foo+Chirpy@bar.com", stripPlusTags: true, want: "foo@bar.com"},
+		{name: "No at sign", email: "Foo+bar", stripPlusTags: true, want: "foo+bar"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeEmail(tt.email, tt.stripPlusTags); got != tt.want {
+				t.Errorf("normalizeEmail(%q) = %q, want %q", tt.email, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUsageCounter(t *testing.T) {
+	c := newUsageCounter()
+	user := uuid.New()
+	day := time.Date(2026, 3, 1, 15, 0, 0, 0, time.UTC)
+
+	c.add(user, day)
+	c.add(user, day.Add(time.Hour))
+	c.add(user, day.AddDate(0, 0, 1))
+	c.add(uuid.New(), day)
+
+	pending := c.pendingFor(user)
+	if got := pending[usageDay(day)]; got != 2 {
+		t.Errorf("calls on %v = %d, want 2", usageDay(day), got)
+	}
+	if len(pending) != 2 {
+		t.Errorf("pendingFor() has %d days, want 2", len(pending))
+	}
+
+	if taken := c.take(); len(taken) != 3 {
+		t.Errorf("take() returned %d keys, want 3", len(taken))
+	}
+	if pending := c.pendingFor(user); len(pending) != 0 {
+		t.Errorf("pendingFor() after take() = %v, want empty", pending)
+	}
+}
+
+func TestUsageCounterToday(t *testing.T) {
+	c := newUsageCounter()
+	user := uuid.New()
+	day := time.Date(2026, 3, 1, 15, 0, 0, 0, time.UTC)
+
+	if _, ok := c.callsToday(user, day); ok {
+		t.Fatal("callsToday() known before seeding")
+	}
+	c.add(user, day)
+	if got := c.seedToday(user, day, 5); got != 5 {
+		t.Errorf("seedToday() = %d, want 5", got)
+	}
+	if got := c.seedToday(user, day, 1); got != 5 {
+		t.Errorf("second seedToday() = %d, want 5", got)
+	}
+	c.add(user, day.Add(time.Hour))
+	if got, _ := c.callsToday(user, day.Add(time.Hour)); got != 6 {
+		t.Errorf("callsToday() = %d, want 6", got)
+	}
+	if _, ok := c.callsToday(user, day.AddDate(0, 0, 1)); ok {
+		t.Error("callsToday() still known on the next day")
+	}
+}
+
+func TestAnalyticsCounter(t *testing.T) {
+	if c := newAnalyticsCounter("secret", 0); c != nil {
+		t.Fatal("newAnalyticsCounter() with no retention isn't nil")
+	}
+	c := newAnalyticsCounter("secret", 24*time.Hour)
+	user := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+	day := time.Date(2026, 3, 1, 15, 0, 0, 0, time.UTC)
+
+	c.add("GET /chirps", "DE", user, day)
+	c.add("GET /chirps", "DE", user, day.Add(time.Hour))
+	c.add("GET /chirps", "DE", user, day.AddDate(0, 0, 1))
+	c.add("GET /chirps", "", uuid.NullUUID{}, day)
+
+	taken := c.take()
+	if len(taken) != 3 {
+		t.Fatalf("take() returned %d keys, want 3", len(taken))
+	}
+	visitors := map[string]bool{}
+	for key, requests := range taken {
+		if key.visitor == "" {
+			continue
+		}
+		if strings.Contains(key.visitor, user.UUID.String()) {
+			t.Errorf("visitor %q contains the user ID", key.visitor)
+		}
+		if key.day.Equal(usageDay(day)) && requests != 2 {
+			t.Errorf("requests on %v = %d, want 2", key.day, requests)
+		}
+		visitors[key.visitor] = true
+	}
+	if len(visitors) != 2 {
+		t.Errorf("got %d distinct visitors over two days, want a new hash every day", len(visitors))
+	}
+	if other := newAnalyticsCounter("other", time.Hour); other.visitor(user.UUID, day) == c.visitor(user.UUID, day) {
+		t.Error("visitor hash doesn't depend on the key")
+	}
+}
+
+func TestMiddlewareAnalytics(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	cfg := &apiConfig{countryHeader: "CF-IPCountry"}
+	if h := cfg.middlewareAnalytics("GET /chirps", next); reflect.ValueOf(h).Pointer() != reflect.ValueOf(next).Pointer() {
+		t.Error("middlewareAnalytics() wrapped the handler with analytics off")
+	}
+
+	cfg.analytics = newAnalyticsCounter("secret", time.Hour)
+	req := httptest.NewRequest("GET", "/api/v1/chirps", nil)
+	req.Header.Set("CF-IPCountry", "NZ")
+	cfg.middlewareAnalytics("GET /chirps", next).ServeHTTP(httptest.NewRecorder(), req)
+	taken := cfg.analytics.take()
+	if len(taken) != 1 {
+		t.Fatalf("take() returned %d keys, want 1", len(taken))
+	}
+	for key, requests := range taken {
+		if key.route != "GET /chirps" || key.country != "NZ" || key.visitor != "" || requests != 1 {
+			t.Errorf("counted %+v %d times, want one signed-out request to GET /chirps from NZ", key, requests)
+		}
+	}
+}
+
+func TestEmbedCSPMatchesStylesheet(t *testing.T) {
+	var buf bytes.Buffer
+	err := embedTemplate.Execute(&buf, map[string]any{
+		"CSS":       template.CSS(embedCSS),
+		"Theme":     "dark",
+		"Body":      "<script>alert(1)</script>",
+		"CreatedAt": time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := buf.String()
+
+	_, rest, _ := strings.Cut(page, "<style>")
+	style, _, _ := strings.Cut(rest, "</style>")
+	hash := base64.StdEncoding.EncodeToString(sha256Sum(style))
+	if !strings.Contains(embedCSP, "'sha256-"+hash+"'") {
+		t.Errorf("CSP %q doesn't allow the rendered stylesheet (sha256-%s)", embedCSP, hash)
+	}
+	if strings.Contains(page, "<script>") {
+		t.Error("chirp body wasn't escaped")
+	}
+}
+
+func TestReportedChirpID(t *testing.T) {
+	cfg := &apiConfig{}
+	id := uuid.New()
+
+	tests := []struct {
+		name   string
+		url    string
+		wantOK bool
+	}{
+		{name: "API URL", url: "https://chirpy.example/api/v1/chirps/" + id.String(), wantOK: true},
+		{name: "Embed page", url: "/embed/chirp/" + id.String() + "?theme=dark", wantOK: true},
+		{name: "Chirp list", url: "https://chirpy.example/api/v1/chirps", wantOK: false},
+		{name: "Unrelated URL", url: "https://example.com/" + id.String(), wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := cfg.reportedChirpID(tt.url)
+			if ok != tt.wantOK {
+				t.Fatalf("reportedChirpID() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && got != id {
+				t.Errorf("reportedChirpID() = %v, want %v", got, id)
+			}
+		})
+	}
+}
+
+func TestSecurityTxt(t *testing.T) {
+	cfg := &apiConfig{}
+	w := httptest.NewRecorder()
+	cfg.securityTxtHandler(w, httptest.NewRequest("GET", "/.well-known/security.txt", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status without contacts = %d, want 404", w.Code)
+	}
+
+	cfg.securityContacts = splitList("mailto:security@example.com, https://example.com/security")
+	w = httptest.NewRecorder()
+	cfg.securityTxtHandler(w, httptest.NewRequest("GET", "/.well-known/security.txt", nil))
+	body := w.Body.String()
+	for _, want := range []string{
+		"Contact: mailto:security@example.com\n",
+		"Contact: https://example.com/security\n",
+		"Expires: ",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("security.txt = %q, missing %q", body, want)
+		}
+	}
+}
+
+func TestWebhookMetrics(t *testing.T) {
+	cfg := &apiConfig{polkaKey: "polka", webhookMetrics: newWebhookMetrics()}
+
+	deliver := func(apiKey, body string) int {
+		req := httptest.NewRequest("POST", "/api/v1/polka/webhooks", strings.NewReader(body))
+		if apiKey != "" {
+			req.Header.Set("Authorization", "ApiKey "+apiKey)
+		}
+		w := httptest.NewRecorder()
+		cfg.addUserSubscribtionHandler(w, req)
+		return w.Code
+	}
+
+	if code := deliver("", `{}`); code != http.StatusUnauthorized {
+		t.Errorf("without key: status = %d, want 401", code)
+	}
+	if got := cfg.webhookMetrics.stats(); got.LastEventAt != nil {
+		t.Error("an unauthenticated delivery set last_event_at")
+	}
+	if code := deliver("polka", `{"event": "user.downgraded"}`); code != http.StatusNoContent {
+		t.Errorf("unknown event: status = %d, want 204", code)
+	}
+
+	stats := cfg.webhookMetrics.stats()
+	if stats.Received != 2 {
+		t.Errorf("received = %d, want 2", stats.Received)
+	}
+	if stats.Outcomes[webhookAuthFailed] != 1 || stats.Outcomes[webhookUnknownEvent] != 1 {
+		t.Errorf("outcomes = %v", stats.Outcomes)
+	}
+	if stats.LastEventAt == nil {
+		t.Error("last_event_at not set after an authenticated delivery")
+	}
+	var bucketed int64
+	for _, b := range stats.LatencyMillis {
+		bucketed += b.Count
+	}
+	if bucketed != 2 {
+		t.Errorf("histogram holds %d deliveries, want 2", bucketed)
+	}
+}
+
+func TestSetUserMembershipValidation(t *testing.T) {
+	cfg := &apiConfig{}
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "Missing membership", body: `{"reason": "billing webhook missed"}`},
+		{name: "Missing reason", body: `{"is_chirpy_red": true}`},
+		{name: "Blank reason", body: `{"is_chirpy_red": false, "reason": "  "}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PATCH", "/admin/users/x/membership", strings.NewReader(tt.body))
+			req.SetPathValue("userID", uuid.NewString())
+			w := httptest.NewRecorder()
+			cfg.setUserMembershipHandler(w, req)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400", w.Code)
+			}
+		})
+	}
+}
+
+func TestCreateUserValidationErrors(t *testing.T) {
+	cfg := &apiConfig{}
+	req := httptest.NewRequest("POST", "/api/v1/users", strings.NewReader(`{"email": "not-an-email", "password": "short"}`))
+	w := httptest.NewRecorder()
+	cfg.createUserHandler(w, req)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422", w.Code)
+	}
+
+	var res struct {
+		Violations []validate.Violation `json:"violations"`
+	}
+	err := json.NewDecoder(w.Body).Decode(&res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []validate.Violation{
+		{Field: "email", Code: validate.CodeInvalidEmail, Message: "must be an email address"},
+		{Field: "password", Code: validate.CodeTooShort, Message: "must be at least 8 characters"},
+	}
+	if !reflect.DeepEqual(res.Violations, want) {
+		t.Errorf("violations = %+v, want %+v", res.Violations, want)
+	}
+}
+
+func TestReporterReputation(t *testing.T) {
+	tests := []struct {
+		name      string
+		actioned  int64
+		dismissed int64
+		weight    float64
+		badFaith  bool
+	}{
+		{name: "No history", weight: 0.5},
+		{name: "Reliable", actioned: 8, weight: 0.9},
+		{name: "Too few decided to judge", dismissed: 4, weight: 1.0 / 6},
+		{name: "Bad faith", actioned: 1, dismissed: 9, weight: 2.0 / 12, badFaith: true},
+		{name: "Mixed", actioned: 2, dismissed: 6, weight: 0.3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newReporterReputation(tt.actioned, tt.dismissed)
+			if math.Abs(got.Weight-tt.weight) > 1e-9 {
+				t.Errorf("weight = %v, want %v", got.Weight, tt.weight)
+			}
+			if got.BadFaith != tt.badFaith {
+				t.Errorf("bad faith = %v, want %v", got.BadFaith, tt.badFaith)
+			}
+		})
+	}
+}
+
+func TestResolveAbuseReportOutcome(t *testing.T) {
+	cfg := &apiConfig{}
+	req := httptest.NewRequest("POST", "/admin/abuse-reports/x/resolve", strings.NewReader(`{"outcome": "ignored"}`))
+	req.SetPathValue("reportID", uuid.NewString())
+	w := httptest.NewRecorder()
+	cfg.resolveAbuseReportHandler(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+}
+
+func TestGetAllChirpsInvalidQuery(t *testing.T) {
+	cfg := &apiConfig{}
+	for _, query := range []string{"sort=newest", "author_id=not-a-uuid", "author_id=" + uuid.NewString() + "&sort=DESC"} {
+		t.Run(query, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/chirps?"+query, nil)
+			w := httptest.NewRecorder()
+			cfg.getAllChirpsHandler(w, req)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400", w.Code)
+			}
+		})
+	}
+}
+
+func TestSaveMarkersValidation(t *testing.T) {
+	const secret = "markers-secret"
+	cfg := &apiConfig{jwtSecret: secret}
+	token, err := auth.MakeJWT(uuid.New(), secret, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "No markers", body: `{}`},
+		{name: "Unknown timeline", body: `{"mentions": {"last_read_id": "` + uuid.NewString() + `"}}`},
+		{name: "Invalid id", body: `{"home": {"last_read_id": "latest"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PUT", "/api/v1/users/me/markers", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			cfg.saveMarkersHandler(w, req)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400", w.Code)
+			}
+		})
+	}
+}
+
+func TestUpdateChirpValidation(t *testing.T) {
+	const secret = "update-secret"
+	cfg := &apiConfig{jwtSecret: secret}
+	cfg.runtime.Store(newRuntimeSettings(config.DefaultRuntime()))
+	token, err := auth.MakeJWT(uuid.New(), secret, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		token  string
+		body   string
+		status int
+	}{
+		{name: "No JWT", body: `{"body": "hello"}`, status: http.StatusUnauthorized},
+		{name: "Too long", token: token, body: `{"body": "` + strings.Repeat("a", 141) + `"}`, status: http.StatusUnprocessableEntity},
+		{name: "Bad JSON", token: token, body: `{"body": `, status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PUT", "/api/v1/chirps/x", strings.NewReader(tt.body))
+			req.SetPathValue("chirpID", uuid.NewString())
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			cfg.updateChirpHandler(w, req)
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestRedraftChirpRequests(t *testing.T) {
+	const secret = "redraft-secret"
+	cfg := &apiConfig{jwtSecret: secret}
+	token, err := auth.MakeJWT(uuid.New(), secret, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		token   string
+		chirpID string
+		status  int
+	}{
+		{name: "No JWT", chirpID: uuid.NewString(), status: http.StatusUnauthorized},
+		{name: "Bad JWT", token: "nope", chirpID: uuid.NewString(), status: http.StatusUnauthorized},
+		{name: "Invalid ID", token: token, chirpID: "not-a-chirp", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/chirps/x/redraft", nil)
+			req.SetPathValue("chirpID", tt.chirpID)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			cfg.redraftChirpHandler(w, req)
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestRestoreChirpNeedsReason(t *testing.T) {
+	cfg := &apiConfig{}
+	req := httptest.NewRequest("POST", "/admin/chirps/x/restore", strings.NewReader(`{"actor": "support"}`))
+	req.SetPathValue("chirpID", uuid.NewString())
+	w := httptest.NewRecorder()
+	cfg.restoreChirpHandler(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+}
+
+func TestCreateReplyNeedsValidParent(t *testing.T) {
+	const secret = "reply-secret"
+	cfg := &apiConfig{jwtSecret: secret}
+	cfg.runtime.Store(newRuntimeSettings(config.DefaultRuntime()))
+	token, err := auth.MakeJWT(uuid.New(), secret, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("POST", "/api/v1/chirps", strings.NewReader(`{"body": "me too", "parent_chirp_id": "not-a-chirp"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	cfg.createChirpHandler(w, req)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"field":"parent_chirp_id"`) {
+		t.Errorf("body = %s, want a parent_chirp_id violation", w.Body.String())
+	}
+}
+
+func TestLocationParams(t *testing.T) {
+	loc := ChirpLocation{PlaceName: " Berlin ", Latitude: 52.520008, Longitude: 13.404954}
+
+	place, lat, lng, err := locationParams(loc, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if place.String != "Berlin" || lat.Float64 != 52.5 || lng.Float64 != 13.4 {
+		t.Errorf("coarse location = %q %v %v, want Berlin 52.5 13.4", place.String, lat.Float64, lng.Float64)
+	}
+
+	_, lat, lng, err = locationParams(loc, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lat.Float64 != loc.Latitude || lng.Float64 != loc.Longitude {
+		t.Errorf("precise location = %v %v, want %v %v", lat.Float64, lng.Float64, loc.Latitude, loc.Longitude)
+	}
+
+	_, _, _, err = locationParams(ChirpLocation{Latitude: 91, Longitude: -181}, false)
+	var verr *validate.Error
+	if !errors.As(err, &verr) || len(verr.Violations) != 2 {
+		t.Errorf("err = %v, want two violations", err)
+	}
+}
+
+func TestDistanceKm(t *testing.T) {
+	// Berlin to Paris is about 878km.
+	got := distanceKm(52.52, 13.405, 48.8566, 2.3522)
+	if math.Abs(got-878) > 5 {
+		t.Errorf("distance = %.0fkm, want about 878km", got)
+	}
+	if got := distanceKm(10, 10, 10, 10); got != 0 {
+		t.Errorf("distance to self = %v, want 0", got)
+	}
+}
+
+func TestChirpReactionsNeedJWT(t *testing.T) {
+	cfg := &apiConfig{jwtSecret: "reactions-secret"}
+	handlers := map[string]http.HandlerFunc{
+		"POST like":      cfg.likeChirpHandler,
+		"DELETE like":    cfg.unlikeChirpHandler,
+		"POST rechirp":   cfg.createRechirpHandler,
+		"DELETE rechirp": cfg.deleteRechirpHandler,
+	}
+	for name, handler := range handlers {
+		t.Run(name, func(t *testing.T) {
+			method, action, _ := strings.Cut(name, " ")
+			req := httptest.NewRequest(method, "/api/v1/chirps/x/"+action, nil)
+			req.SetPathValue("chirpID", uuid.NewString())
+			req.Header.Set("Authorization", "Bearer not-a-jwt")
+			w := httptest.NewRecorder()
+			handler(w, req)
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("status = %d, want 401", w.Code)
+			}
+		})
+	}
+}
+
+func TestCreateBookmarkValidation(t *testing.T) {
+	const secret = "bookmark-secret"
+	cfg := &apiConfig{jwtSecret: secret}
+	token, err := auth.MakeJWT(uuid.New(), secret, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		token  string
+		body   string
+		status int
+	}{
+		{name: "No JWT", body: `{"chirp_id": "` + uuid.NewString() + `"}`, status: http.StatusUnauthorized},
+		{name: "Missing chirp", token: token, body: `{}`, status: http.StatusUnprocessableEntity},
+		{name: "Invalid chirp ID", token: token, body: `{"chirp_id": "nope"}`, status: http.StatusUnprocessableEntity},
+		{name: "Bad JSON", token: token, body: `{"chirp_id": `, status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/bookmarks", strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			cfg.createBookmarkHandler(w, req)
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestChirpStatus(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Hour)
+	tests := []struct {
+		name      string
+		status    string
+		publishAt *time.Time
+		want      string
+		wantErr   bool
+	}{
+		{name: "Default", want: chirpPublished},
+		{name: "Published", status: chirpPublished, want: chirpPublished},
+		{name: "Draft", status: chirpDraft, want: chirpDraft},
+		{name: "Scheduled", publishAt: &future, want: chirpScheduled},
+		{name: "Scheduled in the past", publishAt: &past, wantErr: true},
+		{name: "Scheduled now", publishAt: &now, wantErr: true},
+		{name: "Scheduled draft", status: chirpDraft, publishAt: &future, wantErr: true},
+		{name: "Status can't be set to scheduled", status: chirpScheduled, wantErr: true},
+		{name: "Unknown status", status: "archived", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, publishAt, err := chirpStatus(tt.status, tt.publishAt, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("chirpStatus() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("chirpStatus() = %q, want %q", got, tt.want)
+			}
+			if publishAt.Valid != (got == chirpScheduled) {
+				t.Errorf("publish_at = %v for a %s chirp", publishAt, got)
+			}
+		})
+	}
+}
+
+func TestMergeUsersValidation(t *testing.T) {
+	cfg := &apiConfig{}
+	from := uuid.NewString()
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "Missing target", body: `{"reason": "duplicate signup"}`},
+		{name: "Into itself", body: `{"into": "` + from + `", "reason": "duplicate signup"}`},
+		{name: "Missing reason", body: `{"into": "` + uuid.NewString() + `"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/admin/users/x/merge", strings.NewReader(tt.body))
+			req.SetPathValue("userID", from)
+			w := httptest.NewRecorder()
+			cfg.mergeUsersHandler(w, req)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400", w.Code)
+			}
+		})
+	}
+}
+
+func TestFollowRequests(t *testing.T) {
+	const secret = "follow-secret"
+	cfg := &apiConfig{jwtSecret: secret}
+	userId := uuid.New()
+	token, err := auth.MakeJWT(userId, secret, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		token   string
+		target  string
+		status  int
+	}{
+		{name: "Follow without JWT", handler: cfg.followUserHandler, target: uuid.NewString(), status: http.StatusUnauthorized},
+		{name: "Unfollow without JWT", handler: cfg.unfollowUserHandler, target: uuid.NewString(), status: http.StatusUnauthorized},
+		{name: "Follow yourself", handler: cfg.followUserHandler, token: token, target: userId.String(), status: http.StatusBadRequest},
+		{name: "Invalid user", handler: cfg.followUserHandler, token: token, target: "nobody", status: http.StatusNotFound},
+		{name: "Followers of invalid user", handler: cfg.getFollowersHandler, target: "nobody", status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/users/x/follow", nil)
+			req.SetPathValue("userID", tt.target)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			tt.handler(w, req)
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestHandle(t *testing.T) {
+	user := database.User{ID: uuid.New()}
+	cfg := &apiConfig{}
+	if got := cfg.handle(user); got != user.ID.String() {
+		t.Errorf("handle() = %q without public IDs, want the UUID", got)
+	}
+	codec, err := publicid.New("handle-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.publicIDs = codec
+	if got := cfg.handle(user); got != codec.Encode(user.ID) {
+		t.Errorf("handle() = %q, want the public ID %q", got, codec.Encode(user.ID))
+	}
+	user.Username = sql.NullString{String: "chirper", Valid: true}
+	if got := cfg.handle(user); got != "chirper" {
+		t.Errorf("handle() = %q, want the username", got)
+	}
+}
+
+func TestPublicIDsHideUUIDs(t *testing.T) {
+	codec, err := publicid.New("expose-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := newFakeDB()
+	cfg := newTestConfig(db)
+	cfg.publicIDs = codec
+	user := database.User{ID: uuid.New()}
+	db.returning("GetUser", user)
+	parent := uuid.New()
+	chirp := database.Chirp{ID: uuid.New(), UserID: user.ID, ParentChirpID: uuid.NullUUID{UUID: parent, Valid: true}}
+	for name, v := range map[string]any{
+		"User":   cfg.newUser(user),
+		"Follow": cfg.newFollow(user.ID, time.Now()),
+		"Chirp":  cfg.newChirp(context.Background(), chirp),
+	} {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range []uuid.UUID{user.ID, chirp.ID, parent} {
+			if bytes.Contains(b, []byte(id.String())) {
+				t.Errorf("%s JSON %s contains the UUID %s", name, b, id)
+			}
+		}
+	}
+	if got := cfg.newChirp(context.Background(), chirp).UserId; got != codec.Encode(user.ID) {
+		t.Errorf("user_id = %q, want the public ID", got)
+	}
+}
+
+func TestValidateUsername(t *testing.T) {
+	tests := []struct {
+		username string
+		wantErr  bool
+	}{
+		{username: "", wantErr: false},
+		{username: "chirper_42", wantErr: false},
+		{username: "abc", wantErr: false},
+		{username: "ab", wantErr: true},
+		{username: "abcdefghijklmnop", wantErr: true},
+		{username: "chirp-er", wantErr: true},
+		{username: "chirpér", wantErr: true},
+	}
+	for _, tt := range tests {
+		err := validateCredentials("user@example.com", "hunter2hunter2", tt.username)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("validateCredentials(username %q) error = %v, wantErr %v", tt.username, err, tt.wantErr)
+		}
+	}
+	if got := normalizeUsername("  Chirper "); got != "chirper" {
+		t.Errorf("normalizeUsername() = %q, want %q", got, "chirper")
+	}
+}
+
+func TestProfileParams(t *testing.T) {
+	str := func(s string) *string { return &s }
+	tests := []struct {
+		name        string
+		displayName *string
+		bio         *string
+		avatarURL   *string
+		wantErr     bool
+	}{
+		{name: "Nothing set"},
+		{name: "All set", displayName: str(" Chirper "), bio: str("Hello"), avatarURL: str("https://example.com/a.png")},
+		{name: "Cleared", displayName: str(""), bio: str(""), avatarURL: str("")},
+		{name: "Bio too long", bio: str(strings.Repeat("a", maxBioLength+1)), wantErr: true},
+		{name: "Display name too long", displayName: str(strings.Repeat("a", maxDisplayNameLength+1)), wantErr: true},
+		{name: "Avatar over http", avatarURL: str("http://example.com/a.png"), wantErr: true},
+		{name: "Avatar not a URL", avatarURL: str("javascript:alert(1)"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, bio, avatar, err := profileParams(tt.displayName, tt.bio, tt.avatarURL)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("profileParams() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if name.Valid != (tt.displayName != nil) || bio.Valid != (tt.bio != nil) || avatar.Valid != (tt.avatarURL != nil) {
+				t.Errorf("profileParams() = %v, %v, %v, want only the sent fields set", name, bio, avatar)
+			}
+			if tt.displayName != nil && name.String != strings.TrimSpace(*tt.displayName) {
+				t.Errorf("display name = %q, want it trimmed", name.String)
+			}
+		})
+	}
+}
+
+func TestGetUserProfileInvalidID(t *testing.T) {
+	cfg := &apiConfig{}
+	req := httptest.NewRequest("GET", "/api/v1/users/nobody", nil)
+	req.SetPathValue("userID", "nobody")
+	w := httptest.NewRecorder()
+	cfg.getUserProfileHandler(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestDeleteUserRequests(t *testing.T) {
+	const secret = "delete-secret"
+	cfg := &apiConfig{jwtSecret: secret}
+	token, err := auth.MakeJWT(uuid.New(), secret, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		token  string
+		body   string
+		status int
+	}{
+		{name: "Without JWT", body: `{"password":"hunter2"}`, status: http.StatusUnauthorized},
+		{name: "Invalid JWT", token: "nope", body: `{"password":"hunter2"}`, status: http.StatusUnauthorized},
+		{name: "Invalid body", token: token, body: `{"password":`, status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("DELETE", "/api/v1/users", strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			cfg.deleteUserHandler(w, req)
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestRefreshWithoutToken(t *testing.T) {
+	cfg := &apiConfig{}
+	req := httptest.NewRequest("POST", "/api/v1/refresh", nil)
+	w := httptest.NewRecorder()
+	cfg.refreshHandler(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestRevokeAllRequiresJWT(t *testing.T) {
+	cfg := &apiConfig{jwtSecret: "revoke-secret"}
+	for name, header := range map[string]string{
+		"Without JWT": "",
+		"Invalid JWT": "Bearer nope",
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/revoke-all", nil)
+			if header != "" {
+				req.Header.Set("Authorization", header)
+			}
+			w := httptest.NewRecorder()
+			cfg.revokeAllHandler(w, req)
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+			}
+		})
+	}
+}
+
+func TestAdminActor(t *testing.T) {
+	cfg := &apiConfig{adminKeys: map[string]string{"admin": "admin-key", "alice": "alice-key"}}
+	var actor string
+	handler := cfg.middlewareAdminOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor = adminActor(r)
+	}))
+	for key, want := range map[string]string{"admin-key": "admin", "alice-key": "alice"} {
+		actor = ""
+		req := httptest.NewRequest("POST", "/admin/chirps/x/restore", nil)
+		req.Header.Set("Authorization", "ApiKey "+key)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		if actor != want {
+			t.Errorf("adminActor() with %s = %q, want %q", key, actor, want)
+		}
+	}
+
+	req := httptest.NewRequest("POST", "/admin/chirps/x/restore", nil)
+	req.Header.Set("Authorization", "ApiKey nope")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status with an unknown key = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestAccessPolicyDeny(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy accessPolicy
+		status int
+		msg    string
+	}{
+		{name: "Chirps", policy: chirpPolicy, status: http.StatusForbidden, msg: "You can't edit this chirp"},
+		{name: "Drafts", policy: draftPolicy, status: http.StatusNotFound, msg: "Draft not found"},
+		{name: "Users", policy: userPolicy, status: http.StatusNotFound, msg: "Couldn't find user"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.policy.deny(w, "You can't edit this chirp", nil)
+			if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.msg) {
+				t.Errorf("deny() = %d %s, want %d with %q", w.Code, w.Body.String(), tt.status, tt.msg)
+			}
+		})
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		values []string
+		want   string
+	}{
+		{name: "No header configured", values: []string{"203.0.113.7"}, want: "192.0.2.1"},
+		{name: "Single value", header: "CF-Connecting-IP", values: []string{"203.0.113.7"}, want: "203.0.113.7"},
+		{name: "Last forwarded entry", header: "X-Forwarded-For", values: []string{"198.51.100.1, 203.0.113.7"}, want: "203.0.113.7"},
+		{name: "Repeated header", header: "X-Forwarded-For", values: []string{"198.51.100.1", "203.0.113.7"}, want: "203.0.113.7"},
+		{name: "Not an address", header: "X-Forwarded-For", values: []string{"spoofed"}, want: "192.0.2.1"},
+		{name: "Missing", header: "X-Forwarded-For", want: "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &apiConfig{clientIPHeader: tt.header}
+			req := httptest.NewRequest("GET", "/api/v1/healthz", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			for _, v := range tt.values {
+				req.Header.Add("X-Forwarded-For", v)
+				req.Header.Add("CF-Connecting-IP", v)
+			}
+			if got := cfg.clientIP(req); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMiddlewareSessions(t *testing.T) {
+	cfg := &apiConfig{jwtSecret: "secret", userCache: cache.New[uuid.UUID, database.User](10, time.Hour)}
+	ended := database.User{ID: uuid.New(), SessionsValidAfter: sql.NullTime{Time: time.Now().Add(time.Minute), Valid: true}}
+	current := database.User{ID: uuid.New(), SessionsValidAfter: sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true}}
+	cfg.userCache.Put(ended.ID, ended)
+	cfg.userCache.Put(current.ID, current)
+
+	handler := cfg.middlewareSessions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func(userID *uuid.UUID) int {
+		req := httptest.NewRequest("GET", "/api/v1/users/me", nil)
+		if userID != nil {
+			token, err := auth.MakeJWT(*userID, "secret", time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if got := serve(&ended.ID); got != http.StatusUnauthorized {
+		t.Errorf("token issued before sessions ended got %d, want %d", got, http.StatusUnauthorized)
+	}
+	if got := serve(&current.ID); got != http.StatusOK {
+		t.Errorf("token issued after sessions ended got %d, want %d", got, http.StatusOK)
+	}
+	if got := serve(nil); got != http.StatusOK {
+		t.Errorf("request without a token got %d, want %d", got, http.StatusOK)
+	}
+}
+
+func TestPasskeyRequests(t *testing.T) {
+	const secret = "passkey-secret"
+	enabled := &apiConfig{jwtSecret: secret, passkeys: newRelyingParty("chirpy.example", []string{"https://chirpy.example"})}
+	disabled := &apiConfig{jwtSecret: secret}
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		status  int
+	}{
+		{name: "Login when disabled", handler: disabled.beginPasskeyLoginHandler, status: http.StatusNotImplemented},
+		{name: "Register when disabled", handler: disabled.beginPasskeyRegistrationHandler, status: http.StatusNotImplemented},
+		{name: "Register without JWT", handler: enabled.beginPasskeyRegistrationHandler, status: http.StatusUnauthorized},
+		{name: "Finish without JWT", handler: enabled.finishPasskeyRegistrationHandler, status: http.StatusUnauthorized},
+		{name: "List without JWT", handler: enabled.getPasskeysHandler, status: http.StatusUnauthorized},
+		{name: "Finish login with invalid body", handler: enabled.finishPasskeyLoginHandler, status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/login/passkey/finish", strings.NewReader(`{"credential":`))
+			w := httptest.NewRecorder()
+			tt.handler(w, req)
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+	if newRelyingParty("", nil) != nil {
+		t.Error("newRelyingParty() without an ID should turn passkeys off")
+	}
+}
+
+func TestPasskeyRegistrationNeedsPassword(t *testing.T) {
+	const secret = "passkey-secret"
+	hash, err := auth.HashPassword("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := database.User{ID: uuid.New(), HashedPassword: hash}
+	cfg := &apiConfig{
+		jwtSecret: secret,
+		passkeys:  newRelyingParty("chirpy.example", []string{"https://chirpy.example"}),
+		userCache: cache.New[uuid.UUID, database.User](10, time.Hour),
+	}
+	cfg.userCache.Put(user.ID, user)
+	token, err := auth.MakeJWT(user.ID, secret, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, body := range map[string]string{
+		"No password":    `{}`,
+		"Wrong password": `{"password":"hunter3"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/users/me/passkeys/begin", strings.NewReader(body))
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			cfg.beginPasskeyRegistrationHandler(w, req)
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+			}
+		})
+	}
+}
+
+func TestCheckCanPost(t *testing.T) {
+	cfg := &apiConfig{
+		publicURL: "https://chirpy.example",
+		userCache: cache.New[uuid.UUID, database.User](10, time.Hour),
+	}
+	verified := sql.NullTime{Time: time.Now(), Valid: true}
+	tests := []struct {
+		name   string
+		user   database.User
+		want   bool
+		status int
+	}{
+		{name: "Verified", user: database.User{ID: uuid.New(), EmailVerifiedAt: verified}, want: true, status: http.StatusOK},
+		{name: "Unverified", user: database.User{ID: uuid.New()}, status: http.StatusForbidden},
+		{name: "Moved", user: database.User{ID: uuid.New(), EmailVerifiedAt: verified, MovedTo: sql.NullString{String: "me@example.com", Valid: true}}, status: http.StatusForbidden},
+		{name: "Deactivated", user: database.User{ID: uuid.New(), EmailVerifiedAt: verified, DeactivatedAt: sql.NullTime{Time: time.Now(), Valid: true}}, status: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.userCache.Put(tt.user.ID, tt.user)
+			w := httptest.NewRecorder()
+			got := cfg.checkCanPost(w, httptest.NewRequest("POST", "/api/v1/chirps", nil), tt.user.ID)
+			if got != tt.want || w.Code != tt.status {
+				t.Errorf("checkCanPost() = %v with status %d, want %v with %d", got, w.Code, tt.want, tt.status)
+			}
+		})
+	}
+
+	// Without PUBLIC_URL nobody can verify, so it isn't required.
+	cfg.publicURL = ""
+	unverified := database.User{ID: uuid.New()}
+	cfg.userCache.Put(unverified.ID, unverified)
+	if !cfg.checkCanPost(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/v1/chirps", nil), unverified.ID) {
+		t.Error("checkCanPost() refused an unverified user with email verification off")
+	}
+}
+
+func TestCheckActive(t *testing.T) {
+	cfg := &apiConfig{userCache: cache.New[uuid.UUID, database.User](10, time.Hour)}
+	active := database.User{ID: uuid.New()}
+	deactivated := database.User{ID: uuid.New(), DeactivatedAt: sql.NullTime{Time: time.Now(), Valid: true}}
+	cfg.userCache.Put(active.ID, active)
+	cfg.userCache.Put(deactivated.ID, deactivated)
+
+	req := httptest.NewRequest("POST", "/api/v1/chirps/x/like", nil)
+	if !cfg.checkActive(httptest.NewRecorder(), req, active.ID) {
+		t.Error("checkActive() refused an active user")
+	}
+	w := httptest.NewRecorder()
+	if cfg.checkActive(w, req, deactivated.ID) || w.Code != http.StatusForbidden {
+		t.Errorf("checkActive() allowed a deactivated user, status %d", w.Code)
+	}
+}
+
+func TestVerificationLink(t *testing.T) {
+	want := "https://chirpy.example/app/verify.html?token=abc%2B1"
+	if got := verificationLink("https://chirpy.example", "abc+1"); got != want {
+		t.Errorf("verificationLink() = %q, want %q", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(filepathRoot, strings.TrimPrefix(verificationPage, "/app/"))); err != nil {
+		t.Errorf("verification page isn't served: %v", err)
+	}
+}
+
+func TestResendVerificationLimit(t *testing.T) {
+	const secret = "resend-secret"
+	userId := uuid.New()
+	token, err := auth.MakeJWT(userId, secret, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resend := func(cfg *apiConfig) int {
+		req := httptest.NewRequest("POST", "/api/v1/users/me/verification", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		cfg.resendVerificationHandler(w, req)
+		return w.Code
+	}
+
+	if code := resend(&apiConfig{jwtSecret: secret}); code != http.StatusNotImplemented {
+		t.Errorf("status without PUBLIC_URL = %d, want %d", code, http.StatusNotImplemented)
+	}
+
+	verified := database.User{ID: userId, EmailVerifiedAt: sql.NullTime{Time: time.Now(), Valid: true}}
+	cfg := &apiConfig{
+		jwtSecret:           secret,
+		publicURL:           "https://chirpy.example",
+		userCache:           cache.New[uuid.UUID, database.User](10, time.Hour),
+		verificationLimiter: ratelimit.New(2, time.Hour),
+	}
+	cfg.userCache.Put(userId, verified)
+	for i, want := range []int{http.StatusBadRequest, http.StatusBadRequest, http.StatusTooManyRequests} {
+		if code := resend(cfg); code != want {
+			t.Errorf("request %d: status = %d, want %d", i+1, code, want)
+		}
+	}
+}
+
+func TestBlockRequests(t *testing.T) {
+	const secret = "block-secret"
+	cfg := &apiConfig{jwtSecret: secret}
+	userId := uuid.New()
+	token, err := auth.MakeJWT(userId, secret, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		token   string
+		target  string
+		status  int
+	}{
+		{name: "Block without JWT", handler: cfg.blockUserHandler, target: uuid.NewString(), status: http.StatusUnauthorized},
+		{name: "Unmute without JWT", handler: cfg.unmuteUserHandler, target: uuid.NewString(), status: http.StatusUnauthorized},
+		{name: "Block yourself", handler: cfg.blockUserHandler, token: token, target: userId.String(), status: http.StatusBadRequest},
+		{name: "Mute yourself", handler: cfg.muteUserHandler, token: token, target: userId.String(), status: http.StatusBadRequest},
+		{name: "Invalid user", handler: cfg.muteUserHandler, token: token, target: "nobody", status: http.StatusNotFound},
+		{name: "Blocks without JWT", handler: cfg.getBlockedUsersHandler, status: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/users/x/block", nil)
+			req.SetPathValue("userID", tt.target)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			tt.handler(w, req)
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestViewerID(t *testing.T) {
+	const secret = "viewer-secret"
+	cfg := &apiConfig{jwtSecret: secret}
+	userId := uuid.New()
+	token, err := auth.MakeJWT(userId, secret, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		header string
+		want   uuid.NullUUID
+	}{
+		{name: "Signed out"},
+		{name: "Signed in", header: "Bearer " + token, want: uuid.NullUUID{UUID: userId, Valid: true}},
+		{name: "Invalid token", header: "Bearer nope"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/chirps", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if got := cfg.viewerID(req); got != tt.want {
+				t.Errorf("viewerID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetFeedInvalidQuery(t *testing.T) {
+	const secret = "feed-secret"
+	cfg := &apiConfig{jwtSecret: secret}
+	token, err := auth.MakeJWT(uuid.New(), secret, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		token  string
+		query  string
+		status int
+	}{
+		{name: "No JWT", status: http.StatusUnauthorized},
+		{name: "Invalid limit", token: token, query: "?limit=0", status: http.StatusBadRequest},
+		{name: "Invalid before", token: token, query: "?before=yesterday", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/feed"+tt.query, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			cfg.getFeedHandler(w, req)
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestAdminSearchInvalidQuery(t *testing.T) {
+	cfg := &apiConfig{}
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{name: "Invalid limit", query: "?limit=-1"},
+		{name: "Invalid since", query: "?since=last+week"},
+		{name: "Invalid until", query: "?until=2024-13-01"},
+		{name: "Empty range", query: "?since=2024-05-02&until=2024-05-01"},
+		{name: "Invalid reported", query: "?reported=closed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/admin/search"+tt.query, nil)
+			w := httptest.NewRecorder()
+			cfg.adminSearchHandler(w, req)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func TestParseSearchTime(t *testing.T) {
+	tests := []struct {
+		in       string
+		endOfDay bool
+		want     time.Time
+	}{
+		{in: "2024-05-01", want: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{in: "2024-05-01", endOfDay: true, want: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)},
+		{in: "2024-05-01T12:00:00+02:00", endOfDay: true, want: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := parseSearchTime(tt.in, tt.endOfDay)
+		if err != nil {
+			t.Errorf("parseSearchTime(%q) failed: %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseSearchTime(%q, %v) = %v, want %v", tt.in, tt.endOfDay, got, tt.want)
+		}
+	}
+}
+
+func TestRespondWithCSV(t *testing.T) {
+	w := httptest.NewRecorder()
+	respondWithCSV(w, "export.csv", []string{"id", "body"}, [][]string{
+		{"1", "hello, world"},
+		{"2", "=HYPERLINK(\"https://example.com\")"},
+		{"3", "line\nbreak"},
+	})
+	if got := w.Header().Get("Content-Disposition"); got != `attachment; filename="export.csv"` {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+	want := "id,body\n1,\"hello, world\"\n2,\"'=HYPERLINK(\"\"https://example.com\"\")\"\n3,\"line\nbreak\"\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestWriteOPML(t *testing.T) {
+	var b strings.Builder
+	err := writeOPML(&b, opmlDocument{
+		Title:   "Chirpy following",
+		Outline: []opmlOutline{{Type: "link", Text: "a&b", URL: "https://chirpy.example/api/v1/chirps?author_id=x&sort=desc"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<opml version="2.0">`,
+		`<title>Chirpy following</title>`,
+		`text="a&amp;b" url="https://chirpy.example/api/v1/chirps?author_id=x&amp;sort=desc"`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("OPML is missing %s:\n%s", want, b.String())
+		}
+	}
+}
+
+func TestNormalizeMoveTarget(t *testing.T) {
+	tests := []struct {
+		target string
+		want   string
+		ok     bool
+	}{
+		{target: "alice@Example.COM", want: "alice@example.com", ok: true},
+		{target: " @alice@example.com ", want: "alice@example.com", ok: true},
+		{target: "https://example.com/@alice", want: "https://example.com/@alice", ok: true},
+		{target: "http://example.com/@alice"},
+		{target: "https://user:pw@example.com/@alice"},
+		{target: "alice"},
+		{target: "alice@localhost"},
+		{target: "al ice@example.com"},
+	}
+	for _, tt := range tests {
+		got, ok := normalizeMoveTarget(tt.target)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("normalizeMoveTarget(%q) = %q, %v, want %q, %v", tt.target, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestExtractHashtags(t *testing.T) {
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{body: "no tags here", want: []string{}},
+		{body: "#Go is fun #golang", want: []string{"go", "golang"}},
+		{body: "#go #GO #Go", want: []string{"go"}},
+		{body: "C# and a#b aren't tags", want: []string{}},
+		{body: "(#paren) #under_score #über", want: []string{"paren", "under_score", "über"}},
+		{body: "#1st #2024 #a1", want: []string{"a1"}},
+		{body: "#" + strings.Repeat("a", 101), want: []string{}},
+	}
+	for _, tt := range tests {
+		got := extractHashtags(tt.body)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("extractHashtags(%q) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestExtractMentions(t *testing.T) {
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{body: "no mentions here", want: []string{}},
+		{body: "hi @Alice and @bob_2", want: []string{"alice", "bob_2"}},
+		{body: "@bob @BOB", want: []string{"bob"}},
+		{body: "mail me at me@example.com", want: []string{}},
+		{body: "@ab is too short", want: []string{}},
+		{body: "@" + strings.Repeat("a", 16), want: []string{}},
+	}
+	for _, tt := range tests {
+		got := extractMentions(tt.body)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("extractMentions(%q) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestFormatChirpBody(t *testing.T) {
+	cfg := &apiConfig{}
+	cfg.runtime.Store(newRuntimeSettings(config.DefaultRuntime()))
+
+	got, err := cfg.formatChirpBody("What a **kerfuffle**")
+	if err != nil {
+		t.Fatalf("formatChirpBody() error = %v", err)
+	}
+	if !got.Masked || strings.Contains(got.Body, "kerfuffle") {
+		t.Errorf("formatChirpBody() = %+v, want the banned word masked", got)
+	}
+	if got.BodyHTML != markdown.Render(got.NormalizedBody) {
+		t.Errorf("formatChirpBody() rendered %q, want the normalized body rendered", got.BodyHTML)
+	}
+
+	plain, err := cfg.formatChirpBody("hello")
+	if err != nil || plain.Masked {
+		t.Errorf("formatChirpBody(\"hello\") = %+v, %v, want it unmasked", plain, err)
+	}
+}
+
+func TestUserHash(t *testing.T) {
+	userID := uuid.New()
+	token, err := auth.MakeJWT(userID, "secret", time.Minute)
+	if err != nil {
+		t.Fatalf("MakeJWT() error = %v", err)
+	}
+	req := httptest.NewRequest("GET", "/api/v1/chirps", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	cfg := &apiConfig{jwtSecret: "secret"}
+	hash := cfg.userHash(req)
+	if hash == "" || hash != cfg.userHash(req) {
+		t.Fatalf("userHash() = %q, want a stable hash", hash)
+	}
+	sum := sha256.Sum256(userID[:])
+	if hash == hex.EncodeToString(sum[:8]) {
+		t.Error("userHash() is a plain hash of the user ID, want it keyed")
+	}
+	if got := cfg.userHash(httptest.NewRequest("GET", "/api/v1/chirps", nil)); got != "" {
+		t.Errorf("userHash() without a token = %q, want empty", got)
+	}
+}
+
+func TestMiddlewareRecord(t *testing.T) {
+	cfg := &apiConfig{platform: "dev", recorder: newRecorder("")}
+	handler := cfg.middlewareRecord(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		respondWithJSON(w, http.StatusCreated, map[string]string{"echo": string(body), "token": "jwt"})
+	}))
+	send := func() {
+		req := httptest.NewRequest("POST", "/api/v1/login", strings.NewReader(`{"email":"a@example.com","password":"hunter2"}`))
+		req.Header.Set("Authorization", "Bearer secret-token")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	send()
+	if got := cfg.recorder.list(); len(got) != 0 {
+		t.Fatalf("recorded %d requests while switched off", len(got))
+	}
+
+	cfg.recorder.enabled.Store(true)
+	send()
+	got := cfg.recorder.list()
+	if len(got) != 1 {
+		t.Fatalf("recorded %d requests, want 1", len(got))
+	}
+	entry := got[0]
+	if entry.Status != http.StatusCreated {
+		t.Errorf("status = %d, want 201", entry.Status)
+	}
+	if entry.RequestHeaders.Get("Authorization") != "[redacted]" {
+		t.Errorf("Authorization = %q, want it redacted", entry.RequestHeaders.Get("Authorization"))
+	}
+	if strings.Contains(entry.RequestBody, "hunter2") || !strings.Contains(entry.RequestBody, "a@example.com") {
+		t.Errorf("request body = %q, want only the password redacted", entry.RequestBody)
+	}
+	if !strings.Contains(entry.ResponseBody, "hunter2") {
+		// The handler still saw the whole body.
+		t.Errorf("response body = %q, handler didn't get the request body", entry.ResponseBody)
+	}
+	if strings.Contains(entry.ResponseBody, `"jwt"`) {
+		t.Errorf("response body = %q, want the token redacted", entry.ResponseBody)
+	}
+
+	cfg.platform = "prod"
+	send()
+	if got := cfg.recorder.list(); len(got) != 1 {
+		t.Errorf("recorded %d requests, want nothing new outside dev", len(got))
+	}
+}
+
+func TestRecorderRingBuffer(t *testing.T) {
+	rec := newRecorder("")
+	for i := range recordingBufferSize + 5 {
+		rec.add(recording{Status: i})
+	}
+	got := rec.list()
+	if len(got) != recordingBufferSize {
+		t.Fatalf("list() has %d entries, want %d", len(got), recordingBufferSize)
+	}
+	if got[0].Status != recordingBufferSize+4 || got[len(got)-1].Status != 5 {
+		t.Errorf("list() runs from %d to %d, want newest first", got[0].Status, got[len(got)-1].Status)
+	}
+}
+
+func TestRollout(t *testing.T) {
+	const secret = "rollout-secret"
+	listed := uuid.New()
+	// Find a user who falls outside a 50% rollout.
+	outside := uuid.New()
+	for rolloutBucket("chirps.cursor", outside) < 50 {
+		outside = uuid.New()
+	}
+
+	cfg := &apiConfig{jwtSecret: secret, rolloutMetrics: newRolloutMetrics()}
+	rt := config.DefaultRuntime()
+	rt.Rollouts = map[string]config.Rollout{
+		"chirps.cursor": {Percent: 50, UserIDs: []uuid.UUID{listed}},
+	}
+	cfg.runtime.Store(newRuntimeSettings(rt))
+
+	handler := cfg.rollout("chirps.cursor",
+		func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(variantCandidate)) },
+		func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(variantCurrent)) },
+	)
+	serve := func(userID *uuid.UUID) string {
+		req := httptest.NewRequest("GET", "/api/v1/chirps", nil)
+		if userID != nil {
+			token, err := auth.MakeJWT(*userID, secret, time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w.Body.String()
+	}
+
+	if got := serve(&listed); got != variantCandidate {
+		t.Errorf("listed user got %s, want %s", got, variantCandidate)
+	}
+	if got := serve(&outside); got != variantCurrent {
+		t.Errorf("user outside the percentage got %s, want %s", got, variantCurrent)
+	}
+	if got := serve(nil); got != variantCurrent {
+		t.Errorf("anonymous request got %s, want %s", got, variantCurrent)
+	}
+
+	stats := cfg.rolloutMetrics.stats()["chirps.cursor"]
+	if stats[variantCandidate].Requests != 1 || stats[variantCurrent].Requests != 2 {
+		t.Errorf("stats = %+v, want 1 candidate and 2 current requests", stats)
+	}
+}
+
+func TestChirpCursor(t *testing.T) {
+	chirp := database.Chirp{ID: uuid.New(), CreatedAt: time.Date(2026, 3, 1, 12, 30, 0, 123456000, time.UTC)}
+	createdAt, id, err := decodeChirpCursor(encodeChirpCursor(chirp))
+	if err != nil {
+		t.Fatalf("decodeChirpCursor() error = %v", err)
+	}
+	if !createdAt.Equal(chirp.CreatedAt) || id != chirp.ID {
+		t.Errorf("decodeChirpCursor() = %v, %v, want %v, %v", createdAt, id, chirp.CreatedAt, chirp.ID)
+	}
+
+	for _, invalid := range []string{"not base64!", base64.RawURLEncoding.EncodeToString([]byte("123")), base64.RawURLEncoding.EncodeToString([]byte("x." + chirp.ID.String()))} {
+		if _, _, err := decodeChirpCursor(invalid); err == nil {
+			t.Errorf("decodeChirpCursor(%q) succeeded, want an error", invalid)
+		}
+	}
+}
+
+func TestValidateChirpKeepsWhitespace(t *testing.T) {
+	badWords := newRuntimeSettings(config.DefaultRuntime()).badWords
+	tests := []struct {
+		body string
+		want string
+	}{
+		{body: "hello world", want: "hello world"},
+		{body: "first line\nkerfuffle\n\nthird", want: "first line\n****\n\nthird"},
+		{body: "  indented\tFornax  ", want: "  indented\t****  "},
+		{body: "sharbert\r\nbye", want: "****\r\nbye"},
+		{body: "", want: ""},
+	}
+	for _, tt := range tests {
+		got, err := validateChirp(tt.body, badWords)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("validateChirp(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeBody(t *testing.T) {
+	defaults := config.DefaultRuntime().Normalization
+	tests := []struct {
+		name  string
+		body  string
+		rules config.Normalization
+		want  string
+	}{
+		{name: "Line endings", body: "one\r\ntwo\rthree", want: "one\ntwo\nthree"},
+		{name: "Defaults", body: "\n  hi   there \n\n\n\nbye\t\n\n", rules: defaults, want: "hi there\n\nbye"},
+		{name: "No blank lines", body: "a\n\n\nb", rules: config.Normalization{}, want: "a\nb"},
+		{name: "Unlimited blank lines", body: "a\n\n\nb", rules: config.Normalization{MaxBlankLines: -1}, want: "a\n\n\nb"},
+		{name: "Spaces kept", body: "a  b ", rules: config.Normalization{MaxBlankLines: -1}, want: "a  b "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeBody(tt.body, tt.rules); got != tt.want {
+				t.Errorf("normalizeBody(%q) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllowedWordsOverrideBannedWords(t *testing.T) {
+	settings := newRuntimeSettings(config.DefaultRuntime())
+
+	allowed := settings.withAllowedWords([]string{"For'nax"})
+	cleaned, err := validateChirp("I saw for'nax and a fornax", allowed.badWords)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "I saw for'nax and a ****"; cleaned != want {
+		t.Errorf("validateChirp() = %q, want %q", cleaned, want)
+	}
+
+	// Removing the word from the allowlist masks it again.
+	banned := allowed.withAllowedWords(nil)
+	cleaned, err = validateChirp("for'nax", banned.badWords)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cleaned != wordfilter.Mask {
+		t.Errorf("validateChirp() = %q after leaving the allowlist, want %q", cleaned, wordfilter.Mask)
+	}
+}
+
+func TestChirpCreatedEventUsesUUIDs(t *testing.T) {
+	chirp := database.Chirp{
+		ID:            uuid.New(),
+		UserID:        uuid.New(),
+		ParentChirpID: uuid.NullUUID{UUID: uuid.New(), Valid: true},
+		Body:          "hello",
+	}
+	data, err := json.Marshal(newChirpCreatedEvent(chirp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	err = json.Unmarshal(data, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"id":              chirp.ID.String(),
+		"user_id":         chirp.UserID.String(),
+		"parent_chirp_id": chirp.ParentChirpID.UUID.String(),
+	}
+	for key, id := range want {
+		if got[key] != id {
+			t.Errorf("%s = %v, want %s", key, got[key], id)
+		}
+	}
+}
+
+const testJWTSecret = "test-secret"
+
+// newTestConfig returns an apiConfig for handler tests whose queries are
+// answered by db.
+func newTestConfig(db *fakeDB) *apiConfig {
+	cfg := &apiConfig{
+		jwtSecret:  testJWTSecret,
+		chirpCache: cache.New[uuid.UUID, database.Chirp](100, time.Hour),
+		userCache:  cache.New[uuid.UUID, database.User](100, time.Hour),
+	}
+	cfg.db = sql.OpenDB(db)
+	cfg.dbQueries = database.New(cfg.db)
+	cfg.runtime.Store(newRuntimeSettings(config.DefaultRuntime()))
+	return cfg
+}
+
+// fakeDB is a database/sql connector for handler tests. Each query is
+// answered by the function registered under its sqlc name; any other query
+// fails, so a test notices when a handler needs more than it expected.
+type fakeDB struct {
+	mu      sync.Mutex
+	queries map[string]fakeQuery
+	ran     map[string]int
+}
+
+// fakeQuery answers a query with rows of column values, as fakeRows
+// builds them, or with the number of rows an :exec query changed.
+type fakeQuery func(args []driver.Value) (fakeResult, error)
+
+type fakeResult struct {
+	rows     [][]driver.Value
+	affected int64
+}
+
+func newFakeDB() *fakeDB {
+	return &fakeDB{queries: map[string]fakeQuery{}, ran: map[string]int{}}
+}
+
+func (db *fakeDB) handle(name string, q fakeQuery) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.queries[name] = q
+}
+
+// returning answers the query named name with items, whatever its
+// arguments.
+func (db *fakeDB) returning(name string, items ...any) {
+	db.handle(name, func([]driver.Value) (fakeResult, error) {
+		return fakeRows(items...), nil
+	})
+}
+
+// ranQuery reports how often the query named name ran.
+func (db *fakeDB) ranQuery(name string) int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.ran[name]
+}
+
+func (db *fakeDB) run(query string, args []driver.NamedValue) (fakeResult, error) {
+	name, _, _ := strings.Cut(strings.TrimPrefix(query, "-- name: "), " ")
+	db.mu.Lock()
+	q, ok := db.queries[name]
+	db.ran[name]++
+	db.mu.Unlock()
+	if !ok {
+		return fakeResult{}, fmt.Errorf("fakedb: unexpected query %s", name)
+	}
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return q(values)
+}
+
+// fakeRows turns sqlc structs into rows: sqlc scans columns in the order of
+// the struct fields. Anything else, time.Time included, is a single column.
+func fakeRows(items ...any) fakeResult {
+	res := fakeResult{affected: int64(len(items))}
+	for _, item := range items {
+		v := reflect.ValueOf(item)
+		if v.Kind() != reflect.Struct || driver.IsValue(item) {
+			value, err := driver.DefaultParameterConverter.ConvertValue(item)
+			if err != nil {
+				panic(err)
+			}
+			res.rows = append(res.rows, []driver.Value{value})
+			continue
+		}
+		row := make([]driver.Value, v.NumField())
+		for i := range row {
+			value, err := driver.DefaultParameterConverter.ConvertValue(v.Field(i).Interface())
+			if err != nil {
+				panic(err)
+			}
+			row[i] = value
+		}
+		res.rows = append(res.rows, row)
+	}
+	return res
+}
+
+func (db *fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{db}, nil }
+func (db *fakeDB) Driver() driver.Driver                        { return fakeDriver{db} }
+
+type fakeDriver struct{ db *fakeDB }
+
+func (d fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{d.db}, nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("fakedb: prepared statements aren't supported")
+}
+func (c fakeConn) Close() error              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+func (c fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	res, err := c.db.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRowIter{rows: res.rows}, nil
+}
+
+func (c fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	res, err := c.db.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(res.affected), nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRowIter struct{ rows [][]driver.Value }
+
+func (r *fakeRowIter) Columns() []string {
+	if len(r.rows) == 0 {
+		return nil
+	}
+	return make([]string, len(r.rows[0]))
+}
+
+func (r *fakeRowIter) Close() error { return nil }
+
+func (r *fakeRowIter) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestReportLoginEventEndsSessions(t *testing.T) {
+	db := newFakeDB()
+	cfg := newTestConfig(db)
+	user := database.User{ID: uuid.New()}
+	login := database.LoginEvent{ID: uuid.New(), UserID: user.ID}
+	db.handle("GetUser", func([]driver.Value) (fakeResult, error) {
+		return fakeRows(user), nil
+	})
+	db.returning("ReportLoginEvent", login)
+	db.returning("RevokeAllUserTokens")
+	db.handle("EndUserSessions", func([]driver.Value) (fakeResult, error) {
+		user.SessionsValidAfter = sql.NullTime{Time: time.Now().UTC().Truncate(time.Second), Valid: true}
+		return fakeResult{affected: 1}, nil
+	})
+
+	// The attacker's token was issued before the report.
+	stolen, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &jwt.RegisteredClaims{
+		Issuer:    auth.TokenIssuer,
+		IssuedAt:  jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		Subject:   user.ID.String(),
+	}).SignedString([]byte(testJWTSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	protected := cfg.middlewareSessions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func() int {
+		req := httptest.NewRequest("GET", "/api/v1/users/me", nil)
+		req.Header.Set("Authorization", "Bearer "+stolen)
+		w := httptest.NewRecorder()
+		protected.ServeHTTP(w, req)
+		return w.Code
+	}
+	if got := serve(); got != http.StatusOK {
+		t.Fatalf("stolen token before the report got %d, want %d", got, http.StatusOK)
+	}
+
+	token, err := auth.MakeJWT(user.ID, testJWTSecret, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("POST", "/api/v1/users/me/logins/x/report", nil)
+	req.SetPathValue("loginID", login.ID.String())
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	cfg.reportLoginEventHandler(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("report status = %d, want %d: %s", w.Code, http.StatusNoContent, w.Body)
+	}
+	if db.ranQuery("RevokeAllUserTokens") != 1 {
+		t.Error("refresh tokens weren't revoked")
+	}
+
+	if got := serve(); got != http.StatusUnauthorized {
+		t.Errorf("stolen token after the report got %d, want %d", got, http.StatusUnauthorized)
+	}
+}
+
+func TestDeactivateUserEndsSessions(t *testing.T) {
+	db := newFakeDB()
+	cfg := newTestConfig(db)
+	userId := uuid.New()
+	db.returning("DeactivateUser")
+	db.returning("RevokeAllUserTokens")
+	db.returning("EndUserSessions")
+
+	token, err := auth.MakeJWT(userId, testJWTSecret, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("POST", "/api/v1/users/me/deactivate", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	cfg.deactivateUserHandler(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusNoContent, w.Body)
+	}
+	for _, query := range []string{"RevokeAllUserTokens", "EndUserSessions"} {
+		if db.ranQuery(query) != 1 {
+			t.Errorf("%s ran %d times, want once", query, db.ranQuery(query))
+		}
+	}
+}
+
+func TestChirpsPageMeta(t *testing.T) {
+	db := newFakeDB()
+	cfg := newTestConfig(db)
+	chirps := []any{}
+	for i := range 3 {
+		chirps = append(chirps, database.Chirp{ID: uuid.New(), UserID: uuid.New(), CreatedAt: time.Now().Add(-time.Duration(i) * time.Minute), Status: "published"})
+	}
+	db.returning("GetChirpsPage", chirps...)
+	db.returning("GetUsersByIDs")
+
+	req := httptest.NewRequest("GET", "/api/v1/chirps?limit=2&envelope=true", nil)
+	w := httptest.NewRecorder()
+	cfg.getChirpsPageHandler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	var got struct {
+		Data []Chirp `json:"data"`
+		Meta struct {
+			Count int `json:"count"`
+			listPage
+		} `json:"meta"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := listPage{Limit: 2, HasMore: true, NextCursor: encodeChirpCursor(chirps[1].(database.Chirp))}
+	if len(got.Data) != 2 || got.Meta.Count != 2 || got.Meta.listPage != want {
+		t.Errorf("got %d chirps with meta %+v, want 2 with %+v", len(got.Data), got.Meta, want)
+	}
+	if link := w.Header().Get("Link"); !strings.Contains(link, "cursor="+want.NextCursor) {
+		t.Errorf("Link = %q, want the next cursor", link)
+	}
+}
+
+func TestReloadSettingsRateLimit(t *testing.T) {
+	db := newFakeDB()
+	db.returning("GetAllowedWords")
+	cfg := newTestConfig(db)
+	cfg.rateLimit = config.RateLimit{Limit: 10, Window: time.Minute}
+	cfg.runtimeConfigFile = filepath.Join(t.TempDir(), "runtime.json")
+
+	write := func(contents string) {
+		t.Helper()
+		err := os.WriteFile(cfg.runtimeConfigFile, []byte(contents), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	reload := func() *ratelimit.Limiter {
+		t.Helper()
+		err := cfg.reloadSettings()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cfg.settings().rateLimiter
+	}
+
+	write(`{}`)
+	limiter := reload()
+	if limiter.Limit() != 10 || limiter.Window() != time.Minute {
+		t.Fatalf("got %d per %v, want the environment's 10 per 1m", limiter.Limit(), limiter.Window())
+	}
+	limiter.Allow("192.0.2.1")
+	if reload() != limiter {
+		t.Error("reload with unchanged limits replaced the limiter")
+	}
+
+	write(`{"rate_limit": {"limit": 2, "window": "10s"}}`)
+	limiter = reload()
+	if limiter.Limit() != 2 || limiter.Window() != 10*time.Second {
+		t.Errorf("got %d per %v, want 2 per 10s", limiter.Limit(), limiter.Window())
+	}
+}
+
+func TestChirpUpdatesSinceGoneChirp(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	newer := database.Chirp{ID: uuid.New(), UserID: uuid.New(), CreatedAt: now, Status: "published"}
+	older := database.Chirp{ID: uuid.New(), UserID: uuid.New(), CreatedAt: now.Add(-time.Minute), Status: "published"}
+
+	tests := []struct {
+		name  string
+		setup func(db *fakeDB)
+		want  []string
+	}{
+		{
+			// A deleted chirp still marks a point in time.
+			name: "deleted",
+			setup: func(db *fakeDB) {
+				db.returning("GetChirpCreatedAt", now.Add(-time.Hour))
+				db.returning("GetChirpsCreatedAfter", older, newer)
+			},
+			want: []string{older.ID.String(), newer.ID.String()},
+		},
+		{
+			name: "purged",
+			setup: func(db *fakeDB) {
+				db.returning("GetChirpCreatedAt")
+				db.returning("GetChirpsPage", newer, older)
+			},
+			want: []string{older.ID.String(), newer.ID.String()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			tt.setup(db)
+			db.returning("GetUsersByIDs")
+			cfg := newTestConfig(db)
+			cfg.chirpHub = pubsub.NewHub[Chirp]()
+
+			req := httptest.NewRequest("GET", "/api/v1/chirps/updates?wait=0&since_id="+uuid.NewString(), nil)
+			w := httptest.NewRecorder()
+			cfg.chirpUpdatesHandler(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+			}
+			var got []Chirp
+			err := json.Unmarshal(w.Body.Bytes(), &got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := []string{}
+			for _, chirp := range got {
+				ids = append(ids, chirp.ID)
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("got chirps %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+// fakeBus hands every published message to its subscriber.
+type fakeBus struct {
+	messages chan []byte
+	closed   chan struct{}
+}
+
+func (b *fakeBus) Publish(_ context.Context, _ string, data []byte) error {
+	b.messages <- data
+	return nil
+}
+
+func (b *fakeBus) Subscribe(_ context.Context, _ string, handle func(data []byte)) error {
+	for {
+		select {
+		case data := <-b.messages:
+			handle(data)
+		case <-b.closed:
+			return errors.New("fakebus: closed")
+		}
+	}
+}
+
+func (b *fakeBus) Close() error { return nil }
+
+func TestChirpCreatedAnnouncedFromBus(t *testing.T) {
+	chirp := database.Chirp{ID: uuid.New(), UserID: uuid.New(), Body: "hello", Status: "published"}
+	db := newFakeDB()
+	db.returning("GetChirp", chirp)
+	db.returning("GetUser", database.User{ID: chirp.UserID, Username: sql.NullString{String: "alice", Valid: true}})
+	cfg := newTestConfig(db)
+	cfg.chirpHub = pubsub.NewHub[Chirp]()
+	bus := &fakeBus{messages: make(chan []byte, 1), closed: make(chan struct{})}
+	t.Cleanup(func() { close(bus.closed) })
+	cfg.eventBus = bus
+	updates, unsubscribe := cfg.chirpHub.Subscribe(1)
+	defer unsubscribe()
+
+	payload, err := json.Marshal(newChirpCreatedEvent(chirp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cfg.publishEvent(database.OutboxEvent{EventType: eventChirpCreated, Payload: payload})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-updates:
+		t.Fatal("relaying instance announced the chirp besides the subscription")
+	default:
+	}
+
+	go cfg.announceChirpsFromBus(time.Hour)
+	select {
+	case got := <-updates:
+		if got.ID != chirp.ID.String() {
+			t.Errorf("announced chirp %s, want %s", got.ID, chirp.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("chirp from the bus wasn't announced")
+	}
+}
+
+// storeChirp answers CreateChirp with the chirp it was asked to store.
+func storeChirp(args []driver.Value) (fakeResult, error) {
+	chirp := database.Chirp{
+		ID:        uuid.MustParse(args[0].(string)),
+		Body:      args[1].(string),
+		UserID:    uuid.MustParse(args[2].(string)),
+		Status:    args[7].(string),
+		CreatedAt: time.Now(),
+	}
+	if parent, ok := args[3].(string); ok {
+		chirp.ParentChirpID = uuid.NullUUID{UUID: uuid.MustParse(parent), Valid: true}
+	}
+	return fakeRows(chirp), nil
+}
+
+func TestCreateThreadPublishesEveryChirp(t *testing.T) {
+	db := newFakeDB()
+	cfg := newTestConfig(db)
+	user := database.User{ID: uuid.New(), Username: sql.NullString{String: "alice", Valid: true}}
+	db.returning("GetUser", user)
+	db.returning("CountChirpsByUserSince", int64(0))
+	db.handle("CreateChirp", storeChirp)
+	db.returning("AddChirpHashtags")
+	db.returning("AddChirpReplies")
+	db.returning("CreateOutboxEvent")
+	db.returning("GetUsersByIDs", user)
+
+	token, err := auth.MakeJWT(user.ID, testJWTSecret, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("POST", "/api/v1/threads", strings.NewReader(`{"chirps": ["one #go", "two", "three"]}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	cfg.createThreadHandler(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+	want := map[string]int{"CreateChirp": 3, "AddChirpHashtags": 1, "AddChirpReplies": 2, "CreateOutboxEvent": 3}
+	for query, n := range want {
+		if got := db.ranQuery(query); got != n {
+			t.Errorf("%s ran %d times, want %d", query, got, n)
+		}
+	}
+}
+
+func TestCreateChirpBatch(t *testing.T) {
+	user := database.User{ID: uuid.New(), Username: sql.NullString{String: "alice", Valid: true}}
+	token, err := auth.MakeJWT(user.ID, testJWTSecret, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := func(bodies ...string) string {
+		chirps := []map[string]string{}
+		for _, body := range bodies {
+			chirps = append(chirps, map[string]string{"body": body})
+		}
+		data, err := json.Marshal(map[string]any{"chirps": chirps})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantOK     []bool
+	}{
+		{
+			name:       "All valid",
+			body:       items("one", "two"),
+			wantStatus: http.StatusCreated,
+			wantOK:     []bool{true, true},
+		},
+		{
+			name:       "One too long",
+			body:       items("one", strings.Repeat("a", 141), "three"),
+			wantStatus: http.StatusBadRequest,
+			wantOK:     []bool{false, false, false},
+		},
+		{
+			name:       "Too many items",
+			body:       items(slices.Repeat([]string{"chirp"}, 51)...),
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			cfg := newTestConfig(db)
+			db.returning("GetUser", user)
+			db.returning("CountChirpsByUserSince", int64(0))
+			db.handle("CreateChirp", storeChirp)
+			db.returning("CreateOutboxEvent")
+			db.returning("GetUsersByIDs", user)
+
+			req := httptest.NewRequest("POST", "/api/v1/chirps/batch", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			cfg.createChirpBatchHandler(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusCreated && db.ranQuery("CreateChirp") != 0 {
+				t.Errorf("stored %d chirps of a rejected batch", db.ranQuery("CreateChirp"))
+			}
+			if tt.wantOK == nil {
+				return
+			}
+
+			var got struct {
+				Results []batchItemResult `json:"results"`
+			}
+			err := json.Unmarshal(w.Body.Bytes(), &got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got.Results) != len(tt.wantOK) {
+				t.Fatalf("got %d results, want %d", len(got.Results), len(tt.wantOK))
+			}
+			for i, result := range got.Results {
+				if result.OK != tt.wantOK[i] {
+					t.Errorf("results[%d].OK = %v, want %v", i, result.OK, tt.wantOK[i])
+				}
+				if result.OK && (result.Chirp == nil || result.Chirp.AuthorHandle != "alice") {
+					t.Errorf("results[%d].Chirp = %+v, want alice's chirp", i, result.Chirp)
+				}
+			}
+			if tt.wantStatus == http.StatusBadRequest && got.Results[1].Error == "" {
+				t.Error("the invalid item has no error")
+			}
+		})
+	}
+}
+
+func TestGetChirpHiddenFromOthers(t *testing.T) {
+	owner := database.User{ID: uuid.New(), Username: sql.NullString{String: "alice", Valid: true}}
+	other := database.User{ID: uuid.New(), Username: sql.NullString{String: "bob", Valid: true}}
+	draft := database.Chirp{ID: uuid.New(), UserID: owner.ID, Body: "soon", Status: "draft"}
+	chirp := database.Chirp{ID: uuid.New(), UserID: owner.ID, Body: "hello", Status: "published"}
+
+	tests := []struct {
+		name   string
+		chirp  database.Chirp
+		viewer database.User
+		want   int
+	}{
+		{name: "Draft for its author", chirp: draft, viewer: owner, want: http.StatusOK},
+		{name: "Draft for another user", chirp: draft, viewer: other, want: http.StatusNotFound},
+		{name: "Chirp for its author", chirp: chirp, viewer: owner, want: http.StatusOK},
+		{name: "Chirp for a blocked user", chirp: chirp, viewer: other, want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			cfg := newTestConfig(db)
+			db.handle("GetChirp", func(args []driver.Value) (fakeResult, error) {
+				if chirp.ID.String() != args[0] {
+					return fakeRows(), nil
+				}
+				return fakeRows(chirp), nil
+			})
+			db.handle("GetDraft", func(args []driver.Value) (fakeResult, error) {
+				if draft.ID.String() != args[0] || draft.UserID.String() != args[1] {
+					return fakeRows(), nil
+				}
+				return fakeRows(draft), nil
+			})
+			// The author blocked everyone else.
+			db.handle("IsHiddenFrom", func(args []driver.Value) (fakeResult, error) {
+				return fakeRows(args[0] != owner.ID.String()), nil
+			})
+			db.returning("GetUser", owner)
+
+			token, err := auth.MakeJWT(tt.viewer.ID, testJWTSecret, time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest("GET", "/api/v1/chirps/"+tt.chirp.ID.String(), nil)
+			req.SetPathValue("chirpID", tt.chirp.ID.String())
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			cfg.getChirpHandler(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
+
+func TestShareRedirectOpensChirpPage(t *testing.T) {
+	db := newFakeDB()
+	cfg := newTestConfig(db)
+	cfg.publicURL = "https://chirpy.example"
+	share := database.ChirpShare{Code: "abc1234", ChirpID: uuid.New(), RedirectCount: 1}
+	db.returning("RecordChirpShareRedirect", share)
+
+	req := httptest.NewRequest("GET", "/s/abc1234", nil)
+	req.SetPathValue("code", share.Code)
+	w := httptest.NewRecorder()
+	cfg.shareRedirectHandler(w, req)
+	if w.Code != http.StatusFound {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusFound)
+	}
+	want := "https://chirpy.example/app/chirp.html?id=" + share.ChirpID.String()
+	if got := w.Header().Get("Location"); got != want {
+		t.Errorf("Location = %q, want %q", got, want)
+	}
+}
+
+func TestCheckOptionalSettings(t *testing.T) {
+	meili := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer meili.Close()
+
+	tests := []struct {
+		name    string
+		check   func() (string, error)
+		wantErr bool
+	}{
+		{name: "Public IDs off", check: func() (string, error) { return checkPublicIDs("") }},
+		{name: "Public IDs", check: func() (string, error) { return checkPublicIDs("key") }},
+		{name: "Error reporting off", check: func() (string, error) { return checkErrorReporting(errreport.Config{}) }},
+		{name: "Sentry DSN without project", check: func() (string, error) {
+			return checkErrorReporting(errreport.Config{SentryDSN: "https://key@sentry.example/"})
+		}, wantErr: true},
+		{name: "Webhook without scheme", check: func() (string, error) {
+			return checkErrorReporting(errreport.Config{WebhookURL: "hooks.example/errors"})
+		}, wantErr: true},
+		{name: "Postgres search", check: func() (string, error) {
+			return checkSearch(context.Background(), search.Config{})
+		}},
+		{name: "Unknown search backend", check: func() (string, error) {
+			return checkSearch(context.Background(), search.Config{Backend: "elastic"})
+		}, wantErr: true},
+		{name: "Healthy meilisearch", check: func() (string, error) {
+			return checkSearch(context.Background(), search.Config{Backend: "meilisearch", URL: meili.URL})
+		}},
+		{name: "Unhealthy meilisearch", check: func() (string, error) {
+			return checkSearch(context.Background(), search.Config{Backend: "meilisearch", URL: meili.URL + "/down"})
+		}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detail, err := tt.check()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v (detail %q)", err, tt.wantErr, detail)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/auth"
+	"github.com/fkl13/chirpy/internal/database"
+)
+
+// markerTimelines are the positions clients can sync, named like Mastodon's
+// markers so existing clients can reuse their code.
+var markerTimelines = map[string]bool{
+	"home":          true,
+	"notifications": true,
+}
+
+// Marker is where a user stopped reading a timeline. Version goes up with
+// every save so clients can tell a newer position from their own.
+type Marker struct {
+	LastReadID string    `json:"last_read_id"`
+	Version    int32     `json:"version"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func (cfg *apiConfig) newMarker(marker database.Marker) Marker {
+	return Marker{
+		LastReadID: cfg.exposedID(marker.LastReadID),
+		Version:    marker.Version,
+		UpdatedAt:  marker.UpdatedAt,
+	}
+}
+
+// getMarkersHandler returns the user's saved positions keyed by timeline.
+// ?timeline= can be repeated to ask for some timelines only.
+func (cfg *apiConfig) getMarkersHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	wanted := map[string]bool{}
+	for _, timeline := range r.URL.Query()["timeline"] {
+		if !markerTimelines[timeline] {
+			respondWithError(w, http.StatusBadRequest, "Unknown timeline "+timeline, nil)
+			return
+		}
+		wanted[timeline] = true
+	}
+
+	markers, err := cfg.dbQueries.GetMarkers(r.Context(), userId)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get markers", err)
+		return
+	}
+	res := map[string]Marker{}
+	for _, marker := range markers {
+		if len(wanted) > 0 && !wanted[marker.Timeline] {
+			continue
+		}
+		res[marker.Timeline] = cfg.newMarker(marker)
+	}
+	respondWithJSON(w, http.StatusOK, res)
+}
+
+// saveMarkersHandler moves the positions of the timelines in the body and
+// returns them. All timelines are saved together or not at all.
+func (cfg *apiConfig) saveMarkersHandler(w http.ResponseWriter, r *http.Request) {
+	type position struct {
+		LastReadID string `json:"last_read_id"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	params := map[string]position{}
+	err = decodeJSONBody(w, r, &params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	if len(params) == 0 {
+		respondWithError(w, http.StatusBadRequest, "No markers given", nil)
+		return
+	}
+	saves := make([]database.SaveMarkerParams, 0, len(params))
+	for timeline, pos := range params {
+		if !markerTimelines[timeline] {
+			respondWithError(w, http.StatusBadRequest, "Unknown timeline "+timeline, nil)
+			return
+		}
+		id, err := cfg.parseID(pos.LastReadID)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid last_read_id for "+timeline, err)
+			return
+		}
+		saves = append(saves, database.SaveMarkerParams{
+			UserID:     userId,
+			Timeline:   timeline,
+			LastReadID: id,
+		})
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	res := map[string]Marker{}
+	for _, save := range saves {
+		marker, err := qtx.SaveMarker(r.Context(), save)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't save marker", err)
+			return
+		}
+		res[marker.Timeline] = cfg.newMarker(marker)
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save markers", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, res)
+}
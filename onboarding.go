@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/fkl13/chirpy/internal/config"
+	"github.com/fkl13/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// onboardUser applies the onboarding settings to a new account: it follows
+// the configured accounts and makes sure the welcome chirp is there. Signup has already
+// succeeded, so problems are only logged.
+func (cfg *apiConfig) onboardUser(ctx context.Context, user database.User) {
+	s := cfg.settings()
+	if s == nil {
+		return
+	}
+	onboarding := s.onboarding
+
+	for _, id := range onboarding.Follow {
+		if id == user.ID {
+			continue
+		}
+		followee, err := cfg.getUser(ctx, id)
+		if err != nil || followee.DeactivatedAt.Valid {
+			log.Printf("Couldn't onboard %s: can't follow %s: %v", user.ID, id, err)
+			continue
+		}
+		_, err = cfg.dbQueries.FollowUser(ctx, database.FollowUserParams{FollowerID: user.ID, FolloweeID: id})
+		if err != nil {
+			log.Printf("Couldn't onboard %s: can't follow %s: %v", user.ID, id, err)
+		}
+	}
+
+	if onboarding.WelcomeChirp != "" {
+		err := cfg.ensureWelcomeChirp(ctx, onboarding)
+		if err != nil {
+			log.Printf("Couldn't onboard %s: %v", user.ID, err)
+		}
+	}
+}
+
+// ensureWelcomeChirp posts the welcome chirp unless its author already
+// has. It's posted once rather than per user: onboarded users all follow the
+// author, and a chirp per signup would fill their timelines.
+func (cfg *apiConfig) ensureWelcomeChirp(ctx context.Context, onboarding config.Onboarding) error {
+	author, err := cfg.getUser(ctx, onboarding.WelcomeAuthor)
+	if err != nil {
+		return fmt.Errorf("couldn't get welcome author: %w", err)
+	}
+	if author.DeactivatedAt.Valid || author.MovedTo.Valid {
+		return fmt.Errorf("welcome author %s can't post", author.ID)
+	}
+	body, err := validateChirp(onboarding.WelcomeChirp, cfg.settings().badWords)
+	if err != nil {
+		return fmt.Errorf("invalid welcome chirp: %w", err)
+	}
+
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	// Signups at the same time would otherwise all see no welcome chirp.
+	err = qtx.LockUser(ctx, author.ID)
+	if err != nil {
+		return err
+	}
+	posted, err := qtx.HasPublishedChirp(ctx, database.HasPublishedChirpParams{UserID: author.ID, Body: body})
+	if err != nil {
+		return err
+	}
+	if posted {
+		return nil
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+	chirp, err := qtx.CreateChirp(ctx, database.CreateChirpParams{
+		ID:     id,
+		Body:   body,
+		UserID: author.ID,
+		Status: chirpPublished,
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't store welcome chirp: %w", err)
+	}
+	err = cfg.publishChirp(ctx, qtx, chirp)
+	if err != nil {
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("couldn't store welcome chirp: %w", err)
+	}
+	cfg.chirpCache.Put(chirp.ID, chirp)
+	cfg.wakeOutboxRelay()
+	return nil
+}
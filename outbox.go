@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/database"
+	"github.com/fkl13/chirpy/internal/search"
+	"github.com/google/uuid"
+)
+
+const (
+	eventChirpCreated   = "chirp.created"
+	eventChirpDeleted   = "chirp.deleted"
+	eventChirpUpdated   = "chirp.updated"
+	eventChirpRestored  = "chirp.restored"
+	eventUserCreated    = "user.created"
+	eventUserUpgraded   = "user.upgraded"
+	eventUserDowngraded = "user.downgraded"
+	eventUserMoved      = "user.moved"
+)
+
+const (
+	outboxBatchSize = 100
+	// outboxRetention is how long published events are kept for debugging.
+	outboxRetention = 24 * time.Hour
+)
+
+// Event payloads carry internal UUIDs, whatever IDs the API shows, so
+// consumers can match up the events about one chirp or user. They leave out
+// personal data such as email addresses.
+
+type chirpCreatedEvent struct {
+	ID            uuid.UUID  `json:"id"`
+	UserId        uuid.UUID  `json:"user_id"`
+	ParentChirpID *uuid.UUID `json:"parent_chirp_id,omitempty"`
+	Body          string     `json:"body"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func newChirpCreatedEvent(chirp database.Chirp) chirpCreatedEvent {
+	event := chirpCreatedEvent{
+		ID:        chirp.ID,
+		UserId:    chirp.UserID,
+		Body:      chirp.Body,
+		CreatedAt: chirp.CreatedAt,
+	}
+	if chirp.ParentChirpID.Valid {
+		event.ParentChirpID = &chirp.ParentChirpID.UUID
+	}
+	return event
+}
+
+type chirpUpdatedEvent struct {
+	ID        uuid.UUID `json:"id"`
+	UserId    uuid.UUID `json:"user_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type chirpRestoredEvent struct {
+	ID        uuid.UUID `json:"id"`
+	UserId    uuid.UUID `json:"user_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type chirpDeletedEvent struct {
+	ID     uuid.UUID `json:"id"`
+	UserId uuid.UUID `json:"user_id"`
+}
+
+type userCreatedEvent struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type userUpgradedEvent struct {
+	ID uuid.UUID `json:"id"`
+}
+
+type userDowngradedEvent struct {
+	ID uuid.UUID `json:"id"`
+}
+
+type userMovedEvent struct {
+	ID      uuid.UUID `json:"id"`
+	MovedTo string    `json:"moved_to"`
+}
+
+// addOutboxEvent records an event in the same transaction as the change it
+// describes, so an event is published if and only if the change commits.
+func addOutboxEvent(ctx context.Context, q *database.Queries, eventType string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("couldn't encode %s event: %w", eventType, err)
+	}
+	return q.CreateOutboxEvent(ctx, database.CreateOutboxEventParams{
+		EventType: eventType,
+		Payload:   data,
+	})
+}
+
+// wakeOutboxRelay asks the relay to publish right away instead of waiting
+// for its next tick. Call it after committing a transaction with events.
+func (cfg *apiConfig) wakeOutboxRelay() {
+	select {
+	case cfg.outboxWake <- struct{}{}:
+	default:
+	}
+}
+
+// relayOutbox publishes outbox events in order. Events stay in the outbox
+// until they were published, so a crash or a failing publisher only delays
+// them.
+func (cfg *apiConfig) relayOutbox(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	cleanup := time.NewTicker(time.Hour)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-cfg.outboxWake:
+		case <-cleanup.C:
+			before := time.Now().UTC().Add(-outboxRetention)
+			_, err := cfg.dbQueries.DeletePublishedOutboxEvents(context.Background(), sql.NullTime{Time: before, Valid: true})
+			if err != nil {
+				log.Printf("Couldn't delete published outbox events: %v", err)
+			}
+			continue
+		}
+
+		for {
+			n, err := cfg.relayOutboxBatch(context.Background())
+			if err != nil {
+				log.Printf("Couldn't relay outbox events: %v", err)
+				break
+			}
+			if n < outboxBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// relayOutboxBatch publishes one batch of events. The rows stay locked
+// until the batch is marked as published, so several instances can run a
+// relay without publishing an event twice.
+func (cfg *apiConfig) relayOutboxBatch(ctx context.Context) (int, error) {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	events, err := qtx.GetUnpublishedOutboxEvents(ctx, outboxBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	ids := make([]int64, 0, len(events))
+	for _, event := range events {
+		err := cfg.publishEvent(event)
+		if err != nil {
+			log.Printf("Couldn't publish %s event %d: %v", event.EventType, event.ID, err)
+			break
+		}
+		ids = append(ids, event.ID)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	err = qtx.MarkOutboxEventsPublished(ctx, ids)
+	if err != nil {
+		return 0, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// publishEvent hands an event to the event bus and, without a bus, to the
+// in-process hub. The bus goes first: if it fails the event is retried, and
+// the hub must not see it twice.
+func (cfg *apiConfig) publishEvent(event database.OutboxEvent) error {
+	if cfg.eventBus != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err := cfg.eventBus.Publish(ctx, cfg.eventSubjectPrefix+event.EventType, event.Payload)
+		if err != nil {
+			return err
+		}
+	}
+
+	ctx := context.Background()
+	switch event.EventType {
+	case eventChirpCreated:
+		var created chirpCreatedEvent
+		err := json.Unmarshal(event.Payload, &created)
+		if err != nil {
+			return err
+		}
+		err = cfg.indexChirp(ctx, created.ID, created.UserId, created.Body, created.CreatedAt)
+		if err != nil {
+			return err
+		}
+		if cfg.eventBus == nil {
+			// With a bus, every instance announces the chirp from its
+			// subscription, this one included.
+			return cfg.announceChirp(ctx, created.ID)
+		}
+	case eventChirpUpdated:
+		var updated chirpUpdatedEvent
+		err := json.Unmarshal(event.Payload, &updated)
+		if err != nil {
+			return err
+		}
+		return cfg.indexChirp(ctx, updated.ID, updated.UserId, updated.Body, updated.CreatedAt)
+	case eventChirpRestored:
+		var restored chirpRestoredEvent
+		err := json.Unmarshal(event.Payload, &restored)
+		if err != nil {
+			return err
+		}
+		return cfg.indexChirp(ctx, restored.ID, restored.UserId, restored.Body, restored.CreatedAt)
+	case eventChirpDeleted:
+		var deleted chirpDeletedEvent
+		err := json.Unmarshal(event.Payload, &deleted)
+		if err != nil {
+			return err
+		}
+		if cfg.search != nil {
+			return cfg.search.Delete(ctx, deleted.ID)
+		}
+	}
+	return nil
+}
+
+// announceChirp hands a new chirp to the long polls waiting on this
+// instance.
+func (cfg *apiConfig) announceChirp(ctx context.Context, id uuid.UUID) error {
+	chirp, err := cfg.getChirp(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Deleted before it was announced.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	cfg.chirpHub.Publish(cfg.newChirp(ctx, chirp))
+	return nil
+}
+
+// announceChirpsFromBus announces the chirps created on any instance, since
+// only one of them relays each outbox event. A broken subscription is
+// renewed after retry; chirps created in between reach long polls with
+// their next since_id.
+func (cfg *apiConfig) announceChirpsFromBus(retry time.Duration) {
+	subject := cfg.eventSubjectPrefix + eventChirpCreated
+	for {
+		err := cfg.eventBus.Subscribe(context.Background(), subject, func(data []byte) {
+			var created chirpCreatedEvent
+			err := json.Unmarshal(data, &created)
+			if err != nil {
+				log.Printf("Couldn't decode %s event: %v", eventChirpCreated, err)
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			err = cfg.announceChirp(ctx, created.ID)
+			if err != nil {
+				log.Printf("Couldn't announce chirp %s: %v", created.ID, err)
+			}
+		})
+		log.Printf("Lost the %s subscription: %v", subject, err)
+		time.Sleep(retry)
+	}
+}
+
+// indexChirp adds or replaces a chirp in the search index, if there is one.
+func (cfg *apiConfig) indexChirp(ctx context.Context, id, userId uuid.UUID, body string, createdAt time.Time) error {
+	if cfg.search == nil {
+		return nil
+	}
+	return cfg.search.Index(ctx, []search.Document{{
+		ID:        id,
+		UserID:    userId,
+		Body:      body,
+		CreatedAt: createdAt,
+	}})
+}
@@ -0,0 +1,456 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/auth"
+	"github.com/fkl13/chirpy/internal/database"
+	"github.com/fkl13/chirpy/internal/validate"
+	"github.com/fkl13/chirpy/internal/webauthn"
+	"github.com/google/uuid"
+)
+
+// Passkeys let users log in with WebAuthn instead of a password. Adding one
+// and logging in with one both take two steps: begin hands out a challenge
+// and the options for navigator.credentials, finish checks what the browser
+// answered. Challenges are stored until they're used or expire.
+
+const (
+	passkeyChallengeTTL  = 5 * time.Minute
+	maxPasskeyNameLength = 50
+)
+
+// newRelyingParty returns nil when no relying party ID is set, which
+// turns passkeys off.
+func newRelyingParty(id string, origins []string) *webauthn.RelyingParty {
+	if id == "" {
+		return nil
+	}
+	return &webauthn.RelyingParty{ID: id, Origins: origins}
+}
+
+// Passkey is a passkey as its owner sees it. The key itself is never sent
+// back.
+type Passkey struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+func newPasskey(passkey database.Passkey) Passkey {
+	p := Passkey{
+		ID:        passkey.ID,
+		Name:      passkey.Name,
+		CreatedAt: passkey.CreatedAt,
+	}
+	if passkey.LastUsedAt.Valid {
+		p.LastUsedAt = &passkey.LastUsedAt.Time
+	}
+	return p
+}
+
+type passkeyCredentialParams struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+type passkeyCredentialDescriptor struct {
+	Type string         `json:"type"`
+	ID   webauthn.Bytes `json:"id"`
+}
+
+func (cfg *apiConfig) checkPasskeysEnabled(w http.ResponseWriter) bool {
+	if cfg.passkeys == nil {
+		respondWithError(w, http.StatusNotImplemented, "Passkeys aren't enabled", nil)
+		return false
+	}
+	return true
+}
+
+// newPasskeyChallenge stores a fresh challenge. userID is set when a
+// signed-in user adds a passkey.
+func (cfg *apiConfig) newPasskeyChallenge(ctx context.Context, userID uuid.NullUUID) (uuid.UUID, []byte, error) {
+	_, err := cfg.dbQueries.DeleteExpiredPasskeyChallenges(ctx)
+	if err != nil {
+		log.Printf("Couldn't delete expired passkey challenges: %v", err)
+	}
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+	id, err := uuid.NewV7()
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+	err = cfg.dbQueries.CreatePasskeyChallenge(ctx, database.CreatePasskeyChallengeParams{
+		ID:        id,
+		Challenge: challenge,
+		UserID:    userID,
+		ExpiresAt: time.Now().UTC().Add(passkeyChallengeTTL),
+	})
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+	return id, challenge, nil
+}
+
+// beginPasskeyRegistrationHandler returns the options for
+// navigator.credentials.create to add a passkey to the caller's account.
+// A passkey logs in without the password, so adding one takes the password
+// rather than just an access token.
+func (cfg *apiConfig) beginPasskeyRegistrationHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Password string `json:"password"`
+	}
+	type relyingParty struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	type userEntity struct {
+		ID          webauthn.Bytes `json:"id"`
+		Name        string         `json:"name"`
+		DisplayName string         `json:"displayName"`
+	}
+	type authenticatorSelection struct {
+		ResidentKey      string `json:"residentKey"`
+		UserVerification string `json:"userVerification"`
+	}
+	type publicKeyOptions struct {
+		RP                     relyingParty                  `json:"rp"`
+		User                   userEntity                    `json:"user"`
+		Challenge              webauthn.Bytes                `json:"challenge"`
+		PubKeyCredParams       []passkeyCredentialParams     `json:"pubKeyCredParams"`
+		Timeout                int64                         `json:"timeout"`
+		ExcludeCredentials     []passkeyCredentialDescriptor `json:"excludeCredentials"`
+		AuthenticatorSelection authenticatorSelection        `json:"authenticatorSelection"`
+		Attestation            string                        `json:"attestation"`
+	}
+	type response struct {
+		ChallengeID uuid.UUID        `json:"challenge_id"`
+		PublicKey   publicKeyOptions `json:"publicKey"`
+	}
+
+	if !cfg.checkPasskeysEnabled(w) {
+		return
+	}
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	params := parameters{}
+	err = decodeJSONBody(w, r, &params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	user, err := cfg.getUser(r.Context(), userId)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+	err = auth.CheckPasswordHash(params.Password, user.HashedPassword)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Incorrect password", err)
+		return
+	}
+
+	registered, err := cfg.dbQueries.GetPasskeyCredentialIDs(r.Context(), userId)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get passkeys", err)
+		return
+	}
+	challengeID, challenge, err := cfg.newPasskeyChallenge(r.Context(), uuid.NullUUID{UUID: userId, Valid: true})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create challenge", err)
+		return
+	}
+
+	options := publicKeyOptions{
+		RP:                 relyingParty{ID: cfg.passkeys.ID, Name: "Chirpy"},
+		User:               userEntity{ID: userId[:], Name: user.Email, DisplayName: cfg.handle(user)},
+		Challenge:          challenge,
+		Timeout:            passkeyChallengeTTL.Milliseconds(),
+		ExcludeCredentials: []passkeyCredentialDescriptor{},
+		AuthenticatorSelection: authenticatorSelection{
+			ResidentKey:      "required",
+			UserVerification: "required",
+		},
+		Attestation: "none",
+	}
+	for _, alg := range webauthn.Algorithms {
+		options.PubKeyCredParams = append(options.PubKeyCredParams, passkeyCredentialParams{Type: "public-key", Alg: alg})
+	}
+	for _, id := range registered {
+		options.ExcludeCredentials = append(options.ExcludeCredentials, passkeyCredentialDescriptor{Type: "public-key", ID: id})
+	}
+	respondWithJSON(w, http.StatusOK, response{ChallengeID: challengeID, PublicKey: options})
+}
+
+// finishPasskeyRegistrationHandler stores the passkey the browser created
+// for a challenge from beginPasskeyRegistrationHandler.
+func (cfg *apiConfig) finishPasskeyRegistrationHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		ChallengeID uuid.UUID `json:"challenge_id"`
+		Name        string    `json:"name"`
+		Credential  struct {
+			Response struct {
+				ClientDataJSON    webauthn.Bytes `json:"clientDataJSON"`
+				AttestationObject webauthn.Bytes `json:"attestationObject"`
+			} `json:"response"`
+		} `json:"credential"`
+	}
+
+	if !cfg.checkPasskeysEnabled(w) {
+		return
+	}
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	params := parameters{}
+	err = decodeJSONBody(w, r, &params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	name := strings.TrimSpace(params.Name)
+	if name == "" {
+		name = "Passkey"
+	}
+	v := validate.Validator{}
+	v.MaxLength("name", name, maxPasskeyNameLength)
+	if err := v.Err(); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+
+	challenge, err := cfg.dbQueries.TakePasskeyChallenge(r.Context(), params.ChallengeID)
+	if err != nil || challenge.UserID != (uuid.NullUUID{UUID: userId, Valid: true}) {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired challenge", err)
+		return
+	}
+	cred, err := cfg.passkeys.VerifyRegistration(challenge.Challenge,
+		params.Credential.Response.ClientDataJSON, params.Credential.Response.AttestationObject)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't verify passkey", err)
+		return
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create passkey ID", err)
+		return
+	}
+	passkey, err := cfg.dbQueries.CreatePasskey(r.Context(), database.CreatePasskeyParams{
+		ID:           id,
+		UserID:       userId,
+		Name:         name,
+		CredentialID: cred.ID,
+		PublicKey:    cred.PublicKey,
+		SignCount:    int64(cred.SignCount),
+	})
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "Passkey already registered", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save passkey", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, newPasskey(passkey))
+}
+
+// getPasskeysHandler lists the caller's passkeys, oldest first.
+func (cfg *apiConfig) getPasskeysHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	passkeys, err := cfg.dbQueries.GetPasskeys(r.Context(), userId)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get passkeys", err)
+		return
+	}
+	res := make([]Passkey, 0, len(passkeys))
+	for _, passkey := range passkeys {
+		res = append(res, newPasskey(passkey))
+	}
+	respondWithList(w, http.StatusOK, res, cfg.wantsEnvelope(r))
+}
+
+func (cfg *apiConfig) deletePasskeyHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	passkeyId, err := uuid.Parse(r.PathValue("passkeyID"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find passkey", err)
+		return
+	}
+	n, err := cfg.dbQueries.DeletePasskey(r.Context(), database.DeletePasskeyParams{ID: passkeyId, UserID: userId})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete passkey", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "Couldn't find passkey", nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// beginPasskeyLoginHandler returns the options for navigator.credentials.get.
+// No account is named, so the browser offers the passkeys it has for this
+// site and nobody learns which emails have one.
+func (cfg *apiConfig) beginPasskeyLoginHandler(w http.ResponseWriter, r *http.Request) {
+	type publicKeyOptions struct {
+		Challenge        webauthn.Bytes `json:"challenge"`
+		RPID             string         `json:"rpId"`
+		Timeout          int64          `json:"timeout"`
+		UserVerification string         `json:"userVerification"`
+	}
+	type response struct {
+		ChallengeID uuid.UUID        `json:"challenge_id"`
+		PublicKey   publicKeyOptions `json:"publicKey"`
+	}
+
+	if !cfg.checkPasskeysEnabled(w) {
+		return
+	}
+	challengeID, challenge, err := cfg.newPasskeyChallenge(r.Context(), uuid.NullUUID{})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create challenge", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, response{
+		ChallengeID: challengeID,
+		PublicKey: publicKeyOptions{
+			Challenge:        challenge,
+			RPID:             cfg.passkeys.ID,
+			Timeout:          passkeyChallengeTTL.Milliseconds(),
+			UserVerification: "required",
+		},
+	})
+}
+
+// finishPasskeyLoginHandler checks the browser's signature over a challenge
+// from beginPasskeyLoginHandler and logs the passkey's owner in like
+// loginHandler does.
+func (cfg *apiConfig) finishPasskeyLoginHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		ChallengeID uuid.UUID `json:"challenge_id"`
+		Credential  struct {
+			RawID    webauthn.Bytes `json:"rawId"`
+			Response struct {
+				ClientDataJSON    webauthn.Bytes `json:"clientDataJSON"`
+				AuthenticatorData webauthn.Bytes `json:"authenticatorData"`
+				Signature         webauthn.Bytes `json:"signature"`
+				UserHandle        webauthn.Bytes `json:"userHandle"`
+			} `json:"response"`
+		} `json:"credential"`
+	}
+
+	if !cfg.checkPasskeysEnabled(w) {
+		return
+	}
+	params := parameters{}
+	err := decodeJSONBody(w, r, &params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	credential := params.Credential
+
+	challenge, err := cfg.dbQueries.TakePasskeyChallenge(r.Context(), params.ChallengeID)
+	if err != nil || challenge.UserID.Valid {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired challenge", err)
+		return
+	}
+	passkey, err := cfg.dbQueries.GetPasskeyByCredentialID(r.Context(), credential.RawID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusUnauthorized, "Unknown passkey", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get passkey", err)
+		return
+	}
+	if len(credential.Response.UserHandle) > 0 && string(credential.Response.UserHandle) != string(passkey.UserID[:]) {
+		respondWithError(w, http.StatusUnauthorized, "Passkey doesn't belong to this user", nil)
+		return
+	}
+
+	signCount, err := cfg.passkeys.VerifyAssertion(challenge.Challenge, webauthn.Credential{
+		ID:        passkey.CredentialID,
+		PublicKey: passkey.PublicKey,
+		SignCount: uint32(passkey.SignCount),
+	}, credential.Response.ClientDataJSON, credential.Response.AuthenticatorData, credential.Response.Signature)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't verify passkey", err)
+		return
+	}
+	// The update only matches if nobody used the passkey in the meantime,
+	// so the same signature counter can't log in twice.
+	n, err := cfg.dbQueries.UsePasskey(r.Context(), database.UsePasskeyParams{
+		SignCount:    int64(signCount),
+		ID:           passkey.ID,
+		OldSignCount: passkey.SignCount,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update passkey", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't verify passkey", webauthn.ErrSignCount)
+		return
+	}
+
+	user, err := cfg.getUser(r.Context(), passkey.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find user", err)
+		return
+	}
+	if user.DeactivatedAt.Valid {
+		respondWithError(w, http.StatusForbidden, "Account is deactivated", nil)
+		return
+	}
+
+	cfg.respondWithLogin(w, r, user)
+}
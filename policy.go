@@ -0,0 +1,41 @@
+package main
+
+import "net/http"
+
+// accessPolicy decides how a handler reports a resource the caller isn't
+// allowed to see or act on. Resources whose existence should stay private
+// answer exactly like a missing resource so callers can't probe for them:
+// drafts, which only their author sees, and accounts that blocked the
+// caller or were deactivated. Chirps are public, so acting on someone else's
+// chirp is forbidden rather than hidden.
+type accessPolicy struct {
+	hideExistence bool
+	notFoundMsg   string
+}
+
+var chirpPolicy = accessPolicy{
+	hideExistence: false,
+	notFoundMsg:   "Chirp not found",
+}
+
+var draftPolicy = accessPolicy{
+	hideExistence: true,
+	notFoundMsg:   "Draft not found",
+}
+
+var userPolicy = accessPolicy{
+	hideExistence: true,
+	notFoundMsg:   "Couldn't find user",
+}
+
+func (p accessPolicy) notFound(w http.ResponseWriter, err error) {
+	respondWithError(w, http.StatusNotFound, p.notFoundMsg, err)
+}
+
+func (p accessPolicy) deny(w http.ResponseWriter, msg string, err error) {
+	if p.hideExistence {
+		p.notFound(w, err)
+		return
+	}
+	respondWithError(w, http.StatusForbidden, msg, err)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"github.com/google/uuid"
+)
+
+// publicID returns the obfuscated form of id, or "" when public IDs are off.
+func (cfg *apiConfig) publicID(id uuid.UUID) string {
+	if cfg.publicIDs == nil {
+		return ""
+	}
+	return cfg.publicIDs.Encode(id)
+}
+
+// exposedID is how a chirp or user ID appears in responses and links: its
+// public ID when public IDs are on, so the UUID itself stays private, and
+// the UUID otherwise.
+func (cfg *apiConfig) exposedID(id uuid.UUID) string {
+	if cfg.publicIDs == nil {
+		return id.String()
+	}
+	return cfg.publicIDs.Encode(id)
+}
+
+// parseID accepts both UUIDs and public IDs so existing links keep working
+// after public IDs are turned on.
+func (cfg *apiConfig) parseID(s string) (uuid.UUID, error) {
+	id, err := uuid.Parse(s)
+	if err == nil || cfg.publicIDs == nil {
+		return id, err
+	}
+	return cfg.publicIDs.Decode(s)
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/auth"
+	"github.com/fkl13/chirpy/internal/config"
+	"github.com/fkl13/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	quotaChirps   = "chirps_per_day"
+	quotaAPICalls = "api_calls_per_day"
+)
+
+func membershipTier(user database.User) string {
+	if user.IsChirpyRed {
+		return "red"
+	}
+	return "free"
+}
+
+// quotaFor returns the limits of a user's tier. Tiers missing from the
+// runtime settings are unlimited.
+func (cfg *apiConfig) quotaFor(user database.User) config.Quota {
+	return cfg.settings().quotas[membershipTier(user)]
+}
+
+// quotaReset is when the daily quotas start over: the next UTC midnight.
+func quotaReset(now time.Time) time.Time {
+	return usageDay(now).AddDate(0, 0, 1)
+}
+
+// respondWithQuotaExceeded answers 429 with enough detail for a client to
+// tell which quota ran out, when it resets and whether upgrading helps.
+func respondWithQuotaExceeded(w http.ResponseWriter, tier, quota string, limit int, used int64) {
+	type response struct {
+		Error   string    `json:"error"`
+		Quota   string    `json:"quota"`
+		Tier    string    `json:"tier"`
+		Limit   int       `json:"limit"`
+		Used    int64     `json:"used"`
+		ResetAt time.Time `json:"reset_at"`
+		Upgrade bool      `json:"upgrade_available"`
+	}
+
+	now := time.Now()
+	reset := quotaReset(now)
+	w.Header().Set("Retry-After", strconv.Itoa(int(reset.Sub(now).Seconds())+1))
+	respondWithJSON(w, http.StatusTooManyRequests, response{
+		Error:   "Quota exceeded",
+		Quota:   quota,
+		Tier:    tier,
+		Limit:   limit,
+		Used:    used,
+		ResetAt: reset,
+		Upgrade: tier == "free",
+	})
+}
+
+func (cfg *apiConfig) chirpsToday(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return cfg.dbQueries.CountChirpsByUserSince(ctx, database.CountChirpsByUserSinceParams{
+		UserID:    userID,
+		CreatedAt: usageDay(time.Now()),
+	})
+}
+
+// chirpQuotaUsed returns how many chirps the user posted today and their
+// daily limit. A limit of 0 means unlimited, and nothing is counted.
+func (cfg *apiConfig) chirpQuotaUsed(ctx context.Context, user database.User) (used int64, limit int, err error) {
+	limit = cfg.quotaFor(user).ChirpsPerDay
+	if limit <= 0 {
+		return 0, 0, nil
+	}
+	used, err = cfg.chirpsToday(ctx, user.ID)
+	return used, limit, err
+}
+
+// checkChirpQuota reports whether the user may post n more chirps today.
+// If not, it has already written the response.
+func (cfg *apiConfig) checkChirpQuota(w http.ResponseWriter, r *http.Request, userID uuid.UUID, n int) bool {
+	user, err := cfg.getUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+		return false
+	}
+	used, limit, err := cfg.chirpQuotaUsed(r.Context(), user)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check chirp quota", err)
+		return false
+	}
+	if limit > 0 && used+int64(n) > int64(limit) {
+		respondWithQuotaExceeded(w, membershipTier(user), quotaChirps, limit, used)
+		return false
+	}
+	return true
+}
+
+// checkAPIQuota reports whether the user has API calls left today. If not,
+// it has already written the response.
+func (cfg *apiConfig) checkAPIQuota(w http.ResponseWriter, r *http.Request, userID uuid.UUID) bool {
+	user, err := cfg.getUser(r.Context(), userID)
+	if err != nil {
+		// Unknown users are left for the handler to reject.
+		return true
+	}
+	quota := cfg.quotaFor(user)
+	if quota.APICallsPerDay <= 0 {
+		return true
+	}
+	used, err := cfg.apiCallsToday(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check API quota", err)
+		return false
+	}
+	if used >= int64(quota.APICallsPerDay) {
+		respondWithQuotaExceeded(w, membershipTier(user), quotaAPICalls, quota.APICallsPerDay, used)
+		return false
+	}
+	return true
+}
+
+type quotaUsage struct {
+	// Limit and Remaining are omitted for unlimited quotas.
+	Limit     *int  `json:"limit,omitempty"`
+	Used      int64 `json:"used"`
+	Remaining *int  `json:"remaining,omitempty"`
+}
+
+func newQuotaUsage(limit int, used int64) quotaUsage {
+	usage := quotaUsage{Used: used}
+	if limit > 0 {
+		remaining := max(limit-int(used), 0)
+		usage.Limit = &limit
+		usage.Remaining = &remaining
+	}
+	return usage
+}
+
+func (cfg *apiConfig) getCurrentUserHandler(w http.ResponseWriter, r *http.Request) {
+	type quotas struct {
+		Tier     string     `json:"tier"`
+		ResetAt  time.Time  `json:"reset_at"`
+		Chirps   quotaUsage `json:"chirps_per_day"`
+		APICalls quotaUsage `json:"api_calls_per_day"`
+	}
+	type response struct {
+		User
+		Handle     string `json:"handle"`
+		ChirpCount int64  `json:"chirp_count"`
+		Quota      quotas `json:"quota"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	user, err := cfg.getUser(r.Context(), userId)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+	chirpCount, err := cfg.dbQueries.CountUserChirps(r.Context(), userId)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't count chirps", err)
+		return
+	}
+	chirps, err := cfg.chirpsToday(r.Context(), userId)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't count chirps", err)
+		return
+	}
+	calls, err := cfg.apiCallsToday(r.Context(), userId)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get API usage", err)
+		return
+	}
+
+	quota := cfg.quotaFor(user)
+	respondWithJSON(w, http.StatusOK, response{
+		User:       cfg.newUser(user),
+		Handle:     cfg.handle(user),
+		ChirpCount: chirpCount,
+		Quota: quotas{
+			Tier:     membershipTier(user),
+			ResetAt:  quotaReset(time.Now()),
+			Chirps:   newQuotaUsage(quota.ChirpsPerDay, chirps),
+			APICalls: newQuotaUsage(quota.APICallsPerDay, calls),
+		},
+	})
+}
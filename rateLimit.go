@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// middlewareRateLimit limits requests per client IP, see clientIP, with the
+// limits of the current runtime settings. Every response carries the
+// client's quota, both as the de facto X-RateLimit-* headers and as the
+// RateLimit-* headers from the IETF draft, so clients can slow down before
+// they get a 429.
+func (cfg *apiConfig) middlewareRateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limiter := cfg.settings().rateLimiter
+		if !limiter.Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		result := limiter.Allow(cfg.clientIP(r))
+		resetIn := int(time.Until(result.Reset).Round(time.Second).Seconds())
+		resetIn = max(resetIn, 0)
+
+		h := w.Header()
+		h.Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		h.Set("X-RateLimit-Reset", strconv.FormatInt(result.Reset.Unix(), 10))
+		h.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		h.Set("RateLimit-Reset", strconv.Itoa(resetIn))
+		h.Set("RateLimit-Policy", strconv.Itoa(result.Limit)+";w="+strconv.Itoa(int(limiter.Window().Seconds())))
+
+		if !result.Allowed {
+			h.Set("Retry-After", strconv.Itoa(resetIn))
+			respondWithError(w, http.StatusTooManyRequests, "Too many requests", nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/auth"
+	"github.com/fkl13/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Rechirp is a user reposting someone's chirp. The original is embedded so
+// clients can show its author and body. Rechirps of a deleted chirp stay in
+// place but aren't shown with it, and come back if the chirp is restored.
+type Rechirp struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    string    `json:"user_id"`
+	Chirp     Chirp     `json:"chirp"`
+}
+
+func (cfg *apiConfig) createRechirpHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+	if !cfg.checkActive(w, r, userId) {
+		return
+	}
+
+	chirpId, err := cfg.parseID(r.PathValue("chirpID"))
+	if err != nil {
+		chirpPolicy.notFound(w, err)
+		return
+	}
+	_, err = cfg.getChirp(r.Context(), chirpId)
+	if err != nil {
+		chirpPolicy.notFound(w, err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	rechirp, err := qtx.CreateRechirp(r.Context(), database.CreateRechirpParams{
+		UserID:  userId,
+		ChirpID: chirpId,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusConflict, "You already rechirped this chirp", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't store rechirp", err)
+		return
+	}
+	chirp, err := qtx.AddChirpRechirps(r.Context(), database.AddChirpRechirpsParams{Delta: 1, ID: chirpId})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't count rechirp", err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't store rechirp", err)
+		return
+	}
+	cfg.chirpCache.Put(chirp.ID, chirp)
+
+	respondWithJSON(w, http.StatusCreated, Rechirp{
+		ID:        rechirp.ID,
+		CreatedAt: rechirp.CreatedAt,
+		UserID:    cfg.exposedID(rechirp.UserID),
+		Chirp:     cfg.newChirp(r.Context(), chirp),
+	})
+}
+
+// deleteRechirpHandler undoes the caller's rechirp of a chirp.
+func (cfg *apiConfig) deleteRechirpHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	chirpId, err := cfg.parseID(r.PathValue("chirpID"))
+	if err != nil {
+		chirpPolicy.notFound(w, err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	deleted, err := qtx.DeleteRechirp(r.Context(), database.DeleteRechirpParams{
+		UserID:  userId,
+		ChirpID: chirpId,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete rechirp", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Rechirp not found", nil)
+		return
+	}
+	_, err = qtx.AddChirpRechirps(r.Context(), database.AddChirpRechirpsParams{Delta: -1, ID: chirpId})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't count rechirp", err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete rechirp", err)
+		return
+	}
+	cfg.chirpCache.Invalidate(chirpId)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	recordingBufferSize = 200
+	// recordingMaxBody caps how much of each body is kept.
+	recordingMaxBody = 64 << 10
+)
+
+// Headers and JSON fields that are never recorded as sent.
+var (
+	recordingSecretHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+	recordingSecretFields  = map[string]bool{
+		"password":      true,
+		"token":         true,
+		"refresh_token": true,
+		"captcha_token": true,
+		"secret":        true,
+		"api_key":       true,
+	}
+)
+
+type recording struct {
+	RequestID       string      `json:"request_id"`
+	StartedAt       time.Time   `json:"started_at"`
+	DurationMillis  float64     `json:"duration_ms"`
+	Method          string      `json:"method"`
+	URL             string      `json:"url"`
+	RequestHeaders  http.Header `json:"request_headers"`
+	RequestBody     string      `json:"request_body,omitempty"`
+	Status          int         `json:"status"`
+	ResponseHeaders http.Header `json:"response_headers"`
+	ResponseBody    string      `json:"response_body,omitempty"`
+}
+
+// recorder keeps the most recent request/response pairs in a ring buffer
+// and, with a directory set, appends them to a file per day. It's meant for
+// reproducing client bug reports on dev servers.
+type recorder struct {
+	enabled atomic.Bool
+	dir     string
+
+	mu      sync.Mutex
+	entries []recording
+	next    int
+}
+
+func newRecorder(dir string) *recorder {
+	return &recorder{dir: dir}
+}
+
+func (rec *recorder) add(entry recording) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if len(rec.entries) < recordingBufferSize {
+		rec.entries = append(rec.entries, entry)
+	} else {
+		rec.entries[rec.next] = entry
+	}
+	rec.next = (rec.next + 1) % recordingBufferSize
+
+	if rec.dir != "" {
+		err := rec.appendToFile(entry)
+		if err != nil {
+			slog.Warn("Couldn't write recording", "error", err)
+		}
+	}
+}
+
+func (rec *recorder) appendToFile(entry recording) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	name := filepath.Join(rec.dir, "recordings-"+entry.StartedAt.UTC().Format(time.DateOnly)+".jsonl")
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// list returns the buffered recordings, newest first.
+func (rec *recorder) list() []recording {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	entries := make([]recording, 0, len(rec.entries))
+	for i := range rec.entries {
+		idx := (rec.next - 1 - i + 2*len(rec.entries)) % len(rec.entries)
+		entries = append(entries, rec.entries[idx])
+	}
+	return entries
+}
+
+func (rec *recorder) clear() {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.entries = nil
+	rec.next = 0
+}
+
+func redactHeaders(h http.Header) http.Header {
+	h = h.Clone()
+	for _, name := range recordingSecretHeaders {
+		if _, ok := h[name]; ok {
+			h.Set(name, "[redacted]")
+		}
+	}
+	return h
+}
+
+// redactBody blanks out secret fields of a JSON body. Bodies that aren't
+// JSON are kept as they are.
+func redactBody(body []byte) string {
+	var value any
+	if json.Unmarshal(body, &value) != nil {
+		return string(body)
+	}
+	redactJSON(value)
+	data, err := json.Marshal(value)
+	if err != nil {
+		return string(body)
+	}
+	return string(data)
+}
+
+func redactJSON(value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if recordingSecretFields[strings.ToLower(key)] {
+				v[key] = "[redacted]"
+				continue
+			}
+			redactJSON(field)
+		}
+	case []any:
+		for _, item := range v {
+			redactJSON(item)
+		}
+	}
+}
+
+type bodyRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bodyRecorder) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if room := recordingMaxBody - w.body.Len(); room > 0 {
+		w.body.Write(b[:min(len(b), room)])
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// middlewareRecord records requests while recording is switched on. It
+// never records anything outside the dev platform.
+func (cfg *apiConfig) middlewareRecord(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cfg.platform != "dev" || !cfg.recorder.enabled.Load() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var reqBody []byte
+		if r.Body != nil {
+			reqBody, _ = io.ReadAll(io.LimitReader(r.Body, recordingMaxBody))
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(reqBody), r.Body), r.Body}
+		}
+
+		start := time.Now()
+		bw := &bodyRecorder{ResponseWriter: w}
+		next.ServeHTTP(bw, r)
+
+		entry := recording{
+			StartedAt:       start,
+			DurationMillis:  float64(time.Since(start)) / float64(time.Millisecond),
+			Method:          r.Method,
+			URL:             r.URL.String(),
+			RequestHeaders:  redactHeaders(r.Header),
+			RequestBody:     redactBody(reqBody),
+			Status:          bw.status,
+			ResponseHeaders: redactHeaders(w.Header()),
+			ResponseBody:    redactBody(bw.body.Bytes()),
+		}
+		if rw := findRequestWriter(w); rw != nil {
+			entry.RequestID = rw.requestID
+		}
+		cfg.recorder.add(entry)
+	})
+}
+
+// setRecordingHandler switches recording on or off at runtime.
+func (cfg *apiConfig) setRecordingHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Enabled bool `json:"enabled"`
+	}
+	type response struct {
+		Enabled bool `json:"enabled"`
+	}
+
+	if cfg.platform != "dev" {
+		respondWithError(w, http.StatusForbidden, "Recording is only available on dev", nil)
+		return
+	}
+	params := parameters{}
+	err := decodeJSONBody(w, r, &params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	cfg.recorder.enabled.Store(params.Enabled)
+	slog.Info("Request recording switched", "enabled", params.Enabled)
+	respondWithJSON(w, http.StatusOK, response{Enabled: params.Enabled})
+}
+
+func (cfg *apiConfig) getRecordingsHandler(w http.ResponseWriter, r *http.Request) {
+	respondWithList(w, http.StatusOK, cfg.recorder.list(), cfg.wantsEnvelope(r))
+}
+
+func (cfg *apiConfig) clearRecordingsHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.recorder.clear()
+	w.WriteHeader(http.StatusNoContent)
+}
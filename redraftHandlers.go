@@ -0,0 +1,110 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/fkl13/chirpy/internal/auth"
+	"github.com/fkl13/chirpy/internal/database"
+)
+
+// Redraft is what a client needs to put a deleted chirp back in the
+// composer: its body, location, and the chirp it replied to. InReplyTo is
+// missing when the parent was deleted in the meantime.
+type Redraft struct {
+	Body          string         `json:"body"`
+	ParentChirpID *string        `json:"parent_chirp_id,omitempty"`
+	InReplyTo     *Chirp         `json:"in_reply_to,omitempty"`
+	Location      *ChirpLocation `json:"location,omitempty"`
+}
+
+// redraftChirpHandler deletes one of the caller's chirps and answers with
+// its contents, so clients can delete and redraft without racing another
+// delete or edit of the same chirp.
+func (cfg *apiConfig) redraftChirpHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	chirpId, err := cfg.parseID(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	chirp, err := cfg.getChirp(r.Context(), chirpId)
+	if err != nil {
+		chirpPolicy.notFound(w, err)
+		return
+	}
+	if chirp.UserID != userId {
+		chirpPolicy.deny(w, "You can't redraft this chirp", nil)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	// The cached chirp may be stale; the row returned here is the one that
+	// was deleted.
+	chirp, err = qtx.RedraftChirp(r.Context(), database.RedraftChirpParams{
+		ID:     chirpId,
+		UserID: userId,
+	})
+	if err != nil {
+		chirpPolicy.notFound(w, err)
+		return
+	}
+	res := Redraft{
+		Body:     chirp.Body,
+		Location: newChirpLocation(chirp),
+	}
+	if chirp.ParentChirpID.Valid {
+		parentID := cfg.exposedID(chirp.ParentChirpID.UUID)
+		res.ParentChirpID = &parentID
+		err = qtx.AddChirpReplies(r.Context(), database.AddChirpRepliesParams{Delta: -1, ID: chirp.ParentChirpID.UUID})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't count reply", err)
+			return
+		}
+		parent, err := qtx.GetChirp(r.Context(), chirp.ParentChirpID.UUID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't get parent chirp", err)
+			return
+		}
+		if err == nil {
+			inReplyTo := cfg.newChirp(r.Context(), parent)
+			res.InReplyTo = &inReplyTo
+		}
+	}
+	err = addOutboxEvent(r.Context(), qtx, eventChirpDeleted, chirpDeletedEvent{ID: chirp.ID, UserId: chirp.UserID})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't store chirp event", err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete chirp", err)
+		return
+	}
+	cfg.chirpCache.Invalidate(chirpId)
+	if chirp.ParentChirpID.Valid {
+		cfg.chirpCache.Invalidate(chirp.ParentChirpID.UUID)
+	}
+	cfg.wakeOutboxRelay()
+
+	respondWithJSON(w, http.StatusOK, res)
+}
@@ -2,16 +2,21 @@ package main
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"runtime/debug"
+
+	"github.com/fkl13/chirpy/internal/validate"
 )
 
 func respondWithError(w http.ResponseWriter, code int, msg string, err error) {
-	if err != nil {
-		log.Println(err)
-	}
 	if code > 499 {
-		log.Printf("Responding with 5XX error: %s", msg)
+		slog.Error("Responding with 5XX error", "status", code, "msg", msg, "error", err)
+		reportError(w, code, msg, err)
+	} else if err != nil {
+		slog.Debug("Responding with error", "status", code, "msg", msg, "error", err)
 	}
 	type errorResponse struct {
 		Error string `json:"error"`
@@ -21,14 +26,142 @@ func respondWithError(w http.ResponseWriter, code int, msg string, err error) {
 	})
 }
 
+// respondWithValidationError answers 422 listing every invalid field when
+// err comes from the validate package, and 400 otherwise.
+func respondWithValidationError(w http.ResponseWriter, err error) {
+	var verr *validate.Error
+	if !errors.As(err, &verr) {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	type response struct {
+		Error      string               `json:"error"`
+		Violations []validate.Violation `json:"violations"`
+	}
+	respondWithJSON(w, http.StatusUnprocessableEntity, response{
+		Error:      "Validation failed",
+		Violations: verr.Violations,
+	})
+}
+
 func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	dat, err := json.Marshal(payload)
 	if err != nil {
-		log.Printf("Error marshalling JSON: %s", err)
+		slog.Error("Error marshalling JSON", "error", err)
 		w.WriteHeader(500)
 		return
 	}
 	w.WriteHeader(code)
 	w.Write(dat)
 }
+
+type listMeta struct {
+	Count int `json:"count"`
+	*listPage
+}
+
+// listPage is the part of listMeta for lists that come in pages.
+// NextCursor is passed back in the list's cursor parameter to get the
+// next page.
+type listPage struct {
+	Limit      int    `json:"limit"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type listEnvelope[T any] struct {
+	Data []T      `json:"data"`
+	Meta listMeta `json:"meta"`
+}
+
+// respondWithList writes a list payload either as a bare JSON array or, for
+// clients that expect it, wrapped as {"data": [...], "meta": {...}}.
+func respondWithList[T any](w http.ResponseWriter, code int, items []T, envelope bool) {
+	respondWithListMeta(w, code, items, nil, envelope)
+}
+
+// respondWithPage is respondWithList for one page of a longer list. The
+// next page is also linked in a Link header with the cursor in cursorParam,
+// for clients that take bare arrays.
+func respondWithPage[T any](w http.ResponseWriter, r *http.Request, items []T, page listPage, cursorParam string, envelope bool) {
+	if page.HasMore {
+		next := *r.URL
+		query := next.Query()
+		query.Set(cursorParam, page.NextCursor)
+		next.RawQuery = query.Encode()
+		w.Header().Add("Link", `<`+next.RequestURI()+`>; rel="next"`)
+	}
+	respondWithListMeta(w, http.StatusOK, items, &page, envelope)
+}
+
+func respondWithListMeta[T any](w http.ResponseWriter, code int, items []T, page *listPage, envelope bool) {
+	if items == nil {
+		items = []T{}
+	}
+	if !envelope {
+		respondWithJSON(w, code, items)
+		return
+	}
+	respondWithJSON(w, code, listEnvelope[T]{
+		Data: items,
+		Meta: listMeta{Count: len(items), listPage: page},
+	})
+}
+
+// wantsEnvelope lets a request override the configured envelope mode with
+// ?envelope=true or ?envelope=false.
+func (cfg *apiConfig) wantsEnvelope(r *http.Request) bool {
+	switch r.URL.Query().Get("envelope") {
+	case "true", "1":
+		return true
+	case "false", "0":
+		return false
+	}
+	return cfg.envelopeResponses
+}
+
+// decodeJSONBody decodes a single JSON value from the request body into dst.
+// Bodies over maxBodyBytes and trailing data count as malformed input.
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, dst any) error {
+	const maxBodyBytes = 1 << 20
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	err := decoder.Decode(dst)
+	if err != nil {
+		return err
+	}
+	if decoder.More() {
+		return errors.New("unexpected data after JSON value")
+	}
+	return nil
+}
+
+// panicError carries a recovered panic and its stack to the error reporter.
+type panicError struct {
+	value any
+	stack []byte
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.value)
+}
+
+// middlewareRecover turns a panicking handler into a logged 500 response
+// instead of a dropped connection.
+func middlewareRecover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			stack := debug.Stack()
+			slog.Error("Handler panicked", "method", r.Method, "path", r.URL.Path, "panic", rec, "stack", string(stack))
+			respondWithError(w, http.StatusInternalServerError, "Internal server error", &panicError{value: rec, stack: stack})
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
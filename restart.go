@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// inheritedListenFDEnv tells a process started by startSuccessor which file
+// descriptor holds the listener it takes over.
+const inheritedListenFDEnv = "CHIRPY_LISTEN_FD"
+
+// drainTimeout bounds how long in-flight requests, including long polls,
+// may keep an old process alive after it stopped accepting connections.
+const drainTimeout = 90 * time.Second
+
+// serve runs srv until SIGINT or SIGTERM, then drains it. On SIGUSR2 it
+// first starts a new copy of the binary on the same listener, so upgrades
+// don't drop a single connection.
+func serve(srv *http.Server, ln net.Listener) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGUSR2)
+	defer signal.Stop(signals)
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.Serve(ln)
+	}()
+
+	for {
+		select {
+		case err := <-errc:
+			return err
+		case sig := <-signals:
+			if sig == syscall.SIGUSR2 {
+				pid, err := startSuccessor(ln)
+				if err != nil {
+					log.Printf("Couldn't start new process, keep serving: %v", err)
+					continue
+				}
+				log.Printf("Handed listener to process %d, draining", pid)
+			} else {
+				log.Printf("Got %v, draining", sig)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+			defer cancel()
+			err := srv.Shutdown(ctx)
+			if err != nil {
+				srv.Close()
+				return err
+			}
+			if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		}
+	}
+}
+
+func startSuccessor(ln net.Listener) (int, error) {
+	type filer interface {
+		File() (*os.File, error)
+	}
+	l, ok := ln.(filer)
+	if !ok {
+		return 0, fmt.Errorf("can't hand over a %T", ln)
+	}
+	f, err := l.File()
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	executable, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = []*os.File{f}
+	cmd.Env = append(os.Environ(), inheritedListenFDEnv+"="+strconv.Itoa(systemdListenFDsStart))
+	err = cmd.Start()
+	if err != nil {
+		return 0, err
+	}
+
+	// The successor serves on the same socket file now, closing our
+	// listener must not remove it.
+	if ul, ok := ln.(*net.UnixListener); ok {
+		ul.SetUnlinkOnClose(false)
+	}
+	return cmd.Process.Pid, nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/fkl13/chirpy/internal/config"
+	"github.com/fkl13/chirpy/internal/database"
+	"github.com/fkl13/chirpy/internal/search"
+	"github.com/google/uuid"
+)
+
+const reindexBatchSize = 500
+
+// searchChirpsHandler answers ?q= with matching chirps, best match first.
+// Hits that were deleted since they were indexed are skipped. With
+// ?near=lat,lng only chirps tagged within ?radius_km= of that point are
+// returned.
+func (cfg *apiConfig) searchChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	const defaultLimit = 20
+	const maxLimit = 100
+	const defaultRadiusKm = 25
+	const maxRadiusKm = 500
+
+	if cfg.search == nil {
+		respondWithError(w, http.StatusNotImplemented, "Search isn't configured", nil)
+		return
+	}
+
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		respondWithError(w, http.StatusBadRequest, "Missing search query", nil)
+		return
+	}
+	limit := defaultLimit
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		n, err := strconv.Atoi(limitParam)
+		if err != nil || n <= 0 {
+			respondWithError(w, http.StatusBadRequest, "Invalid limit", err)
+			return
+		}
+		limit = min(n, maxLimit)
+	}
+
+	near := r.URL.Query().Get("near")
+	var nearLat, nearLng float64
+	radiusKm := float64(defaultRadiusKm)
+	if near != "" {
+		var err error
+		nearLat, nearLng, err = parseNear(near)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		if radiusParam := r.URL.Query().Get("radius_km"); radiusParam != "" {
+			radiusKm, err = strconv.ParseFloat(radiusParam, 64)
+			if err != nil || radiusKm <= 0 || radiusKm > maxRadiusKm {
+				respondWithError(w, http.StatusBadRequest, fmt.Sprintf("radius_km must be between 0 and %d", maxRadiusKm), err)
+				return
+			}
+		}
+	}
+
+	// Location filtering happens on the hits, so ask for as many as we may
+	// return to still fill the page.
+	hits := limit
+	if near != "" {
+		hits = maxLimit
+	}
+	ids, err := cfg.search.Search(r.Context(), query, hits)
+	if err != nil {
+		respondWithError(w, http.StatusBadGateway, "Couldn't search chirps", err)
+		return
+	}
+	if viewer := cfg.viewerID(r); viewer.Valid && len(ids) > 0 {
+		ids, err = cfg.dbQueries.GetVisibleChirpIDs(r.Context(), database.GetVisibleChirpIDsParams{
+			Ids:      ids,
+			ViewerID: viewer.UUID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't search chirps", err)
+			return
+		}
+	}
+
+	chirps := []database.Chirp{}
+	for _, id := range ids {
+		if len(chirps) == limit {
+			break
+		}
+		chirp, err := cfg.getChirp(r.Context(), id)
+		if err != nil {
+			continue
+		}
+		if near != "" {
+			if !chirp.Latitude.Valid || !chirp.Longitude.Valid {
+				continue
+			}
+			if distanceKm(nearLat, nearLng, chirp.Latitude.Float64, chirp.Longitude.Float64) > radiusKm {
+				continue
+			}
+		}
+		chirps = append(chirps, chirp)
+	}
+	respondWithList(w, http.StatusOK, cfg.newChirps(r.Context(), chirps), cfg.wantsEnvelope(r))
+}
+
+// reindexChirps rebuilds the search index from the database and reports
+// the number of chirps indexed so far after every batch.
+func reindexChirps(ctx context.Context, q *database.Queries, backend search.Backend, progress func(indexed int)) (int, error) {
+	err := backend.DeleteAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	indexed := 0
+	after := uuid.Nil
+	for {
+		chirps, err := q.ListChirpsAfterID(ctx, database.ListChirpsAfterIDParams{
+			ID:    after,
+			Limit: reindexBatchSize,
+		})
+		if err != nil {
+			return indexed, err
+		}
+		if len(chirps) == 0 {
+			return indexed, nil
+		}
+
+		docs := make([]search.Document, len(chirps))
+		for i, chirp := range chirps {
+			docs[i] = search.Document{
+				ID:        chirp.ID,
+				UserID:    chirp.UserID,
+				Body:      chirp.Body,
+				CreatedAt: chirp.CreatedAt,
+			}
+		}
+		err = backend.Index(ctx, docs)
+		if err != nil {
+			return indexed, err
+		}
+
+		indexed += len(chirps)
+		after = chirps[len(chirps)-1].ID
+		if progress != nil {
+			progress(indexed)
+		}
+	}
+}
+
+// runReindex rebuilds the search index from the command line. It returns
+// the process exit code.
+func runReindex(out io.Writer) int {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(out, "couldn't load config: %v\n", err)
+		return 1
+	}
+	dbConn, err := sql.Open("postgres", cfg.DBURL)
+	if err != nil {
+		fmt.Fprintf(out, "couldn't open db: %v\n", err)
+		return 1
+	}
+	defer dbConn.Close()
+	dbQueries := database.New(dbConn)
+
+	backend, err := search.New(cfg.Search, dbQueries)
+	if err != nil {
+		fmt.Fprintf(out, "couldn't set up search: %v\n", err)
+		return 1
+	}
+	if backend == nil {
+		fmt.Fprintln(out, "SEARCH_BACKEND is off, nothing to reindex")
+		return 1
+	}
+	if _, ok := backend.(*search.Postgres); ok {
+		fmt.Fprintln(out, "the postgres search backend indexes chirps itself, nothing to reindex")
+		return 0
+	}
+
+	n, err := reindexChirps(context.Background(), dbQueries, backend, func(indexed int) {
+		fmt.Fprintf(out, "indexed %d chirps\n", indexed)
+	})
+	if err != nil {
+		fmt.Fprintf(out, "reindex failed after %d chirps: %v\n", n, err)
+		return 1
+	}
+	fmt.Fprintf(out, "reindexed %d chirps\n", n)
+	return 0
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"log"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/config"
+	"github.com/fkl13/chirpy/internal/ratelimit"
+	"github.com/fkl13/chirpy/internal/wordfilter"
+)
+
+// runtimeSettings is the reloadable part of the configuration, prepared for
+// fast lookups. A new value replaces the old one atomically on reload.
+type runtimeSettings struct {
+	// badWords masks the banned words, except in the allowlisted words.
+	badWords         *wordfilter.Filter
+	allowedWords     map[string]struct{}
+	matchConfusables bool
+	featureFlags     map[string]bool
+	quotas           map[string]config.Quota
+	rollouts         map[string]config.Rollout
+	normalization    config.Normalization
+	onboarding       config.Onboarding
+	rateLimiter      *ratelimit.Limiter
+}
+
+func newRuntimeSettings(rt config.Runtime) *runtimeSettings {
+	s := &runtimeSettings{
+		allowedWords:     map[string]struct{}{},
+		matchConfusables: rt.MatchConfusables,
+		featureFlags:     rt.FeatureFlags,
+		quotas:           rt.Quotas,
+		rollouts:         rt.Rollouts,
+		normalization:    rt.Normalization,
+		onboarding:       rt.Onboarding,
+		rateLimiter:      ratelimit.New(0, time.Minute),
+	}
+	if rt.RateLimit != nil {
+		s.rateLimiter = ratelimit.New(rt.RateLimit.Limit, rt.RateLimit.Window)
+	}
+	s.badWords = wordfilter.New(rt.BannedWords, s.matchConfusables)
+	return s
+}
+
+// withAllowedWords returns a copy of the settings in which the given words
+// are left alone by the banned-word filter.
+func (s *runtimeSettings) withAllowedWords(words []string) *runtimeSettings {
+	next := *s
+	next.allowedWords = map[string]struct{}{}
+	for _, word := range words {
+		next.allowedWords[strings.ToLower(word)] = struct{}{}
+	}
+	next.badWords = s.badWords.WithAllowed(words)
+	return &next
+}
+
+func (cfg *apiConfig) settings() *runtimeSettings {
+	return cfg.runtime.Load()
+}
+
+// normalization returns the rules for normalized chirp bodies, or the
+// defaults if the settings haven't been loaded.
+func (cfg *apiConfig) normalization() config.Normalization {
+	if s := cfg.settings(); s != nil {
+		return s.normalization
+	}
+	return config.DefaultRuntime().Normalization
+}
+
+func (cfg *apiConfig) featureEnabled(name string) bool {
+	return cfg.settings().featureFlags[name]
+}
+
+func (cfg *apiConfig) reloadSettings() error {
+	cfg.reloadMu.Lock()
+	defer cfg.reloadMu.Unlock()
+
+	rt, err := config.LoadRuntime(cfg.runtimeConfigFile)
+	if err != nil {
+		return err
+	}
+	if rt.LogLevel != "" {
+		err = cfg.logLevel.UnmarshalText([]byte(rt.LogLevel))
+		if err != nil {
+			return err
+		}
+	}
+	if rt.RateLimit == nil {
+		rt.RateLimit = &cfg.rateLimit
+	}
+	settings := newRuntimeSettings(rt)
+	current := cfg.settings()
+	if current != nil && current.rateLimiter.Limit() == rt.RateLimit.Limit && current.rateLimiter.Window() == rt.RateLimit.Window {
+		// Unchanged limits keep counting where they were.
+		settings.rateLimiter = current.rateLimiter
+	}
+	allowed, err := cfg.loadAllowedWords()
+	if err != nil {
+		// Keep the allowlist we had rather than banning exempted words.
+		log.Printf("Couldn't load allowed words: %v", err)
+		if current != nil {
+			allowed = slices.Collect(maps.Keys(current.allowedWords))
+		}
+	}
+	cfg.runtime.Store(settings.withAllowedWords(allowed))
+	return nil
+}
+
+func (cfg *apiConfig) loadAllowedWords() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := cfg.dbQueries.GetAllowedWords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	words := make([]string, len(rows))
+	for i, row := range rows {
+		words[i] = row.Word
+	}
+	return words, nil
+}
+
+// watchReloadSignal reloads the runtime settings whenever the process gets
+// SIGHUP. A broken settings file keeps the previous settings in place.
+func (cfg *apiConfig) watchReloadSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		for range signals {
+			err := cfg.reloadSettings()
+			if err != nil {
+				log.Printf("Couldn't reload settings: %v", err)
+				continue
+			}
+			log.Println("Reloaded settings")
+		}
+	}()
+}
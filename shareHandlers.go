@@ -0,0 +1,105 @@
+package main
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/url"
+
+	"github.com/fkl13/chirpy/internal/database"
+)
+
+// chirpPage is the page, served from /app, that shows a single chirp to
+// whoever follows a share link.
+const chirpPage = "/app/chirp.html"
+
+// chirpLink points at the chirp page of the web app at publicURL.
+func chirpLink(publicURL, id string) string {
+	return publicURL + chirpPage + "?" + url.Values{"id": {id}}.Encode()
+}
+
+const shareCodeAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+func makeShareCode() (string, error) {
+	const length = 7
+	code := make([]byte, length)
+	max := big.NewInt(int64(len(shareCodeAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = shareCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// shareCodeAttempts is how often a new share code is drawn when the last
+// one was already taken.
+const shareCodeAttempts = 3
+
+// createChirpShareHandler returns the short link for a chirp, creating it
+// the first time the chirp is shared. RedirectCount is how often the link
+// was followed.
+func (cfg *apiConfig) createChirpShareHandler(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Code          string `json:"code"`
+		URL           string `json:"url"`
+		RedirectCount int32  `json:"redirect_count"`
+	}
+
+	chirpId, err := cfg.parseID(r.PathValue("chirpID"))
+	if err != nil {
+		chirpPolicy.notFound(w, err)
+		return
+	}
+	chirp, err := cfg.getChirp(r.Context(), chirpId)
+	if err != nil {
+		chirpPolicy.notFound(w, err)
+		return
+	}
+
+	var share database.ChirpShare
+	for range shareCodeAttempts {
+		var code string
+		code, err = makeShareCode()
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't create share code", err)
+			return
+		}
+		share, err = cfg.dbQueries.CreateChirpShare(r.Context(), database.CreateChirpShareParams{
+			Code:    code,
+			ChirpID: chirp.ID,
+		})
+		if !isUniqueViolation(err) {
+			break
+		}
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save share code", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		Code:          share.Code,
+		URL:           "/s/" + share.Code,
+		RedirectCount: share.RedirectCount,
+	})
+}
+
+// shareRedirectHandler sends a browser that followed a share link to the
+// chirp's page.
+func (cfg *apiConfig) shareRedirectHandler(w http.ResponseWriter, r *http.Request) {
+	share, err := cfg.dbQueries.RecordChirpShareRedirect(r.Context(), r.PathValue("code"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.NotFound(w, r)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't resolve share code", err)
+		return
+	}
+	http.Redirect(w, r, chirpLink(cfg.publicURL, cfg.exposedID(share.ChirpID)), http.StatusFound)
+}
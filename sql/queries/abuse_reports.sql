@@ -0,0 +1,45 @@
+-- name: CreateAbuseReport :one
+INSERT INTO abuse_reports (id, created_at, url, reason, details, contact_email, chirp_id, reporter_id, outcome, resolved_at)
+VALUES (
+	gen_random_uuid(),
+	NOW(),
+	$1,
+	$2,
+	$3,
+	$4,
+	$5,
+	$6,
+	$7,
+	CASE WHEN $7::text IS NULL THEN NULL ELSE NOW() END
+)
+RETURNING *;
+
+-- name: GetOpenAbuseReports :many
+SELECT abuse_reports.*,
+	COALESCE(reporters.actioned, 0)::bigint AS reporter_actioned,
+	COALESCE(reporters.dismissed, 0)::bigint AS reporter_dismissed
+FROM abuse_reports
+LEFT JOIN (
+	SELECT reporter_id,
+		COUNT(*) FILTER (WHERE outcome = 'actioned') AS actioned,
+		COUNT(*) FILTER (WHERE outcome = 'dismissed') AS dismissed
+	FROM abuse_reports
+	WHERE reporter_id IS NOT NULL
+	GROUP BY reporter_id
+) reporters ON reporters.reporter_id = abuse_reports.reporter_id
+WHERE abuse_reports.resolved_at IS NULL
+ORDER BY abuse_reports.created_at
+LIMIT $1;
+
+-- name: GetReporterStats :one
+SELECT
+	COUNT(*) FILTER (WHERE outcome = 'actioned')::bigint AS actioned,
+	COUNT(*) FILTER (WHERE outcome = 'dismissed')::bigint AS dismissed
+FROM abuse_reports
+WHERE reporter_id = $1;
+
+-- name: ResolveAbuseReport :one
+UPDATE abuse_reports
+SET resolved_at = NOW(), outcome = $2
+WHERE id = $1
+RETURNING *;
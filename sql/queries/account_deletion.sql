@@ -0,0 +1,42 @@
+-- name: GetUserChirpIDs :many
+SELECT id FROM chirps
+WHERE user_id = $1
+AND deleted_at IS NULL;
+
+-- name: DeleteUserLikes :execrows
+WITH deleted AS (
+	DELETE FROM chirp_likes
+	WHERE user_id = @user_id
+	RETURNING chirp_id
+)
+UPDATE chirps
+SET likes_count = likes_count - 1
+WHERE id IN (SELECT chirp_id FROM deleted);
+
+-- name: DeleteUserRechirps :execrows
+WITH deleted AS (
+	DELETE FROM rechirps
+	WHERE user_id = @user_id
+	RETURNING chirp_id
+)
+UPDATE chirps
+SET rechirp_count = rechirp_count - 1
+WHERE id IN (SELECT chirp_id FROM deleted);
+
+-- name: UncountUserReplies :execrows
+UPDATE chirps
+SET reply_count = chirps.reply_count - replies.count
+FROM (
+	SELECT parent_chirp_id, count(*) AS count
+	FROM chirps
+	WHERE user_id = @user_id
+	AND parent_chirp_id IS NOT NULL
+	AND deleted_at IS NULL
+	AND status = 'published'
+	GROUP BY parent_chirp_id
+) replies
+WHERE chirps.id = replies.parent_chirp_id;
+
+-- name: DeleteUser :execrows
+DELETE FROM users
+WHERE id = $1;
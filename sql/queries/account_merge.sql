@@ -0,0 +1,112 @@
+-- name: MergeUserChirps :execrows
+UPDATE chirps
+SET user_id = @into_user_id
+WHERE user_id = @from_user_id;
+
+-- name: MergeUserLikes :execrows
+UPDATE chirp_likes
+SET user_id = @into_user_id
+WHERE user_id = @from_user_id
+AND chirp_id NOT IN (SELECT chirp_id FROM chirp_likes WHERE user_id = @into_user_id);
+
+-- name: DeleteDuplicateLikes :execrows
+WITH deleted AS (
+	DELETE FROM chirp_likes
+	WHERE user_id = @from_user_id
+	RETURNING chirp_id
+)
+UPDATE chirps
+SET likes_count = likes_count - 1
+WHERE id IN (SELECT chirp_id FROM deleted);
+
+-- name: MergeUserRechirps :execrows
+UPDATE rechirps
+SET user_id = @into_user_id
+WHERE user_id = @from_user_id
+AND chirp_id NOT IN (SELECT chirp_id FROM rechirps WHERE user_id = @into_user_id);
+
+-- name: DeleteDuplicateRechirps :execrows
+WITH deleted AS (
+	DELETE FROM rechirps
+	WHERE user_id = @from_user_id
+	RETURNING chirp_id
+)
+UPDATE chirps
+SET rechirp_count = rechirp_count - 1
+WHERE id IN (SELECT chirp_id FROM deleted);
+
+-- name: MergeUserBookmarks :execrows
+UPDATE bookmarks
+SET user_id = @into_user_id
+WHERE user_id = @from_user_id
+AND chirp_id NOT IN (SELECT chirp_id FROM bookmarks WHERE user_id = @into_user_id);
+
+-- name: DeleteDuplicateBookmarks :execrows
+DELETE FROM bookmarks
+WHERE user_id = @from_user_id;
+
+-- name: MergeUserFollowing :execrows
+UPDATE follows
+SET follower_id = @into_user_id
+WHERE follower_id = @from_user_id
+AND followee_id <> @into_user_id
+AND followee_id NOT IN (SELECT followee_id FROM follows WHERE follower_id = @into_user_id);
+
+-- name: MergeUserFollowers :execrows
+UPDATE follows
+SET followee_id = @into_user_id
+WHERE followee_id = @from_user_id
+AND follower_id <> @into_user_id
+AND follower_id NOT IN (SELECT follower_id FROM follows WHERE followee_id = @into_user_id);
+
+-- name: DeleteDuplicateFollows :execrows
+DELETE FROM follows
+WHERE follower_id = @from_user_id
+OR followee_id = @from_user_id;
+
+-- name: MergeUserBlocking :execrows
+UPDATE blocks
+SET blocker_id = @into_user_id
+WHERE blocker_id = @from_user_id
+AND blocked_id <> @into_user_id
+AND blocked_id NOT IN (SELECT blocked_id FROM blocks WHERE blocker_id = @into_user_id);
+
+-- name: MergeUserBlockers :execrows
+UPDATE blocks
+SET blocked_id = @into_user_id
+WHERE blocked_id = @from_user_id
+AND blocker_id <> @into_user_id
+AND blocker_id NOT IN (SELECT blocker_id FROM blocks WHERE blocked_id = @into_user_id);
+
+-- name: DeleteDuplicateBlocks :execrows
+DELETE FROM blocks
+WHERE blocker_id = @from_user_id
+OR blocked_id = @from_user_id;
+
+-- name: MergeUserMuting :execrows
+UPDATE mutes
+SET muter_id = @into_user_id
+WHERE muter_id = @from_user_id
+AND muted_id <> @into_user_id
+AND muted_id NOT IN (SELECT muted_id FROM mutes WHERE muter_id = @into_user_id);
+
+-- name: MergeUserMuters :execrows
+UPDATE mutes
+SET muted_id = @into_user_id
+WHERE muted_id = @from_user_id
+AND muter_id <> @into_user_id
+AND muter_id NOT IN (SELECT muter_id FROM mutes WHERE muted_id = @into_user_id);
+
+-- name: DeleteDuplicateMutes :execrows
+DELETE FROM mutes
+WHERE muter_id = @from_user_id
+OR muted_id = @from_user_id;
+
+-- name: DeleteBlockedFollows :execrows
+DELETE FROM follows
+WHERE (follower_id = @user_id OR followee_id = @user_id)
+AND EXISTS (
+	SELECT 1 FROM blocks
+	WHERE (blocker_id = follows.follower_id AND blocked_id = follows.followee_id)
+	OR (blocker_id = follows.followee_id AND blocked_id = follows.follower_id)
+);
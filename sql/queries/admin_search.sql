@@ -0,0 +1,22 @@
+-- name: AdminSearchChirps :many
+SELECT chirps.id, chirps.created_at, chirps.user_id, users.email AS author_email, chirps.body, chirps.status,
+	chirps.deleted_at, users.deactivated_at AS author_deactivated_at,
+	COUNT(abuse_reports.id) FILTER (WHERE abuse_reports.resolved_at IS NULL)::bigint AS open_reports,
+	COUNT(abuse_reports.id) FILTER (WHERE abuse_reports.outcome = 'actioned')::bigint AS actioned_reports,
+	COUNT(abuse_reports.id) FILTER (WHERE abuse_reports.outcome = 'dismissed')::bigint AS dismissed_reports
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+LEFT JOIN abuse_reports ON abuse_reports.chirp_id = chirps.id
+WHERE (sqlc.narg(query)::text IS NULL OR to_tsvector('english', chirps.body) @@ websearch_to_tsquery('english', sqlc.narg(query)))
+AND (sqlc.narg(email)::text IS NULL OR lower(users.email) = lower(sqlc.narg(email)))
+AND (sqlc.narg(ip_address)::text IS NULL OR chirps.user_id IN (
+	SELECT login_events.user_id FROM login_events WHERE login_events.ip_address = sqlc.narg(ip_address)
+))
+AND (sqlc.narg(since)::timestamp IS NULL OR chirps.created_at >= sqlc.narg(since))
+AND (sqlc.narg(until)::timestamp IS NULL OR chirps.created_at < sqlc.narg(until))
+GROUP BY chirps.id, users.id
+HAVING sqlc.narg(reported)::text IS NULL
+	OR (sqlc.narg(reported) = 'open' AND COUNT(abuse_reports.id) FILTER (WHERE abuse_reports.resolved_at IS NULL) > 0)
+	OR (sqlc.narg(reported) = 'any' AND COUNT(abuse_reports.id) > 0)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT @max_results;
@@ -0,0 +1,18 @@
+-- name: GetAllowedWords :many
+SELECT *
+FROM allowed_words
+ORDER BY word;
+
+-- name: AddAllowedWord :one
+INSERT INTO allowed_words (word, created_at, reason)
+VALUES (
+	$1,
+	NOW(),
+	$2
+)
+ON CONFLICT (word) DO UPDATE SET reason = EXCLUDED.reason
+RETURNING *;
+
+-- name: DeleteAllowedWord :execrows
+DELETE FROM allowed_words
+WHERE word = $1;
@@ -0,0 +1,41 @@
+-- name: AddAnalyticsEvents :exec
+INSERT INTO analytics_events (day, route, country, visitor, requests)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4,
+	$5
+)
+ON CONFLICT (day, route, country, visitor) DO UPDATE
+SET requests = analytics_events.requests + EXCLUDED.requests;
+
+-- name: DeleteAnalyticsEventsBefore :execrows
+DELETE FROM analytics_events
+WHERE day < $1;
+
+-- name: GetAnalyticsByDay :many
+SELECT day,
+	count(DISTINCT visitor) FILTER (WHERE visitor <> '')::bigint AS visitors,
+	sum(requests)::bigint AS requests
+FROM analytics_events
+WHERE day >= $1
+GROUP BY day
+ORDER BY day DESC;
+
+-- name: GetTopAnalyticsRoutes :many
+SELECT route, sum(requests)::bigint AS requests
+FROM analytics_events
+WHERE day >= $1
+GROUP BY route
+ORDER BY requests DESC, route
+LIMIT $2;
+
+-- name: GetTopAnalyticsCountries :many
+SELECT country, sum(requests)::bigint AS requests
+FROM analytics_events
+WHERE day >= $1
+AND country <> ''
+GROUP BY country
+ORDER BY requests DESC, country
+LIMIT $2;
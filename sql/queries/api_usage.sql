@@ -0,0 +1,32 @@
+-- name: AddAPIUsage :exec
+INSERT INTO api_usage (user_id, day, calls)
+VALUES (
+	$1,
+	$2,
+	$3
+)
+ON CONFLICT (user_id, day) DO UPDATE
+SET calls = api_usage.calls + EXCLUDED.calls;
+
+-- name: GetUserAPIUsage :many
+SELECT day, calls
+FROM api_usage
+WHERE user_id = $1
+AND day >= $2
+ORDER BY day DESC;
+
+-- name: GetAPIUsageByDay :many
+SELECT day, count(*) AS users, sum(calls)::bigint AS calls
+FROM api_usage
+WHERE day >= $1
+GROUP BY day
+ORDER BY day DESC;
+
+-- name: GetTopAPIUsers :many
+SELECT api_usage.user_id, users.email, sum(api_usage.calls)::bigint AS calls
+FROM api_usage
+JOIN users ON users.id = api_usage.user_id
+WHERE api_usage.day >= $1
+GROUP BY api_usage.user_id, users.email
+ORDER BY calls DESC
+LIMIT $2;
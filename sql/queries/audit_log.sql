@@ -0,0 +1,17 @@
+-- name: CreateAuditLogEntry :one
+INSERT INTO audit_log (id, created_at, actor, action, user_id, reason)
+VALUES (
+	gen_random_uuid(),
+	NOW(),
+	$1,
+	$2,
+	$3,
+	$4
+)
+RETURNING *;
+
+-- name: GetAuditLog :many
+SELECT *
+FROM audit_log
+ORDER BY created_at DESC
+LIMIT $1;
@@ -0,0 +1,64 @@
+-- name: BlockUser :execrows
+INSERT INTO blocks (blocker_id, blocked_id, created_at)
+VALUES (
+	$1,
+	$2,
+	NOW()
+)
+ON CONFLICT (blocker_id, blocked_id) DO NOTHING;
+
+-- name: UnblockUser :execrows
+DELETE FROM blocks
+WHERE blocker_id = $1
+AND blocked_id = $2;
+
+-- name: GetBlockedUsers :many
+SELECT blocked_id AS user_id, created_at
+FROM blocks
+WHERE blocker_id = $1
+ORDER BY created_at DESC;
+
+-- name: IsBlocked :one
+SELECT EXISTS (
+	SELECT 1 FROM blocks WHERE blocker_id = $1 AND blocked_id = $2
+)::bool AS blocked;
+
+-- name: MuteUser :execrows
+INSERT INTO mutes (muter_id, muted_id, created_at)
+VALUES (
+	$1,
+	$2,
+	NOW()
+)
+ON CONFLICT (muter_id, muted_id) DO NOTHING;
+
+-- name: UnmuteUser :execrows
+DELETE FROM mutes
+WHERE muter_id = $1
+AND muted_id = $2;
+
+-- name: GetMutedUsers :many
+SELECT muted_id AS user_id, created_at
+FROM mutes
+WHERE muter_id = $1
+ORDER BY created_at DESC;
+
+-- name: IsHiddenFrom :one
+SELECT EXISTS (
+	SELECT 1 FROM blocks WHERE blocker_id = @viewer_id AND blocked_id = @user_id
+	UNION ALL
+	SELECT 1 FROM blocks WHERE blocker_id = @user_id AND blocked_id = @viewer_id
+	UNION ALL
+	SELECT 1 FROM mutes WHERE muter_id = @viewer_id AND muted_id = @user_id
+)::bool AS hidden;
+
+-- name: GetVisibleChirpIDs :many
+SELECT chirps.id
+FROM unnest(@ids::uuid[]) WITH ORDINALITY AS hits (id, position)
+JOIN chirps ON chirps.id = hits.id
+WHERE chirps.user_id NOT IN (
+	SELECT blocked_id FROM blocks WHERE blocker_id = @viewer_id
+	UNION ALL
+	SELECT muted_id FROM mutes WHERE muter_id = @viewer_id
+)
+ORDER BY hits.position;
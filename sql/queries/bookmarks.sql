@@ -0,0 +1,30 @@
+-- name: CreateBookmark :one
+INSERT INTO bookmarks (user_id, chirp_id, created_at)
+VALUES (
+	$1,
+	$2,
+	NOW()
+)
+ON CONFLICT (user_id, chirp_id) DO UPDATE
+SET user_id = EXCLUDED.user_id
+RETURNING *;
+
+-- name: DeleteBookmark :execrows
+DELETE FROM bookmarks
+WHERE user_id = $1
+AND chirp_id = $2;
+
+-- name: GetBookmarkedChirps :many
+SELECT chirps.*
+FROM chirps
+JOIN bookmarks ON bookmarks.chirp_id = chirps.id
+WHERE bookmarks.user_id = $1
+AND chirps.user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
+AND chirps.deleted_at IS NULL
+AND chirps.status = 'published'
+AND chirps.user_id NOT IN (
+	SELECT blocked_id FROM blocks WHERE blocker_id = $1
+	UNION ALL
+	SELECT muted_id FROM mutes WHERE muter_id = $1
+)
+ORDER BY bookmarks.created_at DESC;
@@ -0,0 +1,33 @@
+-- name: GetDrafts :many
+SELECT *
+FROM chirps
+WHERE user_id = $1
+AND status <> 'published'
+AND deleted_at IS NULL
+ORDER BY publish_at asc NULLS LAST, created_at desc;
+
+-- name: GetDraft :one
+SELECT *
+FROM chirps
+WHERE id = $1
+AND user_id = $2
+AND status <> 'published'
+AND deleted_at IS NULL;
+
+-- name: GetDueChirps :many
+SELECT *
+FROM chirps
+WHERE status = 'scheduled'
+AND publish_at <= $1
+AND deleted_at IS NULL
+AND user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL OR moved_to IS NOT NULL)
+ORDER BY publish_at asc
+LIMIT $2;
+
+-- name: PublishChirp :one
+UPDATE chirps
+SET status = 'published', created_at = NOW(), updated_at = NOW()
+WHERE id = $1
+AND status <> 'published'
+AND deleted_at IS NULL
+RETURNING *;
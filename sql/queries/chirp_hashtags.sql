@@ -0,0 +1,36 @@
+-- name: AddChirpHashtags :exec
+INSERT INTO chirp_hashtags (chirp_id, tag, created_at)
+SELECT $1, unnest(@tags::text[]), NOW()
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteChirpHashtags :exec
+DELETE FROM chirp_hashtags
+WHERE chirp_id = $1;
+
+-- name: GetChirpsByHashtag :many
+SELECT chirps.*
+FROM chirps
+JOIN chirp_hashtags ON chirp_hashtags.chirp_id = chirps.id
+WHERE chirp_hashtags.tag = $1
+AND chirps.user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
+AND chirps.deleted_at IS NULL
+AND chirps.status = 'published'
+AND chirps.user_id NOT IN (
+	SELECT blocked_id FROM blocks WHERE blocker_id = sqlc.narg(viewer_id)
+	UNION ALL
+	SELECT muted_id FROM mutes WHERE muter_id = sqlc.narg(viewer_id)
+)
+ORDER BY chirps.created_at DESC
+LIMIT $2;
+
+-- name: GetTrendingHashtags :many
+SELECT chirp_hashtags.tag, count(*) AS chirps
+FROM chirp_hashtags
+JOIN chirps ON chirps.id = chirp_hashtags.chirp_id
+WHERE chirp_hashtags.created_at > $1
+AND chirps.user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
+AND chirps.deleted_at IS NULL
+AND chirps.status = 'published'
+GROUP BY chirp_hashtags.tag
+ORDER BY count(*) DESC, chirp_hashtags.tag
+LIMIT $2;
@@ -0,0 +1,34 @@
+-- name: LikeChirp :execrows
+INSERT INTO chirp_likes (user_id, chirp_id, created_at)
+VALUES (
+	$1,
+	$2,
+	NOW()
+)
+ON CONFLICT (user_id, chirp_id) DO NOTHING;
+
+-- name: UnlikeChirp :execrows
+DELETE FROM chirp_likes
+WHERE user_id = $1
+AND chirp_id = $2;
+
+-- name: AddChirpLikes :one
+UPDATE chirps
+SET likes_count = likes_count + @delta
+WHERE id = @id
+RETURNING *;
+
+-- name: GetLikedChirps :many
+SELECT chirps.*
+FROM chirps
+JOIN chirp_likes ON chirp_likes.chirp_id = chirps.id
+WHERE chirp_likes.user_id = $1
+AND chirps.user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
+AND chirps.deleted_at IS NULL
+AND chirps.status = 'published'
+AND chirps.user_id NOT IN (
+	SELECT blocked_id FROM blocks WHERE blocker_id = $1
+	UNION ALL
+	SELECT muted_id FROM mutes WHERE muter_id = $1
+)
+ORDER BY chirp_likes.created_at DESC;
@@ -0,0 +1,19 @@
+-- name: CreateChirpShare :one
+INSERT INTO chirp_shares (code, created_at, chirp_id)
+VALUES (
+	$1,
+	NOW(),
+	$2
+)
+ON CONFLICT (chirp_id) DO UPDATE SET chirp_id = EXCLUDED.chirp_id
+RETURNING *;
+
+-- name: RecordChirpShareRedirect :one
+UPDATE chirp_shares
+SET redirect_count = redirect_count + 1
+WHERE code = $1
+RETURNING *;
+
+-- name: GetChirpShareStats :one
+SELECT count(*) AS shared_chirps, COALESCE(sum(redirect_count), 0)::bigint AS redirects
+FROM chirp_shares;
@@ -1,17 +1,31 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, parent_chirp_id, place_name, latitude, longitude, status, publish_at)
 VALUES (
-	gen_random_uuid(),
+	$1,
 	NOW(),
 	NOW(),
-	$1,
-	$2
+	$2,
+	$3,
+	$4,
+	$5,
+	$6,
+	$7,
+	$8,
+	$9
 )
 RETURNING *;
 
 -- name: GetChirps :many
 SELECT *
 FROM chirps
+WHERE user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
+AND deleted_at IS NULL
+AND status = 'published'
+AND user_id NOT IN (
+	SELECT blocked_id FROM blocks WHERE blocker_id = sqlc.narg(viewer_id)
+	UNION ALL
+	SELECT muted_id FROM mutes WHERE muter_id = sqlc.narg(viewer_id)
+)
 ORDER BY
   CASE WHEN @sort::text = 'asc' THEN created_at END asc,
   CASE WHEN @sort = 'desc' THEN created_at END desc;
@@ -20,14 +34,173 @@ ORDER BY
 SELECT *
 FROM chirps
 WHERE user_id = $1
+AND user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
+AND deleted_at IS NULL
+AND status = 'published'
+AND user_id NOT IN (
+	SELECT blocked_id FROM blocks WHERE blocker_id = sqlc.narg(viewer_id)
+	UNION ALL
+	SELECT muted_id FROM mutes WHERE muter_id = sqlc.narg(viewer_id)
+)
 ORDER BY
   CASE WHEN @sort::text = 'asc' THEN created_at END asc,
   CASE WHEN @sort = 'desc' THEN created_at END desc;
 
+-- name: GetChirpsPage :many
+SELECT *
+FROM chirps
+WHERE (sqlc.narg(author_id)::uuid IS NULL OR user_id = sqlc.narg(author_id))
+AND user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
+AND deleted_at IS NULL
+AND status = 'published'
+AND user_id NOT IN (
+	SELECT blocked_id FROM blocks WHERE blocker_id = sqlc.narg(viewer_id)
+	UNION ALL
+	SELECT muted_id FROM mutes WHERE muter_id = sqlc.narg(viewer_id)
+)
+AND (
+	sqlc.narg(after_created_at)::timestamp IS NULL
+	OR (@sort::text = 'asc' AND (created_at, id) > (sqlc.narg(after_created_at), sqlc.narg(after_id)::uuid))
+	OR (@sort = 'desc' AND (created_at, id) < (sqlc.narg(after_created_at), sqlc.narg(after_id)::uuid))
+)
+ORDER BY
+  CASE WHEN @sort = 'asc' THEN created_at END asc,
+  CASE WHEN @sort = 'asc' THEN id END asc,
+  CASE WHEN @sort = 'desc' THEN created_at END desc,
+  CASE WHEN @sort = 'desc' THEN id END desc
+LIMIT @page_size;
+
 -- name: GetChirp :one
 SELECT *
 FROM chirps
-WHERE id = $1;
+WHERE id = $1
+AND user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
+AND deleted_at IS NULL
+AND status = 'published';
+
+-- name: DeleteChirp :execrows
+UPDATE chirps
+SET deleted_at = NOW()
+WHERE id = $1
+AND deleted_at IS NULL;
+
+-- name: RedraftChirp :one
+UPDATE chirps
+SET deleted_at = NOW()
+WHERE id = $1
+AND user_id = $2
+AND deleted_at IS NULL
+RETURNING *;
+
+-- name: RestoreChirp :one
+UPDATE chirps
+SET deleted_at = NULL
+WHERE id = $1
+AND deleted_at IS NOT NULL
+AND status = 'published'
+RETURNING *;
+
+-- name: UpdateChirp :one
+UPDATE chirps
+SET body = $2, updated_at = NOW()
+WHERE id = $1
+AND deleted_at IS NULL
+RETURNING *;
+
+-- name: GetChirpsCreatedAfter :many
+SELECT *
+FROM chirps
+WHERE created_at > $1
+AND user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
+AND deleted_at IS NULL
+AND status = 'published'
+AND user_id NOT IN (
+	SELECT blocked_id FROM blocks WHERE blocker_id = sqlc.narg(viewer_id)
+	UNION ALL
+	SELECT muted_id FROM mutes WHERE muter_id = sqlc.narg(viewer_id)
+)
+ORDER BY created_at asc;
 
--- name: DeleteChirp :exec
-DELETE FROM chirps WHERE id = $1;
+-- name: ListChirpsAfterID :many
+SELECT *
+FROM chirps
+WHERE id > $1
+AND user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
+AND deleted_at IS NULL
+AND status = 'published'
+ORDER BY id
+LIMIT $2;
+
+-- name: CountChirps :one
+SELECT count(*)
+FROM chirps
+WHERE user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
+AND deleted_at IS NULL
+AND status = 'published';
+
+-- name: CountChirpsByUserSince :one
+SELECT count(*)
+FROM chirps
+WHERE user_id = $1
+AND created_at >= $2;
+
+-- name: SearchChirps :many
+SELECT id
+FROM chirps
+WHERE to_tsvector('english', body) @@ websearch_to_tsquery('english', @query)
+AND user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
+AND deleted_at IS NULL
+AND status = 'published'
+ORDER BY ts_rank(to_tsvector('english', body), websearch_to_tsquery('english', @query)) DESC, created_at DESC
+LIMIT @max_results;
+
+-- name: GetChirpReplies :many
+SELECT *
+FROM chirps
+WHERE parent_chirp_id = $1
+AND user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
+AND deleted_at IS NULL
+AND status = 'published'
+AND user_id NOT IN (
+	SELECT blocked_id FROM blocks WHERE blocker_id = sqlc.narg(viewer_id)
+	UNION ALL
+	SELECT muted_id FROM mutes WHERE muter_id = sqlc.narg(viewer_id)
+)
+ORDER BY created_at asc;
+
+-- name: AddChirpReplies :exec
+UPDATE chirps
+SET reply_count = reply_count + @delta
+WHERE id = @id;
+
+-- name: GetPlaces :many
+SELECT place_name::text AS place_name,
+	round(avg(latitude)::numeric, 1)::double precision AS latitude,
+	round(avg(longitude)::numeric, 1)::double precision AS longitude,
+	count(*) AS chirps
+FROM chirps
+WHERE lower(place_name) LIKE lower(@prefix) || '%'
+AND deleted_at IS NULL
+AND status = 'published'
+GROUP BY place_name
+ORDER BY count(*) DESC, place_name
+LIMIT @max_results;
+
+-- name: LockUser :exec
+SELECT id FROM users
+WHERE id = $1
+FOR UPDATE;
+
+-- name: HasPublishedChirp :one
+SELECT EXISTS (
+	SELECT 1 FROM chirps
+	WHERE user_id = $1
+	AND body = $2
+	AND status = 'published'
+	AND deleted_at IS NULL
+) AS posted;
+
+-- name: GetChirpCreatedAt :one
+SELECT created_at
+FROM chirps
+WHERE id = $1;
@@ -0,0 +1,18 @@
+-- name: CreateEmailVerification :exec
+INSERT INTO email_verifications (token, created_at, user_id, email, expires_at)
+VALUES ($1, NOW(), $2, $3, $4);
+
+-- name: DeleteStaleEmailVerifications :exec
+DELETE FROM email_verifications
+WHERE user_id = $1 OR expires_at <= NOW();
+
+-- name: TakeEmailVerification :one
+DELETE FROM email_verifications
+WHERE token = $1 AND expires_at > NOW()
+RETURNING *;
+
+-- name: MarkEmailVerified :one
+UPDATE users
+SET email_verified_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND email = $2
+RETURNING *;
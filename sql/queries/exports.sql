@@ -0,0 +1,19 @@
+-- name: ExportLikes :many
+SELECT chirp_likes.created_at AS liked_at, chirps.id, chirps.user_id, chirps.created_at, chirps.body
+FROM chirp_likes
+JOIN chirps ON chirps.id = chirp_likes.chirp_id
+WHERE chirp_likes.user_id = $1
+AND chirps.user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
+AND chirps.deleted_at IS NULL
+AND chirps.status = 'published'
+ORDER BY chirp_likes.created_at;
+
+-- name: ExportBookmarks :many
+SELECT bookmarks.created_at AS bookmarked_at, chirps.id, chirps.user_id, chirps.created_at, chirps.body
+FROM bookmarks
+JOIN chirps ON chirps.id = bookmarks.chirp_id
+WHERE bookmarks.user_id = $1
+AND chirps.user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
+AND chirps.deleted_at IS NULL
+AND chirps.status = 'published'
+ORDER BY bookmarks.created_at;
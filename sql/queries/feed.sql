@@ -0,0 +1,19 @@
+-- name: GetFeed :many
+SELECT chirps.*
+FROM chirps
+JOIN follows ON follows.followee_id = chirps.user_id
+WHERE follows.follower_id = @user_id
+AND chirps.user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
+AND chirps.deleted_at IS NULL
+AND chirps.status = 'published'
+AND chirps.user_id NOT IN (
+	SELECT blocked_id FROM blocks WHERE blocker_id = @user_id
+	UNION ALL
+	SELECT muted_id FROM mutes WHERE muter_id = @user_id
+)
+AND (
+	sqlc.narg(before)::uuid IS NULL
+	OR (chirps.created_at, chirps.id) < (SELECT c.created_at, c.id FROM chirps c WHERE c.id = sqlc.narg(before))
+)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT @max_results;
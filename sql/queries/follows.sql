@@ -0,0 +1,30 @@
+-- name: FollowUser :execrows
+INSERT INTO follows (follower_id, followee_id, created_at)
+SELECT $1, $2, NOW()
+WHERE NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE (blocker_id = $1 AND blocked_id = $2)
+	OR (blocker_id = $2 AND blocked_id = $1)
+)
+ON CONFLICT (follower_id, followee_id) DO NOTHING;
+
+-- name: UnfollowUser :execrows
+DELETE FROM follows
+WHERE follower_id = $1
+AND followee_id = $2;
+
+-- name: GetFollowers :many
+SELECT follows.follower_id AS user_id, follows.created_at
+FROM follows
+JOIN users ON users.id = follows.follower_id
+WHERE follows.followee_id = $1
+AND users.deactivated_at IS NULL
+ORDER BY follows.created_at DESC;
+
+-- name: GetFollowing :many
+SELECT follows.followee_id AS user_id, follows.created_at
+FROM follows
+JOIN users ON users.id = follows.followee_id
+WHERE follows.follower_id = $1
+AND users.deactivated_at IS NULL
+ORDER BY follows.created_at DESC;
@@ -0,0 +1,32 @@
+-- name: CreateLoginEvent :one
+INSERT INTO login_events (id, created_at, user_id, ip_address, user_agent, country)
+VALUES (
+	gen_random_uuid(),
+	NOW(),
+	$1,
+	$2,
+	$3,
+	$4
+)
+RETURNING *;
+
+-- name: GetLoginHistorySummary :one
+SELECT
+	count(*) AS logins,
+	count(*) FILTER (WHERE user_agent = $2) AS same_device,
+	count(*) FILTER (WHERE country = $3) AS same_country
+FROM login_events
+WHERE user_id = $1;
+
+-- name: GetRecentLoginEvents :many
+SELECT *
+FROM login_events
+WHERE user_id = $1
+ORDER BY created_at desc
+LIMIT 20;
+
+-- name: ReportLoginEvent :one
+UPDATE login_events
+SET reported_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING *;
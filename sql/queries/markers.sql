@@ -0,0 +1,20 @@
+-- name: GetMarkers :many
+SELECT *
+FROM markers
+WHERE user_id = $1
+ORDER BY timeline;
+
+-- name: SaveMarker :one
+INSERT INTO markers (user_id, timeline, last_read_id, version, updated_at)
+VALUES (
+	$1,
+	$2,
+	$3,
+	1,
+	NOW()
+)
+ON CONFLICT (user_id, timeline) DO UPDATE
+SET last_read_id = EXCLUDED.last_read_id,
+	version = markers.version + 1,
+	updated_at = NOW()
+RETURNING *;
@@ -0,0 +1,24 @@
+-- name: CreateOutboxEvent :exec
+INSERT INTO outbox_events (created_at, event_type, payload)
+VALUES (
+	NOW(),
+	$1,
+	$2
+);
+
+-- name: GetUnpublishedOutboxEvents :many
+SELECT *
+FROM outbox_events
+WHERE published_at IS NULL
+ORDER BY id
+LIMIT $1
+FOR UPDATE SKIP LOCKED;
+
+-- name: MarkOutboxEventsPublished :exec
+UPDATE outbox_events
+SET published_at = NOW()
+WHERE id = ANY(@ids::bigint[]);
+
+-- name: DeletePublishedOutboxEvents :execrows
+DELETE FROM outbox_events
+WHERE published_at < $1;
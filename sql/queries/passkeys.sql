@@ -0,0 +1,43 @@
+-- name: CreatePasskeyChallenge :exec
+INSERT INTO passkey_challenges (id, challenge, user_id, expires_at)
+VALUES ($1, $2, $3, $4);
+
+-- name: TakePasskeyChallenge :one
+DELETE FROM passkey_challenges
+WHERE id = $1 AND expires_at > NOW()
+RETURNING *;
+
+-- name: DeleteExpiredPasskeyChallenges :execrows
+DELETE FROM passkey_challenges
+WHERE expires_at <= NOW();
+
+-- name: CreatePasskey :one
+INSERT INTO passkeys (id, created_at, user_id, name, credential_id, public_key, sign_count)
+VALUES ($1, NOW(), $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetPasskeys :many
+SELECT * FROM passkeys
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: GetPasskeyCredentialIDs :many
+SELECT credential_id FROM passkeys
+WHERE user_id = $1;
+
+-- name: GetPasskeyByCredentialID :one
+SELECT * FROM passkeys
+WHERE credential_id = $1;
+
+-- name: UsePasskey :execrows
+UPDATE passkeys
+SET sign_count = @sign_count, last_used_at = NOW()
+WHERE id = @id AND sign_count = @old_sign_count;
+
+-- name: DeletePasskey :execrows
+DELETE FROM passkeys
+WHERE id = $1 AND user_id = $2;
+
+-- name: DeleteUserPasskeys :execrows
+DELETE FROM passkeys
+WHERE user_id = $1;
@@ -0,0 +1,21 @@
+-- name: CreateRechirp :one
+INSERT INTO rechirps (id, created_at, user_id, chirp_id)
+VALUES (
+	gen_random_uuid(),
+	NOW(),
+	$1,
+	$2
+)
+ON CONFLICT (user_id, chirp_id) DO NOTHING
+RETURNING *;
+
+-- name: DeleteRechirp :execrows
+DELETE FROM rechirps
+WHERE user_id = $1
+AND chirp_id = $2;
+
+-- name: AddChirpRechirps :one
+UPDATE chirps
+SET rechirp_count = rechirp_count + @delta
+WHERE id = @id
+RETURNING *;
@@ -1,11 +1,12 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, username)
 VALUES (
-	gen_random_uuid(),
+	$1,
 	NOW(),
 	NOW(),
-	$1,
-	$2
+	$2,
+	$3,
+	$4
 )
 RETURNING *;
 
@@ -13,11 +14,17 @@ RETURNING *;
 DELETE FROM users;
 
 -- name: GetUserByEmail :one
-SELECT * FROM users WHERE email = $1;
+SELECT * FROM users WHERE lower(email) = lower($1);
 
 -- name: UpdateUser :one
 UPDATE users
-SET email = $1, hashed_password = $2, updated_at = NOW()
+SET email = $1, hashed_password = $2,
+	email_verified_at = CASE WHEN email = $1 THEN email_verified_at END,
+	username = COALESCE(sqlc.narg(username), username),
+	display_name = NULLIF(COALESCE(sqlc.narg(display_name), display_name), ''),
+	bio = NULLIF(COALESCE(sqlc.narg(bio), bio), ''),
+	avatar_url = NULLIF(COALESCE(sqlc.narg(avatar_url), avatar_url), ''),
+	updated_at = NOW()
 WHERE id = $3
 RETURNING *;
 
@@ -26,3 +33,61 @@ UPDATE users
 SET is_chirpy_red = TRUE, updated_at = NOW()
 WHERE id = $1
 RETURNING *;
+
+-- name: UpdateUserMembership :one
+UPDATE users
+SET is_chirpy_red = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: GetUser :one
+SELECT * FROM users WHERE id = $1;
+
+-- name: DeactivateUser :exec
+UPDATE users
+SET deactivated_at = COALESCE(deactivated_at, NOW()), updated_at = NOW()
+WHERE id = $1;
+
+-- name: ReactivateUser :one
+UPDATE users
+SET deactivated_at = NULL, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: GetExpiredDeactivatedUserIDs :many
+SELECT id FROM users
+WHERE deactivated_at < $1;
+
+-- name: SetLocationSettings :one
+UPDATE users
+SET location_enabled = $2, precise_location = $3, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetUserMovedTo :one
+UPDATE users
+SET moved_to = $2,
+	moved_at = CASE WHEN $2::text IS NULL THEN NULL ELSE NOW() END,
+	updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: CountUserChirps :one
+SELECT count(*)
+FROM chirps
+WHERE user_id = $1
+AND deleted_at IS NULL
+AND status = 'published';
+
+-- name: GetUsersByUsernames :many
+SELECT * FROM users
+WHERE lower(username) = ANY(@usernames::text[]) AND deactivated_at IS NULL;
+
+-- name: GetUsersByIDs :many
+SELECT * FROM users
+WHERE id = ANY(@ids::uuid[]);
+
+-- name: EndUserSessions :exec
+UPDATE users
+SET sessions_valid_after = date_trunc('second', NOW()), updated_at = NOW()
+WHERE id = $1;
@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE chirp_shares (
+	code text PRIMARY KEY,
+	created_at timestamp NOT NULL,
+	chirp_id uuid NOT NULL,
+	redirect_count integer NOT NULL DEFAULT 0,
+	UNIQUE(chirp_id),
+	CONSTRAINT fk_chirp FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE chirp_shares;
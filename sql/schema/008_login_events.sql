@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE login_events (
+	id uuid PRIMARY KEY,
+	created_at timestamp NOT NULL,
+	user_id uuid NOT NULL,
+	ip_address text NOT NULL,
+	user_agent text NOT NULL,
+	country text,
+	reported_at timestamp,
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX login_events_user_id_idx ON login_events (user_id, created_at);
+
+-- +goose Down
+DROP TABLE login_events;
@@ -0,0 +1,37 @@
+-- +goose Up
+-- Accounts whose emails only differ in case or surrounding whitespace can't
+-- all keep their address. The oldest one does; the others get a placeholder
+-- address that can't log in and are listed in user_email_duplicates, so
+-- support can merge them into the kept account.
+CREATE TABLE user_email_duplicates (
+	user_id uuid PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	kept_user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	email text NOT NULL
+);
+
+INSERT INTO user_email_duplicates (user_id, kept_user_id, email)
+SELECT id, kept_user_id, email FROM (
+	SELECT
+		id,
+		email,
+		first_value(id) OVER (PARTITION BY lower(trim(email)) ORDER BY created_at, id) AS kept_user_id
+	FROM users
+) ranked
+WHERE id <> kept_user_id;
+
+UPDATE users
+SET email = 'duplicate-' || users.id || '@invalid', updated_at = NOW()
+FROM user_email_duplicates
+WHERE users.id = user_email_duplicates.user_id;
+
+UPDATE users SET email = lower(trim(email));
+CREATE UNIQUE INDEX users_email_lower_idx ON users (lower(email));
+
+-- +goose Down
+DROP INDEX users_email_lower_idx;
+UPDATE users
+SET email = user_email_duplicates.email
+FROM user_email_duplicates
+WHERE users.id = user_email_duplicates.user_id
+AND NOT EXISTS (SELECT 1 FROM users taken WHERE taken.email = user_email_duplicates.email);
+DROP TABLE user_email_duplicates;
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE outbox_events (
+	id bigserial PRIMARY KEY,
+	created_at timestamp NOT NULL,
+	event_type text NOT NULL,
+	payload jsonb NOT NULL,
+	published_at timestamp
+);
+
+CREATE INDEX outbox_events_unpublished_idx ON outbox_events (id) WHERE published_at IS NULL;
+
+-- +goose Down
+DROP TABLE outbox_events;
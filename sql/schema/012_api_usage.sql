@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE api_usage (
+	user_id uuid NOT NULL,
+	day date NOT NULL,
+	calls bigint NOT NULL DEFAULT 0,
+	PRIMARY KEY (user_id, day),
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE api_usage;
@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE abuse_reports (
+	id uuid PRIMARY KEY,
+	created_at timestamp NOT NULL,
+	url text NOT NULL,
+	reason text NOT NULL,
+	details text NOT NULL,
+	contact_email text NOT NULL,
+	chirp_id uuid,
+	resolved_at timestamp,
+	CONSTRAINT fk_chirp FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE SET NULL
+);
+
+CREATE INDEX abuse_reports_open_idx ON abuse_reports (created_at) WHERE resolved_at IS NULL;
+
+-- +goose Down
+DROP TABLE abuse_reports;
@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE audit_log (
+	id uuid PRIMARY KEY,
+	created_at timestamp NOT NULL,
+	actor text NOT NULL,
+	action text NOT NULL,
+	user_id uuid,
+	reason text NOT NULL,
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX audit_log_created_at_idx ON audit_log (created_at);
+
+-- +goose Down
+DROP TABLE audit_log;
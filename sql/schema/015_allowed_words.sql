@@ -0,0 +1,9 @@
+-- +goose Up
+CREATE TABLE allowed_words (
+	word text PRIMARY KEY,
+	created_at timestamp NOT NULL,
+	reason text NOT NULL
+);
+
+-- +goose Down
+DROP TABLE allowed_words;
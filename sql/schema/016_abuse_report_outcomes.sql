@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE abuse_reports
+	ADD COLUMN reporter_id uuid REFERENCES users(id) ON DELETE SET NULL,
+	ADD COLUMN outcome text;
+
+UPDATE abuse_reports SET outcome = 'actioned' WHERE resolved_at IS NOT NULL;
+
+CREATE INDEX abuse_reports_reporter_idx ON abuse_reports (reporter_id) WHERE reporter_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX abuse_reports_reporter_idx;
+
+ALTER TABLE abuse_reports
+	DROP COLUMN outcome,
+	DROP COLUMN reporter_id;
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE markers (
+	user_id uuid NOT NULL,
+	timeline text NOT NULL,
+	last_read_id uuid NOT NULL,
+	version integer NOT NULL,
+	updated_at timestamp NOT NULL,
+	PRIMARY KEY (user_id, timeline),
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE markers;
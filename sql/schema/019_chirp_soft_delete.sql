@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE chirps
+	ADD COLUMN deleted_at timestamp;
+
+CREATE INDEX chirps_deleted_idx ON chirps (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX chirps_deleted_idx;
+
+DELETE FROM chirps WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE chirps
+	DROP COLUMN deleted_at;
@@ -0,0 +1,22 @@
+-- +goose Up
+ALTER TABLE chirps
+	ADD COLUMN reply_count integer NOT NULL DEFAULT 0;
+
+UPDATE chirps
+SET reply_count = replies.count
+FROM (
+	SELECT parent_chirp_id, count(*) AS count
+	FROM chirps
+	WHERE parent_chirp_id IS NOT NULL
+	AND deleted_at IS NULL
+	GROUP BY parent_chirp_id
+) replies
+WHERE chirps.id = replies.parent_chirp_id;
+
+CREATE INDEX chirps_parent_idx ON chirps (parent_chirp_id, created_at) WHERE parent_chirp_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX chirps_parent_idx;
+
+ALTER TABLE chirps
+	DROP COLUMN reply_count;
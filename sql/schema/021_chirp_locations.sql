@@ -0,0 +1,23 @@
+-- +goose Up
+ALTER TABLE users
+	ADD COLUMN location_enabled boolean NOT NULL DEFAULT false,
+	ADD COLUMN precise_location boolean NOT NULL DEFAULT false;
+
+ALTER TABLE chirps
+	ADD COLUMN place_name text,
+	ADD COLUMN latitude double precision,
+	ADD COLUMN longitude double precision;
+
+CREATE INDEX chirps_place_idx ON chirps (lower(place_name)) WHERE place_name IS NOT NULL;
+
+-- +goose Down
+DROP INDEX chirps_place_idx;
+
+ALTER TABLE chirps
+	DROP COLUMN longitude,
+	DROP COLUMN latitude,
+	DROP COLUMN place_name;
+
+ALTER TABLE users
+	DROP COLUMN precise_location,
+	DROP COLUMN location_enabled;
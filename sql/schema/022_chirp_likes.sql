@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE chirp_likes (
+	user_id uuid NOT NULL,
+	chirp_id uuid NOT NULL,
+	created_at timestamp NOT NULL,
+	PRIMARY KEY (user_id, chirp_id),
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_chirp FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE
+);
+
+CREATE INDEX chirp_likes_user_idx ON chirp_likes (user_id, created_at);
+
+ALTER TABLE chirps
+	ADD COLUMN likes_count integer NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE chirps
+	DROP COLUMN likes_count;
+
+DROP TABLE chirp_likes;
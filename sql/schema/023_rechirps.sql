@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE rechirps (
+	id uuid PRIMARY KEY,
+	created_at timestamp NOT NULL,
+	user_id uuid NOT NULL,
+	chirp_id uuid NOT NULL,
+	UNIQUE (user_id, chirp_id),
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_chirp FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE
+);
+
+ALTER TABLE chirps
+	ADD COLUMN rechirp_count integer NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE chirps
+	DROP COLUMN rechirp_count;
+
+DROP TABLE rechirps;
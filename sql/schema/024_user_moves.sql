@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE users
+	ADD COLUMN moved_to text,
+	ADD COLUMN moved_at timestamp;
+
+-- +goose Down
+ALTER TABLE users
+	DROP COLUMN moved_at,
+	DROP COLUMN moved_to;
@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE chirp_hashtags (
+	chirp_id uuid NOT NULL,
+	tag text NOT NULL,
+	created_at timestamp NOT NULL,
+	PRIMARY KEY (chirp_id, tag),
+	CONSTRAINT fk_chirp FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE
+);
+
+CREATE INDEX chirp_hashtags_tag_idx ON chirp_hashtags (tag, created_at);
+
+INSERT INTO chirp_hashtags (chirp_id, tag, created_at)
+SELECT DISTINCT chirps.id, lower(m[1]), chirps.created_at
+FROM chirps, regexp_matches(chirps.body, '(?:^|[^[:alnum:]_&])#([[:alpha:]][[:alnum:]_]{0,99})(?![[:alnum:]_])', 'g') AS m;
+
+-- +goose Down
+DROP TABLE chirp_hashtags;
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE bookmarks (
+	user_id uuid NOT NULL,
+	chirp_id uuid NOT NULL,
+	created_at timestamp NOT NULL,
+	PRIMARY KEY (user_id, chirp_id),
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_chirp FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE
+);
+
+CREATE INDEX bookmarks_user_idx ON bookmarks (user_id, created_at);
+
+-- +goose Down
+DROP TABLE bookmarks;
@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE follows (
+	follower_id uuid NOT NULL,
+	followee_id uuid NOT NULL,
+	created_at timestamp NOT NULL,
+	PRIMARY KEY (follower_id, followee_id),
+	CHECK (follower_id <> followee_id),
+	CONSTRAINT fk_follower FOREIGN KEY (follower_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_followee FOREIGN KEY (followee_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX follows_followee_idx ON follows (followee_id, created_at);
+
+-- +goose Down
+DROP TABLE follows;
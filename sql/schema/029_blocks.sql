@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE blocks (
+	blocker_id uuid NOT NULL,
+	blocked_id uuid NOT NULL,
+	created_at timestamp NOT NULL,
+	PRIMARY KEY (blocker_id, blocked_id),
+	CHECK (blocker_id <> blocked_id),
+	CONSTRAINT fk_blocker FOREIGN KEY (blocker_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_blocked FOREIGN KEY (blocked_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE mutes (
+	muter_id uuid NOT NULL,
+	muted_id uuid NOT NULL,
+	created_at timestamp NOT NULL,
+	PRIMARY KEY (muter_id, muted_id),
+	CHECK (muter_id <> muted_id),
+	CONSTRAINT fk_muter FOREIGN KEY (muter_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_muted FOREIGN KEY (muted_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE mutes;
+DROP TABLE blocks;
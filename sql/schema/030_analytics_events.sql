@@ -0,0 +1,14 @@
+-- +goose Up
+-- One row per day, route, country and visitor. Visitors are keyed by a hash
+-- of their user ID that changes every day, or '' when signed out.
+CREATE TABLE analytics_events (
+	day date NOT NULL,
+	route text NOT NULL,
+	country text NOT NULL DEFAULT '',
+	visitor text NOT NULL DEFAULT '',
+	requests bigint NOT NULL DEFAULT 0,
+	PRIMARY KEY (day, route, country, visitor)
+);
+
+-- +goose Down
+DROP TABLE analytics_events;
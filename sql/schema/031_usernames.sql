@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN username text;
+
+CREATE UNIQUE INDEX users_username_key ON users (lower(username));
+
+-- +goose Down
+DROP INDEX users_username_key;
+
+ALTER TABLE users DROP COLUMN username;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE users
+	ADD COLUMN display_name text,
+	ADD COLUMN bio text,
+	ADD COLUMN avatar_url text;
+
+-- +goose Down
+ALTER TABLE users
+	DROP COLUMN avatar_url,
+	DROP COLUMN bio,
+	DROP COLUMN display_name;
@@ -0,0 +1,28 @@
+-- +goose Up
+CREATE TABLE passkeys (
+	id uuid PRIMARY KEY,
+	created_at timestamp NOT NULL,
+	user_id uuid NOT NULL,
+	name text NOT NULL,
+	credential_id bytea NOT NULL UNIQUE,
+	public_key bytea NOT NULL,
+	sign_count bigint NOT NULL,
+	last_used_at timestamp,
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX passkeys_user_id_idx ON passkeys (user_id, created_at);
+
+-- Challenges are used once: registration ones belong to the signed-in
+-- user, login ones to no one yet.
+CREATE TABLE passkey_challenges (
+	id uuid PRIMARY KEY,
+	challenge bytea NOT NULL,
+	user_id uuid,
+	expires_at timestamp NOT NULL,
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE passkey_challenges;
+DROP TABLE passkeys;
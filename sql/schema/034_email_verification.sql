@@ -0,0 +1,21 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN email_verified_at timestamp;
+
+-- Accounts from before verification existed keep posting.
+UPDATE users SET email_verified_at = created_at;
+
+CREATE TABLE email_verifications (
+	token text PRIMARY KEY,
+	created_at timestamp NOT NULL,
+	user_id uuid NOT NULL,
+	email text NOT NULL,
+	expires_at timestamp NOT NULL,
+	CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX email_verifications_user_id_idx ON email_verifications (user_id);
+
+-- +goose Down
+DROP TABLE email_verifications;
+
+ALTER TABLE users DROP COLUMN email_verified_at;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- Refreshing replaces a token with a new one of the same family. rotated_at
+-- marks replaced tokens, so presenting one again can be told apart from
+-- presenting a token that was revoked.
+ALTER TABLE refresh_tokens
+	ADD COLUMN family_id uuid,
+	ADD COLUMN rotated_at timestamp;
+
+UPDATE refresh_tokens SET family_id = gen_random_uuid();
+
+ALTER TABLE refresh_tokens ALTER COLUMN family_id SET NOT NULL;
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+
+-- +goose Down
+DROP INDEX refresh_tokens_family_id_idx;
+
+ALTER TABLE refresh_tokens
+	DROP COLUMN rotated_at,
+	DROP COLUMN family_id;
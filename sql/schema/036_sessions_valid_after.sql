@@ -0,0 +1,7 @@
+-- +goose Up
+-- Access tokens issued before sessions_valid_after are refused, so logging
+-- out everywhere doesn't have to wait for them to expire.
+ALTER TABLE users ADD COLUMN sessions_valid_after timestamp;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN sessions_valid_after;
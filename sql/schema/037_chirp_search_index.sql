@@ -0,0 +1,17 @@
+-- +goose Up
+-- Search matches on an expression index rather than a stored column, so
+-- selecting a chirp doesn't drag its tsvector along.
+DROP INDEX chirps_body_tsv_idx;
+
+ALTER TABLE chirps
+	DROP COLUMN body_tsv;
+
+CREATE INDEX chirps_body_tsv_idx ON chirps USING GIN (to_tsvector('english', body));
+
+-- +goose Down
+DROP INDEX chirps_body_tsv_idx;
+
+ALTER TABLE chirps
+	ADD COLUMN body_tsv tsvector GENERATED ALWAYS AS (to_tsvector('english', body)) STORED;
+
+CREATE INDEX chirps_body_tsv_idx ON chirps USING GIN (body_tsv);
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"runtime/debug"
+	"sort"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/config"
+)
+
+// buildInfo describes the binary: the module version and VCS revision it
+// was built from, the Go version and the versions of its dependencies.
+func buildInfo() slog.Value {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return slog.StringValue("unknown")
+	}
+
+	attrs := []slog.Attr{
+		slog.String("version", info.Main.Version),
+		slog.String("go", info.GoVersion),
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision", "vcs.time", "vcs.modified":
+			attrs = append(attrs, slog.String(setting.Key, setting.Value))
+		}
+	}
+	deps := make([]slog.Attr, 0, len(info.Deps))
+	for _, dep := range info.Deps {
+		deps = append(deps, slog.String(dep.Path, dep.Version))
+	}
+	attrs = append(attrs, slog.Attr{Key: "deps", Value: slog.GroupValue(deps...)})
+	return slog.GroupValue(attrs...)
+}
+
+// enabledFeatures lists the optional subsystems and feature flags that are
+// turned on.
+func (cfg *apiConfig) enabledFeatures() []string {
+	features := []string{}
+	optional := []struct {
+		name    string
+		enabled bool
+	}{
+		{"admin", len(cfg.adminKeys) > 0},
+		{"search", cfg.search != nil},
+		{"event_bus", cfg.eventBus != nil},
+		{"error_reporting", cfg.errorReporter != nil},
+		{"public_ids", cfg.publicIDs != nil},
+		{"abuse_reports", cfg.captcha != nil},
+		{"security_txt", len(cfg.securityContacts) > 0},
+		{"rate_limit", cfg.settings().rateLimiter.Enabled()},
+		{"breakers", cfg.breakerThreshold > 0},
+		{"analytics", cfg.analytics != nil},
+		{"passkeys", cfg.passkeys != nil},
+		{"email_verification", cfg.publicURL != ""},
+	}
+	for _, feature := range optional {
+		if feature.enabled {
+			features = append(features, feature.name)
+		}
+	}
+
+	flags := []string{}
+	for name, enabled := range cfg.settings().featureFlags {
+		if enabled {
+			flags = append(flags, "flag:"+name)
+		}
+	}
+	sort.Strings(flags)
+	return append(features, flags...)
+}
+
+// logStartup logs one structured summary of how the server was started, to
+// be pasted into bug reports and support requests.
+func (cfg *apiConfig) logStartup(config config.Config, addr net.Addr) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	schema := slog.String("schema_version", "unknown")
+	version, err := schemaVersion(ctx, cfg.db)
+	if err == nil {
+		schema = slog.Int("schema_version", version)
+	} else {
+		slog.Warn("Couldn't read schema version", "error", err)
+	}
+
+	slog.Info("Serving chirpy",
+		"listen_addr", addr.String(),
+		"build", buildInfo(),
+		schema,
+		"features", cfg.enabledFeatures(),
+		"config", config,
+	)
+}
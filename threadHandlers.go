@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/fkl13/chirpy/internal/auth"
+	"github.com/fkl13/chirpy/internal/database"
+	"github.com/fkl13/chirpy/internal/validate"
+	"github.com/google/uuid"
+)
+
+// createThreadHandler posts an ordered list of chirp bodies as a chain of
+// self-replies. Either the whole thread is stored or none of it is.
+func (cfg *apiConfig) createThreadHandler(w http.ResponseWriter, r *http.Request) {
+	const maxThreadLength = 25
+
+	type parameters struct {
+		Chirps []string `json:"chirps"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "No JWT provided", err)
+		return
+	}
+	userId, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	params := parameters{}
+	err = decodeJSONBody(w, r, &params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	if len(params.Chirps) == 0 {
+		respondWithError(w, http.StatusBadRequest, "No chirps provided", nil)
+		return
+	}
+	if len(params.Chirps) > maxThreadLength {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("A thread can have at most %d chirps", maxThreadLength), nil)
+		return
+	}
+
+	v := validate.Validator{}
+	cleaned := make([]string, len(params.Chirps))
+	for i, body := range params.Chirps {
+		cleaned[i], err = validateChirp(body, cfg.settings().badWords)
+		v.Merge(fmt.Sprintf("chirps[%d]", i), err)
+	}
+	if err := v.Err(); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+
+	if !cfg.checkCanPost(w, r, userId) {
+		return
+	}
+	if !cfg.checkChirpQuota(w, r, userId, len(cleaned)) {
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	thread := make([]database.Chirp, 0, len(cleaned))
+	parent := uuid.NullUUID{}
+	for _, body := range cleaned {
+		id, err := uuid.NewV7()
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't create chirp ID", err)
+			return
+		}
+		chirp, err := qtx.CreateChirp(r.Context(), database.CreateChirpParams{
+			ID:            id,
+			Body:          body,
+			UserID:        userId,
+			ParentChirpID: parent,
+			Status:        chirpPublished,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't store thread", err)
+			return
+		}
+		err = cfg.publishChirp(r.Context(), qtx, chirp)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't publish thread", err)
+			return
+		}
+		if parent.Valid {
+			// publishChirp counted the reply in the database only.
+			thread[len(thread)-1].ReplyCount++
+		}
+		thread = append(thread, chirp)
+		parent = uuid.NullUUID{UUID: chirp.ID, Valid: true}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't store thread", err)
+		return
+	}
+	cfg.wakeOutboxRelay()
+
+	for _, chirp := range thread {
+		cfg.chirpCache.Put(chirp.ID, chirp)
+	}
+	respondWithList(w, http.StatusCreated, cfg.newChirps(r.Context(), thread), cfg.wantsEnvelope(r))
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/fkl13/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// chirpUpdatesHandler long-polls for chirps newer than since_id. It answers
+// immediately when newer chirps already exist, otherwise it holds the
+// request until the hub announces a new chirp or the wait expires.
+//
+// since_id only marks a point in time, so it may name a chirp that was
+// deleted or hidden since. When the chirp is gone altogether the client
+// gets the newest chirps to start over from.
+func (cfg *apiConfig) chirpUpdatesHandler(w http.ResponseWriter, r *http.Request) {
+	const defaultWait = 30 * time.Second
+	const maxWait = 60 * time.Second
+
+	wait := defaultWait
+	if waitParam := r.URL.Query().Get("wait"); waitParam != "" {
+		seconds, err := strconv.Atoi(waitParam)
+		if err != nil || seconds < 0 {
+			respondWithError(w, http.StatusBadRequest, "Invalid wait", err)
+			return
+		}
+		wait = min(time.Duration(seconds)*time.Second, maxWait)
+	}
+
+	// Subscribe before looking at the database so a chirp created in
+	// between can't slip through unnoticed.
+	updates, unsubscribe := cfg.chirpHub.Subscribe(16)
+	defer unsubscribe()
+
+	viewer := cfg.viewerID(r)
+	createdAfter := func(t time.Time) ([]database.Chirp, error) {
+		return cfg.dbQueries.GetChirpsCreatedAfter(r.Context(), database.GetChirpsCreatedAfterParams{
+			CreatedAt: t,
+			ViewerID:  viewer,
+		})
+	}
+
+	var since *time.Time
+	payload := []Chirp{}
+	if sinceParam := r.URL.Query().Get("since_id"); sinceParam != "" {
+		id, err := cfg.parseID(sinceParam)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid since_id", err)
+			return
+		}
+		createdAt, err := cfg.dbQueries.GetChirpCreatedAt(r.Context(), id)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't get chirp", err)
+			return
+		}
+		var chirps []database.Chirp
+		if err == nil {
+			since = &createdAt
+			chirps, err = createdAfter(createdAt)
+		} else {
+			chirps, err = cfg.newestChirps(r.Context(), viewer)
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't get chirps", err)
+			return
+		}
+		payload = cfg.newChirps(r.Context(), chirps)
+	}
+
+	if len(payload) == 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+
+		select {
+		case chirp := <-updates:
+			if since == nil {
+				payload = append(payload, chirp)
+				payload = append(payload, drain(updates)...)
+				var err error
+				payload, err = cfg.visibleChirps(r.Context(), viewer, payload)
+				if err != nil {
+					respondWithError(w, http.StatusInternalServerError, "Couldn't get chirps", err)
+					return
+				}
+				break
+			}
+			chirps, err := createdAfter(*since)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Couldn't get chirps", err)
+				return
+			}
+			payload = cfg.newChirps(r.Context(), chirps)
+		case <-timer.C:
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	respondWithList(w, http.StatusOK, payload, cfg.wantsEnvelope(r))
+}
+
+// newestChirps returns the latest chirps the viewer can see, oldest first
+// like the updates that follow them.
+func (cfg *apiConfig) newestChirps(ctx context.Context, viewer uuid.NullUUID) ([]database.Chirp, error) {
+	const count = 20
+
+	chirps, err := cfg.dbQueries.GetChirpsPage(ctx, database.GetChirpsPageParams{
+		ViewerID: viewer,
+		Sort:     "desc",
+		PageSize: count,
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(chirps)
+	return chirps, nil
+}
+
+func drain[T any](ch <-chan T) []T {
+	var items []T
+	for {
+		select {
+		case v := <-ch:
+			items = append(items, v)
+		default:
+			return items
+		}
+	}
+}
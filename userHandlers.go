@@ -1,37 +1,179 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"database/sql"
+	"errors"
+	"log"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/fkl13/chirpy/internal/auth"
 	"github.com/fkl13/chirpy/internal/database"
+	"github.com/fkl13/chirpy/internal/validate"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type User struct {
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	Email       string    `json:"email"`
-	ID          uuid.UUID `json:"id"`
-	IsChirpyRed bool      `json:"is_chirpy_red"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Email     string    `json:"email"`
+	// EmailVerified is false until the address is confirmed, which is
+	// needed to post.
+	EmailVerified bool   `json:"email_verified"`
+	ID            string `json:"id"`
+	PublicID      string `json:"public_id,omitempty"`
+	Username      string `json:"username,omitempty"`
+	DisplayName   string `json:"display_name,omitempty"`
+	Bio           string `json:"bio,omitempty"`
+	AvatarURL     string `json:"avatar_url,omitempty"`
+	IsChirpyRed   bool   `json:"is_chirpy_red"`
+	MovedTo       string `json:"moved_to,omitempty"`
+}
+
+// normalizeEmail lowercases and trims an address so the same mailbox can't
+// be registered twice with different spelling. With stripPlusTags the
+// "+tag" suffix of the local part is dropped too.
+func normalizeEmail(email string, stripPlusTags bool) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if !stripPlusTags {
+		return email
+	}
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok {
+		return email
+	}
+	local, _, _ = strings.Cut(local, "+")
+	return local + "@" + domain
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// respondWithUserConflict answers a unique violation on users with 409,
+// naming the username or the email depending on which index it hit.
+func respondWithUserConflict(w http.ResponseWriter, err error) {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Constraint == "users_username_key" {
+		respondWithError(w, http.StatusConflict, "Username already taken", err)
+		return
+	}
+	respondWithError(w, http.StatusConflict, "Email already in use", err)
+}
+
+const (
+	minPasswordLength = 8
+	minUsernameLength = 3
+	maxUsernameLength = 15
+)
+
+// normalizeUsername lowercases and trims a username. Usernames are unique
+// regardless of case, so they're stored the way they're compared.
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+func validUsernameChar(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_'
+}
+
+// validateCredentials checks the signup and update payloads. The username
+// is optional and expected normalized.
+func validateCredentials(email, password, username string) error {
+	v := validate.Validator{}
+	v.Email("email", strings.TrimSpace(email))
+	if v.Required("password", password) {
+		v.MinLength("password", password, minPasswordLength)
+	}
+	if username != "" {
+		v.MinLength("username", username, minUsernameLength)
+		v.MaxLength("username", username, maxUsernameLength)
+		if strings.IndexFunc(username, func(r rune) bool { return !validUsernameChar(r) }) >= 0 {
+			v.Add("username", validate.CodeInvalid, "may only contain letters, digits and underscores")
+		}
+	}
+	return v.Err()
+}
+
+func nullUsername(username string) sql.NullString {
+	return sql.NullString{String: username, Valid: username != ""}
+}
+
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+	maxAvatarURLLength   = 2048
+)
+
+// profileParams checks the profile fields of an update. A field left out
+// stays as it is and an empty one clears it, so each is sent to
+// UpdateUser as NULL or as its trimmed value.
+func profileParams(displayName, bio, avatarURL *string) (sql.NullString, sql.NullString, sql.NullString, error) {
+	v := validate.Validator{}
+	trimmed := func(s *string) sql.NullString {
+		if s == nil {
+			return sql.NullString{}
+		}
+		return sql.NullString{String: strings.TrimSpace(*s), Valid: true}
+	}
+	name, about, avatar := trimmed(displayName), trimmed(bio), trimmed(avatarURL)
+	v.MaxLength("display_name", name.String, maxDisplayNameLength)
+	v.MaxLength("bio", about.String, maxBioLength)
+	if avatar.String != "" {
+		v.MaxLength("avatar_url", avatar.String, maxAvatarURLLength)
+		u, err := url.Parse(avatar.String)
+		if err != nil || u.Scheme != "https" || u.Host == "" || u.User != nil {
+			v.Add("avatar_url", validate.CodeInvalid, "must be an https URL")
+		}
+	}
+	if err := v.Err(); err != nil {
+		return sql.NullString{}, sql.NullString{}, sql.NullString{}, err
+	}
+	return name, about, avatar, nil
+}
+
+func (cfg *apiConfig) newUser(user database.User) User {
+	return User{
+		ID:            cfg.exposedID(user.ID),
+		PublicID:      cfg.publicID(user.ID),
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+		Username:      user.Username.String,
+		DisplayName:   user.DisplayName.String,
+		Bio:           user.Bio.String,
+		AvatarURL:     user.AvatarUrl.String,
+		IsChirpyRed:   user.IsChirpyRed,
+		MovedTo:       user.MovedTo.String,
+	}
 }
 
 func (cfg *apiConfig) createUserHandler(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
 		Password string `json:"password"`
 		Email    string `json:"email"`
+		Username string `json:"username"`
 	}
 	type response struct {
 		User
 	}
 
-	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
-	err := decoder.Decode(&params)
+	err := decodeJSONBody(w, r, &params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	username := normalizeUsername(params.Username)
+	err = validateCredentials(params.Email, params.Password, username)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		respondWithValidationError(w, err)
 		return
 	}
 
@@ -41,30 +183,69 @@ func (cfg *apiConfig) createUserHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	user, err := cfg.dbQueries.CreateUser(r.Context(), database.CreateUserParams{
-		Email:          params.Email,
+	id, err := uuid.NewV7()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create user ID", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	user, err := qtx.CreateUser(r.Context(), database.CreateUserParams{
+		ID:             id,
+		Email:          normalizeEmail(params.Email, cfg.stripEmailPlusTags),
 		HashedPassword: hashedPassword,
+		Username:       nullUsername(username),
 	})
+	if isUniqueViolation(err) {
+		respondWithUserConflict(w, err)
+		return
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't store user", err)
 		return
 	}
+	err = addOutboxEvent(r.Context(), qtx, eventUserCreated, userCreatedEvent{
+		ID:        user.ID,
+		Username:  user.Username.String,
+		CreatedAt: user.CreatedAt,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't store user event", err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't store user", err)
+		return
+	}
+	cfg.userCache.Put(user.ID, user)
+	cfg.wakeOutboxRelay()
+	err = cfg.sendVerificationEmail(r.Context(), user)
+	if err != nil {
+		log.Printf("Couldn't send verification email to %s: %v", user.ID, err)
+	}
+	cfg.onboardUser(r.Context(), user)
 
 	respondWithJSON(w, http.StatusCreated, response{
-		User: User{
-			ID:          user.ID,
-			CreatedAt:   user.CreatedAt,
-			UpdatedAt:   user.UpdatedAt,
-			Email:       user.Email,
-			IsChirpyRed: user.IsChirpyRed,
-		},
+		User: cfg.newUser(user),
 	})
 }
 
 func (cfg *apiConfig) updateUserHandler(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
-		Password string `json:"password"`
-		Email    string `json:"email"`
+		Password    string  `json:"password"`
+		Email       string  `json:"email"`
+		Username    string  `json:"username"`
+		DisplayName *string `json:"display_name"`
+		Bio         *string `json:"bio"`
+		AvatarURL   *string `json:"avatar_url"`
 	}
 	type response struct {
 		User
@@ -81,11 +262,21 @@ func (cfg *apiConfig) updateUserHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
-	err = decoder.Decode(&params)
+	err = decodeJSONBody(w, r, &params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	username := normalizeUsername(params.Username)
+	err = validateCredentials(params.Email, params.Password, username)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		respondWithValidationError(w, err)
+		return
+	}
+	displayName, bio, avatarURL, err := profileParams(params.DisplayName, params.Bio, params.AvatarURL)
+	if err != nil {
+		respondWithValidationError(w, err)
 		return
 	}
 
@@ -95,22 +286,121 @@ func (cfg *apiConfig) updateUserHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	previous, err := cfg.getUser(r.Context(), userId)
+	if err != nil {
+		userPolicy.notFound(w, err)
+		return
+	}
 	user, err := cfg.dbQueries.UpdateUser(r.Context(), database.UpdateUserParams{
 		ID:             userId,
-		Email:          params.Email,
+		Email:          normalizeEmail(params.Email, cfg.stripEmailPlusTags),
 		HashedPassword: hashedPassword,
+		Username:       nullUsername(username),
+		DisplayName:    displayName,
+		Bio:            bio,
+		AvatarUrl:      avatarURL,
 	})
+	if isUniqueViolation(err) {
+		respondWithUserConflict(w, err)
+		return
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't update user", err)
 		return
 	}
+	cfg.userCache.Put(user.ID, user)
+	if user.Email != previous.Email {
+		err = cfg.sendVerificationEmail(r.Context(), user)
+		if err != nil {
+			log.Printf("Couldn't send verification email to %s: %v", user.ID, err)
+		}
+	}
 	respondWithJSON(w, http.StatusOK, response{
-		User: User{
-			ID:          user.ID,
-			CreatedAt:   user.CreatedAt,
-			UpdatedAt:   user.UpdatedAt,
-			Email:       user.Email,
-			IsChirpyRed: user.IsChirpyRed,
-		},
+		User: cfg.newUser(user),
+	})
+}
+
+// Profile is what anyone can see of an account.
+type Profile struct {
+	ID          string    `json:"id"`
+	Handle      string    `json:"handle"`
+	DisplayName string    `json:"display_name,omitempty"`
+	Bio         string    `json:"bio,omitempty"`
+	AvatarURL   string    `json:"avatar_url,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	ChirpCount  int64     `json:"chirp_count"`
+	MovedTo     string    `json:"moved_to,omitempty"`
+}
+
+// handle is how an account is referred to publicly: its username, else its
+// public ID, or its UUID when public IDs are off.
+func (cfg *apiConfig) handle(user database.User) string {
+	if user.Username.Valid {
+		return user.Username.String
+	}
+	if handle := cfg.publicID(user.ID); handle != "" {
+		return handle
+	}
+	return user.ID.String()
+}
+
+// authorHandle is the handle shown next to a chirp. It falls back to the
+// author's ID alone when the author can't be looked up.
+func (cfg *apiConfig) authorHandle(ctx context.Context, userID uuid.UUID) string {
+	user, err := cfg.getUser(ctx, userID)
+	if err != nil {
+		return cfg.handle(database.User{ID: userID})
+	}
+	return cfg.handle(user)
+}
+
+// authorHandles returns the handles of the authors of chirps, falling back
+// to their IDs like authorHandle.
+func (cfg *apiConfig) authorHandles(ctx context.Context, chirps []database.Chirp) map[uuid.UUID]string {
+	ids := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		ids = append(ids, chirp.UserID)
+	}
+	users, err := cfg.getUsers(ctx, ids)
+	if err != nil {
+		log.Printf("Couldn't get chirp authors: %v", err)
+	}
+	handles := make(map[uuid.UUID]string, len(ids))
+	for _, id := range ids {
+		user, ok := users[id]
+		if !ok {
+			user = database.User{ID: id}
+		}
+		handles[id] = cfg.handle(user)
+	}
+	return handles
+}
+
+// getUserProfileHandler shows the public profile of the user in the path.
+func (cfg *apiConfig) getUserProfileHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := cfg.activeUserFromPath(w, r)
+	if !ok {
+		return
+	}
+	user, err := cfg.getUser(r.Context(), userId)
+	if err != nil {
+		userPolicy.notFound(w, err)
+		return
+	}
+	chirps, err := cfg.dbQueries.CountUserChirps(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't count chirps", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, Profile{
+		ID:          cfg.exposedID(user.ID),
+		Handle:      cfg.handle(user),
+		DisplayName: user.DisplayName.String,
+		Bio:         user.Bio.String,
+		AvatarURL:   user.AvatarUrl.String,
+		CreatedAt:   user.CreatedAt,
+		ChirpCount:  chirps,
+		MovedTo:     user.MovedTo.String,
 	})
 }
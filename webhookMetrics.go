@@ -0,0 +1,149 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Outcomes of a Polka webhook delivery.
+const (
+	webhookAuthFailed   = "auth_failed"
+	webhookBadRequest   = "bad_request"
+	webhookUnknownEvent = "unknown_event"
+	webhookUserNotFound = "user_not_found"
+	webhookUpgraded     = "upgraded"
+	webhookFailed       = "failed"
+)
+
+// webhookLatencyBuckets are the upper bounds of the latency histogram.
+var webhookLatencyBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+}
+
+// webhookMetrics counts Polka webhook deliveries so operators can tell
+// when billing events stop arriving or start failing.
+type webhookMetrics struct {
+	mu       sync.Mutex
+	received int64
+	outcomes map[string]int64
+	// buckets[i] counts deliveries that took at most
+	// webhookLatencyBuckets[i]; the last one counts the slower rest.
+	buckets       []int64
+	latencySum    time.Duration
+	lastEventAt   time.Time
+	lastUpgradeAt time.Time
+}
+
+func newWebhookMetrics() *webhookMetrics {
+	return &webhookMetrics{
+		outcomes: map[string]int64{},
+		buckets:  make([]int64, len(webhookLatencyBuckets)+1),
+	}
+}
+
+func (m *webhookMetrics) record(outcome string, took time.Duration, at time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.received++
+	m.outcomes[outcome]++
+	m.latencySum += took
+
+	i := 0
+	for i < len(webhookLatencyBuckets) && took > webhookLatencyBuckets[i] {
+		i++
+	}
+	m.buckets[i]++
+
+	// Deliveries Polka couldn't authenticate don't count as events.
+	if outcome != webhookAuthFailed {
+		m.lastEventAt = at
+	}
+	if outcome == webhookUpgraded {
+		m.lastUpgradeAt = at
+	}
+}
+
+type latencyBucket struct {
+	// LeMillis is the bucket's upper bound; nil for the overflow bucket.
+	LeMillis *float64 `json:"le_ms"`
+	Count    int64    `json:"count"`
+}
+
+type webhookStats struct {
+	Received         int64            `json:"received"`
+	Outcomes         map[string]int64 `json:"outcomes"`
+	LatencyMillis    []latencyBucket  `json:"latency_ms"`
+	AvgLatencyMillis float64          `json:"avg_latency_ms"`
+	LastEventAt      *time.Time       `json:"last_event_at"`
+	LastUpgradeAt    *time.Time       `json:"last_upgrade_at"`
+}
+
+func (m *webhookMetrics) stats() webhookStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := webhookStats{
+		Received:      m.received,
+		Outcomes:      map[string]int64{},
+		LatencyMillis: make([]latencyBucket, len(m.buckets)),
+	}
+	for outcome, n := range m.outcomes {
+		s.Outcomes[outcome] = n
+	}
+	for i, n := range m.buckets {
+		s.LatencyMillis[i].Count = n
+		if i < len(webhookLatencyBuckets) {
+			le := float64(webhookLatencyBuckets[i]) / float64(time.Millisecond)
+			s.LatencyMillis[i].LeMillis = &le
+		}
+	}
+	if m.received > 0 {
+		s.AvgLatencyMillis = float64(m.latencySum) / float64(m.received) / float64(time.Millisecond)
+	}
+	if !m.lastEventAt.IsZero() {
+		lastEvent := m.lastEventAt
+		s.LastEventAt = &lastEvent
+	}
+	if !m.lastUpgradeAt.IsZero() {
+		lastUpgrade := m.lastUpgradeAt
+		s.LastUpgradeAt = &lastUpgrade
+	}
+	return s
+}
+
+// getStatsHandler reports operational counters as JSON. Analytics cover
+// the last ?days=, and are left out when they're off.
+func (cfg *apiConfig) getStatsHandler(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Webhooks  webhookStats                       `json:"webhooks"`
+		Rollouts  map[string]map[string]variantStats `json:"rollouts"`
+		Analytics *analyticsStats                    `json:"analytics,omitempty"`
+	}
+	res := response{
+		Webhooks: cfg.webhookMetrics.stats(),
+		Rollouts: cfg.rolloutMetrics.stats(),
+	}
+	if cfg.analytics != nil {
+		days, err := usageDays(r)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid days", err)
+			return
+		}
+		analytics, err := cfg.analyticsStats(r.Context(), days)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't get analytics", err)
+			return
+		}
+		res.Analytics = &analytics
+	}
+	respondWithJSON(w, http.StatusOK, res)
+}
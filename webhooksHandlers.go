@@ -2,9 +2,9 @@ package main
 
 import (
 	"database/sql"
-	"encoding/json"
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/fkl13/chirpy/internal/auth"
 	"github.com/google/uuid"
@@ -18,38 +18,69 @@ func (cfg *apiConfig) addUserSubscribtionHandler(w http.ResponseWriter, r *http.
 		} `json:"data"`
 	}
 
+	start := time.Now()
+	outcome := webhookFailed
+	defer func() {
+		cfg.webhookMetrics.record(outcome, time.Since(start), start)
+	}()
+
 	apiKey, err := auth.GetAPIKey(r.Header)
 	if err != nil {
+		outcome = webhookAuthFailed
 		respondWithError(w, http.StatusUnauthorized, "No api key provided", err)
 		return
 	}
 	if apiKey != cfg.polkaKey {
+		outcome = webhookAuthFailed
 		respondWithError(w, http.StatusUnauthorized, "API key is invalid", err)
 		return
 	}
 
-	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
-	err = decoder.Decode(&params)
+	err = decodeJSONBody(w, r, &params)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		outcome = webhookBadRequest
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
 		return
 	}
 
 	if params.Event != "user.upgraded" {
+		outcome = webhookUnknownEvent
 		respondWithJSON(w, http.StatusNoContent, nil)
 		return
 	}
 
-	_, err = cfg.dbQueries.SetUserMembership(r.Context(), params.Data.UserID)
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	user, err := qtx.SetUserMembership(r.Context(), params.Data.UserID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
+			outcome = webhookUserNotFound
 			respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
 			return
 		}
 		respondWithError(w, http.StatusInternalServerError, "Couldn't set subscription", err)
 		return
 	}
+	err = addOutboxEvent(r.Context(), qtx, eventUserUpgraded, userUpgradedEvent{ID: user.ID})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't store user event", err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't set subscription", err)
+		return
+	}
+	cfg.userCache.Put(user.ID, user)
+	cfg.wakeOutboxRelay()
 
+	outcome = webhookUpgraded
 	respondWithJSON(w, http.StatusNoContent, nil)
 }
@@ -0,0 +1,40 @@
+// Chirpy embed script. Add it to a page once and mark up each chirp as
+//
+//   <div class="chirpy-embed" data-chirp-id="CHIRP_ID" data-theme="dark"></div>
+//
+// Every marker is replaced with an iframe showing the chirp.
+(function () {
+	"use strict";
+
+	var script = document.currentScript;
+	var origin = script ? new URL(script.src).origin : "";
+
+	function embed(el) {
+		var id = el.getAttribute("data-chirp-id");
+		if (!id || el.getAttribute("data-chirpy-embedded")) {
+			return;
+		}
+		var theme = el.getAttribute("data-theme") === "dark" ? "dark" : "light";
+		var frame = document.createElement("iframe");
+		frame.src = origin + "/embed/chirp/" + encodeURIComponent(id) + "?theme=" + theme;
+		frame.title = "Chirp";
+		frame.loading = "lazy";
+		frame.setAttribute("sandbox", "allow-popups");
+		frame.style.border = "0";
+		frame.style.width = "100%";
+		frame.style.maxWidth = "550px";
+		frame.style.height = "180px";
+		el.setAttribute("data-chirpy-embedded", "true");
+		el.replaceChildren(frame);
+	}
+
+	function run() {
+		document.querySelectorAll(".chirpy-embed").forEach(embed);
+	}
+
+	if (document.readyState === "loading") {
+		document.addEventListener("DOMContentLoaded", run);
+	} else {
+		run();
+	}
+})();